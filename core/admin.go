@@ -0,0 +1,128 @@
+package core
+
+// 管理员角色与权限。
+//
+// 角色是权限的固定组合, 不支持逐人配置权限: 群里的分工只有"管人""管词"两类,
+// 逐项勾选只会让名册难以审阅。
+import "log"
+
+// 管理员角色
+const (
+	RoleOwner         = "owner"          // 所有者: 全部权限, 可任免其他管理员、管理受管群
+	RoleModerator     = "moderator"      // 版主: 封禁、撤销处置、维护词表
+	RoleKeywordEditor = "keyword_editor" // 词表编辑: 只能维护关键词与自动回复
+)
+
+// Permission 一类需要授权的管理动作
+type Permission int
+
+const (
+	// PermAdmin 在册即可, 用于只读查看类命令
+	PermAdmin Permission = iota
+	// PermKeywords 维护关键词与自动回复
+	PermKeywords
+	// PermModerate 封禁、撤销处置, 并接收处置通知
+	PermModerate
+	// PermManageAdmins 任免管理员
+	PermManageAdmins
+	// PermManageGroups 登记受管群与修改群设置
+	PermManageGroups
+)
+
+// rolePermissions 每个角色拥有的权限, PermAdmin 对所有在册角色隐式成立
+var rolePermissions = map[string][]Permission{
+	RoleOwner:         {PermKeywords, PermModerate, PermManageAdmins, PermManageGroups},
+	RoleModerator:     {PermKeywords, PermModerate},
+	RoleKeywordEditor: {PermKeywords},
+}
+
+// roleLabels 角色的中文名, 用于命令回复与名册展示
+var roleLabels = map[string]string{
+	RoleOwner:         "所有者",
+	RoleModerator:     "版主",
+	RoleKeywordEditor: "词表编辑",
+}
+
+// ValidRole 判断角色名是否合法
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleLabel 返回角色的中文名, 未知角色原样返回
+func RoleLabel(role string) string {
+	if label, ok := roleLabels[role]; ok {
+		return label
+	}
+	return role
+}
+
+// RoleNames 返回全部角色名, 按权限从大到小排列, 用于提示管理员可选值
+func RoleNames() []string {
+	return []string{RoleOwner, RoleModerator, RoleKeywordEditor}
+}
+
+// RoleOf 返回用户的角色, 不是管理员返回空字符串。
+// ADMIN_ID 恒为所有者, 不查库; 查库失败按"不是管理员"处理 —— 宁可让管理员重试, 不可误放权限。
+func RoleOf(userID int64) string {
+	if userID == AdminID {
+		return RoleOwner
+	}
+	if DB == nil {
+		return ""
+	}
+
+	role, err := DB.GetAdminRole(userID)
+	if err != nil {
+		log.Printf("[Core] 读取管理员名册失败: %v", err)
+		return ""
+	}
+	return role
+}
+
+// IsAdmin 判断用户是否为任一角色的管理员; 管理员的群消息不走内容审核
+func IsAdmin(userID int64) bool {
+	return RoleOf(userID) != ""
+}
+
+// Can 判断用户是否拥有指定权限
+func Can(userID int64, perm Permission) bool {
+	return roleAllows(RoleOf(userID), perm)
+}
+
+// roleAllows 判断角色是否拥有指定权限, 与名册读取分开以便单测
+func roleAllows(role string, perm Permission) bool {
+	perms, ok := rolePermissions[role]
+	if !ok {
+		return false
+	}
+	if perm == PermAdmin {
+		return true
+	}
+	for _, p := range perms {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminsWith 列出拥有指定权限的全部管理员 ID (含 ADMIN_ID), 用于推送通知
+func AdminsWith(perm Permission) []int64 {
+	ids := []int64{AdminID}
+	if DB == nil {
+		return ids
+	}
+
+	admins, err := DB.GetAdmins()
+	if err != nil {
+		log.Printf("[Core] 读取管理员名册失败, 只通知所有者: %v", err)
+		return ids
+	}
+	for _, admin := range admins {
+		if admin.UserID != AdminID && roleAllows(admin.Role, perm) {
+			ids = append(ids, admin.UserID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,76 @@
+package core
+
+// 权限判断直接决定谁能封人、谁能改词, 错一处就是越权, 因此每个角色的边界都要钉死。
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRoleAllows(t *testing.T) {
+	cases := []struct {
+		role string
+		perm Permission
+		want bool
+	}{
+		{RoleOwner, PermManageAdmins, true},
+		{RoleOwner, PermModerate, true},
+		{RoleModerator, PermModerate, true},
+		{RoleModerator, PermKeywords, true},
+		{RoleModerator, PermManageAdmins, false}, // 版主不能任免, 否则可以给自己升级
+		{RoleKeywordEditor, PermKeywords, true},
+		{RoleKeywordEditor, PermModerate, false},
+		{RoleKeywordEditor, PermAdmin, true},
+		{"", PermAdmin, false}, // 不在册的人连只读命令也不能用
+		{"superuser", PermKeywords, false},
+	}
+
+	for _, c := range cases {
+		if got := roleAllows(c.role, c.perm); got != c.want {
+			t.Errorf("roleAllows(%q, %d) = %v, 期望 %v", c.role, c.perm, got, c.want)
+		}
+	}
+}
+
+// TestAdminRoster 任免要即时生效, 不能被名册缓存挡住
+func TestAdminRoster(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "admins.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	const userID = int64(1001)
+
+	if role, err := db.GetAdminRole(userID); err != nil || role != "" {
+		t.Fatalf("未任命时角色 = %q (err=%v), 期望空", role, err)
+	}
+
+	if err := db.SetAdminRole(userID, RoleKeywordEditor, 1); err != nil {
+		t.Fatalf("任命失败: %v", err)
+	}
+	if role, _ := db.GetAdminRole(userID); role != RoleKeywordEditor {
+		t.Errorf("任命后角色 = %q, 期望 %q", role, RoleKeywordEditor)
+	}
+
+	// 改角色是覆盖而不是新增一行
+	if err := db.SetAdminRole(userID, RoleModerator, 1); err != nil {
+		t.Fatalf("修改角色失败: %v", err)
+	}
+	if role, _ := db.GetAdminRole(userID); role != RoleModerator {
+		t.Errorf("修改后角色 = %q, 期望 %q", role, RoleModerator)
+	}
+	if admins, _ := db.GetAdmins(); len(admins) != 1 {
+		t.Errorf("名册人数 = %d, 期望 1", len(admins))
+	}
+
+	removed, err := db.RemoveAdmin(userID)
+	if err != nil || !removed {
+		t.Fatalf("撤销失败: removed=%v err=%v", removed, err)
+	}
+	if role, _ := db.GetAdminRole(userID); role != "" {
+		t.Errorf("撤销后角色 = %q, 期望空", role)
+	}
+	if removed, _ := db.RemoveAdmin(userID); removed {
+		t.Error("重复撤销应当返回 false")
+	}
+}
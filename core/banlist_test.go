@@ -0,0 +1,48 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestBannedUserList 名单以首次入名单为准, 导入跳过已有条目, 搜索区分 ID 与文本
+func TestBannedUserList(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "banlist.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	if added, err := db.AddBannedUser(BannedUser{UserID: 42, UserName: "水果机 @fruit", Reason: "关键词：水果机", ActionID: 7, ChatID: -100}); !added || err != nil {
+		t.Fatalf("AddBannedUser = %v,%v", added, err)
+	}
+	// 在别的群被顺带封禁时不改写原始来源, 否则撤销原处置时就找不到它了
+	if added, _ := db.AddBannedUser(BannedUser{UserID: 42, Reason: "管理员 /ban", ChatID: -200}); added {
+		t.Error("已在名单上的账号不应重复加入")
+	}
+	if entry, ok, _ := db.GetBannedUser(42); !ok || entry.ActionID != 7 || entry.ChatID != -100 {
+		t.Errorf("原始记录被改写: %+v", entry)
+	}
+
+	added, err := db.ImportBannedUsers([]BannedUser{{UserID: 42}, {UserID: 43, Reason: "导入"}, {UserID: 44, Reason: "导入"}})
+	if err != nil || added != 2 {
+		t.Errorf("ImportBannedUsers = %d,%v, 期望新增 2 条", added, err)
+	}
+
+	if found, _ := db.SearchBannedUsers("43", 10); len(found) != 1 || found[0].UserID != 43 {
+		t.Errorf("按 ID 搜索结果 = %+v", found)
+	}
+	if found, _ := db.SearchBannedUsers("fruit", 10); len(found) != 1 || found[0].UserID != 42 {
+		t.Errorf("按用户名搜索结果 = %+v", found)
+	}
+	if entries, total, _ := db.ListBannedUsers(2); total != 3 || len(entries) != 2 {
+		t.Errorf("ListBannedUsers = %d 条 / 共 %d, 期望 2 / 3", len(entries), total)
+	}
+
+	if removed, _ := db.RemoveBannedUser(42); !removed {
+		t.Error("移出名单失败")
+	}
+	if _, ok, _ := db.GetBannedUser(42); ok {
+		t.Error("移出后仍在名单上")
+	}
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestJoinChallengeLifecycle 验证题可覆盖、可按超时列出, 且只能被认领一次
+func TestJoinChallengeLifecycle(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "captcha.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	if err := db.SaveChallenge(JoinChallenge{ChatID: -100, UserID: 1, Kind: CaptchaMath, Answer: 2, MessageID: 10, ExpiresAt: now.Add(-time.Second)}); err != nil {
+		t.Fatalf("SaveChallenge 失败: %v", err)
+	}
+	// 同一个人退群重进, 新题覆盖旧题
+	if err := db.SaveChallenge(JoinChallenge{ChatID: -100, UserID: 1, Kind: CaptchaEmoji, Answer: 4, MessageID: 11, ExpiresAt: now.Add(time.Minute)}); err != nil {
+		t.Fatalf("覆盖验证题失败: %v", err)
+	}
+	if err := db.SaveChallenge(JoinChallenge{ChatID: -100, UserID: 2, Kind: CaptchaMath, MessageID: 12, ExpiresAt: now.Add(-time.Second)}); err != nil {
+		t.Fatalf("SaveChallenge 失败: %v", err)
+	}
+
+	if challenge, ok, _ := db.GetChallenge(-100, 1); !ok || challenge.Answer != 4 || challenge.MessageID != 11 {
+		t.Errorf("GetChallenge = %+v,%v, 期望读到覆盖后的题", challenge, ok)
+	}
+	expired, err := db.ExpiredChallenges(now)
+	if err != nil || len(expired) != 1 || expired[0].UserID != 2 {
+		t.Errorf("ExpiredChallenges = %+v,%v, 期望只有用户 2", expired, err)
+	}
+
+	if claimed, err := db.ClaimChallenge(-100, 2); !claimed || err != nil {
+		t.Errorf("首次认领 = %v,%v", claimed, err)
+	}
+	if claimed, _ := db.ClaimChallenge(-100, 2); claimed {
+		t.Error("同一道题不应被认领两次")
+	}
+	if _, ok, _ := db.GetChallenge(-100, 2); ok {
+		t.Error("认领后验证题应已删除")
+	}
+}
+
+// TestRecordVerificationResetsCount 通过验证后发言计数从零开始, 新用户窗口重新计算
+func TestRecordVerificationResetsCount(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "verify.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	for range 5 {
+		if _, _, err := db.BumpUserMessageCount(1, -100); err != nil {
+			t.Fatalf("BumpUserMessageCount 失败: %v", err)
+		}
+	}
+	if err := db.RecordVerification(1, -100, true); err != nil {
+		t.Fatalf("RecordVerification 失败: %v", err)
+	}
+	if stat, _, _ := db.BumpUserMessageCount(1, -100); stat.MessageCount != 1 {
+		t.Errorf("验证后第一条消息计数 = %d, 期望 1", stat.MessageCount)
+	}
+
+	// 没发过言的新成员同样留下验证记录
+	if err := db.RecordVerification(2, -100, true); err != nil {
+		t.Fatalf("RecordVerification 失败: %v", err)
+	}
+	var stat UserStat
+	if err := db.db.Where("user_id = ? AND chat_id = ?", 2, -100).First(&stat).Error; err != nil || stat.VerifiedAt.IsZero() {
+		t.Errorf("新成员的验证记录 = %+v,%v", stat, err)
+	}
+}
+
+// TestRecordVerificationKeepsVeteranCount 老成员退群重进再通过验证, 发言计数不清零, 不被当成新人重审
+func TestRecordVerificationKeepsVeteranCount(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "verify.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	if firstJoin, err := db.RecordJoin(2, -100); err != nil || !firstJoin {
+		t.Fatalf("首次入群 RecordJoin = %v,%v, 期望新建记录", firstJoin, err)
+	}
+	for range 5 {
+		if _, _, err := db.BumpUserMessageCount(1, -100); err != nil {
+			t.Fatalf("BumpUserMessageCount 失败: %v", err)
+		}
+	}
+	firstJoin, err := db.RecordJoin(1, -100)
+	if err != nil || firstJoin {
+		t.Fatalf("重新入群 RecordJoin = %v,%v, 不应算首次入群", firstJoin, err)
+	}
+	if err := db.RecordVerification(1, -100, firstJoin); err != nil {
+		t.Fatalf("RecordVerification 失败: %v", err)
+	}
+	if stat, _, _ := db.GetUserStat(1, -100); stat.MessageCount != 5 || stat.VerifiedAt.IsZero() {
+		t.Errorf("老成员验证后的统计 = %+v, 期望保留 5 条并记下验证时间", stat)
+	}
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParseChannelRef(t *testing.T) {
+	cases := map[string]string{
+		"-1001234567890":         "-1001234567890",
+		"@SpamHub":               "spamhub",
+		"https://t.me/Spam_Hub/": "spam_hub",
+		"t.me/spamhub":           "spamhub",
+		"  spamhub ":             "spamhub",
+	}
+	for raw, want := range cases {
+		if got, err := ParseChannelRef(raw); err != nil || got != want {
+			t.Errorf("ParseChannelRef(%q) = %q,%v, 期望 %q", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"5912366993", "@ab", "水果频道", "t.me/+invite"} {
+		if _, err := ParseChannelRef(raw); err == nil {
+			t.Errorf("ParseChannelRef(%q) 应当报错", raw)
+		}
+	}
+}
+
+// TestSourceChannelLists 同一个频道只在一张名单上, 改名单即时生效
+func TestSourceChannelLists(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "channels.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.SetSourceChannel(SourceChannel{Ref: "spamhub", List: ChannelBlock}); err != nil {
+		t.Fatalf("SetSourceChannel 失败: %v", err)
+	}
+	if entry, ok, _ := db.LookupSourceChannel(-1001, "SpamHub"); !ok || entry.List != ChannelBlock {
+		t.Errorf("按用户名查找 = %+v,%v", entry, ok)
+	}
+
+	if err := db.SetSourceChannel(SourceChannel{Ref: "spamhub", List: ChannelAllow}); err != nil {
+		t.Fatalf("改名单失败: %v", err)
+	}
+	if entry, _, _ := db.LookupSourceChannel(-1001, "spamhub"); entry.List != ChannelAllow {
+		t.Errorf("改名单后 = %+v, 期望白名单", entry)
+	}
+	if err := db.SetSourceChannel(SourceChannel{Ref: "x", List: "grey"}); err == nil {
+		t.Error("未知的名单应当报错")
+	}
+
+	if removed, _ := db.RemoveSourceChannel("spamhub"); !removed {
+		t.Error("RemoveSourceChannel 应当删除成功")
+	}
+	if _, ok, _ := db.LookupSourceChannel(-1001, "spamhub"); ok {
+		t.Error("移出后不应再查到")
+	}
+}
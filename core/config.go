@@ -4,8 +4,11 @@ package core
 import (
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -18,16 +21,16 @@ var (
 	Bot *tgbotapi.BotAPI
 
 	BotToken   string
-	ChatID     int64
-	AdminID    int64
-	Symbols    []string // 币安交易对, 为空表示只提供查询不主动推送
+	ChatID     int64    // 启动时自动登记的首个受管群, 0 表示未配置
+	AdminID    int64    // 所有者; 其余管理员在 admins 表里由所有者任免
+	Symbols    []string // 新登记群默认推送的币安交易对, 为空表示只提供查询不主动推送
 	BusinessTZ *time.Location
 	DBFile     string
 	DebugMode  bool
 
-	// AutoBanThreshold 累计违规多少次后自动封禁, 0 表示只删消息不封禁
+	// AutoBanThreshold 新登记群的默认封禁阈值: 累计违规多少次后自动封禁, 0 表示只删消息不封禁
 	AutoBanThreshold int
-	// DeleteServiceMessages 是否自动清理"加入/退出群组"这类群务通知
+	// DeleteServiceMessages 新登记群是否默认清理"加入/退出群组"这类群务通知
 	DeleteServiceMessages bool
 	// BackupKeep 保留最近多少份数据库快照, 0 表示不清理旧快照
 	BackupKeep int
@@ -42,6 +45,31 @@ var (
 	AIHourlyBudget     int // 全局每小时最大调用次数, 防止异常情况下跑量
 	AIMinConfidence    float64
 	AICurationInterval time.Duration // AI 整理词表的间隔
+	// 试行配置, 命中只留痕不处置, 用来评估放宽阈值或换模型的效果; 零值表示不试行
+	AIShadowMinConfidence float64 // 试行阈值, 置信度落在它与 AIMinConfidence 之间的判定记为"本会拦截"
+	AIShadowModel         string  // 试行模型, 与主模型并行判定同一批消息
+	// AIVisionModel 识图模型, 用于没有说明文字的图片; 为空表示不审图片
+	AIVisionModel string
+
+	// 短链展开: 开启后对已知短链服务的链接跟随跳转, 用落地页的域名与地址做检查; 关闭时只看短链域名本身
+	ShortLinkResolve bool
+	ShortLinkHosts   []string // 内置清单之外的短链域名
+
+	// HTTPAddr 网页面板与管理 API 共用的监听地址, 如 :8080; 为空时两者都不启动
+	HTTPAddr string
+	// 网页管理面板: 登录方式至少开一种, 否则面板不挂载
+	DashboardToken         string // 口令登录, 登录后按所有者对待
+	DashboardTelegramLogin bool   // Telegram 登录, 需要先在 BotFather 为机器人绑定面板域名
+	// APIToken 管理 API 的 Bearer 口令, 为空时不挂载 API
+	APIToken string
+	// MetricsEnabled 是否在 HTTPAddr 上暴露 Prometheus 的 /metrics; 指标不含消息内容与用户 ID, 不设鉴权
+	MetricsEnabled bool
+
+	// 接收更新的方式: WebhookURL 为空时走长轮询; 设置后改用 webhook, 在 WebhookAddr 上单独监听
+	WebhookURL     string // Telegram 推送的公网地址, 必须是 https; 路径部分同时是本地监听的路径
+	WebhookAddr    string // 本地监听地址, 由反向代理转发过来
+	WebhookSecret  string // 校验请求头 X-Telegram-Bot-Api-Secret-Token; 为空时每次启动随机生成
+	WebhookWorkers int    // 并发处理更新的 worker 数
 
 	DB *Database
 )
@@ -57,6 +85,8 @@ const (
 	defaultCurationInterval = 7 * 24 * time.Hour
 	defaultTimezone         = "Asia/Shanghai"
 	defaultBackupKeep       = 7
+	defaultWebhookAddr      = ":8443"
+	defaultWebhookWorkers   = 16
 )
 
 // Init 按依赖顺序完成启动初始化, 任一必需项缺失都返回错误由 main 终止进程
@@ -70,8 +100,11 @@ func Init() error {
 	if AdminID, err = parseInt64(os.Getenv("ADMIN_ID")); err != nil {
 		return fmt.Errorf("invalid ADMIN_ID: %w", err)
 	}
-	if ChatID, err = parseInt64(os.Getenv("CHAT_ID")); err != nil {
-		return fmt.Errorf("invalid CHAT_ID: %w", err)
+	// CHAT_ID 可选: 配了就在启动时登记为首个受管群, 其余群由管理员用 /addgroup 登记
+	if raw := cleanEnvValue(os.Getenv("CHAT_ID")); raw != "" {
+		if ChatID, err = parseInt64(raw); err != nil {
+			return fmt.Errorf("invalid CHAT_ID: %w", err)
+		}
 	}
 
 	DebugMode = os.Getenv("DEBUG_MODE") == "true"
@@ -79,6 +112,16 @@ func Init() error {
 	AutoBanThreshold = parseIntEnv("AUTO_BAN_THRESHOLD", defaultAutoBanThreshold)
 	DeleteServiceMessages = parseBoolEnv("DELETE_SERVICE_MESSAGES", true)
 	BackupKeep = parseIntEnv("BACKUP_KEEP", defaultBackupKeep)
+	ShortLinkResolve = parseBoolEnv("SHORT_LINK_RESOLVE", false)
+	ShortLinkHosts = parseHosts(os.Getenv("SHORT_LINK_HOSTS"))
+	HTTPAddr = strings.TrimSpace(os.Getenv("HTTP_ADDR"))
+	DashboardToken = strings.TrimSpace(os.Getenv("DASHBOARD_TOKEN"))
+	DashboardTelegramLogin = parseBoolEnv("DASHBOARD_TELEGRAM_LOGIN", false)
+	APIToken = strings.TrimSpace(os.Getenv("API_TOKEN"))
+	MetricsEnabled = parseBoolEnv("METRICS_ENABLED", false)
+	if err := initWebhookConfig(); err != nil {
+		return err
+	}
 	initAIConfig()
 	BusinessTZ = loadBusinessTZ(envOr("TZ", defaultTimezone))
 	time.Local = BusinessTZ
@@ -88,8 +131,10 @@ func Init() error {
 		return fmt.Errorf("初始化数据库失败: %w", err)
 	}
 	logTableCounts()
+	seedPrimaryGroup()
 
-	if Bot, err = tgbotapi.NewBotAPI(BotToken); err != nil {
+	// 传输层套一层计数, 各模块的 Bot API 调用失败都记入 sunai_telegram_errors_total
+	if Bot, err = tgbotapi.NewBotAPIWithClient(BotToken, tgbotapi.APIEndpoint, telegramClient{client: &http.Client{}}); err != nil {
 		return fmt.Errorf("创建 Bot API 失败: %w", err)
 	}
 	// Debug 必须在任何模块开始用 Bot 之前设定, 之后不再写入, 避免与并发的 Send 竞争
@@ -99,11 +144,6 @@ func Init() error {
 	return nil
 }
 
-// IsAdmin 判断用户是否为机器人管理员
-func IsAdmin(userID int64) bool {
-	return userID == AdminID
-}
-
 // parseInt64 解析必填的数字型环境变量, 空值视为错误。
 // 面板里粘贴的值常带尾随空格, 不清理会让机器人直接起不来。
 func parseInt64(s string) (int64, error) {
@@ -134,6 +174,30 @@ func cleanEnvValue(s string) string {
 	return strings.TrimSpace(s)
 }
 
+// webhookSecretPattern Telegram 对 secret_token 的要求: 1-256 个字母、数字、下划线或连字符
+var webhookSecretPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// initWebhookConfig 读取 webhook 配置。配错了直接报错退出, 而不是悄悄退回长轮询:
+// 反向代理那边已经按 webhook 部署, 静默换回轮询只会让人以为机器人收不到消息。
+func initWebhookConfig() error {
+	WebhookURL = cleanEnvValue(os.Getenv("WEBHOOK_URL"))
+	WebhookAddr = envOr("WEBHOOK_ADDR", defaultWebhookAddr)
+	WebhookSecret = cleanEnvValue(os.Getenv("WEBHOOK_SECRET"))
+	WebhookWorkers = max(parseIntEnv("WEBHOOK_WORKERS", defaultWebhookWorkers), 1)
+	if WebhookURL == "" {
+		return nil
+	}
+
+	link, err := url.Parse(WebhookURL)
+	if err != nil || link.Scheme != "https" || link.Host == "" {
+		return fmt.Errorf("WEBHOOK_URL %q 无效, Telegram 只接受 https 地址", WebhookURL)
+	}
+	if WebhookSecret != "" && !webhookSecretPattern.MatchString(WebhookSecret) {
+		return fmt.Errorf("WEBHOOK_SECRET 只能包含字母、数字、下划线和连字符, 长度不超过 256")
+	}
+	return nil
+}
+
 // initAIConfig 读取 AI 审核相关配置。
 // AI_API_KEY 缺失即整层关闭 —— 没有密钥时静默降级到确定性规则, 而不是每条消息都报错。
 func initAIConfig() {
@@ -145,11 +209,20 @@ func initAIConfig() {
 	AIHourlyBudget = parseIntEnv("AI_HOURLY_BUDGET", defaultAIHourlyBudget)
 	AIMinConfidence = parseFloatEnv("AI_MIN_CONFIDENCE", defaultAIMinConfidence)
 	AICurationInterval = defaultCurationInterval
+	AIShadowMinConfidence = parseFloatEnv("AI_SHADOW_MIN_CONFIDENCE", 0)
+	AIShadowModel = strings.TrimSpace(os.Getenv("AI_SHADOW_MODEL"))
+	AIVisionModel = strings.TrimSpace(os.Getenv("AI_VISION_MODEL"))
 
 	AIEnabled = AIAPIKey != "" && parseBoolEnv("AI_ENABLED", true)
 	if AIEnabled {
 		log.Printf("[Core] AI 审核已启用: model=%s effort=%s 新用户前 %d 条 每小时上限 %d 次",
 			AIModel, AIReasoningEffort, AINewUserMessages, AIHourlyBudget)
+		if AIShadowMinConfidence > 0 || AIShadowModel != "" {
+			log.Printf("[Core] AI 试行中: 阈值=%.2f 模型=%q (命中只记录, 不处置)", AIShadowMinConfidence, AIShadowModel)
+		}
+		if AIVisionModel != "" {
+			log.Printf("[Core] AI 图片审核已启用: model=%s", AIVisionModel)
+		}
 	} else {
 		log.Println("[Core] AI 审核未启用 (AI_API_KEY 未设置), 只运行确定性规则")
 	}
@@ -218,6 +291,23 @@ func parseSymbols(raw string) []string {
 	return symbols
 }
 
+// parseHosts 把逗号分隔的域名列表解析为小写主机名; 无法识别的写法跳过并告警
+func parseHosts(raw string) []string {
+	var hosts []string
+	for _, s := range strings.Split(raw, ",") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		host := HostOf(s)
+		if host == "" {
+			log.Printf("[Core] 无法识别的域名 %q, 已跳过", s)
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
 // loadBusinessTZ 按名称加载业务时区; 镜像缺 tzdata 或名称非法时回退到固定 +8 偏移。
 // 不静默回落到 UTC —— 那会让所有时间显示悄悄错 8 小时。
 func loadBusinessTZ(name string) *time.Location {
@@ -238,6 +328,7 @@ func logTableCounts() {
 	}{
 		{"keywords", &Keyword{}},
 		{"prompt_replies", &PromptReply{}},
+		{"managed_groups", &ManagedGroup{}},
 	}
 
 	for _, t := range tables {
@@ -117,3 +117,39 @@ func TestParseSymbols(t *testing.T) {
 		t.Errorf("parseSymbols 未去掉斜杠: %v", got)
 	}
 }
+
+// TestParseHosts 域名列表容忍空格、协议与空项, 无法识别的跳过
+func TestParseHosts(t *testing.T) {
+	got := parseHosts(" xx.gd, https://YY.cc/ ,,localhost")
+	if len(got) != 2 || got[0] != "xx.gd" || got[1] != "yy.cc" {
+		t.Errorf("parseHosts = %v", got)
+	}
+}
+
+// TestInitWebhookConfig 没配 WEBHOOK_URL 走长轮询; 配了就必须是 https, 口令只能含 Telegram 允许的字符
+func TestInitWebhookConfig(t *testing.T) {
+	cases := []struct {
+		url, secret string
+		ok          bool
+	}{
+		{"", "", true},
+		{"https://bot.example.com/telegram", "", true},
+		{"https://bot.example.com/telegram  # 反向代理地址", "abc_DEF-123", true},
+		{"http://bot.example.com/telegram", "", false},
+		{"bot.example.com/telegram", "", false},
+		{"https://bot.example.com/telegram", "含空格 的口令", false},
+	}
+	for _, c := range cases {
+		t.Setenv("WEBHOOK_URL", c.url)
+		t.Setenv("WEBHOOK_SECRET", c.secret)
+		if err := initWebhookConfig(); (err == nil) != c.ok {
+			t.Errorf("WEBHOOK_URL=%q WEBHOOK_SECRET=%q: err = %v", c.url, c.secret, err)
+		}
+	}
+
+	t.Setenv("WEBHOOK_WORKERS", "0")
+	initWebhookConfig()
+	if WebhookWorkers < 1 {
+		t.Errorf("WEBHOOK_WORKERS=0 时 worker 数 = %d, 至少应为 1", WebhookWorkers)
+	}
+}
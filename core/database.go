@@ -10,6 +10,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 
@@ -26,27 +27,80 @@ type cacheKind int
 
 const (
 	cacheKeywords cacheKind = iota
+	cacheAdmins
+	cacheGroups
+	cacheRuleModes
+	cacheSourceChannels
+	cacheLinkDomains
+	cacheMediaHashes
 )
 
-// cachedList 带加载时间的字符串列表缓存, 零值表示尚未加载
-type cachedList struct {
-	items    []string
+// cachedList 带加载时间的列表缓存, 零值表示尚未加载
+type cachedList[T comparable] struct {
+	items    []T
 	loadedAt time.Time
+	// derived 由 items 推导出的结构 (如关键词自动机), 构建代价高, 只在 items 真正变化时丢弃重建
+	derived any
 }
 
 // expired 判断缓存未加载或已超过 TTL
-func (c *cachedList) expired() bool {
+func (c *cachedList[T]) expired() bool {
 	return c.items == nil || time.Since(c.loadedAt) > cacheTTL
 }
 
+// cachedMap 按键索引的整表缓存, 用于名册、群设置这类行数少但每条消息都要查的表
+type cachedMap[K comparable, V any] struct {
+	items    map[K]V
+	loadedAt time.Time
+}
+
+// expired 判断缓存未加载或已超过 TTL
+func (c *cachedMap[K, V]) expired() bool {
+	return c.items == nil || time.Since(c.loadedAt) > cacheTTL
+}
+
+// lookupCached 在整表缓存里按键取值, 未命中 TTL 时回源全量重载; 调用方不得持有 d.mu
+func lookupCached[K comparable, V any](d *Database, cache *cachedMap[K, V], key K, load func() (map[K]V, error)) (V, bool, error) {
+	items, err := cachedItems(d, cache, load)
+	if err != nil {
+		var zero V
+		return zero, false, err
+	}
+	value, ok := items[key]
+	return value, ok, nil
+}
+
+// cachedItems 返回整表缓存, 用于需要遍历全表的查找。
+// 重载与失效都是整个换掉 map 而不是原地修改, 因此返回的 map 在锁外读也安全, 但调用方不得修改它
+func cachedItems[K comparable, V any](d *Database, cache *cachedMap[K, V], load func() (map[K]V, error)) (map[K]V, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if cache.expired() {
+		items, err := load()
+		if err != nil {
+			return nil, err
+		}
+		cache.items = items
+		cache.loadedAt = time.Now()
+	}
+	return cache.items, nil
+}
+
 type Database struct {
 	db *gorm.DB
 	// path 库文件路径, 快照要据此定位存放目录; 不复用全局 DBFile 是为了让测试能指向临时目录
 	path string
 
 	// mu 保护下面所有缓存字段
-	mu             sync.Mutex
-	manualKeywords cachedList // 参与匹配的关键词, 每条群消息都要读
+	mu          sync.Mutex
+	keywords    map[Scope]*cachedList[Keyword]   // 参与匹配的关键词与规则, 按作用域各自缓存; 每条群消息都要读
+	adminRoster cachedMap[int64, string]         // 管理员名册, 每条群消息都要判断发送者是否豁免
+	groups      cachedMap[int64, ManagedGroup]   // 受管群设置, 每条群消息都要判断是否受管
+	ruleModes   cachedMap[string, string]        // 内置规则的执行模式, 每条群消息都要查
+	channels    cachedMap[string, SourceChannel] // 来源频道黑白名单, 每条转发或频道身份发言都要查
+	domains     cachedMap[string, LinkDomain]    // 链接域名黑白名单, 每条带链接的消息都要查
+	media       cachedMap[string, MediaHash]     // 已知广告图片的特征, 每条图片、贴纸消息都要查
 }
 
 // NewDatabase 打开 SQLite 连接并把 schema 迁移到最新
@@ -69,7 +123,7 @@ func NewDatabase() (*Database, error) {
 		return nil, fmt.Errorf("打开数据库失败: %w", err)
 	}
 
-	database := &Database{db: db, path: DBFile}
+	database := &Database{db: db, path: DBFile, keywords: make(map[Scope]*cachedList[Keyword])}
 
 	// 顺序不能反: 先快照再采样, 最后才动 schema。快照是唯一的回滚手段,
 	// 采样是发现"迁移把数据搞没了"的唯一手段 —— 两者都必须在迁移之前完成。
@@ -100,7 +154,7 @@ func NewDatabaseAt(path string) (*Database, error) {
 		return nil, err
 	}
 
-	database := &Database{db: db, path: path}
+	database := &Database{db: db, path: path, keywords: make(map[Scope]*cachedList[Keyword])}
 	if err := database.migrate(); err != nil {
 		return nil, err
 	}
@@ -120,35 +174,83 @@ func (d *Database) migrate() error {
 
 // queryCached 读取带 TTL 的列表缓存, 未命中时回源查库。
 // 返回的是副本, 调用方修改不会污染缓存。
-func (d *Database) queryCached(kind cacheKind, load func() ([]string, error)) ([]string, error) {
+func queryCached[T comparable](d *Database, cache func() *cachedList[T], load func() ([]T, error)) ([]T, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	cache := d.cacheOf(kind)
-	if cache.expired() {
-		items, err := load()
-		if err != nil {
-			return nil, err
-		}
-		cache.items = items
-		cache.loadedAt = time.Now()
+	list := cache()
+	if err := refreshCached(list, load); err != nil {
+		return nil, err
 	}
 
-	result := make([]string, len(cache.items))
-	copy(result, cache.items)
+	result := make([]T, len(list.items))
+	copy(result, list.items)
 	return result, nil
 }
 
-// invalidateCache 清空指定列表缓存, 由写操作在提交后调用
+// refreshCached 缓存过期时回源重载; 调用方必须已持有 d.mu。
+// TTL 到期重载出的内容与原来一致时保留 derived, 词表没变就不必重建派生结构。
+func refreshCached[T comparable](list *cachedList[T], load func() ([]T, error)) error {
+	if !list.expired() {
+		return nil
+	}
+
+	items, err := load()
+	if err != nil {
+		return err
+	}
+	if items == nil {
+		items = []T{} // 空表也要算"已加载", 否则每次都回源
+	}
+	if !slices.Equal(items, list.items) {
+		list.derived = nil
+	}
+	list.items = items
+	list.loadedAt = time.Now()
+	return nil
+}
+
+// invalidateCache 清空指定种类的全部缓存, 由写操作在提交后调用
 func (d *Database) invalidateCache(kind cacheKind) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	*d.cacheOf(kind) = cachedList{}
+
+	switch kind {
+	case cacheAdmins:
+		d.adminRoster = cachedMap[int64, string]{}
+	case cacheGroups:
+		d.groups = cachedMap[int64, ManagedGroup]{}
+	case cacheRuleModes:
+		d.ruleModes = cachedMap[string, string]{}
+	case cacheSourceChannels:
+		d.channels = cachedMap[string, SourceChannel]{}
+	case cacheLinkDomains:
+		d.domains = cachedMap[string, LinkDomain]{}
+	case cacheMediaHashes:
+		d.media = cachedMap[string, MediaHash]{}
+	case cacheKeywords:
+		clear(d.keywords)
+	}
+}
+
+// invalidateKeywordScope 只清空一个作用域的词表缓存, 用于已知改动落在哪个作用域的写操作
+func (d *Database) invalidateKeywordScope(scope Scope) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.keywords, scope)
 }
 
-// cacheOf 取指定种类的缓存指针; 调用方必须已持有 d.mu
-func (d *Database) cacheOf(cacheKind) *cachedList {
-	return &d.manualKeywords
+// keywordCache 取某个作用域的词表缓存, 没有则建一个空的, 未过期即记一次命中; 调用方必须已持有 d.mu
+func (d *Database) keywordCache(scope Scope) *cachedList[Keyword] {
+	list, ok := d.keywords[scope]
+	if !ok {
+		list = &cachedList[Keyword]{}
+		d.keywords[scope] = list
+	}
+	if !list.expired() {
+		keywordCacheHits.Inc()
+	}
+	return list
 }
 
 // CountRecords 统计指定模型的记录数
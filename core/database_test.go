@@ -132,10 +132,10 @@ func TestAutoMigratePreservesLegacyData(t *testing.T) {
 
 	// batch2/3 才引入的表在老库里不存在, AutoMigrate 应当把它们建出来并可正常读写
 	t.Run("新增表可用", func(t *testing.T) {
-		if _, err := db.AddStrike(1001, -100200); err != nil {
+		if _, err := db.AddStrike(1001, -100200, 1); err != nil {
 			t.Errorf("写入违规计分失败: %v", err)
 		}
-		if _, err := db.BumpUserMessageCount(1001, -100200); err != nil {
+		if _, _, err := db.BumpUserMessageCount(1001, -100200); err != nil {
 			t.Errorf("写入发言统计失败: %v", err)
 		}
 		if err := db.RejectKeyword("多少"); err != nil {
@@ -159,13 +159,13 @@ func TestAutoMigrateAddsNoDuplicateColumns(t *testing.T) {
 	defer db.Close()
 
 	expected := map[string][]string{
-		"keywords":           {"id", "keyword", "is_link", "is_auto_added", "added_at", "source", "hit_count"},
+		"keywords":           {"id", "keyword", "is_link", "is_auto_added", "added_at", "source", "hit_count", "kind", "mode", "scope_chat_id", "scope_topic_id"},
 		"prompt_replies":     {"prompt", "reply"},
 		"config":             {"key", "value"},
 		"keyword_rejects":    {"keyword", "rejected_at"},
 		"user_strikes":       {"user_id", "chat_id", "strikes", "last_hit_at"},
-		"user_stats":         {"user_id", "chat_id", "message_count", "first_seen_at", "last_seen_at"},
-		"moderation_actions": {"id", "user_id", "chat_id", "user_name", "message_text", "rule", "learned_words", "banned", "undone", "created_at"},
+		"user_stats":         {"user_id", "chat_id", "message_count", "first_seen_at", "last_seen_at", "verified_at", "released_at", "trust_override"},
+		"moderation_actions": {"id", "user_id", "chat_id", "user_name", "message_text", "rule", "detail", "learned_words", "banned", "undone", "shadow", "penalty", "weight", "sender_chat", "media_hash", "created_at"},
 	}
 
 	for table, wantColumns := range expected {
@@ -262,7 +262,35 @@ func TestRejectBlocksAIKeyword(t *testing.T) {
 	}
 }
 
-// TestStrikeLifecycle 计分的累加、扣回与不减到负数
+// TestUnrejectKeyword 否决表可以列出, 移出时容忍首尾空白, 移出后 AI 又能添加该词
+func TestUnrejectKeyword(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "unreject.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.RejectKeyword("多少"); err != nil {
+		t.Fatalf("写入否决表失败: %v", err)
+	}
+	if rows, err := db.GetRejectedKeywords(); err != nil || len(rows) != 1 || rows[0].Word != "多少" {
+		t.Errorf("否决表 = %+v,%v", rows, err)
+	}
+	if removed, err := db.UnrejectKeyword(" 多少 "); err != nil || !removed {
+		t.Errorf("移出否决表 = %v,%v", removed, err)
+	}
+	if rejected, _ := db.IsKeywordRejected("多少"); rejected {
+		t.Error("移出后不应再处于否决状态")
+	}
+	if removed, _ := db.UnrejectKeyword("多少"); removed {
+		t.Error("不在否决表里的词不应报告移出")
+	}
+	if added, err := db.AddKeyword("多少", SourceAI); err != nil || !added {
+		t.Errorf("移出后 AI 添加 = %v,%v, 期望成功", added, err)
+	}
+}
+
+// TestStrikeLifecycle 计分按权重累加、按同样的权重扣回, 且不减到负数
 func TestStrikeLifecycle(t *testing.T) {
 	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "strike.db"))
 	if err != nil {
@@ -273,13 +301,19 @@ func TestStrikeLifecycle(t *testing.T) {
 	const userID, chatID = int64(42), int64(-100)
 
 	for i := 1; i <= 3; i++ {
-		got, err := db.AddStrike(userID, chatID)
+		got, err := db.AddStrike(userID, chatID, 1)
 		if err != nil || got != i {
 			t.Fatalf("第 %d 次记分 = %d (err=%v), 期望 %d", i, got, err, i)
 		}
 	}
+	if got, _ := db.AddStrike(userID, chatID, 3); got != 6 {
+		t.Fatalf("按权重 3 记分后 = %d, 期望 6", got)
+	}
 
-	if err := db.DecrementStrike(userID, chatID); err != nil {
+	if err := db.DecrementStrike(userID, chatID, 3); err != nil {
+		t.Fatalf("扣回计分失败: %v", err)
+	}
+	if err := db.DecrementStrike(userID, chatID, 1); err != nil {
 		t.Fatalf("扣回计分失败: %v", err)
 	}
 	if got, _ := db.GetStrikes(userID, chatID); got != 2 {
@@ -287,7 +321,7 @@ func TestStrikeLifecycle(t *testing.T) {
 	}
 
 	for i := 0; i < 5; i++ {
-		if err := db.DecrementStrike(userID, chatID); err != nil {
+		if err := db.DecrementStrike(userID, chatID, 1); err != nil {
 			t.Fatalf("扣回计分失败: %v", err)
 		}
 	}
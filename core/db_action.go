@@ -17,9 +17,15 @@ type ModerationAction struct {
 	UserName     string
 	MessageText  string
 	Rule         string
+	Detail       string   // 命中细节, 如关键词或 AI 置信度
 	LearnedWords []string // 本次判定新增的 AI 关键词, 撤销时一并回滚
 	Banned       bool
 	Undone       bool
+	Shadow       bool    // 试行规则的"本会拦截"记录, 没有实际处置, 也就没有撤销可言
+	Penalty      Penalty // 实际执行的处罚; 禁言、封禁失败时降级记为 delete
+	Weight       int     // 本次记的违规分数
+	SenderChat   bool    // 以频道身份发言, UserID 为频道 ID
+	MediaHash    string  // 命中的已知广告图片特征, 撤销时从库里移除
 	CreatedAt    time.Time
 }
 
@@ -31,8 +37,14 @@ func (d *Database) RecordModerationAction(action ModerationAction) (int64, error
 		UserName:     action.UserName,
 		MessageText:  action.MessageText,
 		Rule:         action.Rule,
+		Detail:       action.Detail,
 		LearnedWords: strings.Join(action.LearnedWords, "\n"),
 		Banned:       action.Banned,
+		Shadow:       action.Shadow,
+		Penalty:      penaltyColumn(action.Penalty),
+		Weight:       action.Weight,
+		SenderChat:   action.SenderChat,
+		MediaHash:    action.MediaHash,
 		CreatedAt:    time.Now(),
 	}
 	if err := d.db.Create(&row).Error; err != nil {
@@ -47,7 +59,47 @@ func (d *Database) GetModerationAction(id int64) (ModerationAction, error) {
 	if err := d.db.First(&row, id).Error; err != nil {
 		return ModerationAction{}, err
 	}
+	return row.toAction(), nil
+}
+
+// GetShadowActions 列出某个时间点之后的试行记录, 按时间先后排序, 供定期汇总
+func (d *Database) GetShadowActions(since time.Time) ([]ModerationAction, error) {
+	var rows []ModerationActionRow
+	if err := d.db.Where("shadow = ? AND created_at > ?", true, since).Order("created_at").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	actions := make([]ModerationAction, 0, len(rows))
+	for _, row := range rows {
+		actions = append(actions, row.toAction())
+	}
+	return actions, nil
+}
+
+// GetRecentActions 按时间倒序列出处置记录 (含试行), 供网页面板翻阅。
+// userID 非零时只看该用户; beforeID 非零时只取比它更早的, 用作翻页游标 —— 新处置不断写入, 按偏移翻页会错位。
+func (d *Database) GetRecentActions(userID, beforeID int64, limit int) ([]ModerationAction, error) {
+	tx := d.db.Order("id DESC").Limit(limit)
+	if userID != 0 {
+		tx = tx.Where("user_id = ?", userID)
+	}
+	if beforeID > 0 {
+		tx = tx.Where("id < ?", beforeID)
+	}
 
+	var rows []ModerationActionRow
+	if err := tx.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	actions := make([]ModerationAction, 0, len(rows))
+	for _, row := range rows {
+		actions = append(actions, row.toAction())
+	}
+	return actions, nil
+}
+
+// toAction 把落库形态转成对外形态
+func (row ModerationActionRow) toAction() ModerationAction {
 	action := ModerationAction{
 		ID:          row.ID,
 		UserID:      row.UserID,
@@ -55,14 +107,31 @@ func (d *Database) GetModerationAction(id int64) (ModerationAction, error) {
 		UserName:    row.UserName,
 		MessageText: row.MessageText,
 		Rule:        row.Rule,
+		Detail:      row.Detail,
 		Banned:      row.Banned,
 		Undone:      row.Undone,
+		Shadow:      row.Shadow,
+		Weight:      row.Weight,
+		SenderChat:  row.SenderChat,
+		MediaHash:   row.MediaHash,
 		CreatedAt:   row.CreatedAt,
 	}
 	if row.LearnedWords != "" {
 		action.LearnedWords = strings.Split(row.LearnedWords, "\n")
 	}
-	return action, nil
+	if row.Penalty != "" {
+		// 解析失败 (只可能是手改过库) 时留空, 撤销按 Banned 兜底
+		action.Penalty, _ = ParsePenalty(row.Penalty)
+	}
+	return action
+}
+
+// penaltyColumn 处罚的落库形态; 试行记录等没有处罚的留空
+func penaltyColumn(p Penalty) string {
+	if p.Action == "" {
+		return ""
+	}
+	return p.String()
 }
 
 // MarkActionUndone 把处置标记为已撤销; 返回 false 表示此前已经撤销过。
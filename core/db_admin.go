@@ -0,0 +1,57 @@
+package core
+
+// admins 表读写; 管理员名册。
+// 名册每条群消息都要查 (判断发送者是否豁免审核), 因此整表常驻 TTL 缓存, 任免时主动失效。
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// SetAdminRole 任命管理员或修改其角色
+func (d *Database) SetAdminRole(userID int64, role string, grantedBy int64) error {
+	err := d.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&Admin{
+		UserID:    userID,
+		Role:      role,
+		GrantedBy: grantedBy,
+		GrantedAt: time.Now(),
+	}).Error
+	if err != nil {
+		return err
+	}
+	d.invalidateCache(cacheAdmins)
+	return nil
+}
+
+// RemoveAdmin 撤销管理员, 返回此前是否在册
+func (d *Database) RemoveAdmin(userID int64) (bool, error) {
+	result := d.db.Where("user_id = ?", userID).Delete(&Admin{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	d.invalidateCache(cacheAdmins)
+	return result.RowsAffected > 0, nil
+}
+
+// GetAdmins 列出名册, 按任命时间排序, 供管理员查看
+func (d *Database) GetAdmins() ([]Admin, error) {
+	var admins []Admin
+	err := d.db.Order("granted_at").Find(&admins).Error
+	return admins, err
+}
+
+// GetAdminRole 读取用户的角色, 不在册返回空字符串; 走 TTL 缓存
+func (d *Database) GetAdminRole(userID int64) (string, error) {
+	role, _, err := lookupCached(d, &d.adminRoster, userID, func() (map[int64]string, error) {
+		admins, err := d.GetAdmins()
+		if err != nil {
+			return nil, err
+		}
+		roles := make(map[int64]string, len(admins))
+		for _, admin := range admins {
+			roles[admin.UserID] = admin.Role
+		}
+		return roles, nil
+	})
+	return role, err
+}
@@ -32,16 +32,22 @@ const (
 func (d *Database) Backup(label string, keep int) (string, error) {
 	backupDir := filepath.Join(filepath.Dir(d.path), backupDirName)
 	if err := os.MkdirAll(backupDir, os.ModePerm); err != nil {
+		backupFailures.Inc()
 		return "", fmt.Errorf("创建快照目录失败: %w", err)
 	}
 
-	name := fmt.Sprintf("%s%s-%s%s", backupPrefix, time.Now().Format("20060102-150405"), label, backupSuffix)
+	startedAt := time.Now()
+	name := fmt.Sprintf("%s%s-%s%s", backupPrefix, startedAt.Format("20060102-150405"), label, backupSuffix)
 	target := filepath.Join(backupDir, name)
 
 	// VACUUM INTO 要求目标文件不存在, 时间戳命名天然满足
 	if err := d.db.Exec("VACUUM INTO ?", target).Error; err != nil {
+		backupFailures.Inc()
 		return "", fmt.Errorf("生成快照失败: %w", err)
 	}
+	if info, err := os.Stat(target); err == nil {
+		recordBackup(info.Size(), time.Since(startedAt))
+	}
 
 	if err := rotateBackups(backupDir, keep); err != nil {
 		// 轮转失败不影响本次快照已经成功这个事实
@@ -51,6 +57,55 @@ func (d *Database) Backup(label string, keep int) (string, error) {
 	return target, nil
 }
 
+// BackupFile 一份已有快照的概要
+type BackupFile struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// ListBackups 列出快照目录里自己产生的快照, 最新的排前面; 目录还不存在时返回空
+func (d *Database) ListBackups() ([]BackupFile, error) {
+	entries, err := os.ReadDir(filepath.Join(filepath.Dir(d.path), backupDirName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []BackupFile
+	for _, entry := range entries {
+		if entry.IsDir() || !isBackupName(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, BackupFile{Name: entry.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name > files[j].Name })
+	return files, nil
+}
+
+// BackupPath 把快照文件名换成完整路径; 只认自己产生的文件名, 挡住 ../ 之类的路径穿越
+func (d *Database) BackupPath(name string) (string, error) {
+	if name != filepath.Base(name) || !isBackupName(name) {
+		return "", fmt.Errorf("不是有效的快照文件名: %s", name)
+	}
+	path := filepath.Join(filepath.Dir(d.path), backupDirName, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// isBackupName 判断文件名是否为本程序产生的快照
+func isBackupName(name string) bool {
+	return strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupSuffix)
+}
+
 // rotateBackups 只保留最近 keep 份快照; keep <= 0 表示不清理
 func rotateBackups(dir string, keep int) error {
 	if keep <= 0 {
@@ -66,7 +121,7 @@ func rotateBackups(dir string, keep int) error {
 	for _, entry := range entries {
 		name := entry.Name()
 		// 只动自己产生的文件, 不碰管理员手工放进来的备份
-		if !entry.IsDir() && strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupSuffix) {
+		if !entry.IsDir() && isBackupName(name) {
 			names = append(names, name)
 		}
 	}
@@ -182,3 +182,37 @@ func TestCleanupInvalidKeywords(t *testing.T) {
 		t.Errorf("正常关键词被误删或残留未清: %v", keywords)
 	}
 }
+
+// TestListBackups 只列出自己产生的快照, 最新的在前; 取路径时拒绝目录之外的文件名
+func TestListBackups(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "sunai.db")
+	db, err := NewDatabaseAt(dbPath)
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	if files, err := db.ListBackups(); err != nil || len(files) != 0 {
+		t.Errorf("还没有快照目录时 = %v,%v", files, err)
+	}
+
+	backupDir := filepath.Join(filepath.Dir(dbPath), backupDirName)
+	os.MkdirAll(backupDir, os.ModePerm)
+	for _, name := range []string{"sunai-20240101-000000-daily.db", "sunai-20240102-000000-daily.db", "manual.db"} {
+		os.WriteFile(filepath.Join(backupDir, name), []byte("x"), 0o644)
+	}
+
+	files, err := db.ListBackups()
+	if err != nil || len(files) != 2 || files[0].Name != "sunai-20240102-000000-daily.db" {
+		t.Errorf("ListBackups = %+v,%v", files, err)
+	}
+
+	if _, err := db.BackupPath("sunai-20240101-000000-daily.db"); err != nil {
+		t.Errorf("已有快照取路径失败: %v", err)
+	}
+	for _, name := range []string{"manual.db", "../sunai.db", "sunai-../../x.db", "sunai-missing.db"} {
+		if _, err := db.BackupPath(name); err == nil {
+			t.Errorf("BackupPath(%q) 应当报错", name)
+		}
+	}
+}
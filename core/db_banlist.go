@@ -0,0 +1,84 @@
+package core
+
+// banned_users 表读写; 跨群封禁名单, 规则见 federation.go
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AddBannedUser 把用户加入名单, 返回是否新加入; 已在名单上的保留原记录
+func (d *Database) AddBannedUser(entry BannedUser) (bool, error) {
+	if entry.BannedAt.IsZero() {
+		entry.BannedAt = time.Now()
+	}
+	result := d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ImportBannedUsers 批量导入名单, 返回新加入的条数; 已在名单上的跳过
+func (d *Database) ImportBannedUsers(entries []BannedUser) (int, error) {
+	var added int64
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, entry := range entries {
+			if entry.BannedAt.IsZero() {
+				entry.BannedAt = now
+			}
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry)
+			if result.Error != nil {
+				return result.Error
+			}
+			added += result.RowsAffected
+		}
+		return nil
+	})
+	return int(added), err
+}
+
+// GetBannedUser 查询用户是否在名单上
+func (d *Database) GetBannedUser(userID int64) (BannedUser, bool, error) {
+	var entry BannedUser
+	if err := d.db.Where("user_id = ?", userID).First(&entry).Error; err != nil {
+		if isNoRows(err) {
+			return BannedUser{}, false, nil
+		}
+		return BannedUser{}, false, err
+	}
+	return entry, true, nil
+}
+
+// RemoveBannedUser 把用户移出名单, 返回此前是否在名单上
+func (d *Database) RemoveBannedUser(userID int64) (bool, error) {
+	result := d.db.Where("user_id = ?", userID).Delete(&BannedUser{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ListBannedUsers 按封禁时间倒序列出最近 limit 条, 同时返回名单总数
+func (d *Database) ListBannedUsers(limit int) ([]BannedUser, int64, error) {
+	var total int64
+	if err := d.db.Model(&BannedUser{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var entries []BannedUser
+	err := d.db.Order("banned_at DESC").Limit(limit).Find(&entries).Error
+	return entries, total, err
+}
+
+// SearchBannedUsers 查找名单: 纯数字按用户 ID 精确匹配, 其余按昵称或原因模糊匹配
+func (d *Database) SearchBannedUsers(query string, limit int) ([]BannedUser, error) {
+	query = strings.TrimSpace(query)
+	var entries []BannedUser
+	tx := d.db.Order("banned_at DESC").Limit(limit)
+	if userID, err := strconv.ParseInt(query, 10, 64); err == nil {
+		tx = tx.Where("user_id = ?", userID)
+	} else {
+		pattern := "%" + query + "%"
+		tx = tx.Where("user_name LIKE ? OR reason LIKE ?", pattern, pattern)
+	}
+	err := tx.Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,39 @@
+package core
+
+// join_challenges 表读写; 验证流程见 service/group_member_management/captcha.go
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// SaveChallenge 保存一道验证题; 同一个人重复入群时覆盖旧题
+func (d *Database) SaveChallenge(challenge JoinChallenge) error {
+	return d.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&challenge).Error
+}
+
+// GetChallenge 读取某人在某群的验证题
+func (d *Database) GetChallenge(chatID, userID int64) (JoinChallenge, bool, error) {
+	var challenge JoinChallenge
+	if err := d.db.Where("chat_id = ? AND user_id = ?", chatID, userID).First(&challenge).Error; err != nil {
+		if isNoRows(err) {
+			return JoinChallenge{}, false, nil
+		}
+		return JoinChallenge{}, false, err
+	}
+	return challenge, true, nil
+}
+
+// ClaimChallenge 删除验证题并返回是否确实删掉了。
+// 作答与超时清理可能同时发生, 以删除成功的一方为准, 避免一个人既被放行又被踢出
+func (d *Database) ClaimChallenge(chatID, userID int64) (bool, error) {
+	result := d.db.Where("chat_id = ? AND user_id = ?", chatID, userID).Delete(&JoinChallenge{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ExpiredChallenges 列出已超时的验证题
+func (d *Database) ExpiredChallenges(now time.Time) ([]JoinChallenge, error) {
+	var challenges []JoinChallenge
+	err := d.db.Where("expires_at <= ?", now).Find(&challenges).Error
+	return challenges, err
+}
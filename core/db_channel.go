@@ -0,0 +1,105 @@
+package core
+
+// source_channels 表读写: 来源频道的黑白名单。
+//
+// 广告除了直接发, 还常以"转发自某频道"或"以频道身份发言"的形式进群。黑名单上的频道, 转发即撤回、
+// 以其身份发言即封禁该频道身份; 白名单上的频道 (如本群的官方频道) 不做来源检查, 新成员限制也放行其转发。
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// 名单种类
+const (
+	ChannelBlock = "block"
+	ChannelAllow = "allow"
+)
+
+// ParseChannelRef 把管理员输入的频道规整成存储用的 Ref:
+// 数字 ID 原样保留 (频道 ID 一定是负数), @用户名、t.me 链接一律转成不带 @ 的小写用户名
+func ParseChannelRef(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if id, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		if id >= 0 {
+			return "", fmt.Errorf("频道 ID 应当是负数（形如 -100xxxx），%d 看起来是用户 ID", id)
+		}
+		return raw, nil
+	}
+
+	name := strings.ToLower(raw)
+	for _, prefix := range []string{"https://", "http://", "t.me/", "telegram.me/", "@"} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	name = strings.TrimSuffix(name, "/")
+	if len(name) < 4 || len(name) > 32 || strings.Trim(name, "abcdefghijklmnopqrstuvwxyz0123456789_") != "" {
+		return "", fmt.Errorf("无法识别的频道：%s（请用 @用户名 或 -100 开头的 ID）", raw)
+	}
+	return name, nil
+}
+
+// ChannelRefs 一个频道可能命中的全部 Ref: 数字 ID 与用户名
+func ChannelRefs(chatID int64, username string) []string {
+	refs := []string{strconv.FormatInt(chatID, 10)}
+	if username != "" {
+		refs = append(refs, strings.ToLower(username))
+	}
+	return refs
+}
+
+// SetSourceChannel 把频道加入黑名单或白名单; 已在另一张名单上的直接改过来
+func (d *Database) SetSourceChannel(entry SourceChannel) error {
+	if entry.List != ChannelBlock && entry.List != ChannelAllow {
+		return fmt.Errorf("未知的名单 %q", entry.List)
+	}
+	entry.AddedAt = time.Now()
+	err := d.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&entry).Error
+	if err != nil {
+		return err
+	}
+	d.invalidateCache(cacheSourceChannels)
+	return nil
+}
+
+// RemoveSourceChannel 把频道移出名单, 返回是否确实删除了
+func (d *Database) RemoveSourceChannel(ref string) (bool, error) {
+	result := d.db.Where("ref = ?", ref).Delete(&SourceChannel{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	d.invalidateCache(cacheSourceChannels)
+	return result.RowsAffected > 0, nil
+}
+
+// ListSourceChannels 列出全部名单条目, 按名单与登记时间排序
+func (d *Database) ListSourceChannels() ([]SourceChannel, error) {
+	var entries []SourceChannel
+	err := d.db.Order("list, added_at").Find(&entries).Error
+	return entries, err
+}
+
+// LookupSourceChannel 按 ID 与用户名查频道所在的名单; 两者都登记过时以 ID 为准。走 TTL 缓存
+func (d *Database) LookupSourceChannel(chatID int64, username string) (SourceChannel, bool, error) {
+	for _, ref := range ChannelRefs(chatID, username) {
+		entry, ok, err := lookupCached(d, &d.channels, ref, d.loadSourceChannels)
+		if err != nil || ok {
+			return entry, ok, err
+		}
+	}
+	return SourceChannel{}, false, nil
+}
+
+func (d *Database) loadSourceChannels() (map[string]SourceChannel, error) {
+	entries, err := d.ListSourceChannels()
+	if err != nil {
+		return nil, err
+	}
+	byRef := make(map[string]SourceChannel, len(entries))
+	for _, entry := range entries {
+		byRef[entry.Ref] = entry
+	}
+	return byRef, nil
+}
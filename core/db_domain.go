@@ -0,0 +1,73 @@
+package core
+
+// link_domains 表读写: 链接域名的黑白名单。
+//
+// 登记的域名连同其下全部子域名生效: 名单上有 example.com 时, sub.example.com 与 a.sub.example.com 都算;
+// 有 sub.example.com 时只管它和更深的子域名, 不管 example.com 与 other.example.com。
+// 同一个主机名命中多条时以最具体的为准, 因此可以拉黑 example.com 的同时放行 docs.example.com。
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// 名单种类, 与来源频道名单同名
+const (
+	DomainBlock = "block"
+	DomainAllow = "allow"
+)
+
+// SetLinkDomain 把域名加入黑名单或白名单; 已在另一张名单上的直接改过来
+func (d *Database) SetLinkDomain(entry LinkDomain) error {
+	if entry.List != DomainBlock && entry.List != DomainAllow {
+		return fmt.Errorf("未知的名单 %q", entry.List)
+	}
+	entry.AddedAt = time.Now()
+	err := d.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&entry).Error
+	if err != nil {
+		return err
+	}
+	d.invalidateCache(cacheLinkDomains)
+	return nil
+}
+
+// RemoveLinkDomain 把域名移出名单, 返回是否确实删除了
+func (d *Database) RemoveLinkDomain(domain string) (bool, error) {
+	result := d.db.Where("domain = ?", domain).Delete(&LinkDomain{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	d.invalidateCache(cacheLinkDomains)
+	return result.RowsAffected > 0, nil
+}
+
+// ListLinkDomains 列出全部名单条目, 按名单与域名排序
+func (d *Database) ListLinkDomains() ([]LinkDomain, error) {
+	var entries []LinkDomain
+	err := d.db.Order("list, domain").Find(&entries).Error
+	return entries, err
+}
+
+// LookupLinkDomain 查主机名所在的名单, 从最具体的条目往上找到可注册域名为止。走 TTL 缓存
+func (d *Database) LookupLinkDomain(host string) (LinkDomain, bool, error) {
+	for _, candidate := range domainCandidates(host) {
+		entry, ok, err := lookupCached(d, &d.domains, candidate, d.loadLinkDomains)
+		if err != nil || ok {
+			return entry, ok, err
+		}
+	}
+	return LinkDomain{}, false, nil
+}
+
+func (d *Database) loadLinkDomains() (map[string]LinkDomain, error) {
+	entries, err := d.ListLinkDomains()
+	if err != nil {
+		return nil, err
+	}
+	byDomain := make(map[string]LinkDomain, len(entries))
+	for _, entry := range entries {
+		byDomain[entry.Domain] = entry
+	}
+	return byDomain, nil
+}
@@ -0,0 +1,66 @@
+package core
+
+// managed_groups 表读写; 受管群组及其独立设置。
+// 每条群消息都要先确认所在群是否受管, 因此整表常驻 TTL 缓存, 登记与修改时主动失效。
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// EnsureGroup 登记群组, 已登记则保持原设置不动, 返回是否新登记。
+// 用于启动时把 CHAT_ID 自动登记为首个受管群: 重启不能把管理员改过的设置冲掉。
+func (d *Database) EnsureGroup(group ManagedGroup) (bool, error) {
+	group.AddedAt = time.Now()
+	result := d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&group)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	d.invalidateCache(cacheGroups)
+	return result.RowsAffected > 0, nil
+}
+
+// SaveGroup 写回群设置, 整行覆盖
+func (d *Database) SaveGroup(group ManagedGroup) error {
+	if group.AddedAt.IsZero() {
+		group.AddedAt = time.Now()
+	}
+	if err := d.db.Save(&group).Error; err != nil {
+		return err
+	}
+	d.invalidateCache(cacheGroups)
+	return nil
+}
+
+// RemoveGroup 取消登记, 返回此前是否已登记。
+// 只删设置, 不动该群的计分和处置记录 —— 重新登记后历史照旧生效。
+func (d *Database) RemoveGroup(chatID int64) (bool, error) {
+	result := d.db.Where("chat_id = ?", chatID).Delete(&ManagedGroup{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	d.invalidateCache(cacheGroups)
+	return result.RowsAffected > 0, nil
+}
+
+// GetGroups 列出全部受管群, 按登记时间排序
+func (d *Database) GetGroups() ([]ManagedGroup, error) {
+	var groups []ManagedGroup
+	err := d.db.Order("added_at").Find(&groups).Error
+	return groups, err
+}
+
+// GetGroup 读取群设置, 第二个返回值表示是否已登记; 走 TTL 缓存
+func (d *Database) GetGroup(chatID int64) (ManagedGroup, bool, error) {
+	return lookupCached(d, &d.groups, chatID, func() (map[int64]ManagedGroup, error) {
+		groups, err := d.GetGroups()
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[int64]ManagedGroup, len(groups))
+		for _, group := range groups {
+			byID[group.ChatID] = group
+		}
+		return byID, nil
+	})
+}
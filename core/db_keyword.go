@@ -9,6 +9,8 @@ package core
 // 否决表的写入是**显式**的, 只在"管理员手动删除 AI 词"和"撤销误判"两处调用 RejectKeyword;
 // RemoveKeyword 本身不写否决表 —— 定期整理清掉零命中词只是"这次没用上", 不该永久拉黑。
 import (
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,9 +24,74 @@ const (
 	SourceAI     = "ai"
 )
 
-// AddKeyword 新增关键词, 已存在则静默忽略。
-// source 为 SourceAI 时先查否决表, 被管理员否决过的词不再添加, 返回 false。
+// Scope 关键词的作用域: 零值为全局, 只填 ChatID 为某个群, 再填 TopicID 为群里的某个话题
+type Scope struct {
+	ChatID  int64
+	TopicID int
+}
+
+// GlobalScope 全局作用域, 所有 keyword_scope=global 的群都会匹配
+var GlobalScope = Scope{}
+
+// IsGlobal 判断是否为全局作用域
+func (s Scope) IsGlobal() bool {
+	return s == GlobalScope
+}
+
+// String 作用域的展示形式, 同时也是管理员在命令里书写的形式 (不含前缀 @)
+func (s Scope) String() string {
+	switch {
+	case s.IsGlobal():
+		return "global"
+	case s.TopicID != 0:
+		return fmt.Sprintf("%d/%d", s.ChatID, s.TopicID)
+	default:
+		return strconv.FormatInt(s.ChatID, 10)
+	}
+}
+
+// ParseScope 解析管理员书写的作用域: global / 群ID / 群ID/话题ID
+func ParseScope(raw string) (Scope, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.EqualFold(raw, "global") {
+		return GlobalScope, nil
+	}
+
+	chatPart, topicPart, hasTopic := strings.Cut(raw, "/")
+	chatID, err := strconv.ParseInt(chatPart, 10, 64)
+	if err != nil || chatID >= 0 {
+		return Scope{}, fmt.Errorf("作用域 %q 无效, 应为 global、群ID 或 群ID/话题ID", raw)
+	}
+	scope := Scope{ChatID: chatID}
+	if hasTopic {
+		topicID, err := strconv.Atoi(topicPart)
+		if err != nil || topicID <= 0 {
+			return Scope{}, fmt.Errorf("话题 ID %q 无效", topicPart)
+		}
+		scope.TopicID = topicID
+	}
+	return scope, nil
+}
+
+// AddKeyword 新增全局关键词, 已存在则静默忽略; 见 AddScopedKeyword
 func (d *Database) AddKeyword(keyword, source string) (bool, error) {
+	return d.AddScopedKeyword(keyword, source, GlobalScope)
+}
+
+// AddScopedKeyword 在指定作用域新增关键词, 已存在 (无论在哪个作用域) 则静默忽略。
+// source 为 SourceAI 时先查否决表, 被管理员否决过的词不再添加, 返回 false。
+func (d *Database) AddScopedKeyword(keyword, source string, scope Scope) (bool, error) {
+	return d.addEntry(keyword, KindPlain, source, scope)
+}
+
+// AddRule 在指定作用域新增规则型词条, 调用前须已通过 ValidateKeyword。
+// 规则只由管理员手工添加, AI 只会提取纯文本词, 因此来源固定为 manual。
+func (d *Database) AddRule(expr, kind string, scope Scope) (bool, error) {
+	return d.addEntry(expr, kind, SourceManual, scope)
+}
+
+// addEntry 写入一条词条, 已存在则静默忽略
+func (d *Database) addEntry(keyword, kind, source string, scope Scope) (bool, error) {
 	if source == SourceAI {
 		rejected, err := d.IsKeywordRejected(keyword)
 		if err != nil {
@@ -36,14 +103,17 @@ func (d *Database) AddKeyword(keyword, source string) (bool, error) {
 	}
 
 	result := d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&Keyword{
-		Word:    keyword,
-		AddedAt: time.Now(),
-		Source:  source,
+		Word:         keyword,
+		AddedAt:      time.Now(),
+		Source:       source,
+		Kind:         kind,
+		ScopeChatID:  scope.ChatID,
+		ScopeTopicID: scope.TopicID,
 	})
 	if result.Error != nil {
 		return false, result.Error
 	}
-	d.invalidateCache(cacheKeywords)
+	d.invalidateKeywordScope(scope)
 
 	return result.RowsAffected > 0, nil
 }
@@ -64,7 +134,7 @@ func (d *Database) RemoveKeyword(keyword string) (bool, string, error) {
 	if result.Error != nil {
 		return false, "", result.Error
 	}
-	d.invalidateCache(cacheKeywords)
+	d.invalidateKeywordScope(existing.Scope())
 
 	return result.RowsAffected > 0, existing.Source, nil
 }
@@ -89,16 +159,107 @@ func (d *Database) RemoveKeywordsContaining(substring string) ([]string, error)
 	return removed, nil
 }
 
-// GetActiveKeywords 返回参与匹配的全部关键词 (手工 + AI), 走 TTL 缓存; 增删会立即失效缓存
+// GetActiveKeywords 返回参与匹配的全部全局关键词 (手工 + AI); 见 GetScopeKeywords
 func (d *Database) GetActiveKeywords() ([]string, error) {
-	return d.queryCached(cacheKeywords, func() ([]string, error) {
-		var keywords []string
-		err := d.db.Model(&Keyword{}).Where("is_auto_added = ?", false).Pluck("keyword", &keywords).Error
-		return keywords, err
-	})
+	return d.GetScopeKeywords(GlobalScope)
+}
+
+// GetScopeKeywords 返回某个作用域内参与匹配的纯文本关键词, 不含上级作用域的词
+func (d *Database) GetScopeKeywords(scope Scope) ([]string, error) {
+	entries, err := d.scopeEntries(scope)
+	if err != nil {
+		return nil, err
+	}
+	return plainWords(entries), nil
 }
 
-// GetKeywordsBySource 按来源列出关键词及其元数据, 供管理员查看与 AI 定期整理
+// GetScopeRules 返回某个作用域内的规则型词条 (只填了 Word / Kind / Mode), 不含上级作用域的规则
+func (d *Database) GetScopeRules(scope Scope) ([]Keyword, error) {
+	entries, err := d.scopeEntries(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := entries[:0]
+	for _, entry := range entries {
+		if entry.Kind != KindPlain {
+			rules = append(rules, entry)
+		}
+	}
+	return rules, nil
+}
+
+// ScopeKeywordIndex 返回由某个作用域的纯文本关键词构建的匹配结构 (moderation 的自动机)。
+// 结构与词表缓存绑在一起, 只在词表被增删失效、或 TTL 重载后内容确有变化时才调用 build 重建,
+// 否则每条消息直接复用。build 拿到的词条只填了 Word / Kind / Mode; 它在持有缓存锁时执行, 不得回调 Database。
+func (d *Database) ScopeKeywordIndex(scope Scope, build func(keywords []Keyword) any) (any, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	list := d.keywordCache(scope)
+	if err := refreshCached(list, d.scopeLoader(scope)); err != nil {
+		return nil, err
+	}
+	if list.derived == nil {
+		keywordIndexBuilds.Inc()
+		list.derived = build(plainEntries(list.items))
+	}
+	return list.derived, nil
+}
+
+// plainEntries 从词条里挑出纯文本关键词
+func plainEntries(entries []Keyword) []Keyword {
+	plain := make([]Keyword, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Kind == KindPlain {
+			plain = append(plain, entry)
+		}
+	}
+	return plain
+}
+
+// plainWords 从词条里挑出纯文本关键词的词本身
+func plainWords(entries []Keyword) []string {
+	words := make([]string, 0, len(entries))
+	for _, entry := range plainEntries(entries) {
+		words = append(words, entry.Word)
+	}
+	return words
+}
+
+// scopeEntries 读取一个作用域的全部词条。关键词和规则共用一份缓存, 一条消息每个作用域只回源一次;
+// 每个作用域各自走 TTL 缓存, 增删会立即失效缓存。
+func (d *Database) scopeEntries(scope Scope) ([]Keyword, error) {
+	return queryCached(d, func() *cachedList[Keyword] { return d.keywordCache(scope) }, d.scopeLoader(scope))
+}
+
+// scopeLoader 回源读取一个作用域词条的函数; 按 keyword 排序, TTL 重载时才能逐项比较内容是否变化
+func (d *Database) scopeLoader(scope Scope) func() ([]Keyword, error) {
+	return func() ([]Keyword, error) {
+		keywordCacheReloads.Inc()
+		var entries []Keyword
+		err := d.db.Select("keyword", "kind", "mode").
+			Where("is_auto_added = ? AND scope_chat_id = ? AND scope_topic_id = ?", false, scope.ChatID, scope.TopicID).
+			Order("keyword").
+			Find(&entries).Error
+		return entries, err
+	}
+}
+
+// GetKeyword 按词读取完整词条, 第二个返回值表示是否存在; 用于告诉管理员一个词落在哪个作用域
+func (d *Database) GetKeyword(keyword string) (Keyword, bool, error) {
+	var row Keyword
+	err := d.db.Where("keyword = ?", keyword).First(&row).Error
+	if err != nil {
+		if isNoRows(err) {
+			return Keyword{}, false, nil
+		}
+		return Keyword{}, false, err
+	}
+	return row, true, nil
+}
+
+// GetKeywordsBySource 按来源列出全部作用域的关键词及其元数据, 供管理员查看与 AI 定期整理
 func (d *Database) GetKeywordsBySource(source string) ([]Keyword, error) {
 	var keywords []Keyword
 	err := d.db.
@@ -115,6 +276,23 @@ func (d *Database) RecordKeywordHit(keyword string) error {
 		UpdateColumn("hit_count", gorm.Expr("hit_count + 1")).Error
 }
 
+// ListKeywords 分页列出全部作用域的词条及其元数据, query 非空时按子串过滤; 第二个返回值是过滤后的总数。
+// 供网页面板浏览, 命中多的排前面, 与 GetKeywordsBySource 的顺序一致。
+func (d *Database) ListKeywords(query string, offset, limit int) ([]Keyword, int64, error) {
+	tx := d.db.Model(&Keyword{}).Where("is_auto_added = ?", false)
+	if query = strings.TrimSpace(query); query != "" {
+		tx = tx.Where("keyword LIKE ?", "%"+query+"%")
+	}
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var keywords []Keyword
+	err := tx.Order("hit_count DESC, added_at DESC").Offset(offset).Limit(limit).Find(&keywords).Error
+	return keywords, total, err
+}
+
 // SearchKeywords 模糊查找关键词, 用于删除失败时提示相似项
 func (d *Database) SearchKeywords(pattern string) ([]string, error) {
 	var keywords []string
@@ -142,6 +320,19 @@ func (d *Database) IsKeywordRejected(keyword string) (bool, error) {
 	return count > 0, err
 }
 
+// GetRejectedKeywords 列出否决表, 最近否决的排前面
+func (d *Database) GetRejectedKeywords() ([]KeywordReject, error) {
+	var rows []KeywordReject
+	err := d.db.Order("rejected_at DESC").Find(&rows).Error
+	return rows, err
+}
+
+// UnrejectKeyword 把关键词移出否决表, AI 此后可以再次添加; 返回是否确实在表里
+func (d *Database) UnrejectKeyword(keyword string) (bool, error) {
+	result := d.db.Where("keyword = ?", normalizeRejectKey(keyword)).Delete(&KeywordReject{})
+	return result.RowsAffected > 0, result.Error
+}
+
 // normalizeRejectKey 否决表按小写去空白存储, 避免大小写差异导致否决失效
 func normalizeRejectKey(keyword string) string {
 	return strings.ToLower(strings.TrimSpace(keyword))
@@ -167,7 +358,7 @@ func (d *Database) CleanupLegacyAutoLinks() (int64, error) {
 // CleanupInvalidKeywords 删除内容为空的关键词行并返回删除条数。
 //
 // 这类行是 2026-08-13 迁移事故的残留: 整表重建把 keyword 列清空, 留下一批 NULL 行。
-// 它们永远匹配不到任何消息 (matchKeyword 会跳过空词), 但会让 /list 显示成一串空条目。
+// 它们永远匹配不到任何消息 (构建关键词自动机时会跳过空词), 但会让 /list 显示成一串空条目。
 // 放在启动流程里自愈, 避免手工去生产库上删数据。
 func (d *Database) CleanupInvalidKeywords() (int64, error) {
 	result := d.db.Where("keyword IS NULL OR TRIM(keyword) = ''").Delete(&Keyword{})
@@ -0,0 +1,126 @@
+package core
+
+// media_hashes 表读写: 已知广告图片与贴纸的特征库。
+//
+// 同一批广告图和贴纸会被新注册的账号反复发。管理员 /ban 一条图片消息时记下它的特征,
+// 之后同样的图片 (哪怕重新压缩、缩放过) 不必再等 AI, 直接按已知广告处置; 误判撤销时把对应特征移除。
+// 贴纸、动图与原样转发的图片 file_unique_id 不变, 按它精确匹配; 重新上传的图片按感知哈希的汉明距离近似匹配。
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// 特征种类
+const (
+	MediaDHash = "dhash" // 图片的差值感知哈希, 64 位
+	MediaFile  = "file"  // Telegram 的 file_unique_id
+)
+
+// Key 特征的唯一标识 "kind:hash", 处置记录里存的就是它
+func (m MediaHash) Key() string {
+	return m.Kind + ":" + m.Hash
+}
+
+// ParseMediaKey 拆开 Key 的结果
+func ParseMediaKey(key string) (kind, hash string, ok bool) {
+	kind, hash, ok = strings.Cut(key, ":")
+	if !ok || (kind != MediaDHash && kind != MediaFile) || hash == "" {
+		return "", "", false
+	}
+	return kind, hash, true
+}
+
+// FormatDHash 感知哈希的存储格式: 16 位小写十六进制
+func FormatDHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// AddMediaHash 登记一条特征, 返回是否是新登记的; 已有的不改写来源
+func (d *Database) AddMediaHash(entry MediaHash) (bool, error) {
+	if _, _, ok := ParseMediaKey(entry.Key()); !ok {
+		return false, fmt.Errorf("无效的图片特征 %q", entry.Key())
+	}
+	entry.ID = 0
+	entry.AddedAt = time.Now()
+	result := d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	d.invalidateCache(cacheMediaHashes)
+	return result.RowsAffected > 0, nil
+}
+
+// RemoveMediaHash 移除一条特征, 返回是否确实删除了
+func (d *Database) RemoveMediaHash(kind, hash string) (bool, error) {
+	result := d.db.Where("kind = ? AND hash = ?", kind, hash).Delete(&MediaHash{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	d.invalidateCache(cacheMediaHashes)
+	return result.RowsAffected > 0, nil
+}
+
+// LookupMediaFile 按 file_unique_id 精确查找。走 TTL 缓存
+func (d *Database) LookupMediaFile(uniqueID string) (MediaHash, bool, error) {
+	return lookupCached(d, &d.media, MediaFile+":"+uniqueID, d.loadMediaHashes)
+}
+
+// HasPerceptualHashes 库里是否有感知哈希; 没有时调用方可以省掉下载图片
+func (d *Database) HasPerceptualHashes() (bool, error) {
+	items, err := cachedItems(d, &d.media, d.loadMediaHashes)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range items {
+		if entry.Kind == MediaDHash {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// NearestMediaHash 找与 hash 汉明距离最近、且不超过 maxDistance 的感知哈希; 第二个返回值是距离
+func (d *Database) NearestMediaHash(hash uint64, maxDistance int) (MediaHash, int, bool, error) {
+	items, err := cachedItems(d, &d.media, d.loadMediaHashes)
+	if err != nil {
+		return MediaHash{}, 0, false, err
+	}
+
+	var (
+		best     MediaHash
+		bestDist = maxDistance + 1
+	)
+	for _, entry := range items {
+		if entry.Kind != MediaDHash {
+			continue
+		}
+		stored, err := strconv.ParseUint(entry.Hash, 16, 64)
+		if err != nil {
+			continue
+		}
+		if dist := bits.OnesCount64(stored ^ hash); dist < bestDist {
+			best, bestDist = entry, dist
+		}
+	}
+	if bestDist > maxDistance {
+		return MediaHash{}, 0, false, nil
+	}
+	return best, bestDist, true, nil
+}
+
+func (d *Database) loadMediaHashes() (map[string]MediaHash, error) {
+	var entries []MediaHash
+	if err := d.db.Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]MediaHash, len(entries))
+	for _, entry := range entries {
+		byKey[entry.Key()] = entry
+	}
+	return byKey, nil
+}
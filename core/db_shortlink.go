@@ -0,0 +1,35 @@
+package core
+
+// short_links 表读写: 短链展开结果的缓存。
+// 展开要发网络请求, 同一个短链在刷屏时会出现成百上千次, 只应请求一次。
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// GetShortLink 读取一个短链的展开结果, 未展开过时第二个返回值为 false
+func (d *Database) GetShortLink(url string) (ShortLink, bool, error) {
+	var link ShortLink
+	if err := d.db.Where("url = ?", url).First(&link).Error; err != nil {
+		if isNoRows(err) {
+			return ShortLink{}, false, nil
+		}
+		return ShortLink{}, false, err
+	}
+	return link, true, nil
+}
+
+// SaveShortLink 写入或覆盖一个短链的展开结果
+func (d *Database) SaveShortLink(link ShortLink) error {
+	if link.ResolvedAt.IsZero() {
+		link.ResolvedAt = time.Now()
+	}
+	return d.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&link).Error
+}
+
+// CleanupShortLinks 删除早于 olderThan 展开的缓存
+func (d *Database) CleanupShortLinks(olderThan time.Duration) (int64, error) {
+	result := d.db.Where("resolved_at < ?", time.Now().Add(-olderThan)).Delete(&ShortLink{})
+	return result.RowsAffected, result.Error
+}
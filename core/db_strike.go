@@ -9,18 +9,18 @@ import (
 	"gorm.io/gorm/clause"
 )
 
-// AddStrike 给用户记一次违规并返回累计次数
-func (d *Database) AddStrike(userID, chatID int64) (int, error) {
+// AddStrike 给用户记一次违规, 按规则权重累加 weight 分, 返回累计分数
+func (d *Database) AddStrike(userID, chatID int64, weight int) (int, error) {
 	err := d.db.Clauses(clause.OnConflict{
 		Columns: []clause.Column{{Name: "user_id"}, {Name: "chat_id"}},
 		DoUpdates: clause.Assignments(map[string]any{
-			"strikes":     gorm.Expr("user_strikes.strikes + 1"),
+			"strikes":     gorm.Expr("user_strikes.strikes + ?", weight),
 			"last_hit_at": time.Now(),
 		}),
 	}).Create(&UserStrike{
 		UserID:    userID,
 		ChatID:    chatID,
-		Strikes:   1,
+		Strikes:   weight,
 		LastHitAt: time.Now(),
 	}).Error
 	if err != nil {
@@ -42,11 +42,22 @@ func (d *Database) GetStrikes(userID, chatID int64) (int, error) {
 	return row.Strikes, nil
 }
 
-// DecrementStrike 撤销一次违规计分, 不会减到负数
-func (d *Database) DecrementStrike(userID, chatID int64) error {
+// DecrementStrike 撤销一次违规记的 weight 分, 不会减到负数
+func (d *Database) DecrementStrike(userID, chatID int64, weight int) error {
 	return d.db.Model(&UserStrike{}).
 		Where("user_id = ? AND chat_id = ?", userID, chatID).
-		UpdateColumn("strikes", gorm.Expr("MAX(strikes - 1, 0)")).Error
+		UpdateColumn("strikes", gorm.Expr("MAX(strikes - ?, 0)", weight)).Error
+}
+
+// GetStrikeRecords 列出仍有计分的记录, 最近违规的排前面; userID 非零时只看该用户在各群的计分
+func (d *Database) GetStrikeRecords(userID int64, limit int) ([]UserStrike, error) {
+	tx := d.db.Where("strikes > 0").Order("last_hit_at DESC").Limit(limit)
+	if userID != 0 {
+		tx = tx.Where("user_id = ?", userID)
+	}
+	var rows []UserStrike
+	err := tx.Find(&rows).Error
+	return rows, err
 }
 
 // ResetStrikes 清空某用户的违规计分, 供管理员解封后调用
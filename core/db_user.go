@@ -1,7 +1,7 @@
 package core
 
-// user_stats 表读写; 记录每个用户在每个群的发言条数。
-// 用途是识别"新用户": AI 审核只对新用户的前若干条消息全量开启, 老用户靠弱信号触发, 以此控制调用量。
+// user_stats 表读写; 记录每个用户在每个群的发言条数与首次出现时间。
+// 每条受审核的群消息都会计数, 与 AI 是否开启无关; 新成员限制、突袭检测、信任等级与 AI 的新用户窗口都以它为准。
 import (
 	"time"
 
@@ -9,39 +9,148 @@ import (
 	"gorm.io/gorm/clause"
 )
 
-// BumpUserMessageCount 累加发言计数并返回累加后的条数
-func (d *Database) BumpUserMessageCount(userID, chatID int64) (int, error) {
+// BumpUserMessageCount 累加发言计数并返回累加后的统计; 第二个返回值表示记录是这次新建的,
+// 即此前在本群既没有入群记录也没有发过言
+func (d *Database) BumpUserMessageCount(userID, chatID int64) (UserStat, bool, error) {
 	now := time.Now()
-	err := d.db.Clauses(clause.OnConflict{
-		Columns: []clause.Column{{Name: "user_id"}, {Name: "chat_id"}},
-		DoUpdates: clause.Assignments(map[string]any{
-			"message_count": gorm.Expr("user_stats.message_count + 1"),
-			"last_seen_at":  now,
-		}),
-	}).Create(&UserStat{
-		UserID:       userID,
-		ChatID:       chatID,
-		MessageCount: 1,
-		FirstSeenAt:  now,
-		LastSeenAt:   now,
-	}).Error
+	created := false
+	var row UserStat
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&UserStat{
+			UserID:       userID,
+			ChatID:       chatID,
+			MessageCount: 1,
+			FirstSeenAt:  now,
+			LastSeenAt:   now,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if created = result.RowsAffected > 0; !created {
+			err := tx.Model(&UserStat{}).Where("user_id = ? AND chat_id = ?", userID, chatID).Updates(map[string]any{
+				"message_count": gorm.Expr("message_count + 1"),
+				"last_seen_at":  now,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Where("user_id = ? AND chat_id = ?", userID, chatID).First(&row).Error
+	})
 	if err != nil {
-		return 0, err
+		return UserStat{}, false, err
 	}
+	return row, created, nil
+}
+
+// RecordVerification 记下用户通过了入群验证。firstJoin 表示统计记录是这次入群才建的,
+// 此时把发言计数清零, 让 AI 的新用户窗口从此刻开始; 退群重进的老成员保留原有计数, 不被当成新人重审
+func (d *Database) RecordVerification(userID, chatID int64, firstJoin bool) error {
+	now := time.Now()
+	updates := map[string]any{
+		"last_seen_at": now,
+		"verified_at":  now,
+	}
+	if firstJoin {
+		updates["message_count"] = 0
+	}
+	return d.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "chat_id"}},
+		DoUpdates: clause.Assignments(updates),
+	}).Create(&UserStat{
+		UserID:      userID,
+		ChatID:      chatID,
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+		VerifiedAt:  now,
+	}).Error
+}
 
+// GetUserStat 读取某用户在某群的发言统计, 第二个返回值表示是否有记录
+func (d *Database) GetUserStat(userID, chatID int64) (UserStat, bool, error) {
 	var row UserStat
 	if err := d.db.Where("user_id = ? AND chat_id = ?", userID, chatID).First(&row).Error; err != nil {
 		if isNoRows(err) {
-			return 0, nil
+			return UserStat{}, false, nil
 		}
-		return 0, err
+		return UserStat{}, false, err
 	}
-	return row.MessageCount, nil
+	return row, true, nil
+}
+
+// RecordJoin 记下用户入群, 让新成员限制从入群而不是首次发言算起; 已有记录的老成员退群重进不重置。
+// 返回记录是否是这次新建的, 即此前在本群没有任何统计
+func (d *Database) RecordJoin(userID, chatID int64) (bool, error) {
+	now := time.Now()
+	created := false
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&UserStat{
+			UserID:      userID,
+			ChatID:      chatID,
+			FirstSeenAt: now,
+			LastSeenAt:  now,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if created = result.RowsAffected > 0; created {
+			return nil
+		}
+		return tx.Model(&UserStat{}).Where("user_id = ? AND chat_id = ?", userID, chatID).Update("last_seen_at", now).Error
+	})
+	return created, err
+}
+
+// ReleaseProbation 在给定的群里提前解除用户的新成员限制; 没有发言统计的群同样写入, 之后进群也不受限
+func (d *Database) ReleaseProbation(userID int64, chatIDs []int64) error {
+	now := time.Now()
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		for _, chatID := range chatIDs {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "chat_id"}},
+				DoUpdates: clause.Assignments(map[string]any{"released_at": now}),
+			}).Create(&UserStat{
+				UserID:      userID,
+				ChatID:      chatID,
+				FirstSeenAt: now,
+				LastSeenAt:  now,
+				ReleasedAt:  now,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetTrustOverride 在给定的群里指定用户的信任等级, override 为空即恢复按统计计算; 没有发言统计的群同样写入
+func (d *Database) SetTrustOverride(userID int64, chatIDs []int64, override string) error {
+	now := time.Now()
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		for _, chatID := range chatIDs {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "chat_id"}},
+				DoUpdates: clause.Assignments(map[string]any{"trust_override": override}),
+			}).Create(&UserStat{
+				UserID:        userID,
+				ChatID:        chatID,
+				FirstSeenAt:   now,
+				LastSeenAt:    now,
+				TrustOverride: override,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
-// CleanupStaleUserStats 清理长期不发言用户的统计, 避免表无限增长。
-// 注意副作用: 被清理的用户再次发言会重新被当作新用户走 AI 审核, 这正是期望行为。
+// CleanupStaleUserStats 清理长期没有动静、且从没在群里发过言的统计 (多是进群后就没说过话的账号), 避免表无限增长。
+// 发过言的记录一律保留: 删掉后老成员再开口会被当成刚出现, 又要过一遍新成员限制与 AI 审核。
+// 管理员指定过信任等级的记录同样保留, 否则 /distrust 过的人沉寂一阵就洗白了。
 func (d *Database) CleanupStaleUserStats(olderThan time.Duration) (int64, error) {
-	result := d.db.Where("last_seen_at < ?", time.Now().Add(-olderThan)).Delete(&UserStat{})
+	result := d.db.Where("last_seen_at < ? AND message_count = 0 AND (trust_override IS NULL OR trust_override = '')", time.Now().Add(-olderThan)).Delete(&UserStat{})
 	return result.RowsAffected, result.Error
 }
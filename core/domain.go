@@ -0,0 +1,79 @@
+package core
+
+// 链接域名的解析与规整。
+//
+// 名单按"可注册域名"(registrable domain, 即公共后缀再加一级, 如 example.com、example.com.cn) 理解链接:
+// 登记的条目不能比它更宽 —— 登记 com 等于拉黑全部 .com 网站, 多半是手误。
+// 公共后缀取自 Public Suffix List (golang.org/x/net/publicsuffix), 含 blogspot.com、azurewebsites.net 这类
+// 托管平台的私有后缀: 这些平台上每个子域名都是不相干的网站, 拉黑一个不能连坐全部。
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// HostOf 从一段链接文本里取出小写的主机名, 可以不带协议; 取不出时返回空串
+func HostOf(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if !strings.Contains(host, ".") {
+		return ""
+	}
+	return host
+}
+
+// RegistrableDomain 主机名的可注册域名, 如 a.b.example.com.cn -> example.com.cn; 主机名本身就是公共后缀时返回空串
+func RegistrableDomain(host string) string {
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return ""
+	}
+	return domain
+}
+
+// ParseDomain 把管理员输入的域名规整成存储格式: 去掉协议、路径、端口与开头的 www. / *.;
+// 比可注册域名还宽的写法 (如 com、com.cn) 拒绝登记
+func ParseDomain(raw string) (string, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(raw), "*.")
+	host := HostOf(trimmed)
+	host = strings.TrimPrefix(host, "www.")
+	if host == "" || strings.Trim(host, "abcdefghijklmnopqrstuvwxyz0123456789.-") != "" {
+		return "", fmt.Errorf("无法识别的域名：%s", raw)
+	}
+	if RegistrableDomain(host) == "" {
+		return "", fmt.Errorf("%s 是公共后缀，登记它会影响所有网站，请写具体的域名", host)
+	}
+	return host, nil
+}
+
+// domainCandidates 主机名可能命中的名单条目, 从最具体到可注册域名:
+// a.b.example.com -> a.b.example.com, b.example.com, example.com
+func domainCandidates(host string) []string {
+	root := RegistrableDomain(host)
+	if root == "" {
+		return nil
+	}
+	var candidates []string
+	for {
+		candidates = append(candidates, host)
+		if host == root {
+			return candidates
+		}
+		_, host, _ = strings.Cut(host, ".")
+	}
+}
+
+// StrictLinkWindow 新成员只能发白名单域名链接的时长, 0 表示未开启; 起算与解除方式同新成员限制 (见 ProbationEnds)
+func (g ManagedGroup) StrictLinkWindow() time.Duration {
+	return time.Duration(g.StrictLinkHours) * time.Hour
+}
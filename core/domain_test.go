@@ -0,0 +1,100 @@
+package core
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestRegistrableDomain(t *testing.T) {
+	cases := map[string]string{
+		"example.com":           "example.com",
+		"a.b.example.com":       "example.com",
+		"shop.example.com.cn":   "example.com.cn",
+		"user.github.io":        "user.github.io",
+		"com":                   "",
+		"com.cn":                "",
+		"t.me":                  "t.me",
+		"x.co.za":               "x.co.za",
+		"shop.x.co.za":          "x.co.za",
+		"a.blogspot.com":        "a.blogspot.com",
+		"app.azurewebsites.net": "app.azurewebsites.net",
+		"co.za":                 "",
+		"blogspot.com":          "",
+	}
+	for host, want := range cases {
+		if got := RegistrableDomain(host); got != want {
+			t.Errorf("RegistrableDomain(%q) = %q, 期望 %q", host, got, want)
+		}
+	}
+}
+
+func TestParseDomain(t *testing.T) {
+	cases := map[string]string{
+		"Example.COM":                      "example.com",
+		"https://www.example.com/path?q=1": "example.com",
+		"*.sub.example.com":                "sub.example.com",
+		"sub.example.com:8443":             "sub.example.com",
+	}
+	for raw, want := range cases {
+		if got, err := ParseDomain(raw); err != nil || got != want {
+			t.Errorf("ParseDomain(%q) = %q,%v, 期望 %q", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"com", "com.cn", "co.za", "com.pk", "blogspot.com", "azurewebsites.net", "localhost", "水果.com", ""} {
+		if _, err := ParseDomain(raw); err == nil {
+			t.Errorf("ParseDomain(%q) 应当报错", raw)
+		}
+	}
+}
+
+func TestDomainCandidates(t *testing.T) {
+	got := domainCandidates("a.sub.example.com")
+	want := []string{"a.sub.example.com", "sub.example.com", "example.com"}
+	if !slices.Equal(got, want) {
+		t.Errorf("domainCandidates = %v, 期望 %v", got, want)
+	}
+}
+
+// TestLinkDomainLists 条目对子域名生效, 更具体的条目优先, 不向上匹配到父域名
+func TestLinkDomainLists(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "domains.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	db.SetLinkDomain(LinkDomain{Domain: "example.com", List: DomainBlock})
+	db.SetLinkDomain(LinkDomain{Domain: "docs.example.com", List: DomainAllow})
+	db.SetLinkDomain(LinkDomain{Domain: "sub.other.com", List: DomainAllow})
+
+	cases := map[string]string{
+		"example.com":         DomainBlock,
+		"a.b.example.com":     DomainBlock,
+		"docs.example.com":    DomainAllow,
+		"v2.docs.example.com": DomainAllow,
+		"sub.other.com":       DomainAllow,
+		"deep.sub.other.com":  DomainAllow,
+		"other.com":           "",
+		"x.other.com":         "",
+	}
+	for host, want := range cases {
+		entry, ok, err := db.LookupLinkDomain(host)
+		if err != nil {
+			t.Fatalf("LookupLinkDomain(%q) 失败: %v", host, err)
+		}
+		if got := entry.List; !ok && got != "" || got != want {
+			t.Errorf("LookupLinkDomain(%q) = %q, 期望 %q", host, got, want)
+		}
+	}
+
+	if err := db.SetLinkDomain(LinkDomain{Domain: "x.com", List: "grey"}); err == nil {
+		t.Error("未知的名单应当报错")
+	}
+	if removed, _ := db.RemoveLinkDomain("example.com"); !removed {
+		t.Error("RemoveLinkDomain 应当删除成功")
+	}
+	if _, ok, _ := db.LookupLinkDomain("a.example.com"); ok {
+		t.Error("移出后不应再查到")
+	}
+}
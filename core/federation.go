@@ -0,0 +1,78 @@
+package core
+
+// 跨群封禁名单。
+//
+// 广告号在一个群被封后会换个群接着发。加入名单的受管群 (群设置 federation on) 共享一份封禁名单:
+//   - 任一成员群里的封禁, 无论是处罚阶梯自动封禁还是管理员 /ban, 都写入名单并同步封禁到其余成员群
+//   - 名单上的账号加入任一成员群时立即踢出
+//
+// 名单只在成员群之间生效; 没加入的群既不贡献也不执行, 尺度不同的群可以各管各的。
+import (
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// FederatedGroups 列出加入名单的受管群
+func FederatedGroups() []ManagedGroup {
+	groups, err := DB.GetGroups()
+	if err != nil {
+		log.Printf("[Core] 读取受管群列表失败: %v", err)
+		return nil
+	}
+
+	var members []ManagedGroup
+	for _, group := range groups {
+		if group.Federation {
+			members = append(members, group)
+		}
+	}
+	return members
+}
+
+// FederateBan 把发生在 entry.ChatID 的一次封禁写入名单, 并同步封禁到其余成员群, 返回同步成功的群数。
+// 封禁发生的群没有加入名单, 或用户早已在名单上 (说明之前已同步过) 时什么也不做。
+func FederateBan(bot *tgbotapi.BotAPI, entry BannedUser) int {
+	if group, ok := GroupSettings(entry.ChatID); !ok || !group.Federation {
+		return 0
+	}
+
+	added, err := DB.AddBannedUser(entry)
+	if err != nil {
+		log.Printf("[Core] 写入封禁名单失败 (用户 %d): %v", entry.UserID, err)
+		return 0
+	}
+	if !added {
+		return 0
+	}
+
+	synced := 0
+	for _, group := range FederatedGroups() {
+		if group.ChatID == entry.ChatID {
+			continue
+		}
+		if err := BanUser(bot, group.ChatID, entry.UserID); err != nil {
+			log.Printf("[Core] 同步封禁用户 %d 到群 %d 失败: %v", entry.UserID, group.ChatID, err)
+			continue
+		}
+		synced++
+	}
+	log.Printf("[Core] 用户 %d 已加入封禁名单, 同步封禁到 %d 个群", entry.UserID, synced)
+	return synced
+}
+
+// LiftFederatedBan 把用户移出名单并在全部成员群解封, 返回此前是否在名单上
+func LiftFederatedBan(bot *tgbotapi.BotAPI, userID int64) (bool, error) {
+	removed, err := DB.RemoveBannedUser(userID)
+	if err != nil || !removed {
+		return false, err
+	}
+
+	for _, group := range FederatedGroups() {
+		if err := UnbanUser(bot, group.ChatID, userID); err != nil {
+			log.Printf("[Core] 在群 %d 解封用户 %d 失败: %v", group.ChatID, userID, err)
+		}
+	}
+	log.Printf("[Core] 用户 %d 已移出封禁名单", userID)
+	return true, nil
+}
@@ -0,0 +1,87 @@
+package core
+
+// 发言频率限制、突袭检测与多账号重复内容的群设置。
+//
+// 三项设置都写成"条数/秒数": flood 8/10 表示单人 10 秒内超过 8 条即临时禁言;
+// raid 10/60 表示 60 秒内有 10 个新账号入群或发言即开启突袭模式;
+// duplicate 3/600 表示 600 秒内有 3 个账号发出近似内容即成批撤回。
+// 判定本身在 moderation 包的 flood.go 与 wave.go。
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 单人限速的可设范围
+const (
+	MinFloodMessages = 2
+	MaxFloodMessages = 100
+	MinFloodSeconds  = 1
+	MaxFloodSeconds  = 300
+)
+
+// 突袭检测的可设范围; 人数太少时几个朋友一起进群就会误触发
+const (
+	MinRaidMembers = 3
+	MaxRaidMembers = 500
+	MinRaidSeconds = 10
+	MaxRaidSeconds = 3600
+)
+
+// 多账号重复内容的可设范围与默认值; 两个账号撞上同一句话并不罕见, 至少要 3 个
+const (
+	MinWaveAccounts = 3
+	MaxWaveAccounts = 50
+	MinWaveSeconds  = 60
+	MaxWaveSeconds  = 3600
+
+	defaultWaveAccounts = 3
+	defaultWaveWindow   = 10 * time.Minute
+)
+
+// ParseRate 解析"条数/秒数"形式的频率, 如 8/10 或 8/10s; 两个数都要落在给定范围内
+func ParseRate(raw string, minCount, maxCount, minSeconds, maxSeconds int) (int, int, error) {
+	countPart, secondsPart, ok := strings.Cut(strings.TrimSpace(raw), "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("格式应为 条数/秒数，如 8/10")
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(countPart))
+	if err != nil || count < minCount || count > maxCount {
+		return 0, 0, fmt.Errorf("条数需要在 %d 到 %d 之间", minCount, maxCount)
+	}
+	seconds, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(secondsPart), "s"))
+	if err != nil || seconds < minSeconds || seconds > maxSeconds {
+		return 0, 0, fmt.Errorf("秒数需要在 %d 到 %d 之间", minSeconds, maxSeconds)
+	}
+	return count, seconds, nil
+}
+
+// FloodLimit 单人限速: 窗口内最多几条; 条数为 0 表示未开启
+func (g ManagedGroup) FloodLimit() (int, time.Duration) {
+	if g.FloodMessages <= 0 || g.FloodSeconds <= 0 {
+		return 0, 0
+	}
+	return g.FloodMessages, time.Duration(g.FloodSeconds) * time.Second
+}
+
+// RaidLimit 突袭检测: 窗口内多少个新账号活跃即开启突袭模式; 人数为 0 表示未开启
+func (g ManagedGroup) RaidLimit() (int, time.Duration) {
+	if g.RaidMembers <= 0 || g.RaidSeconds <= 0 {
+		return 0, 0
+	}
+	return g.RaidMembers, time.Duration(g.RaidSeconds) * time.Second
+}
+
+// WaveLimit 多账号重复内容: 窗口内多少个不同账号发出近似内容即判定为成批广告; 未设置的取默认 3 个/10 分钟。
+// 零值 ManagedGroup 同样得到默认值, 不受管或读不到设置时直接用它
+func (g ManagedGroup) WaveLimit() (int, time.Duration) {
+	accounts, window := defaultWaveAccounts, defaultWaveWindow
+	if g.WaveAccounts > 0 {
+		accounts = g.WaveAccounts
+	}
+	if g.WaveSeconds > 0 {
+		window = time.Duration(g.WaveSeconds) * time.Second
+	}
+	return accounts, window
+}
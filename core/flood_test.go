@@ -0,0 +1,42 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	count, seconds, err := ParseRate(" 8 / 10s ", MinFloodMessages, MaxFloodMessages, MinFloodSeconds, MaxFloodSeconds)
+	if err != nil || count != 8 || seconds != 10 {
+		t.Errorf("ParseRate = %d,%d,%v, 期望 8,10", count, seconds, err)
+	}
+	for _, raw := range []string{"8", "8/0", "1/10", "abc/10", "8/9999"} {
+		if _, _, err := ParseRate(raw, MinFloodMessages, MaxFloodMessages, MinFloodSeconds, MaxFloodSeconds); err == nil {
+			t.Errorf("ParseRate(%q) 应当报错", raw)
+		}
+	}
+}
+
+func TestFloodAndRaidLimit(t *testing.T) {
+	group := ManagedGroup{FloodMessages: 8, FloodSeconds: 10, RaidMembers: 10}
+	if count, window := group.FloodLimit(); count != 8 || window != 10*time.Second {
+		t.Errorf("FloodLimit = %d,%v", count, window)
+	}
+	// 只填了一半的设置按未开启处理
+	if count, _ := group.RaidLimit(); count != 0 {
+		t.Errorf("缺秒数的突袭检测应视为关闭, 实际 %d", count)
+	}
+}
+
+// TestWaveLimit 未设置的一项取默认值, 零值 ManagedGroup 得到默认的 3 个/10 分钟
+func TestWaveLimit(t *testing.T) {
+	if count, window := (ManagedGroup{}).WaveLimit(); count != 3 || window != 10*time.Minute {
+		t.Errorf("默认 WaveLimit = %d,%v", count, window)
+	}
+	if count, window := (ManagedGroup{WaveAccounts: 5}).WaveLimit(); count != 5 || window != 10*time.Minute {
+		t.Errorf("只设账号数 WaveLimit = %d,%v", count, window)
+	}
+	if count, window := (ManagedGroup{WaveAccounts: 4, WaveSeconds: 300}).WaveLimit(); count != 4 || window != 5*time.Minute {
+		t.Errorf("WaveLimit = %d,%v", count, window)
+	}
+}
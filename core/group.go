@@ -0,0 +1,103 @@
+package core
+
+// 受管群组。
+//
+// 一个进程可以同时管理多个群, 每个群的行情推送、封禁阈值、AI 开关、群务通知清理和关键词作用域各自独立。
+// 环境变量里的 SYMBOLS / AUTO_BAN_THRESHOLD 等只作为新登记群的默认值, 登记之后以库里的设置为准。
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// 关键词作用域
+const (
+	KeywordScopeGlobal = "global" // 全局词表与本群专属词都参与匹配
+	KeywordScopeLocal  = "local"  // 只匹配本群专属词, 不继承全局词表
+)
+
+// ValidKeywordScope 判断关键词作用域取值是否合法
+func ValidKeywordScope(scope string) bool {
+	return scope == KeywordScopeGlobal || scope == KeywordScopeLocal
+}
+
+// 入群验证题型; 为空表示不验证
+const (
+	CaptchaMath  = "math"  // 两位数加法, 四选一
+	CaptchaEmoji = "emoji" // 按名称点选表情, 六选一
+)
+
+const (
+	// defaultCaptchaTimeout 未设置时限时的入群验证时长
+	defaultCaptchaTimeout = 2 * time.Minute
+	// MinCaptchaTimeout / MaxCaptchaTimeout 验证时限的可设范围 (秒); 太短来不及看题, 太长广告号可以一直挂着
+	MinCaptchaTimeout = 30
+	MaxCaptchaTimeout = 600
+)
+
+// ValidCaptcha 判断入群验证题型取值是否合法
+func ValidCaptcha(kind string) bool {
+	return kind == CaptchaMath || kind == CaptchaEmoji
+}
+
+// CaptchaWindow 入群验证的作答时限
+func (g ManagedGroup) CaptchaWindow() time.Duration {
+	if g.CaptchaTimeout <= 0 {
+		return defaultCaptchaTimeout
+	}
+	return time.Duration(g.CaptchaTimeout) * time.Second
+}
+
+// GroupSettings 读取受管群设置, 第二个返回值表示该群是否受管。
+// 查库失败也按"不受管"处理: 拿不到设置时宁可不管, 不能按默认值去处置一个没登记的群。
+func GroupSettings(chatID int64) (ManagedGroup, bool) {
+	if DB == nil {
+		return ManagedGroup{}, false
+	}
+
+	group, ok, err := DB.GetGroup(chatID)
+	if err != nil {
+		log.Printf("[Core] 读取群 %d 的设置失败: %v", chatID, err)
+		return ManagedGroup{}, false
+	}
+	return group, ok
+}
+
+// DefaultGroup 按环境变量里的默认值构造一个新群的设置
+func DefaultGroup(chatID int64, title string) ManagedGroup {
+	return ManagedGroup{
+		ChatID:                chatID,
+		Title:                 title,
+		Symbols:               strings.Join(Symbols, ","),
+		AutoBanThreshold:      AutoBanThreshold,
+		AIEnabled:             true,
+		DeleteServiceMessages: DeleteServiceMessages,
+		KeywordScope:          KeywordScopeGlobal,
+	}
+}
+
+// SymbolList 把存储的交易对拆成币安接口需要的切片, 未配置返回 nil
+func (g ManagedGroup) SymbolList() []string {
+	return parseSymbols(g.Symbols)
+}
+
+// NormalizeSymbols 把管理员输入的 "DOGS/USDT, TON/USDT" 规整成存储格式 "DOGSUSDT,TONUSDT"
+func NormalizeSymbols(raw string) string {
+	return strings.ToUpper(strings.Join(parseSymbols(raw), ","))
+}
+
+// seedPrimaryGroup 把 CHAT_ID 登记为受管群; 已登记则保持库里的设置不动
+func seedPrimaryGroup() {
+	if ChatID == 0 {
+		return
+	}
+
+	added, err := DB.EnsureGroup(DefaultGroup(ChatID, ""))
+	if err != nil {
+		log.Printf("[Core] 登记 CHAT_ID %d 为受管群失败: %v", ChatID, err)
+		return
+	}
+	if added {
+		log.Printf("[Core] 已把 CHAT_ID %d 登记为受管群", ChatID)
+	}
+}
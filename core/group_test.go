@@ -0,0 +1,83 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestEnsureGroupKeepsSettings 重启时自动登记 CHAT_ID 不能把管理员改过的设置冲掉
+func TestEnsureGroupKeepsSettings(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "groups.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	const chatID = int64(-1001)
+
+	added, err := db.EnsureGroup(ManagedGroup{ChatID: chatID, AutoBanThreshold: 3, AIEnabled: true, KeywordScope: KeywordScopeGlobal})
+	if err != nil || !added {
+		t.Fatalf("首次登记: added=%v err=%v", added, err)
+	}
+
+	group, ok, err := db.GetGroup(chatID)
+	if err != nil || !ok {
+		t.Fatalf("读取群设置: ok=%v err=%v", ok, err)
+	}
+	group.AIEnabled = false
+	group.AutoBanThreshold = 0
+	if err := db.SaveGroup(group); err != nil {
+		t.Fatalf("保存群设置失败: %v", err)
+	}
+
+	added, err = db.EnsureGroup(ManagedGroup{ChatID: chatID, AutoBanThreshold: 3, AIEnabled: true, KeywordScope: KeywordScopeGlobal})
+	if err != nil || added {
+		t.Fatalf("重复登记: added=%v err=%v, 期望 false", added, err)
+	}
+
+	// 零值设置 (关闭 AI、阈值 0) 最容易在写库时被列默认值悄悄改回去
+	group, _, _ = db.GetGroup(chatID)
+	if group.AIEnabled || group.AutoBanThreshold != 0 {
+		t.Errorf("设置被改动: %+v", group)
+	}
+}
+
+// TestUnmanagedGroupIgnored 未登记的群查不到设置, 删除登记后立即失效
+func TestUnmanagedGroupIgnored(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "groups.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	if _, ok, _ := db.GetGroup(-2002); ok {
+		t.Fatal("未登记的群不应查到设置")
+	}
+
+	if _, err := db.EnsureGroup(ManagedGroup{ChatID: -2002, KeywordScope: KeywordScopeGlobal}); err != nil {
+		t.Fatalf("登记失败: %v", err)
+	}
+	if _, ok, _ := db.GetGroup(-2002); !ok {
+		t.Fatal("登记后应当查到设置 (缓存需要失效)")
+	}
+
+	if removed, err := db.RemoveGroup(-2002); err != nil || !removed {
+		t.Fatalf("取消登记: removed=%v err=%v", removed, err)
+	}
+	if _, ok, _ := db.GetGroup(-2002); ok {
+		t.Error("取消登记后仍能查到设置")
+	}
+}
+
+func TestNormalizeSymbols(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"DOGS/USDT,TON/USDT", "DOGSUSDT,TONUSDT"},
+		{" dogs/usdt , ton/usdt ", "DOGSUSDT,TONUSDT"},
+		{"", ""},
+	}
+	for _, c := range cases {
+		if got := NormalizeSymbols(c.in); got != c.want {
+			t.Errorf("NormalizeSymbols(%q) = %q, 期望 %q", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseScope(t *testing.T) {
+	cases := map[string]Scope{
+		"global":            GlobalScope,
+		"-1001234567890":    {ChatID: -1001234567890},
+		"-1001234567890/15": {ChatID: -1001234567890, TopicID: 15},
+	}
+	for raw, want := range cases {
+		got, err := ParseScope(raw)
+		if err != nil || got != want {
+			t.Errorf("ParseScope(%q) = %v,%v, 期望 %v", raw, got, err, want)
+		}
+		if err == nil && got.String() != want.String() {
+			t.Errorf("String() 往返不一致: %q", got.String())
+		}
+	}
+	// 正数是用户 ID, 话题 ID 必须为正, 写错了要报错而不是悄悄落到全局
+	for _, raw := range []string{"5912366993", "-100/0", "-100/x", "abc"} {
+		if _, err := ParseScope(raw); err == nil {
+			t.Errorf("ParseScope(%q) 应当报错", raw)
+		}
+	}
+}
+
+// TestScopedKeywordsIsolated 群词、话题词互不串门, 缓存按作用域分别失效
+func TestScopedKeywordsIsolated(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "scope.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	group := Scope{ChatID: -1001}
+	topic := Scope{ChatID: -1001, TopicID: 7}
+
+	for word, scope := range map[string]Scope{"全局词": GlobalScope, "群词": group, "话题词": topic} {
+		if _, err := db.AddScopedKeyword(word, SourceManual, scope); err != nil {
+			t.Fatalf("添加 %s 失败: %v", word, err)
+		}
+	}
+
+	// 先读一遍把三个作用域都装进缓存, 再验证删除只让对应作用域失效
+	for scope, want := range map[Scope]string{GlobalScope: "全局词", group: "群词", topic: "话题词"} {
+		words, err := db.GetScopeKeywords(scope)
+		if err != nil || len(words) != 1 || words[0] != want {
+			t.Errorf("作用域 %v 的词表 = %v,%v, 期望 [%s]", scope, words, err, want)
+		}
+	}
+
+	// 同一个词只能在一个作用域里
+	if added, _ := db.AddScopedKeyword("群词", SourceManual, GlobalScope); added {
+		t.Error("已在群作用域的词不应再加进全局")
+	}
+
+	if removed, _, err := db.RemoveKeyword("话题词"); err != nil || !removed {
+		t.Fatalf("删除话题词: removed=%v err=%v", removed, err)
+	}
+	if words, _ := db.GetScopeKeywords(topic); len(words) != 0 {
+		t.Errorf("删除后话题词表仍为 %v, 缓存没有失效", words)
+	}
+	if words, _ := db.GetScopeKeywords(group); len(words) != 1 {
+		t.Errorf("删除话题词不应影响群词表, 实际 %v", words)
+	}
+}
+
+func TestDecodeUpdateRecordsTopic(t *testing.T) {
+	raw := json.RawMessage(`{"update_id":1,"message":{"message_id":42,"message_thread_id":9,"is_topic_message":true,
+		"chat":{"id":-1001,"type":"supergroup"},"date":0,"text":"hi"}}`)
+	update, err := DecodeUpdate(raw)
+	if err != nil || update.Message == nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	if got := MessageTopic(update.Message); got != 9 {
+		t.Errorf("MessageTopic = %d, 期望 9", got)
+	}
+
+	// 普通群里的回复也带 message_thread_id, 但不是话题, 不能当话题词作用域
+	raw = json.RawMessage(`{"update_id":2,"message":{"message_id":43,"message_thread_id":40,
+		"chat":{"id":-1001,"type":"supergroup"},"date":0,"text":"re"}}`)
+	update, _ = DecodeUpdate(raw)
+	if got := MessageTopic(update.Message); got != 0 {
+		t.Errorf("非话题消息 MessageTopic = %d, 期望 0", got)
+	}
+}
+
+// TestScopeKeywordIndexRebuild 派生结构只在词表变化时重建, 否则每条消息复用同一份
+func TestScopeKeywordIndexRebuild(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	builds := 0
+	build := func(keywords []Keyword) any {
+		builds++
+		return len(keywords)
+	}
+
+	db.AddKeyword("水果机", SourceManual)
+	db.AddRule(`\d{7,}微信`, KindRegex, GlobalScope)
+	for i := 0; i < 3; i++ {
+		got, err := db.ScopeKeywordIndex(GlobalScope, build)
+		if err != nil || got != 1 {
+			t.Fatalf("ScopeKeywordIndex = %v,%v, 期望只含 1 个纯文本词", got, err)
+		}
+	}
+	if builds != 1 {
+		t.Errorf("词表未变时构建了 %d 次, 期望 1 次", builds)
+	}
+
+	// 其他作用域的改动不应让全局词表重建
+	db.AddScopedKeyword("群词", SourceManual, Scope{ChatID: -1001})
+	db.ScopeKeywordIndex(GlobalScope, build)
+	if builds != 1 {
+		t.Errorf("改动群词表后全局词表被重建, 共构建 %d 次", builds)
+	}
+
+	db.AddKeyword("特价", SourceManual)
+	if got, _ := db.ScopeKeywordIndex(GlobalScope, build); got != 2 || builds != 2 {
+		t.Errorf("新增关键词后 = %v, 构建 %d 次, 期望 2 个词、构建 2 次", got, builds)
+	}
+}
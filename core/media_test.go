@@ -0,0 +1,63 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestMediaHashes file_unique_id 精确匹配, 感知哈希按汉明距离取最近的一条; 重复登记不算新增
+func TestMediaHashes(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "media.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	if has, _ := db.HasPerceptualHashes(); has {
+		t.Error("空库不应有感知哈希")
+	}
+
+	const base uint64 = 0xf0f0f0f0f0f0f0f0
+	for _, entry := range []MediaHash{
+		{Kind: MediaFile, Hash: "AgADsticker"},
+		{Kind: MediaDHash, Hash: FormatDHash(base)},
+		{Kind: MediaDHash, Hash: FormatDHash(^base)},
+	} {
+		if added, err := db.AddMediaHash(entry); err != nil || !added {
+			t.Fatalf("AddMediaHash(%s) = %v,%v", entry.Key(), added, err)
+		}
+	}
+	if added, _ := db.AddMediaHash(MediaHash{Kind: MediaFile, Hash: "AgADsticker"}); added {
+		t.Error("重复登记不应算新增")
+	}
+	if _, err := db.AddMediaHash(MediaHash{Kind: "md5", Hash: "x"}); err == nil {
+		t.Error("未知种类应当报错")
+	}
+
+	if _, ok, _ := db.LookupMediaFile("AgADsticker"); !ok {
+		t.Error("应当按 file_unique_id 查到")
+	}
+	if has, _ := db.HasPerceptualHashes(); !has {
+		t.Error("应当有感知哈希")
+	}
+
+	// 翻转 3 位仍算同一张图, 最近的是 base 而不是它的反码
+	entry, dist, ok, _ := db.NearestMediaHash(base^0b10101, 6)
+	if !ok || dist != 3 || entry.Hash != FormatDHash(base) {
+		t.Errorf("NearestMediaHash = %+v,%d,%v", entry, dist, ok)
+	}
+	if _, _, ok, _ := db.NearestMediaHash(0x00ff00ff00ff00ff, 6); ok {
+		t.Error("差得远的哈希不应命中")
+	}
+
+	kind, hash, ok := ParseMediaKey(entry.Key())
+	if !ok {
+		t.Fatalf("ParseMediaKey(%q) 失败", entry.Key())
+	}
+	if removed, _ := db.RemoveMediaHash(kind, hash); !removed {
+		t.Error("RemoveMediaHash 应当删除成功")
+	}
+	if _, _, ok, _ := db.NearestMediaHash(base, 6); ok {
+		t.Error("移除后不应再命中")
+	}
+}
@@ -0,0 +1,86 @@
+package core
+
+// Prometheus 指标: core 里的数据库缓存、快照与 Telegram 调用。
+//
+// 指标定义在产生它的包里 (审核、AI、行情各自的 metrics.go), 统一注册到 Prometheus 的默认注册表,
+// 由 service/http_server.go 在 /metrics 暴露。名字一律以 sunai_ 开头, 标签只取有限的取值, 不放用户或群 ID。
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	keywordCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sunai_keyword_cache_hits_total",
+		Help: "词表缓存命中次数 (按作用域计, 一条消息可能查多个作用域)",
+	})
+	keywordCacheReloads = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sunai_keyword_cache_reloads_total",
+		Help: "词表缓存回源查库次数, 含增删失效与 TTL 到期",
+	})
+	keywordIndexBuilds = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sunai_keyword_index_builds_total",
+		Help: "关键词自动机重建次数, 只在词表内容变化时发生",
+	})
+
+	backupSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "sunai_backup_last_size_bytes",
+		Help: "最近一次成功快照的文件大小",
+	})
+	backupDuration = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "sunai_backup_last_duration_seconds",
+		Help: "最近一次成功快照的耗时",
+	})
+	backupLastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "sunai_backup_last_success_timestamp_seconds",
+		Help: "最近一次成功快照的 Unix 时间",
+	})
+	backupFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sunai_backup_failures_total",
+		Help: "快照失败次数",
+	})
+
+	telegramRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sunai_telegram_requests_total",
+		Help: "Bot API 调用次数, 按方法",
+	}, []string{"method"})
+	telegramErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sunai_telegram_errors_total",
+		Help: "Bot API 调用失败次数, 按方法与 HTTP 状态码; 网络错误的 code 为 network",
+	}, []string{"method", "code"})
+)
+
+// recordBackup 记下一次成功快照的大小与耗时
+func recordBackup(size int64, elapsed time.Duration) {
+	backupSize.Set(float64(size))
+	backupDuration.Set(elapsed.Seconds())
+	backupLastSuccess.SetToCurrentTime()
+}
+
+// telegramClient 给 Bot API 的 HTTP 客户端加上计数。
+// 机器人对 Telegram 的调用散落在各个包里, 在传输层统一统计, 不必逐个调用点去加;
+// Telegram 对 ok=false 的响应同时返回非 200 状态码, 看状态码即可, 不必解析响应体。
+type telegramClient struct {
+	client *http.Client
+}
+
+// Do 实现 tgbotapi.HTTPClient
+func (c telegramClient) Do(req *http.Request) (*http.Response, error) {
+	// 路径形如 /bot<token>/sendMessage, 只取最后一段, token 不进标签
+	method := path.Base(req.URL.Path)
+	telegramRequests.WithLabelValues(method).Inc()
+
+	resp, err := c.client.Do(req)
+	switch {
+	case err != nil:
+		telegramErrors.WithLabelValues(method, "network").Inc()
+	case resp.StatusCode != http.StatusOK:
+		telegramErrors.WithLabelValues(method, strconv.Itoa(resp.StatusCode)).Inc()
+	}
+	return resp, err
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestTelegramClientCountsErrors 非 200 的响应按方法与状态码计数, 路径里的 token 不进标签
+func TestTelegramClientCountsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if filepath.Base(r.URL.Path) == "banChatMember" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"ok":false,"error_code":403,"description":"Forbidden"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	defer server.Close()
+
+	client := telegramClient{client: server.Client()}
+	before := testutil.ToFloat64(telegramErrors.WithLabelValues("banChatMember", "403"))
+	for _, method := range []string{"sendMessage", "banChatMember"} {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/bot123:secret/"+method, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s 请求失败: %v", method, err)
+		}
+		resp.Body.Close()
+	}
+
+	if got := testutil.ToFloat64(telegramErrors.WithLabelValues("banChatMember", "403")) - before; got != 1 {
+		t.Errorf("banChatMember 的 403 计数增加了 %v, 期望 1", got)
+	}
+	if got := testutil.ToFloat64(telegramErrors.WithLabelValues("sendMessage", "403")); got != 0 {
+		t.Errorf("成功的调用不应计入失败: %v", got)
+	}
+}
+
+// TestBackupMetrics 成功的快照记下大小与完成时间
+func TestBackupMetrics(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "metrics.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Backup("test", 0); err != nil {
+		t.Fatalf("生成快照失败: %v", err)
+	}
+	if size := testutil.ToFloat64(backupSize); size <= 0 {
+		t.Errorf("快照大小 = %v", size)
+	}
+	if at := testutil.ToFloat64(backupLastSuccess); at <= 0 {
+		t.Errorf("完成时间 = %v", at)
+	}
+}
@@ -28,6 +28,7 @@ var guardedProbes = []tableProbe{
 	{table: "keywords", column: "keyword"},
 	{table: "prompt_replies", column: "reply"},
 	{table: "keyword_rejects", column: "keyword"},
+	{table: "banned_users", column: "user_id"},
 }
 
 // probeResult 一次采样结果
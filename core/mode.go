@@ -0,0 +1,104 @@
+package core
+
+// 规则的执行模式。
+//
+// 新加的词、正则、放宽的 AI 阈值, 上线前都该先"只看不动"跑一阵, 看看本会拦下多少、有没有误伤:
+//   - enforce: 正常处置 (删消息、记分、可能封禁)
+//   - shadow:  试行, 只记日志并在 moderation_actions 里留一条"本会拦截"的记录, 定期汇总给管理员
+//   - off:     停用, 完全不参与匹配
+//
+// 词表里的词条各自带 mode 列; 零宽字符、拆字这类内置规则没有行可挂, 模式存在 config 表里。
+import (
+	"fmt"
+	"strings"
+)
+
+// 执行模式
+const (
+	ModeEnforce = "enforce"
+	ModeShadow  = "shadow"
+	ModeOff     = "off"
+)
+
+// ruleModeKeyPrefix 内置规则模式在 config 表里的键前缀; 没有记录即为 enforce
+const ruleModeKeyPrefix = "rule_mode:"
+
+var modeLabels = map[string]string{
+	ModeEnforce: "执行",
+	ModeShadow:  "试行",
+	ModeOff:     "停用",
+}
+
+// ValidMode 判断执行模式是否合法
+func ValidMode(mode string) bool {
+	_, ok := modeLabels[mode]
+	return ok
+}
+
+// ModeLabel 执行模式的中文名, 未知模式原样返回
+func ModeLabel(mode string) string {
+	if label, ok := modeLabels[mode]; ok {
+		return label
+	}
+	return mode
+}
+
+// SetKeywordMode 修改词条的执行模式, 返回词条是否存在
+func (d *Database) SetKeywordMode(keyword, mode string) (bool, error) {
+	existing, ok, err := d.GetKeyword(keyword)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if err := d.db.Model(&Keyword{}).Where("keyword = ?", keyword).Update("mode", mode).Error; err != nil {
+		return false, err
+	}
+	d.invalidateKeywordScope(existing.Scope())
+	return true, nil
+}
+
+// SetRuleMode 修改内置规则的执行模式; 改回 enforce 时删掉记录, 让 config 表里只留例外
+func (d *Database) SetRuleMode(rule, mode string) error {
+	if !ValidMode(mode) {
+		return fmt.Errorf("未知的执行模式 %q", mode)
+	}
+
+	var err error
+	if mode == ModeEnforce {
+		err = d.DeleteConfig(ruleModeKeyPrefix + rule)
+	} else {
+		err = d.SetConfig(ruleModeKeyPrefix+rule, mode)
+	}
+	if err != nil {
+		return err
+	}
+	d.invalidateCache(cacheRuleModes)
+	return nil
+}
+
+// GetRuleMode 读取内置规则的执行模式, 没有记录时为 enforce; 走 TTL 缓存
+func (d *Database) GetRuleMode(rule string) (string, error) {
+	mode, ok, err := lookupCached(d, &d.ruleModes, rule, d.loadRuleModes)
+	if err != nil || !ok {
+		return ModeEnforce, err
+	}
+	return mode, nil
+}
+
+// GetRuleModes 列出全部非 enforce 的内置规则模式
+func (d *Database) GetRuleModes() (map[string]string, error) {
+	return d.loadRuleModes()
+}
+
+func (d *Database) loadRuleModes() (map[string]string, error) {
+	var rows []Config
+	if err := d.db.Where("key LIKE ?", ruleModeKeyPrefix+"%").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	modes := make(map[string]string, len(rows))
+	for _, row := range rows {
+		modes[strings.TrimPrefix(row.Key, ruleModeKeyPrefix)] = row.Value
+	}
+	return modes, nil
+}
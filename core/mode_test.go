@@ -0,0 +1,67 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestRuleModeCache 内置规则模式走缓存, 修改后立即生效; 改回 enforce 时 config 表里不留记录
+func TestRuleModeCache(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "mode.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	if mode, _ := db.GetRuleMode("zerowidth"); mode != ModeEnforce {
+		t.Fatalf("默认模式 = %s, 期望 enforce", mode)
+	}
+	if err := db.SetRuleMode("zerowidth", ModeShadow); err != nil {
+		t.Fatalf("SetRuleMode: %v", err)
+	}
+	if mode, _ := db.GetRuleMode("zerowidth"); mode != ModeShadow {
+		t.Errorf("修改后模式 = %s, 期望 shadow (缓存未失效)", mode)
+	}
+	if err := db.SetRuleMode("zerowidth", "maybe"); err == nil {
+		t.Error("非法模式应当报错")
+	}
+
+	db.SetRuleMode("zerowidth", ModeEnforce)
+	if modes, _ := db.GetRuleModes(); len(modes) != 0 {
+		t.Errorf("改回 enforce 后不应留记录, 实际 %v", modes)
+	}
+}
+
+// TestSetKeywordMode 词条模式修改后, 作用域缓存里的词条同步更新
+func TestSetKeywordMode(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "mode.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	db.AddKeyword("水果机", SourceManual)
+	if _, err := db.GetScopeKeywords(GlobalScope); err != nil {
+		t.Fatalf("预热缓存失败: %v", err)
+	}
+
+	if ok, err := db.SetKeywordMode("水果机", ModeShadow); !ok || err != nil {
+		t.Fatalf("SetKeywordMode = %v,%v", ok, err)
+	}
+	if ok, _ := db.SetKeywordMode("不存在", ModeShadow); ok {
+		t.Error("不存在的词应返回 false")
+	}
+
+	var got string
+	db.ScopeKeywordIndex(GlobalScope, func(keywords []Keyword) any {
+		for _, k := range keywords {
+			if k.Word == "水果机" {
+				got = k.Mode
+			}
+		}
+		return nil
+	})
+	if got != ModeShadow {
+		t.Errorf("缓存里的模式 = %q, 期望 shadow", got)
+	}
+}
@@ -14,14 +14,30 @@ import "time"
 // Keyword 过滤关键词。
 // IsLink / IsAutoAdded 是"同一链接不能发两次"功能的遗留字段, 已不再写入,
 // 保留是为了让 CleanupLegacyAutoLinks 能排干历史数据。
+//
+// ScopeChatID / ScopeTopicID 是作用域, 全 0 为全局词。唯一索引仍只建在 keyword 上,
+// 即一个词只能属于一个作用域: 改成 (keyword, scope) 联合唯一需要重建 keywords 表,
+// 而 2026-08-13 的数据丢失正是这张表的整表重建造成的, 不值得为"同词多域"冒这个险。
+//
+// Kind 区分纯文本词与规则 (见 rule.go), 规则表达式同样存在 keyword 列里; 存量行加列后取默认值 plain。
+// Mode 是执行模式 (见 mode.go), 新词可以先以 shadow 试行; 存量行取默认值 enforce, 行为不变。
 type Keyword struct {
-	ID          int64     `gorm:"column:id;primaryKey;autoIncrement"`
-	Word        string    `gorm:"column:keyword;uniqueIndex:uq_keywords_word"`
-	IsLink      bool      `gorm:"column:is_link;default:false"`
-	IsAutoAdded bool      `gorm:"column:is_auto_added;default:false"`
-	AddedAt     time.Time `gorm:"column:added_at"`
-	Source      string    `gorm:"column:source;not null;default:manual"`
-	HitCount    int       `gorm:"column:hit_count;not null;default:0"`
+	ID           int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	Word         string    `gorm:"column:keyword;uniqueIndex:uq_keywords_word"`
+	IsLink       bool      `gorm:"column:is_link;default:false"`
+	IsAutoAdded  bool      `gorm:"column:is_auto_added;default:false"`
+	AddedAt      time.Time `gorm:"column:added_at"`
+	Source       string    `gorm:"column:source;not null;default:manual"`
+	HitCount     int       `gorm:"column:hit_count;not null;default:0"`
+	Kind         string    `gorm:"column:kind;not null;default:plain"`
+	Mode         string    `gorm:"column:mode;not null;default:enforce"`
+	ScopeChatID  int64     `gorm:"column:scope_chat_id;not null;default:0;index:idx_keywords_scope"`
+	ScopeTopicID int       `gorm:"column:scope_topic_id;not null;default:0;index:idx_keywords_scope"`
+}
+
+// Scope 返回词条所属的作用域
+func (k Keyword) Scope() Scope {
+	return Scope{ChatID: k.ScopeChatID, TopicID: k.ScopeTopicID}
 }
 
 func (Keyword) TableName() string { return "keywords" }
@@ -50,13 +66,19 @@ type Config struct {
 
 func (Config) TableName() string { return "config" }
 
-// UserStat 每个用户在每个群的发言统计, 用于识别"新用户"决定是否走 AI 审核
+// UserStat 每个用户在每个群的发言统计, 用于识别"新用户"决定是否走 AI 审核。
+// VerifiedAt 是通过入群验证的时间, 通过时发言计数清零, AI 的新用户窗口从验证之后算起;
+// ReleasedAt 是管理员提前解除新成员限制的时间, 非零即不再受限 (见 probation.go);
+// TrustOverride 是管理员用 /trust、/distrust 指定的信任等级, 为空时按统计计算 (见 trust.go)。
 type UserStat struct {
-	UserID       int64     `gorm:"column:user_id;primaryKey"`
-	ChatID       int64     `gorm:"column:chat_id;primaryKey"`
-	MessageCount int       `gorm:"column:message_count;not null;default:0"`
-	FirstSeenAt  time.Time `gorm:"column:first_seen_at"`
-	LastSeenAt   time.Time `gorm:"column:last_seen_at"`
+	UserID        int64     `gorm:"column:user_id;primaryKey"`
+	ChatID        int64     `gorm:"column:chat_id;primaryKey"`
+	MessageCount  int       `gorm:"column:message_count;not null;default:0"`
+	FirstSeenAt   time.Time `gorm:"column:first_seen_at"`
+	LastSeenAt    time.Time `gorm:"column:last_seen_at"`
+	VerifiedAt    time.Time `gorm:"column:verified_at"`
+	ReleasedAt    time.Time `gorm:"column:released_at"`
+	TrustOverride string    `gorm:"column:trust_override"`
 }
 
 func (UserStat) TableName() string { return "user_stats" }
@@ -81,14 +103,138 @@ type ModerationActionRow struct {
 	UserName     string    `gorm:"column:user_name"`
 	MessageText  string    `gorm:"column:message_text"`
 	Rule         string    `gorm:"column:rule"`
+	Detail       string    `gorm:"column:detail"`
 	LearnedWords string    `gorm:"column:learned_words"`
 	Banned       bool      `gorm:"column:banned;not null;default:false"`
 	Undone       bool      `gorm:"column:undone;not null;default:false"`
+	Shadow       bool      `gorm:"column:shadow;not null;default:false"`      // 试行规则的"本会拦截"记录, 消息没删、没记分
+	Penalty      string    `gorm:"column:penalty"`                            // 实际执行的处罚 (见 penalty.go), 撤销时据此回滚; 老记录为空
+	Weight       int       `gorm:"column:weight;not null;default:0"`          // 本次记的违规分数 (见 weight.go), 撤销时扣回同样多; 老记录为 0, 按 1 分扣回
+	SenderChat   bool      `gorm:"column:sender_chat;not null;default:false"` // 以频道身份发言: UserID 存的是频道 ID, 处罚是封禁该频道身份
+	MediaHash    string    `gorm:"column:media_hash"`                         // 命中的已知广告图片特征 (见 MediaHash.Key), 撤销时从库里移除
 	CreatedAt    time.Time `gorm:"column:created_at"`
 }
 
 func (ModerationActionRow) TableName() string { return "moderation_actions" }
 
+// Admin 管理员名册, 每人一个角色。
+// ADMIN_ID 指定的所有者不入表, 由环境变量兜底 —— 名册被误删或误撤时, 至少还有一个人能登录把它修回来。
+type Admin struct {
+	UserID    int64     `gorm:"column:user_id;primaryKey"`
+	Role      string    `gorm:"column:role;not null"`
+	GrantedBy int64     `gorm:"column:granted_by"`
+	GrantedAt time.Time `gorm:"column:granted_at"`
+}
+
+func (Admin) TableName() string { return "admins" }
+
+// ManagedGroup 受管群组及其独立设置; 不在表里的群机器人一律不理会。
+// 布尔与阈值列刻意不写 default 标签: GORM 创建时会跳过零值字段改用列默认值,
+// 那样"关闭 AI""阈值设 0"这类设置在登记时就会被悄悄改回默认。
+// 例外是 federation: 它是给存量表补的列, SQLite 给已有行加 NOT NULL 列必须带默认值;
+// 默认值恰好是零值 false, 不存在被改回默认的问题。
+type ManagedGroup struct {
+	ChatID                int64     `gorm:"column:chat_id;primaryKey"`
+	Title                 string    `gorm:"column:title"`
+	Symbols               string    `gorm:"column:symbols"` // 逗号分隔的币安交易对, 为空表示不推送行情
+	AutoBanThreshold      int       `gorm:"column:auto_ban_threshold;not null"`
+	AIEnabled             bool      `gorm:"column:ai_enabled;not null"`
+	DeleteServiceMessages bool      `gorm:"column:delete_service_messages;not null"`
+	KeywordScope          string    `gorm:"column:keyword_scope;not null"`
+	PenaltyLadder         string    `gorm:"column:penalty_ladder"`                    // 处罚阶梯 (见 penalty.go), 为空时按 AutoBanThreshold 推算
+	Federation            bool      `gorm:"column:federation;not null;default:false"` // 是否加入跨群封禁名单 (见 federation.go)
+	Captcha               string    `gorm:"column:captcha"`                           // 入群验证题型, 为空表示不验证
+	CaptchaTimeout        int       `gorm:"column:captcha_timeout"`                   // 入群验证时限 (秒), 0 取默认值
+	ProbationHours        int       `gorm:"column:probation_hours"`                   // 新成员限制时长 (小时), 0 表示不限制
+	ProbationBlock        string    `gorm:"column:probation_block"`                   // 限制期内禁发的内容类型, 逗号分隔, 为空表示全部
+	StrictLinkHours       int       `gorm:"column:strict_link_hours"`                 // 新成员只能发白名单域名链接的时长 (小时), 0 表示不限制
+	FloodMessages         int       `gorm:"column:flood_messages"`                    // 单人 FloodSeconds 秒内最多发几条, 0 表示不限制 (见 flood.go)
+	FloodSeconds          int       `gorm:"column:flood_seconds"`                     // 单人限速的统计窗口 (秒)
+	RaidMembers           int       `gorm:"column:raid_members"`                      // RaidSeconds 秒内有几个新账号活跃即开启突袭模式, 0 表示不检测
+	RaidSeconds           int       `gorm:"column:raid_seconds"`                      // 突袭检测的统计窗口 (秒)
+	WaveAccounts          int       `gorm:"column:wave_accounts"`                     // WaveSeconds 秒内几个账号发近似内容即成批撤回, 0 取默认值 (见 flood.go)
+	WaveSeconds           int       `gorm:"column:wave_seconds"`                      // 多账号重复内容的统计窗口 (秒), 0 取默认值
+	AddedAt               time.Time `gorm:"column:added_at"`
+}
+
+func (ManagedGroup) TableName() string { return "managed_groups" }
+
+// SourceChannel 来源频道的黑白名单。Ref 是频道 ID 或小写的用户名 (不带 @):
+// 管理员手里往往只有 @用户名, 拿不到数字 ID, 两种写法都要能登记。
+type SourceChannel struct {
+	Ref     string    `gorm:"column:ref;primaryKey"`
+	List    string    `gorm:"column:list;not null"` // block / allow
+	Note    string    `gorm:"column:note"`          // 登记时看到的频道名, 仅供展示
+	AddedBy int64     `gorm:"column:added_by"`
+	AddedAt time.Time `gorm:"column:added_at"`
+}
+
+func (SourceChannel) TableName() string { return "source_channels" }
+
+// LinkDomain 链接域名的黑白名单。Domain 是小写、不带协议与路径的域名,
+// 登记 example.com 时其下全部子域名一并生效 (见 db_domain.go)。
+type LinkDomain struct {
+	Domain  string    `gorm:"column:domain;primaryKey"`
+	List    string    `gorm:"column:list;not null"` // block / allow
+	AddedBy int64     `gorm:"column:added_by"`
+	AddedAt time.Time `gorm:"column:added_at"`
+}
+
+func (LinkDomain) TableName() string { return "link_domains" }
+
+// ShortLink 短链展开结果的缓存: 每个短链只请求一次, 之后直接用落库的落地地址。
+// 展开失败也记下, 隔一段时间再重试 (见 moderation 包的 shortLinkRetry)。
+type ShortLink struct {
+	URL        string    `gorm:"column:url;primaryKey"`
+	Final      string    `gorm:"column:final"` // 落地地址; 展开失败时为空
+	Hops       int       `gorm:"column:hops;not null;default:0"`
+	Error      string    `gorm:"column:error"`
+	ResolvedAt time.Time `gorm:"column:resolved_at;index:idx_short_links_resolved"`
+}
+
+func (ShortLink) TableName() string { return "short_links" }
+
+// MediaHash 已知广告图片与贴纸的特征。Kind 为 dhash 时 Hash 是图片感知哈希的 16 位十六进制,
+// 为 file 时是 Telegram 的 file_unique_id (贴纸、动图与原样转发的图片不会变)。
+type MediaHash struct {
+	ID      int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	Kind    string    `gorm:"column:kind;not null;uniqueIndex:idx_media_hashes_kind_hash"`
+	Hash    string    `gorm:"column:hash;not null;uniqueIndex:idx_media_hashes_kind_hash"`
+	Note    string    `gorm:"column:note"` // 学习来源, 如"管理员 /ban"
+	AddedBy int64     `gorm:"column:added_by"`
+	AddedAt time.Time `gorm:"column:added_at"`
+}
+
+func (MediaHash) TableName() string { return "media_hashes" }
+
+// JoinChallenge 进行中的入群验证。落库是为了重启后还能判定答案、按时踢出超时未答的人;
+// 否则重启期间入群的账号会一直处于禁言状态, 既发不了言也不会被清走。
+type JoinChallenge struct {
+	ChatID    int64     `gorm:"column:chat_id;primaryKey"`
+	UserID    int64     `gorm:"column:user_id;primaryKey"`
+	UserName  string    `gorm:"column:user_name"`
+	Kind      string    `gorm:"column:kind;not null"`
+	Answer    int       `gorm:"column:answer;not null"`     // 正确选项的下标
+	MessageID int       `gorm:"column:message_id;not null"` // 验证题所在的消息, 验证结束后删除
+	ExpiresAt time.Time `gorm:"column:expires_at;index:idx_join_challenges_expires"`
+	FirstJoin bool      `gorm:"column:first_join;not null;default:false"` // 入群前在本群没有统计记录, 通过后发言计数从零算
+}
+
+func (JoinChallenge) TableName() string { return "join_challenges" }
+
+// BannedUser 跨群封禁名单的一条记录。一个用户只有一条, 以首次入名单为准: 后续在别的群被顺带封禁不改写原因与来源。
+type BannedUser struct {
+	UserID   int64     `gorm:"column:user_id;primaryKey"`
+	UserName string    `gorm:"column:user_name"`
+	Reason   string    `gorm:"column:reason"`
+	ActionID int64     `gorm:"column:action_id"` // 触发封禁的处置记录; 管理员手工 /ban 或导入时为 0
+	ChatID   int64     `gorm:"column:chat_id"`   // 最初封禁发生的群; 导入时为 0
+	BannedBy int64     `gorm:"column:banned_by"` // 执行封禁的管理员; 自动封禁为 0
+	BannedAt time.Time `gorm:"column:banned_at"`
+}
+
+func (BannedUser) TableName() string { return "banned_users" }
+
 // allModels AutoMigrate 的目标清单; 新增表必须登记在这里
 func allModels() []any {
 	return []any{
@@ -99,5 +245,13 @@ func allModels() []any {
 		&UserStat{},
 		&UserStrike{},
 		&ModerationActionRow{},
+		&Admin{},
+		&ManagedGroup{},
+		&BannedUser{},
+		&JoinChallenge{},
+		&SourceChannel{},
+		&LinkDomain{},
+		&ShortLink{},
+		&MediaHash{},
 	}
 }
@@ -0,0 +1,186 @@
+package core
+
+// 违规处罚阶梯。
+//
+// 每次违规都先删消息、记一分, 再按累计分数取阶梯上对应的一级处罚:
+// 第 1 次违规取第 1 级, 第 2 次取第 2 级, 超出阶梯长度的一律取最后一级。
+// 阶梯按群存储, 写法形如 "warn,mute:1h,mute:24h,ban"; 没有设置阶梯的群沿用 AutoBanThreshold 的老语义,
+// 即前 N-1 次只删消息、第 N 次封禁, 升级前后行为不变。
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 处罚动作
+const (
+	PenaltyDelete = "delete" // 只删消息
+	PenaltyWarn   = "warn"   // 删消息并在群里点名警告
+	PenaltyMute   = "mute"   // 删消息并禁言一段时间
+	PenaltyBan    = "ban"    // 删消息并永久封禁
+)
+
+const (
+	// minMuteDuration 禁言下限; Telegram 把不足 30 秒的禁言当作永久禁言, 留足余量
+	minMuteDuration = time.Minute
+	// maxMuteDuration 禁言上限; 超过 366 天同样会被当作永久, 永久请直接用 ban
+	maxMuteDuration = 366 * 24 * time.Hour
+	// maxLadderRungs 阶梯级数上限, 防止误粘贴出一条几十级的阶梯
+	maxLadderRungs = 10
+)
+
+// Penalty 阶梯上的一级处罚
+type Penalty struct {
+	Action   string
+	Duration time.Duration // 仅 mute 使用
+}
+
+// ParsePenalty 解析单级处罚: delete / warn / ban / mute:时长, 时长支持 30m、1h、7d 这类写法
+func ParsePenalty(raw string) (Penalty, error) {
+	action, arg, hasArg := strings.Cut(strings.ToLower(strings.TrimSpace(raw)), ":")
+	switch action {
+	case PenaltyDelete, PenaltyWarn, PenaltyBan:
+		if hasArg {
+			return Penalty{}, fmt.Errorf("%s 不带时长", action)
+		}
+		return Penalty{Action: action}, nil
+	case PenaltyMute:
+		if !hasArg {
+			return Penalty{}, fmt.Errorf("mute 需要时长，如 mute:1h")
+		}
+		duration, err := parsePenaltyDuration(arg)
+		if err != nil {
+			return Penalty{}, err
+		}
+		if duration < minMuteDuration || duration > maxMuteDuration || duration%time.Minute != 0 {
+			return Penalty{}, fmt.Errorf("禁言时长需为整分钟, 且在 1 分钟到 366 天之间")
+		}
+		return Penalty{Action: PenaltyMute, Duration: duration}, nil
+	default:
+		return Penalty{}, fmt.Errorf("未知的处罚 %q，可选 delete、warn、mute:时长、ban", raw)
+	}
+}
+
+// ParseLadder 解析逗号分隔的处罚阶梯。ban 之后的级数永远走不到, 写了多半是笔误, 直接报错
+func ParseLadder(raw string) ([]Penalty, error) {
+	var ladder []Penalty
+	for _, part := range strings.Split(raw, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		penalty, err := ParsePenalty(part)
+		if err != nil {
+			return nil, err
+		}
+		if len(ladder) > 0 && ladder[len(ladder)-1].Action == PenaltyBan {
+			return nil, fmt.Errorf("ban 之后不能再有其他处罚")
+		}
+		ladder = append(ladder, penalty)
+	}
+
+	switch {
+	case len(ladder) == 0:
+		return nil, fmt.Errorf("阶梯至少需要一级")
+	case len(ladder) > maxLadderRungs:
+		return nil, fmt.Errorf("阶梯最多 %d 级", maxLadderRungs)
+	}
+	return ladder, nil
+}
+
+// FormatLadder 把阶梯写回存储格式
+func FormatLadder(ladder []Penalty) string {
+	parts := make([]string, 0, len(ladder))
+	for _, penalty := range ladder {
+		parts = append(parts, penalty.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// PenaltyAt 取累计违规 strikes 次时应执行的处罚; strikes 不足 1 (计分失败) 时只删消息
+func PenaltyAt(ladder []Penalty, strikes int) Penalty {
+	if strikes < 1 || len(ladder) == 0 {
+		return Penalty{Action: PenaltyDelete}
+	}
+	return ladder[min(strikes, len(ladder))-1]
+}
+
+// Ladder 返回群的处罚阶梯; 未设置或存储值已无法解析时, 按 AutoBanThreshold 推出等价的老阶梯
+func (g ManagedGroup) Ladder() []Penalty {
+	if g.PenaltyLadder != "" {
+		if ladder, err := ParseLadder(g.PenaltyLadder); err == nil {
+			return ladder
+		}
+	}
+
+	if g.AutoBanThreshold <= 0 {
+		return []Penalty{{Action: PenaltyDelete}}
+	}
+	ladder := make([]Penalty, g.AutoBanThreshold)
+	for i := range ladder {
+		ladder[i] = Penalty{Action: PenaltyDelete}
+	}
+	ladder[len(ladder)-1] = Penalty{Action: PenaltyBan}
+	return ladder
+}
+
+// String 存储格式, 如 "mute:1h"
+func (p Penalty) String() string {
+	if p.Action == PenaltyMute {
+		return PenaltyMute + ":" + formatPenaltyDuration(p.Duration)
+	}
+	return p.Action
+}
+
+// Label 中文展示, 如 "禁言 1 小时"
+func (p Penalty) Label() string {
+	switch p.Action {
+	case PenaltyWarn:
+		return "警告"
+	case PenaltyMute:
+		return "禁言 " + penaltyDurationLabel(p.Duration)
+	case PenaltyBan:
+		return "封禁"
+	default:
+		return "删除消息"
+	}
+}
+
+// parsePenaltyDuration 在 time.ParseDuration 之外补上按天的写法
+func parsePenaltyDuration(raw string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("无法解析时长 %q", raw)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	duration, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("无法解析时长 %q", raw)
+	}
+	return duration, nil
+}
+
+// formatPenaltyDuration 取能整除的最大单位, 保证 ParsePenalty(p.String()) 往返一致
+func formatPenaltyDuration(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+}
+
+func penaltyDurationLabel(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%d 天", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%d 小时", d/time.Hour)
+	default:
+		return fmt.Sprintf("%d 分钟", d/time.Minute)
+	}
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLadder(t *testing.T) {
+	ladder, err := ParseLadder("warn, mute:90m, mute:1d ,ban")
+	if err != nil {
+		t.Fatalf("ParseLadder: %v", err)
+	}
+	if got := FormatLadder(ladder); got != "warn,mute:90m,mute:1d,ban" {
+		t.Errorf("FormatLadder = %q", got)
+	}
+	if ladder[2].Duration != 24*time.Hour || ladder[2].Label() != "禁言 1 天" {
+		t.Errorf("mute:1d 解析为 %+v", ladder[2])
+	}
+
+	// 写错的阶梯要在保存时拦下, 不能等到处罚时才发现
+	for _, raw := range []string{"", "kick", "mute", "mute:10s", "mute:400d", "ban,warn", "warn:1h"} {
+		if _, err := ParseLadder(raw); err == nil {
+			t.Errorf("ParseLadder(%q) 应当报错", raw)
+		}
+	}
+}
+
+func TestPenaltyAt(t *testing.T) {
+	ladder, _ := ParseLadder("warn,mute:1h,ban")
+	cases := map[int]string{0: PenaltyDelete, 1: PenaltyWarn, 2: PenaltyMute, 3: PenaltyBan, 9: PenaltyBan}
+	for strikes, want := range cases {
+		if got := PenaltyAt(ladder, strikes).Action; got != want {
+			t.Errorf("第 %d 次违规处罚 = %s, 期望 %s", strikes, got, want)
+		}
+	}
+}
+
+// TestLegacyLadder 没设置阶梯的群, 行为必须与升级前的 AutoBanThreshold 完全一致
+func TestLegacyLadder(t *testing.T) {
+	group := ManagedGroup{AutoBanThreshold: 3}
+	ladder := group.Ladder()
+	for strikes, want := range map[int]string{1: PenaltyDelete, 2: PenaltyDelete, 3: PenaltyBan, 5: PenaltyBan} {
+		if got := PenaltyAt(ladder, strikes).Action; got != want {
+			t.Errorf("阈值 3 时第 %d 次违规处罚 = %s, 期望 %s", strikes, got, want)
+		}
+	}
+
+	group.AutoBanThreshold = 0
+	if got := PenaltyAt(group.Ladder(), 100).Action; got != PenaltyDelete {
+		t.Errorf("阈值 0 时不应封禁, 实际 %s", got)
+	}
+}
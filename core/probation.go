@@ -0,0 +1,102 @@
+package core
+
+// 新成员限制 (probation)。
+//
+// 广告号的第一条消息往往就是链接、频道转发或带按钮的卡片。开启限制的群里, 新成员在前若干小时内
+// 不能发这几类内容, 发了即撤回; 普通文字照常。限制期从该用户在本群首次出现 (入群或首次发言) 算起,
+// 管理员可以用 /release 提前解除。禁发的内容类型按群设置, 不设置时全部禁发。
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 限制期内可禁发的内容类型
+const (
+	ProbationLink    = "link"    // 正文或说明文字里的链接
+	ProbationForward = "forward" // 转发自频道的消息
+	ProbationMedia   = "media"   // 图片、视频、文件、语音等媒体
+	ProbationButton  = "button"  // 带内联按钮的消息
+	ProbationContact = "contact" // 联系人名片
+)
+
+// probationTypes 全部内容类型, 按展示顺序排列
+var probationTypes = []string{ProbationLink, ProbationForward, ProbationMedia, ProbationButton, ProbationContact}
+
+// probationLabels 内容类型的中文展示
+var probationLabels = map[string]string{
+	ProbationLink:    "链接",
+	ProbationForward: "频道转发",
+	ProbationMedia:   "图片和文件",
+	ProbationButton:  "按钮",
+	ProbationContact: "联系人名片",
+}
+
+// MaxProbationHours 限制时长上限; 再长就不是"新成员"了
+const MaxProbationHours = 30 * 24
+
+// ProbationTypes 列出全部可禁发的内容类型
+func ProbationTypes() []string {
+	return slices.Clone(probationTypes)
+}
+
+// ProbationLabel 内容类型的中文展示
+func ProbationLabel(kind string) string {
+	if label, ok := probationLabels[kind]; ok {
+		return label
+	}
+	return kind
+}
+
+// ParseProbationBlock 解析逗号分隔的内容类型, 去重并按固定顺序返回存储格式
+func ParseProbationBlock(raw string) (string, error) {
+	picked := make(map[string]bool)
+	for _, part := range strings.Split(strings.ToLower(raw), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if _, ok := probationLabels[part]; !ok {
+			return "", fmt.Errorf("未知的内容类型 %q，可选 %s", part, strings.Join(probationTypes, "、"))
+		}
+		picked[part] = true
+	}
+	if len(picked) == 0 {
+		return "", fmt.Errorf("至少需要一种内容类型")
+	}
+
+	kinds := make([]string, 0, len(picked))
+	for _, kind := range probationTypes {
+		if picked[kind] {
+			kinds = append(kinds, kind)
+		}
+	}
+	return strings.Join(kinds, ","), nil
+}
+
+// ProbationWindow 新成员限制时长, 0 表示未开启
+func (g ManagedGroup) ProbationWindow() time.Duration {
+	return time.Duration(g.ProbationHours) * time.Hour
+}
+
+// ProbationBlocks 限制期内禁发的内容类型; 未设置或存储值已无法解析时全部禁发
+func (g ManagedGroup) ProbationBlocks() []string {
+	if g.ProbationBlock != "" {
+		if normalized, err := ParseProbationBlock(g.ProbationBlock); err == nil {
+			return strings.Split(normalized, ",")
+		}
+	}
+	return ProbationTypes()
+}
+
+// ProbationEnds 限制期的结束时间; 第二个返回值为 false 表示已不在限制期内 (或已被管理员解除)。
+// 每条消息审核前都会先记下发言统计, 首次开口的人此时已有记录, 从这一刻起算;
+// 没有记录只可能是查库失败, 按不受限处理, 不能凭空把老成员当成新人。
+func ProbationEnds(stat UserStat, found bool, window time.Duration, now time.Time) (time.Time, bool) {
+	if window <= 0 || !found || stat.FirstSeenAt.IsZero() || !stat.ReleasedAt.IsZero() {
+		return time.Time{}, false
+	}
+	ends := stat.FirstSeenAt.Add(window)
+	return ends, now.Before(ends)
+}
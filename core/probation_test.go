@@ -0,0 +1,86 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseProbationBlock(t *testing.T) {
+	got, err := ParseProbationBlock(" Media, link ,media")
+	if err != nil || got != "link,media" {
+		t.Errorf("ParseProbationBlock = %q,%v, 期望按固定顺序去重为 link,media", got, err)
+	}
+	for _, raw := range []string{"", " , ", "link,sticker"} {
+		if _, err := ParseProbationBlock(raw); err == nil {
+			t.Errorf("ParseProbationBlock(%q) 应当报错", raw)
+		}
+	}
+
+	if blocks := (ManagedGroup{ProbationBlock: "garbage"}).ProbationBlocks(); len(blocks) != len(probationTypes) {
+		t.Errorf("存储值损坏时应全部禁发, 实际 %v", blocks)
+	}
+}
+
+// TestProbationEnds 限制期从首次出现算起, 没有记录的不受限, 被解除的不再受限
+func TestProbationEnds(t *testing.T) {
+	now := time.Now()
+	window := 24 * time.Hour
+
+	cases := []struct {
+		name  string
+		stat  UserStat
+		found bool
+		want  bool
+	}{
+		{"没有记录", UserStat{}, false, false},
+		{"一小时前入群", UserStat{FirstSeenAt: now.Add(-time.Hour)}, true, true},
+		{"两天前入群", UserStat{FirstSeenAt: now.Add(-48 * time.Hour)}, true, false},
+		{"已被解除", UserStat{FirstSeenAt: now.Add(-time.Hour), ReleasedAt: now}, true, false},
+	}
+	for _, c := range cases {
+		if _, got := ProbationEnds(c.stat, c.found, window, now); got != c.want {
+			t.Errorf("%s: 是否受限 = %v, 期望 %v", c.name, got, c.want)
+		}
+	}
+	if _, got := ProbationEnds(UserStat{}, false, 0, now); got {
+		t.Error("未开启限制的群不应受限")
+	}
+}
+
+// TestJoinAndRelease 入群记录不覆盖老成员的首次出现时间, 解除覆盖全部给定的群
+func TestJoinAndRelease(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "probation.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	if _, created, err := db.BumpUserMessageCount(1, -100); err != nil || !created {
+		t.Fatalf("首次发言 BumpUserMessageCount = %v,%v, 应当新建记录", created, err)
+	}
+	before, _, _ := db.GetUserStat(1, -100)
+	time.Sleep(10 * time.Millisecond)
+	if firstJoin, err := db.RecordJoin(1, -100); err != nil || firstJoin {
+		t.Fatalf("老成员重新入群 RecordJoin = %v,%v, 不应算首次入群", firstJoin, err)
+	}
+	if after, _, _ := db.GetUserStat(1, -100); !after.FirstSeenAt.Equal(before.FirstSeenAt) || after.MessageCount != 1 {
+		t.Errorf("老成员重新入群后记录被改写: %+v -> %+v", before, after)
+	}
+	if stat, created, _ := db.BumpUserMessageCount(2, -200); !created || stat.MessageCount != 1 {
+		t.Errorf("没有记录的用户首次发言 = %+v,%v", stat, created)
+	}
+	db.RecordJoin(3, -100)
+	if stat, created, _ := db.BumpUserMessageCount(3, -100); created || stat.MessageCount != 1 {
+		t.Errorf("入群后首次发言应沿用入群记录: %+v,%v", stat, created)
+	}
+
+	if err := db.ReleaseProbation(1, []int64{-100, -200}); err != nil {
+		t.Fatalf("ReleaseProbation 失败: %v", err)
+	}
+	for _, chatID := range []int64{-100, -200} {
+		if stat, ok, _ := db.GetUserStat(1, chatID); !ok || stat.ReleasedAt.IsZero() {
+			t.Errorf("群 %d 的解除记录 = %+v,%v", chatID, stat, ok)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package core
+
+// 规则型词条: 纯文本关键词之外的两种写法, 与关键词同存 keywords 表, 用 kind 列区分。
+//
+//   - regex: RE2 正则, 例如 \d{7,}微信 (一串像手机号的数字紧跟"微信")
+//   - combo: 组合规则, A AND B 要求几个词同时出现; A NEAR/10 B 要求两个词相距不超过 10 个字
+//
+// 这里只负责解析与校验, 真正的匹配在 moderation 包里做 —— 组合规则的词要和消息一样先归一化,
+// 归一化逻辑在那边。
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 词条类型
+const (
+	KindPlain = "plain"
+	KindRegex = "regex"
+	KindCombo = "combo"
+)
+
+const (
+	// maxRuleLength 规则表达式的最大字符数; 比关键词宽一些, 正则写起来本来就长
+	maxRuleLength = 200
+	// maxRuleInstructions 正则编译后的指令数上限。RE2 保证线性时间, 但 (a{100}){100} 这类写法
+	// 会编译出巨大的程序, 每条群消息都要跑一遍, 必须在入库前拦住
+	maxRuleInstructions = 2000
+	// 组合规则的词数与 NEAR 距离上限; 再大就失去"组合"的意义, 更可能是写错了
+	maxComboTerms    = 5
+	maxNearDistance  = 100
+	comboAndOperator = "AND"
+	comboNearPrefix  = "NEAR/"
+)
+
+// ruleKindLabels 词条类型的中文名, 用于管理员界面
+var ruleKindLabels = map[string]string{
+	KindPlain: "关键词",
+	KindRegex: "正则",
+	KindCombo: "组合",
+}
+
+// KindLabel 返回词条类型的中文名, 未知类型原样返回
+func KindLabel(kind string) string {
+	if label, ok := ruleKindLabels[kind]; ok {
+		return label
+	}
+	return kind
+}
+
+// ParsedRule 解析后的规则
+type ParsedRule struct {
+	Kind   string
+	Regexp *regexp.Regexp // regex 规则
+	Terms  []string       // combo 规则的各个词, 未归一化
+	// Near combo 规则为 NEAR 时的最大间隔字数; 0 表示 AND, 只要求同时出现
+	Near int
+}
+
+// ParseRule 解析并校验规则表达式; kind 为 plain 时不应调用
+func ParseRule(kind, expr string) (ParsedRule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return ParsedRule{}, fmt.Errorf("规则不能为空")
+	}
+	if utf8.RuneCountInString(expr) > maxRuleLength {
+		return ParsedRule{}, fmt.Errorf("规则长度不能超过 %d 个字符", maxRuleLength)
+	}
+
+	switch kind {
+	case KindRegex:
+		return parseRegexRule(expr)
+	case KindCombo:
+		return parseComboRule(expr)
+	default:
+		return ParsedRule{}, fmt.Errorf("未知的规则类型 %q", kind)
+	}
+}
+
+// parseRegexRule 编译正则并检查复杂度。能匹配空文本的正则会命中每一条消息, 一律拒绝。
+func parseRegexRule(expr string) (ParsedRule, error) {
+	parsed, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return ParsedRule{}, fmt.Errorf("正则写法有误: %v", err)
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return ParsedRule{}, fmt.Errorf("正则写法有误: %v", err)
+	}
+	if len(prog.Inst) > maxRuleInstructions {
+		return ParsedRule{}, fmt.Errorf("正则过于复杂, 请拆成几条简单的规则")
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return ParsedRule{}, fmt.Errorf("正则写法有误: %v", err)
+	}
+	if re.MatchString("") {
+		return ParsedRule{}, fmt.Errorf("正则能匹配空文本, 会命中所有消息")
+	}
+	return ParsedRule{Kind: KindRegex, Regexp: re}, nil
+}
+
+// parseComboRule 解析 A AND B [AND C ...] 或 A NEAR/n B。
+// 运算符必须大写, 两侧要有空格; 词本身可以含空格 (匹配前会归一化掉)。AND 与 NEAR 不能混用。
+func parseComboRule(expr string) (ParsedRule, error) {
+	rule := ParsedRule{Kind: KindCombo}
+
+	var (
+		current []string
+		nearOps int
+		andOps  int
+	)
+	flush := func() error {
+		term := strings.Join(current, " ")
+		if term == "" {
+			return fmt.Errorf("运算符两侧都要有词")
+		}
+		if strings.ContainsAny(term, forbiddenChars) {
+			return fmt.Errorf("规则包含不允许的字符")
+		}
+		rule.Terms = append(rule.Terms, term)
+		current = current[:0]
+		return nil
+	}
+
+	for _, token := range strings.Fields(expr) {
+		switch {
+		case token == comboAndOperator:
+			andOps++
+		case strings.HasPrefix(token, comboNearPrefix):
+			distance, err := strconv.Atoi(strings.TrimPrefix(token, comboNearPrefix))
+			if err != nil || distance <= 0 || distance > maxNearDistance {
+				return ParsedRule{}, fmt.Errorf("%s 后面应为 1-%d 的距离", comboNearPrefix, maxNearDistance)
+			}
+			rule.Near = distance
+			nearOps++
+		default:
+			current = append(current, token)
+			continue
+		}
+		if err := flush(); err != nil {
+			return ParsedRule{}, err
+		}
+	}
+	if err := flush(); err != nil {
+		return ParsedRule{}, err
+	}
+
+	switch {
+	case nearOps > 0 && andOps > 0:
+		return ParsedRule{}, fmt.Errorf("AND 与 NEAR 不能混用")
+	case nearOps > 1:
+		return ParsedRule{}, fmt.Errorf("NEAR 只支持两个词")
+	case nearOps == 0 && andOps == 0:
+		return ParsedRule{}, fmt.Errorf("组合规则至少要有一个 AND 或 NEAR/距离")
+	case len(rule.Terms) > maxComboTerms:
+		return ParsedRule{}, fmt.Errorf("组合规则最多 %d 个词", maxComboTerms)
+	}
+	return rule, nil
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRuleAccepts(t *testing.T) {
+	cases := []struct {
+		kind, expr string
+		terms      int
+		near       int
+	}{
+		{KindRegex, `\d{7,}微信`, 0, 0},
+		{KindRegex, `(?i)https?://\S+\.apk`, 0, 0},
+		{KindCombo, "水果 NEAR/10 特价", 2, 10},
+		{KindCombo, "出售 AND 私聊 AND 低价", 3, 0},
+		{KindCombo, "iPhone 16 AND 全新", 2, 0},
+	}
+	for _, c := range cases {
+		rule, err := ParseRule(c.kind, c.expr)
+		if err != nil {
+			t.Errorf("ParseRule(%s, %q) 报错: %v", c.kind, c.expr, err)
+			continue
+		}
+		if len(rule.Terms) != c.terms || rule.Near != c.near {
+			t.Errorf("ParseRule(%s, %q) = %d 个词 NEAR/%d, 期望 %d 个词 NEAR/%d", c.kind, c.expr, len(rule.Terms), rule.Near, c.terms, c.near)
+		}
+	}
+}
+
+func TestParseRuleRejects(t *testing.T) {
+	cases := []struct{ kind, expr string }{
+		{KindRegex, `(`},                      // 写错
+		{KindRegex, `.*`},                     // 匹配空文本, 会命中所有消息
+		{KindRegex, `a|`},                     // 同上, 更隐蔽
+		{KindRegex, `((a{50}){50}){50}`},      // 编译后超出复杂度上限
+		{KindRegex, strings.Repeat("a", 201)}, // 超长
+		{KindCombo, "水果 特价"},                  // 没有运算符
+		{KindCombo, "水果 AND"},                 // 运算符一侧没有词
+		{KindCombo, "a NEAR/5 b AND c"},       // 混用
+		{KindCombo, "a NEAR/5 b NEAR/5 c"},    // NEAR 只支持两个词
+		{KindCombo, "a NEAR/0 b"},
+		{KindCombo, "a NEAR/x b"},
+		{KindCombo, "a AND b AND c AND d AND e AND f"},
+		{"glob", "水果*"},
+	}
+	for _, c := range cases {
+		if _, err := ParseRule(c.kind, c.expr); err == nil {
+			t.Errorf("ParseRule(%s, %q) 应当报错", c.kind, c.expr)
+		}
+	}
+}
+
+func TestValidateKeywordByKind(t *testing.T) {
+	// 反斜杠对纯文本词是禁用字符, 对正则却是必需的
+	if err := ValidateKeyword(KindPlain, `\d+`); err == nil {
+		t.Error("纯文本词不应接受反斜杠")
+	}
+	if err := ValidateKeyword(KindRegex, `\d{7,}微信`); err != nil {
+		t.Errorf("正则规则校验失败: %v", err)
+	}
+}
@@ -2,8 +2,12 @@ package core
 
 // Telegram 消息收发的共用封装
 import (
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"net/url"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -12,6 +16,12 @@ import (
 // maxMessageLength Telegram 单条消息上限 4096, 留出余量避免分段边界超限
 const maxMessageLength = 4000
 
+// fileDownloadTimeout 下载群内文件 (图片审核用) 的超时
+const fileDownloadTimeout = 30 * time.Second
+
+// fileClient 下载文件专用的客户端; 与 Bot API 的长轮询分开, 免得大文件占住连接
+var fileClient = &http.Client{Timeout: fileDownloadTimeout}
+
 func SendMessage(bot *tgbotapi.BotAPI, chatID int64, text string) error {
 	msg := tgbotapi.NewMessage(chatID, text)
 	_, err := bot.Send(msg)
@@ -70,10 +80,91 @@ func BanUser(bot *tgbotapi.BotAPI, chatID, userID int64) error {
 	return err
 }
 
-// NotifyAdmin 私聊推送一条运维通知给管理员; 发送失败只记日志, 不影响主流程
+// KickUser 把成员移出群但不拉黑, 之后仍可重新加入: 封禁后立即解封
+func KickUser(bot *tgbotapi.BotAPI, chatID, userID int64) error {
+	if err := BanUser(bot, chatID, userID); err != nil {
+		return err
+	}
+	return UnbanUser(bot, chatID, userID)
+}
+
+// UnbanUser 解除封禁; 只对已封禁的用户生效, 不会把不在群里的人拉回来, 也不会踢出正常成员
+func UnbanUser(bot *tgbotapi.BotAPI, chatID, userID int64) error {
+	unbanConfig := tgbotapi.UnbanChatMemberConfig{
+		ChatMemberConfig: tgbotapi.ChatMemberConfig{
+			ChatID: chatID,
+			UserID: userID,
+		},
+		OnlyIfBanned: true,
+	}
+	_, err := bot.Request(unbanConfig)
+	return err
+}
+
+// BanSenderChat 封禁以频道身份发言的频道: 该频道之后不能再以自身身份在本群发言, 频道主本人的账号不受影响
+func BanSenderChat(bot *tgbotapi.BotAPI, chatID, senderChatID int64) error {
+	_, err := bot.Request(tgbotapi.BanChatSenderChatConfig{ChatID: chatID, SenderChatID: senderChatID})
+	return err
+}
+
+// UnbanSenderChat 解除对频道身份的封禁
+func UnbanSenderChat(bot *tgbotapi.BotAPI, chatID, senderChatID int64) error {
+	_, err := bot.Request(tgbotapi.UnbanChatSenderChatConfig{ChatID: chatID, SenderChatID: senderChatID})
+	return err
+}
+
+// MuteUser 禁言群成员到指定时间, until 为零值表示直到解除; 权限全部置空即禁止发送任何内容
+func MuteUser(bot *tgbotapi.BotAPI, chatID, userID int64, until time.Time) error {
+	restrictConfig := tgbotapi.RestrictChatMemberConfig{
+		ChatMemberConfig: tgbotapi.ChatMemberConfig{
+			ChatID: chatID,
+			UserID: userID,
+		},
+		Permissions: &tgbotapi.ChatPermissions{},
+	}
+	if !until.IsZero() {
+		restrictConfig.UntilDate = until.Unix()
+	}
+	_, err := bot.Request(restrictConfig)
+	return err
+}
+
+// UnmuteUser 解除禁言, 恢复为群的默认权限。
+// 不能直接把权限全给 true: 那会连改群信息、邀请、置顶一起放开, 超出群里普通成员本来的权限
+func UnmuteUser(bot *tgbotapi.BotAPI, chatID, userID int64) error {
+	chat, err := bot.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: chatID}})
+	if err != nil {
+		return fmt.Errorf("读取群默认权限失败: %w", err)
+	}
+	permissions := chat.Permissions
+	if permissions == nil {
+		// 群组总会带上默认权限, 拿不到时只放开发言, 宁可少给
+		permissions = &tgbotapi.ChatPermissions{
+			CanSendMessages:       true,
+			CanSendMediaMessages:  true,
+			CanSendPolls:          true,
+			CanSendOtherMessages:  true,
+			CanAddWebPagePreviews: true,
+		}
+	}
+
+	restrictConfig := tgbotapi.RestrictChatMemberConfig{
+		ChatMemberConfig: tgbotapi.ChatMemberConfig{
+			ChatID: chatID,
+			UserID: userID,
+		},
+		Permissions: permissions,
+	}
+	_, err = bot.Request(restrictConfig)
+	return err
+}
+
+// NotifyAdmin 私聊推送一条运维通知给所有有处置权限的管理员; 发送失败只记日志, 不影响主流程
 func NotifyAdmin(bot *tgbotapi.BotAPI, text string) {
-	if err := SendMessage(bot, AdminID, text); err != nil {
-		log.Printf("[Core] 通知管理员失败: %v", err)
+	for _, adminID := range AdminsWith(PermModerate) {
+		if err := SendMessage(bot, adminID, text); err != nil {
+			log.Printf("[Core] 通知管理员 %d 失败: %v", adminID, err)
+		}
 	}
 }
 
@@ -86,3 +177,33 @@ func DeleteMessages(bot *tgbotapi.BotAPI, chatID int64, messageIDs ...int) {
 		}
 	}
 }
+
+// DownloadFile 通过 Bot API 下载文件内容; 超过 limit 字节的报错, 不整个读进内存。
+// 下载地址里带着 Bot Token, 返回的错误只保留原因, 不带地址, 免得 token 写进日志。
+func DownloadFile(bot *tgbotapi.BotAPI, fileID string, limit int64) ([]byte, error) {
+	link, err := bot.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件地址失败: %w", err)
+	}
+	resp, err := fileClient.Get(link)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("下载文件失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载文件失败: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("文件超过 %d 字节", limit)
+	}
+	return data, nil
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TestUnmuteUserRestoresChatDefaults 解除禁言按群的默认权限恢复, 不额外放开改群信息、邀请、置顶
+func TestUnmuteUserRestoresChatDefaults(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		permissions string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch path.Base(r.URL.Path) {
+		case "getMe":
+			w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"username":"test_bot"}}`))
+		case "getChat":
+			w.Write([]byte(`{"ok":true,"result":{"id":-100,"type":"supergroup","permissions":{"can_send_messages":true,"can_send_media_messages":true}}}`))
+		case "restrictChatMember":
+			mu.Lock()
+			permissions = r.Form.Get("permissions")
+			mu.Unlock()
+			w.Write([]byte(`{"ok":true,"result":true}`))
+		default:
+			w.Write([]byte(`{"ok":true,"result":true}`))
+		}
+	}))
+	defer server.Close()
+
+	bot, err := tgbotapi.NewBotAPIWithAPIEndpoint("123:test", server.URL+"/bot%s/%s")
+	if err != nil {
+		t.Fatalf("创建 bot 失败: %v", err)
+	}
+	if err := UnmuteUser(bot, -100, 42); err != nil {
+		t.Fatalf("UnmuteUser 失败: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.Contains(permissions, `"can_send_media_messages":true`) {
+		t.Errorf("应恢复群默认的发言权限, 实际 %s", permissions)
+	}
+	for _, extra := range []string{"can_change_info", "can_invite_users", "can_pin_messages", "can_send_polls"} {
+		if strings.Contains(permissions, extra) {
+			t.Errorf("群默认权限之外的 %s 不应放开, 实际 %s", extra, permissions)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package core
+
+// 论坛话题识别。
+//
+// 使用的 Bot API 库 (v5.5.1) 早于论坛话题功能, Message 上没有 message_thread_id,
+// 解码进它的结构体后话题信息就丢了。因此更新改由 DecodeUpdate 解码: 先照常解出 tgbotapi.Update,
+// 再从同一份原始 JSON 里单独取出话题 ID, 按 (群, 消息) 记在内存里供审核层查询。
+//
+// 只放内存: 话题 ID 只在处理这条消息及其后续编辑时用到, 重启后丢失的只是旧消息的话题归属。
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// topicMessage 从原始 JSON 里取话题信息所需的最小字段
+type topicMessage struct {
+	MessageID      int  `json:"message_id"`
+	ThreadID       int  `json:"message_thread_id"`
+	IsTopicMessage bool `json:"is_topic_message"`
+	Chat           struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+}
+
+type topicUpdate struct {
+	Message       *topicMessage `json:"message"`
+	EditedMessage *topicMessage `json:"edited_message"`
+}
+
+type topicKey struct {
+	chatID    int64
+	messageID int
+}
+
+type topicEntry struct {
+	topicID  int
+	recorded time.Time
+}
+
+var topics = struct {
+	mu    sync.Mutex
+	items map[topicKey]topicEntry
+}{items: make(map[topicKey]topicEntry)}
+
+// DecodeUpdate 解码一条原始更新, 顺带记下其中消息所属的论坛话题
+func DecodeUpdate(raw json.RawMessage) (tgbotapi.Update, error) {
+	var update tgbotapi.Update
+	if err := json.Unmarshal(raw, &update); err != nil {
+		return tgbotapi.Update{}, err
+	}
+
+	// 话题信息是锦上添花, 解不出来不影响这条更新的正常处理
+	var extra topicUpdate
+	if err := json.Unmarshal(raw, &extra); err == nil {
+		recordTopic(extra.Message)
+		recordTopic(extra.EditedMessage)
+	}
+	return update, nil
+}
+
+// recordTopic 记下一条话题消息的归属; 普通群里回复串也带 message_thread_id, 只认 is_topic_message
+func recordTopic(message *topicMessage) {
+	if message == nil || !message.IsTopicMessage || message.ThreadID == 0 {
+		return
+	}
+
+	topics.mu.Lock()
+	defer topics.mu.Unlock()
+	topics.items[topicKey{message.Chat.ID, message.MessageID}] = topicEntry{
+		topicID:  message.ThreadID,
+		recorded: time.Now(),
+	}
+}
+
+// MessageTopic 返回消息所属的论坛话题 ID, 不在话题里 (或是"综合"话题) 返回 0
+func MessageTopic(message *tgbotapi.Message) int {
+	if message == nil || message.Chat == nil {
+		return 0
+	}
+
+	topics.mu.Lock()
+	defer topics.mu.Unlock()
+	return topics.items[topicKey{message.Chat.ID, message.MessageID}].topicID
+}
+
+// PruneTopics 清掉早于 olderThan 的话题记录, 返回清理条数; 由定时任务调用, 防止 map 无限增长
+func PruneTopics(olderThan time.Duration) int {
+	topics.mu.Lock()
+	defer topics.mu.Unlock()
+
+	removed := 0
+	for key, entry := range topics.items {
+		if time.Since(entry.recorded) > olderThan {
+			delete(topics.items, key)
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,128 @@
+package core
+
+// 用户在每个群的信任等级。
+//
+// 等级由发言统计 (user_stats) 与违规计分 (user_strikes) 现算, 不单独落库: 发言条数、在群时长、
+// 管理员是否提前解除过新成员限制 (/release, 视为担保) 加分, 违规计分扣分, 最近一周内有过违规的直接降为低。
+// 管理员可以用 /trust、/distrust 指定等级, 指定值存在 user_stats.trust_override, 优先于计算结果。
+// 低信任只来自负面记录 (违规或管理员指定): 分数低只说明数据少, 没有发言统计的老成员、刚进群的新人都按普通对待,
+// 新人由新成员限制与链接白名单期管着, 不靠信任等级。
+//
+// 可信用户跳过 AI 审核与昵称检查; 低信任用户在开启 strictlinks 的群里过了新成员期仍只能发白名单链接。
+import (
+	"fmt"
+	"time"
+)
+
+// TrustLevel 信任等级
+type TrustLevel int
+
+const (
+	TrustLow TrustLevel = iota
+	TrustNormal
+	TrustHigh
+)
+
+// 管理员指定的等级, 存在 trust_override 列; 为空表示按统计计算
+const (
+	TrustOverrideTrusted    = "trusted"
+	TrustOverrideDistrusted = "distrusted"
+)
+
+// 计分参数; 分数限定在 0..100
+const (
+	trustTenurePerDay   = 1  // 在群每满一天 +1
+	trustTenureMax      = 30 // 时长最多计 30 分
+	trustMessagesPer    = 5  // 每 5 条发言 +1
+	trustMessagesMax    = 40 // 发言最多计 40 分
+	trustVouchBonus     = 20 // 管理员提前解除过新成员限制
+	trustVerifiedBonus  = 10 // 通过了入群验证
+	trustStrikePenalty  = 15 // 每分违规计分
+	trustHighScore      = 60 // 达到即为可信
+	trustLowScore       = 20 // 有过违规且低于即为低信任
+	trustRecentStrikeIn = 7 * 24 * time.Hour
+)
+
+var trustLabels = map[TrustLevel]string{
+	TrustLow:    "低",
+	TrustNormal: "普通",
+	TrustHigh:   "可信",
+}
+
+// String 信任等级的中文展示
+func (l TrustLevel) String() string {
+	if label, ok := trustLabels[l]; ok {
+		return label
+	}
+	return fmt.Sprintf("未知(%d)", int(l))
+}
+
+// Reputation 一个用户在一个群的信任评估
+type Reputation struct {
+	Score    int        // 0..100 的计算分数, 与是否被管理员指定无关
+	Level    TrustLevel // 最终等级, 管理员指定时以指定为准
+	Override string     // 管理员指定的等级, 为空表示按分数计算
+}
+
+// Trusted 是否为可信用户
+func (r Reputation) Trusted() bool { return r.Level == TrustHigh }
+
+// Distrusted 是否为低信任用户
+func (r Reputation) Distrusted() bool { return r.Level == TrustLow }
+
+// ComputeReputation 按发言统计与违规计分算出信任等级; found 表示有无发言统计, 没有记录的用户只是不加分。
+// 没有违规的用户分数再低也只到普通
+func ComputeReputation(stat UserStat, found bool, strike UserStrike, now time.Time) Reputation {
+	score := 0
+	if found {
+		if !stat.FirstSeenAt.IsZero() && now.After(stat.FirstSeenAt) {
+			days := int(now.Sub(stat.FirstSeenAt) / (24 * time.Hour))
+			score += min(days*trustTenurePerDay, trustTenureMax)
+		}
+		score += min(stat.MessageCount/trustMessagesPer, trustMessagesMax)
+		if !stat.ReleasedAt.IsZero() {
+			score += trustVouchBonus
+		}
+		if !stat.VerifiedAt.IsZero() {
+			score += trustVerifiedBonus
+		}
+	}
+	score -= strike.Strikes * trustStrikePenalty
+	score = max(0, min(score, 100))
+
+	rep := Reputation{Score: score, Override: stat.TrustOverride}
+	switch {
+	case stat.TrustOverride == TrustOverrideTrusted:
+		rep.Level = TrustHigh
+	case stat.TrustOverride == TrustOverrideDistrusted:
+		rep.Level = TrustLow
+	// 撤销会把计分扣回 0, 那次违规就不算数了
+	case strike.Strikes > 0 && now.Sub(strike.LastHitAt) < trustRecentStrikeIn:
+		rep.Level = TrustLow
+	case score >= trustHighScore:
+		rep.Level = TrustHigh
+	case strike.Strikes > 0 && score < trustLowScore:
+		rep.Level = TrustLow
+	default:
+		rep.Level = TrustNormal
+	}
+	return rep
+}
+
+// GetReputation 读取发言统计与违规计分, 算出某用户在某群的信任等级
+func (d *Database) GetReputation(userID, chatID int64) (Reputation, error) {
+	stat, found, err := d.GetUserStat(userID, chatID)
+	if err != nil {
+		return Reputation{}, err
+	}
+	return d.ReputationFor(userID, chatID, stat, found)
+}
+
+// ReputationFor 同 GetReputation, 但发言统计由调用方给出, 只再读一次违规计分; 审核时统计已经在手上
+func (d *Database) ReputationFor(userID, chatID int64, stat UserStat, found bool) (Reputation, error) {
+	var strike UserStrike
+	if err := d.db.Where("user_id = ? AND chat_id = ?", userID, chatID).First(&strike).Error; err != nil && !isNoRows(err) {
+		return Reputation{}, err
+	}
+	return ComputeReputation(stat, found, strike, time.Now()), nil
+}
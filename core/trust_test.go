@@ -0,0 +1,84 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestComputeReputation 老成员可信, 近期违规者低信任, 没有记录与刚进群的只是普通, 管理员指定优先于计算
+func TestComputeReputation(t *testing.T) {
+	now := time.Now()
+	veteran := UserStat{FirstSeenAt: now.Add(-60 * 24 * time.Hour), MessageCount: 300}
+
+	cases := []struct {
+		name   string
+		stat   UserStat
+		found  bool
+		strike UserStrike
+		want   TrustLevel
+	}{
+		{"没有记录", UserStat{}, false, UserStrike{}, TrustNormal},
+		{"刚进群", UserStat{FirstSeenAt: now, MessageCount: 2}, true, UserStrike{}, TrustNormal},
+		{"新人一个月前违规", UserStat{FirstSeenAt: now.Add(-40 * 24 * time.Hour), MessageCount: 10}, true, UserStrike{Strikes: 2, LastHitAt: now.Add(-30 * 24 * time.Hour)}, TrustLow},
+		{"老成员", veteran, true, UserStrike{}, TrustHigh},
+		{"两周前入群", UserStat{FirstSeenAt: now.Add(-14 * 24 * time.Hour), MessageCount: 50}, true, UserStrike{}, TrustNormal},
+		{"老成员昨天违规", veteran, true, UserStrike{Strikes: 1, LastHitAt: now.Add(-24 * time.Hour)}, TrustLow},
+		{"老成员违规已被撤销", veteran, true, UserStrike{Strikes: 0, LastHitAt: now.Add(-24 * time.Hour)}, TrustHigh},
+		{"老成员一个月前违规", veteran, true, UserStrike{Strikes: 1, LastHitAt: now.Add(-30 * 24 * time.Hour)}, TrustNormal},
+		{"新人被管理员担保", UserStat{FirstSeenAt: now, ReleasedAt: now}, true, UserStrike{}, TrustNormal},
+		{"指定为可信", UserStat{TrustOverride: TrustOverrideTrusted}, true, UserStrike{Strikes: 3, LastHitAt: now}, TrustHigh},
+		{"指定为低信任", func() UserStat { s := veteran; s.TrustOverride = TrustOverrideDistrusted; return s }(), true, UserStrike{}, TrustLow},
+	}
+	for _, c := range cases {
+		rep := ComputeReputation(c.stat, c.found, c.strike, now)
+		if rep.Level != c.want {
+			t.Errorf("%s: 等级 = %s (分数 %d), 期望 %s", c.name, rep.Level, rep.Score, c.want)
+		}
+		if rep.Score < 0 || rep.Score > 100 {
+			t.Errorf("%s: 分数 %d 超出 0..100", c.name, rep.Score)
+		}
+	}
+}
+
+// TestTrustOverride 指定覆盖全部给定的群, 清空后恢复计算, 且指定过的与发过言的记录不会被过期清理删掉
+func TestTrustOverride(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "trust.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.SetTrustOverride(1, []int64{-100, -200}, TrustOverrideTrusted); err != nil {
+		t.Fatalf("SetTrustOverride 失败: %v", err)
+	}
+	for _, chatID := range []int64{-100, -200} {
+		if rep, err := db.GetReputation(1, chatID); err != nil || !rep.Trusted() {
+			t.Errorf("群 %d 的信任等级 = %+v,%v, 期望可信", chatID, rep, err)
+		}
+	}
+
+	// 进群后没说过话的会被清掉; 发过言的老成员保留, 否则再开口就成了"刚出现"
+	if _, err := db.RecordJoin(2, -100); err != nil {
+		t.Fatalf("RecordJoin 失败: %v", err)
+	}
+	if _, _, err := db.BumpUserMessageCount(3, -100); err != nil {
+		t.Fatalf("BumpUserMessageCount 失败: %v", err)
+	}
+	if removed, err := db.CleanupStaleUserStats(-time.Hour); err != nil || removed != 1 {
+		t.Errorf("CleanupStaleUserStats = %d,%v, 期望只删掉没有指定等级、也没发过言的 1 条", removed, err)
+	}
+	if _, found, _ := db.GetUserStat(3, -100); !found {
+		t.Error("发过言的成员的统计不应被清理")
+	}
+
+	if err := db.SetTrustOverride(1, []int64{-100}, ""); err != nil {
+		t.Fatalf("清空指定失败: %v", err)
+	}
+	if rep, _ := db.GetReputation(1, -100); rep.Override != "" || rep.Trusted() {
+		t.Errorf("清空后应按统计计算, 实际 %+v", rep)
+	}
+	if rep, _ := db.GetReputation(1, -200); !rep.Trusted() {
+		t.Errorf("只清空了 -100, -200 的指定不应受影响, 实际 %+v", rep)
+	}
+}
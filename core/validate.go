@@ -17,8 +17,14 @@ const (
 // 查询全部走参数化占位符, 这里拦截的是引号反斜杠带来的展示与日志歧义, 不承担防注入职责。
 const forbiddenChars = "';\"\\"
 
-// ValidateKeyword 校验过滤关键词; 长度按字符数而非字节数计, 避免中文被误判超长
-func ValidateKeyword(keyword string) error {
+// ValidateKeyword 校验过滤关键词或规则; 长度按字符数而非字节数计, 避免中文被误判超长。
+// 规则类词条走 ParseRule 做编译与复杂度检查; 正则离不开反斜杠, 不套用 forbiddenChars。
+func ValidateKeyword(kind, keyword string) error {
+	if kind != KindPlain {
+		_, err := ParseRule(kind, keyword)
+		return err
+	}
+
 	keyword = strings.TrimSpace(keyword)
 	if keyword == "" {
 		return fmt.Errorf("关键词不能为空")
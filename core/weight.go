@@ -0,0 +1,86 @@
+package core
+
+// 规则的违规权重。
+//
+// 规则的严重程度并不相同: 零宽字符可能只是复制粘贴带进来的, 而高置信度的 AI 诈骗判定几乎不会错。
+// 每次处置按命中规则的权重累加计分, 处罚阶梯照旧按累计分数取级; 权重设为 ban 的规则命中即封禁, 不看阶梯。
+// 权重存在 config 表里, 没有记录的规则权重为 1, 与引入权重之前一致。
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WeightBan 命中即封禁的权重
+const WeightBan = -1
+
+const (
+	// defaultRuleWeight 未设置权重的规则每次命中记 1 分
+	defaultRuleWeight = 1
+	// maxRuleWeight 权重上限; 再重就该直接设 ban
+	maxRuleWeight = 10
+	// ruleWeightKeyPrefix 规则权重在 config 表里的键前缀
+	ruleWeightKeyPrefix = "rule_weight:"
+)
+
+// ParseWeight 解析管理员输入的权重: 1 到 10 的整数, 或 ban
+func ParseWeight(raw string) (int, error) {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if raw == "ban" {
+		return WeightBan, nil
+	}
+	weight, err := strconv.Atoi(raw)
+	if err != nil || weight < 1 || weight > maxRuleWeight {
+		return 0, fmt.Errorf("权重需为 1 到 %d 的整数，或 ban（命中即封禁）", maxRuleWeight)
+	}
+	return weight, nil
+}
+
+// WeightLabel 权重的中文展示
+func WeightLabel(weight int) string {
+	if weight == WeightBan {
+		return "命中即封禁"
+	}
+	return fmt.Sprintf("%d 分", weight)
+}
+
+// SetRuleWeight 修改规则权重; 改回默认值时删掉记录
+func (d *Database) SetRuleWeight(rule string, weight int) error {
+	if weight == defaultRuleWeight {
+		return d.DeleteConfig(ruleWeightKeyPrefix + rule)
+	}
+	if weight != WeightBan && (weight < 1 || weight > maxRuleWeight) {
+		return fmt.Errorf("非法的权重 %d", weight)
+	}
+	return d.SetConfig(ruleWeightKeyPrefix+rule, strconv.Itoa(weight))
+}
+
+// GetRuleWeight 读取规则权重, 没有记录时为 1。
+// 只在处置时读, 频率远低于逐条消息的匹配, 不走缓存; 存储值损坏时按默认值处理, 不因此放过违规
+func (d *Database) GetRuleWeight(rule string) (int, error) {
+	raw, err := d.GetConfig(ruleWeightKeyPrefix + rule)
+	if err != nil || raw == "" {
+		return defaultRuleWeight, err
+	}
+	weight, err := strconv.Atoi(raw)
+	if err != nil {
+		return defaultRuleWeight, fmt.Errorf("规则 %s 的权重 %q 无法解析: %w", rule, raw, err)
+	}
+	return weight, nil
+}
+
+// GetRuleWeights 列出全部非默认的规则权重
+func (d *Database) GetRuleWeights() (map[string]int, error) {
+	var rows []Config
+	if err := d.db.Where("key LIKE ?", ruleWeightKeyPrefix+"%").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	weights := make(map[string]int, len(rows))
+	for _, row := range rows {
+		if weight, err := strconv.Atoi(row.Value); err == nil {
+			weights[strings.TrimPrefix(row.Key, ruleWeightKeyPrefix)] = weight
+		}
+	}
+	return weights, nil
+}
@@ -0,0 +1,39 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRuleWeight(t *testing.T) {
+	db, err := NewDatabaseAt(filepath.Join(t.TempDir(), "weight.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	defer db.Close()
+
+	if w, _ := db.GetRuleWeight("ai"); w != 1 {
+		t.Errorf("默认权重 = %d, 期望 1", w)
+	}
+
+	for raw, want := range map[string]int{"3": 3, "BAN": WeightBan} {
+		weight, err := ParseWeight(raw)
+		if err != nil || weight != want {
+			t.Fatalf("ParseWeight(%q) = %d,%v, 期望 %d", raw, weight, err, want)
+		}
+		db.SetRuleWeight("ai", weight)
+		if got, _ := db.GetRuleWeight("ai"); got != want {
+			t.Errorf("设置后权重 = %d, 期望 %d", got, want)
+		}
+	}
+	for _, raw := range []string{"0", "11", "-1", "x"} {
+		if _, err := ParseWeight(raw); err == nil {
+			t.Errorf("ParseWeight(%q) 应当报错", raw)
+		}
+	}
+
+	db.SetRuleWeight("ai", 1)
+	if weights, _ := db.GetRuleWeights(); len(weights) != 0 {
+		t.Errorf("改回默认后不应留记录, 实际 %v", weights)
+	}
+}
@@ -19,6 +19,9 @@ func main() {
 
 	go binance.RunBinance()
 
+	// 网页管理面板与管理 API, 未配置 HTTP_ADDR 时立即返回
+	go service.RunHTTPServer(core.Bot)
+
 	// 启动定期任务
 	go service.StartScheduledTasks()
 
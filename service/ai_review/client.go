@@ -8,6 +8,7 @@ package ai_review
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -38,9 +39,17 @@ type responsesRequest struct {
 	Store           bool        `json:"store"`
 }
 
+// inputItem 一条输入消息。Content 是纯文本字符串, 或带图片时的 []contentPart
 type inputItem struct {
 	Role    string `json:"role"`
-	Content string `json:"content"`
+	Content any    `json:"content"`
+}
+
+// contentPart 多模态输入的一段: input_text 或 input_image
+type contentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL string `json:"image_url,omitempty"` // data URL, 不让网关再去 Telegram 拉图 (下载地址带着 Bot Token)
 }
 
 type textFormat struct {
@@ -72,15 +81,54 @@ type responsesReply struct {
 	} `json:"error"`
 }
 
-// complete 发起一次判定并返回模型输出的原始文本。
+// complete 用 AI_MODEL 发起一次判定并返回模型输出的原始文本。
 // schema 非 nil 时要求结构化输出; 网关拒绝该参数时自动重试一次不带 schema 的请求。
 func complete(ctx context.Context, systemPrompt, userPrompt string, schema json.RawMessage) (string, error) {
+	return completeWith(ctx, core.AIModel, systemPrompt, userPrompt, schema)
+}
+
+// completeWith 同 complete, 但指定模型; 试行模型 (AI_SHADOW_MODEL) 走这里, 其余参数与正式模型一致以便对比
+func completeWith(ctx context.Context, model, systemPrompt, userPrompt string, schema json.RawMessage) (string, error) {
+	return request(ctx, model, []inputItem{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: userPrompt},
+	}, schema)
+}
+
+// completeWithImage 同 completeWith, 但在用户消息里附一张图片; 走同一个 Responses 端点
+func completeWithImage(ctx context.Context, model, systemPrompt, userPrompt string, image []byte, schema json.RawMessage) (string, error) {
+	return request(ctx, model, []inputItem{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: []contentPart{
+			{Type: "input_text", Text: userPrompt},
+			{Type: "input_image", ImageURL: imageDataURL(image)},
+		}},
+	}, schema)
+}
+
+// imageDataURL 把图片编码成 data URL; 类型按内容嗅探, Telegram 的图片基本都是 JPEG
+func imageDataURL(image []byte) string {
+	return "data:" + http.DetectContentType(image) + ";base64," + base64.StdEncoding.EncodeToString(image)
+}
+
+// request 发出一次调用并记入指标; 降级重试算在同一次调用里
+func request(ctx context.Context, model string, input []inputItem, schema json.RawMessage) (string, error) {
+	startedAt := time.Now()
+	text, err := requestOnce(ctx, model, input, schema)
+	aiLatency.WithLabelValues(model).Observe(time.Since(startedAt).Seconds())
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	aiRequests.WithLabelValues(model, result).Inc()
+	return text, err
+}
+
+// requestOnce 组装请求体并发出; schema 非 nil 时要求结构化输出, 网关拒绝该参数时自动重试一次不带 schema 的请求
+func requestOnce(ctx context.Context, model string, input []inputItem, schema json.RawMessage) (string, error) {
 	body := responsesRequest{
-		Model: core.AIModel,
-		Input: []inputItem{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		},
+		Model:           model,
+		Input:           input,
 		Reasoning:       &reasoning{Effort: core.AIReasoningEffort},
 		MaxOutputTokens: 2000,
 		Store:           false,
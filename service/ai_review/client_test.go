@@ -0,0 +1,96 @@
+package ai_review
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"SunaiForum-Bot/core"
+)
+
+// newTestGateway 起一个本地 Responses 端点: 记下收到的请求体, 回 reply 原文
+func newTestGateway(t *testing.T, reply string) *responsesRequest {
+	t.Helper()
+	received := &responsesRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/responses" {
+			t.Errorf("请求路径 = %q, 期望 /responses", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if err := json.NewDecoder(r.Body).Decode(received); err != nil {
+			t.Errorf("解析请求体失败: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(reply))
+	}))
+	t.Cleanup(server.Close)
+
+	baseURL, apiKey := core.AIBaseURL, core.AIAPIKey
+	core.AIBaseURL, core.AIAPIKey = server.URL, "test-key"
+	t.Cleanup(func() { core.AIBaseURL, core.AIAPIKey = baseURL, apiKey })
+	return received
+}
+
+// TestCompleteWithImage 图片以 data URL 形式作为 input_image 附在用户消息里, 与说明文字同在一条消息
+func TestCompleteWithImage(t *testing.T) {
+	received := newTestGateway(t, `{"output_text":"{\"is_spam\":true}"}`)
+	image := []byte("\xff\xd8\xff\xe0fake-jpeg")
+
+	text, err := completeWithImage(context.Background(), "vision-model", "系统提示", "用户提示", image, verdictSchema)
+	if err != nil {
+		t.Fatalf("completeWithImage 失败: %v", err)
+	}
+	if text != `{"is_spam":true}` {
+		t.Errorf("输出 = %q", text)
+	}
+
+	if received.Model != "vision-model" {
+		t.Errorf("model = %q, 期望 vision-model", received.Model)
+	}
+	if received.Text == nil {
+		t.Error("请求应当带上结构化输出 schema")
+	}
+	if len(received.Input) != 2 || received.Input[0].Role != "system" || received.Input[1].Role != "user" {
+		t.Fatalf("input = %+v, 期望 system + user 两条", received.Input)
+	}
+
+	// 解码后 Content 是 []any, 重新编解码成 contentPart 再比较
+	raw, _ := json.Marshal(received.Input[1].Content)
+	var parts []contentPart
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		t.Fatalf("用户消息不是多段内容: %s", raw)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("用户消息 = %+v, 期望文字 + 图片两段", parts)
+	}
+	if parts[0].Type != "input_text" || parts[0].Text != "用户提示" {
+		t.Errorf("第一段 = %+v, 期望 input_text", parts[0])
+	}
+	if parts[1].Type != "input_image" || parts[1].ImageURL != imageDataURL(image) {
+		t.Errorf("第二段 = %+v, 期望 input_image", parts[1])
+	}
+	if !strings.HasPrefix(parts[1].ImageURL, "data:image/jpeg;base64,") {
+		t.Errorf("image_url = %q, 期望 JPEG data URL", parts[1].ImageURL)
+	}
+}
+
+// TestCompleteTextOnly 纯文本判定的用户消息仍是字符串, 不带图片段
+func TestCompleteTextOnly(t *testing.T) {
+	received := newTestGateway(t, `{"output":[{"content":[{"type":"output_text","text":"ok"}]}]}`)
+
+	text, err := completeWith(context.Background(), "text-model", "系统提示", "用户提示", nil)
+	if err != nil || text != "ok" {
+		t.Fatalf("completeWith = %q,%v", text, err)
+	}
+	if content, ok := received.Input[1].Content.(string); !ok || content != "用户提示" {
+		t.Errorf("用户消息 = %#v, 期望纯文本", received.Input[1].Content)
+	}
+	if received.Text != nil {
+		t.Error("没有 schema 时不该带 text.format")
+	}
+}
@@ -0,0 +1,34 @@
+package ai_review
+
+// AI 审核的 Prometheus 指标, 注册说明见 core/metrics.go。
+// 调用次数与失败按模型区分, 正式模型、试行模型与识图模型各看各的; 告警"网关持续失败"即基于 result="error"。
+import (
+	"SunaiForum-Bot/core"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	aiRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sunai_ai_requests_total",
+		Help: "AI 网关调用次数, 按模型与结果 (ok / error)",
+	}, []string{"model", "result"})
+	aiLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "sunai_ai_request_duration_seconds",
+		Help: "AI 网关调用耗时, 含不支持结构化输出时的降级重试",
+		// high reasoning 常见十几秒, 上限贴着 requestTimeout
+		Buckets: []float64{0.5, 1, 2, 5, 10, 15, 20, 30, 45, 60},
+	}, []string{"model"})
+	aiConfidence = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sunai_ai_confidence",
+		Help:    "AI 判定给出的置信度分布, 按模型与是否判为广告; 用于校准 AI_MIN_CONFIDENCE",
+		Buckets: prometheus.LinearBuckets(0.1, 0.1, 10),
+	}, []string{"model", "is_spam"})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "sunai_ai_budget_remaining",
+		Help: "本小时剩余的 AI 调用额度",
+	}, func() float64 {
+		return float64(hourlyBudget.remaining(core.AIHourlyBudget))
+	})
+)
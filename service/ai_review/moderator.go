@@ -14,6 +14,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -31,6 +32,15 @@ const (
 	maxExtractedWords = 3
 	// minTextLenForReview 太短的消息没有判定价值, 直接跳过省调用
 	minTextLenForReview = 4
+	// maxImageBytes 送去识图的图片大小上限; Telegram 压缩后的图片一般只有几百 KB
+	maxImageBytes = 5 << 20
+)
+
+// 试行判定在处置记录里的规则名; 以"AI 判定"开头, 汇总时与正式判定归在一起看
+const (
+	ruleShadowThreshold = "AI 判定（试行阈值）"
+	ruleShadowModel     = "AI 判定（试行模型）"
+	ruleShadowMode      = "AI 判定（试行）"
 )
 
 // stopWords 绝不允许成为过滤关键词的常见词; AI 提取到这些一律丢弃
@@ -97,6 +107,10 @@ const systemPrompt = `你是一个 Telegram 中文社区群的审核员。这个
 confidence 是你对 is_spam 判断的把握, 0 到 1。只有非常确定才给 0.9 以上。
 严格按 JSON 输出, 不要任何额外文字。`
 
+// imagePromptNote 识图时附在用户消息末尾的补充说明
+const imagePromptNote = `图片里的文字 (包括画在图上的价格、联系方式、二维码旁的说明) 按同样的标准判断。
+特征词只能取自上面的说明文字; 说明文字为空时 keywords 返回空数组。`
+
 // budget 全局调用预算, 防止异常情况 (比如被灌消息) 把额度烧光
 type budget struct {
 	mu       sync.Mutex
@@ -122,24 +136,45 @@ func (b *budget) take(limit int) bool {
 	return true
 }
 
-// MaybeReview 在满足条件时异步做一次 AI 判定。
+// remaining 本小时还剩多少额度; 窗口已过期的按满额算, 但不在这里重置窗口
+func (b *budget) remaining(limit int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if time.Since(b.windowAt) >= time.Hour {
+		return limit
+	}
+	return max(limit-b.used, 0)
+}
+
+// MaybeReview 在满足条件时异步做一次 AI 判定; AI 层整体关闭或所在群关掉了 AI 时直接返回。
+// stats 是审核前 moderation.ObserveSender 记下的发言统计, 新用户窗口按其中的条数判断。
 // 立即返回, 不阻塞消息处理 —— high reasoning 的响应时间可达数十秒,
 // Telegram 允许 48 小时内删除消息, 迟几秒删掉没有影响。
-func MaybeReview(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
-	if !core.AIEnabled || message.From == nil {
+func MaybeReview(bot *tgbotapi.BotAPI, message *tgbotapi.Message, stats moderation.SenderStats) {
+	sender := moderation.Sender(message)
+	if !core.AIEnabled || sender == nil {
 		return
 	}
+	// 隐藏链接与按钮一并交给 AI: 广告的落地页常常只在这些地方出现
+	text := moderation.InspectionText(message)
+	displayName := moderation.DisplayName(sender)
 
-	text := moderation.MessageText(message)
-	displayName := moderation.DisplayName(message.From)
+	count := stats.Stat.MessageCount
 
-	// 计数必须对每条消息都做, 否则"前 N 条"永远算不准
-	count, err := core.DB.BumpUserMessageCount(message.From.ID, message.Chat.ID)
-	if err != nil {
-		log.Printf("[AIReview] 更新发言计数失败: %v", err)
+	if group, ok := core.GroupSettings(message.Chat.ID); !ok || !group.AIEnabled {
+		return
 	}
-
-	if !shouldReview(text, displayName, count) {
+	if moderation.RuleMode(moderation.RuleIDAI) == core.ModeOff {
+		return
+	}
+	// 没有可读文字的图片广告另走识图模型, 只查新用户窗口内的: 识图比纯文本贵得多
+	photo, reviewPhoto := photoForReview(message, text, count)
+	if !reviewPhoto && !shouldReview(text, displayName, count) {
+		return
+	}
+	// 可信用户不送审; 等级已在审核前随发言统计算好
+	if stats.Trusted() {
 		return
 	}
 	if !hourlyBudget.take(core.AIHourlyBudget) {
@@ -153,10 +188,34 @@ func MaybeReview(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
 				log.Printf("[AIReview] 判定过程 panic: %v", r)
 			}
 		}()
-		review(bot, message, text, displayName)
+		if reviewPhoto {
+			reviewImage(bot, message, text, displayName, photo)
+			return
+		}
+		review(bot, message, text, displayName, nil)
 	}()
 }
 
+// photoForReview 挑出需要识图的图片: 开启了识图模型、文字太短没法按文本判、发送者仍在新用户窗口内。
+// 取不超过 maxImageBytes 的最大一档尺寸, Telegram 按从小到大排列 PhotoSize。
+func photoForReview(message *tgbotapi.Message, text string, messageCount int) (tgbotapi.PhotoSize, bool) {
+	if core.AIVisionModel == "" || len(message.Photo) == 0 {
+		return tgbotapi.PhotoSize{}, false
+	}
+	if len([]rune(strings.TrimSpace(text))) >= minTextLenForReview {
+		return tgbotapi.PhotoSize{}, false
+	}
+	if messageCount <= 0 || messageCount > core.AINewUserMessages {
+		return tgbotapi.PhotoSize{}, false
+	}
+	for i := len(message.Photo) - 1; i >= 0; i-- {
+		if size := message.Photo[i]; size.FileSize <= maxImageBytes {
+			return size, true
+		}
+	}
+	return tgbotapi.PhotoSize{}, false
+}
+
 // shouldReview 决定这条消息值不值得花一次 AI 调用。
 // 新用户的前 N 条全查 (广告号基本进群就发); 老用户只在命中弱信号时查。
 func shouldReview(text, displayName string, messageCount int) bool {
@@ -171,35 +230,154 @@ func shouldReview(text, displayName string, messageCount int) bool {
 	return moderation.HasWeakSignal(text) || moderation.HasWeakSignal(displayName)
 }
 
-// review 执行判定并落实处置
-func review(bot *tgbotapi.BotAPI, message *tgbotapi.Message, text, displayName string) {
+// WouldReview 判断一条消息会不会被送去 AI 判定, 供 /check 预演; 与真实审核走同一个 shouldReview
+func WouldReview(text, displayName string, messageCount int) bool {
+	return shouldReview(text, displayName, messageCount)
+}
+
+// DryRunResult AI 预演的结论
+type DryRunResult struct {
+	IsSpam     bool
+	Confidence float64
+	Reason     string
+	// WouldEnforce 置信度达到 AIMinConfidence, 真实消息会被拦截
+	WouldEnforce bool
+	// WouldLearn 真实判定时会写入词表的提取词
+	WouldLearn []string
+}
+
+// DryRun 对一段文本做一次真实的 AI 判定, 但不删消息、不记分、不学词, 供管理员用 /check 预演。
+// 不占用每小时调用额度: 额度防的是被灌消息烧钱, 管理员手动发起的预演不在此列。
+func DryRun(text, displayName string) (DryRunResult, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
 	defer cancel()
 
-	userPrompt := fmt.Sprintf("发送者昵称: %s\n\n消息内容:\n%s", displayName, text)
+	result, err := judge(ctx, core.AIModel, text, displayName, nil)
+	if err != nil {
+		return DryRunResult{}, err
+	}
+
+	dry := DryRunResult{
+		IsSpam:       result.IsSpam,
+		Confidence:   result.Confidence,
+		Reason:       result.Reason,
+		WouldEnforce: result.IsSpam && result.Confidence >= core.AIMinConfidence,
+	}
+	if dry.WouldEnforce {
+		for _, word := range selectKeywords(text, result.Keywords) {
+			if len(dry.WouldLearn) >= maxExtractedWords {
+				break
+			}
+			// 只读地复现 AddKeyword 的去重与否决检查
+			exists, _ := core.DB.KeywordExists(word)
+			rejected, _ := core.DB.IsKeywordRejected(word)
+			if !exists && !rejected {
+				dry.WouldLearn = append(dry.WouldLearn, word)
+			}
+		}
+	}
+	return dry, nil
+}
+
+// judge 调用指定模型判定一条消息, 不做任何处置; image 非 nil 时连同图片一起送给模型
+func judge(ctx context.Context, model, text, displayName string, image []byte) (reviewResult, error) {
+	var (
+		output string
+		err    error
+	)
+	if image != nil {
+		userPrompt := fmt.Sprintf("发送者昵称: %s\n\n消息是一张图片, 说明文字:\n%s\n\n%s", displayName, text, imagePromptNote)
+		output, err = completeWithImage(ctx, model, systemPrompt, userPrompt, image, verdictSchema)
+	} else {
+		userPrompt := fmt.Sprintf("发送者昵称: %s\n\n消息内容:\n%s", displayName, text)
+		output, err = completeWith(ctx, model, systemPrompt, userPrompt, verdictSchema)
+	}
+	if err != nil {
+		return reviewResult{}, err
+	}
+
+	var result reviewResult
+	if err := decodeJSON(output, &result); err != nil {
+		return reviewResult{}, fmt.Errorf("解析判定结果失败: %w", err)
+	}
+	aiConfidence.WithLabelValues(model, strconv.FormatBool(result.IsSpam)).Observe(result.Confidence)
+	return result, nil
+}
+
+// reviewImage 下载图片后按 review 的流程判定; 下载失败按放行处理
+func reviewImage(bot *tgbotapi.BotAPI, message *tgbotapi.Message, caption, displayName string, photo tgbotapi.PhotoSize) {
+	image, err := core.DownloadFile(bot, photo.FileID, maxImageBytes)
+	if err != nil {
+		log.Printf("[AIReview] 下载图片失败, 本条放行: %v", err)
+		return
+	}
+	review(bot, message, caption, displayName, image)
+}
+
+// review 执行判定并落实处置; image 非 nil 时用识图模型判定图片。
+// AI 规则处于试行时, 达到阈值也只留痕; 另外配置了试行阈值或试行模型的, 在正式判定没有拦截时补记"本会拦截"。
+func review(bot *tgbotapi.BotAPI, message *tgbotapi.Message, text, displayName string, image []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
 
-	output, err := complete(ctx, systemPrompt, userPrompt, verdictSchema)
+	model := core.AIModel
+	if image != nil {
+		model = core.AIVisionModel
+	}
+	result, err := judge(ctx, model, text, displayName, image)
 	if err != nil {
 		// 判定失败一律放行: 网关抖动不该导致误删用户消息
 		log.Printf("[AIReview] 判定失败, 本条放行: %v", err)
 		return
 	}
+	detail := buildDetail(result)
+	if image != nil {
+		detail = "图片 · " + detail
+	}
 
-	var result reviewResult
-	if err := decodeJSON(output, &result); err != nil {
-		log.Printf("[AIReview] 解析判定结果失败, 本条放行: %v", err)
+	switch {
+	case result.IsSpam && result.Confidence >= core.AIMinConfidence:
+		if moderation.RuleMode(moderation.RuleIDAI) == core.ModeShadow {
+			// 试行期不学词: 学进词表的词是正式执行的, 会绕过试行直接拦截
+			moderation.RecordExternalShadow(message, text, ruleShadowMode, detail)
+			break
+		}
+
+		log.Printf("[AIReview] 判定为广告 (置信度 %.2f): %s | 用户 %d(%s)",
+			result.Confidence, result.Reason, moderation.Sender(message).ID, moderation.Sender(message).UserName)
+
+		// 图片里的字不在 text 里, 提取的词过不了"原文中真实出现"的校验, 识图判定实际不会学词
+		accepted := learnKeywords(text, result.Keywords)
+		moderation.EnforceExternalVerdict(bot, message, text, detail, accepted)
 		return
+	case result.IsSpam && core.AIShadowMinConfidence > 0 && result.Confidence >= core.AIShadowMinConfidence:
+		moderation.RecordExternalShadow(message, text, ruleShadowThreshold, detail)
+	}
+
+	// 试行模型不一定能识图, 只对比文本判定
+	if core.AIShadowModel != "" && image == nil {
+		reviewWithShadowModel(message, text, displayName)
 	}
+}
 
-	if !result.IsSpam || result.Confidence < core.AIMinConfidence {
+// reviewWithShadowModel 用试行模型再判一次, 它会拦而正式模型没拦的记为"本会拦截"。
+// 试行模型同样占用每小时额度, 额度用尽时先保正式判定。
+func reviewWithShadowModel(message *tgbotapi.Message, text, displayName string) {
+	if !hourlyBudget.take(core.AIHourlyBudget) {
 		return
 	}
 
-	log.Printf("[AIReview] 判定为广告 (置信度 %.2f): %s | 用户 %d(%s)",
-		result.Confidence, result.Reason, message.From.ID, message.From.UserName)
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
 
-	accepted := learnKeywords(text, result.Keywords)
-	moderation.EnforceExternalVerdict(bot, message, text, buildDetail(result), accepted)
+	result, err := judge(ctx, core.AIShadowModel, text, displayName, nil)
+	if err != nil {
+		log.Printf("[AIReview] 试行模型 %s 判定失败: %v", core.AIShadowModel, err)
+		return
+	}
+	if result.IsSpam && result.Confidence >= core.AIMinConfidence {
+		moderation.RecordExternalShadow(message, text, ruleShadowModel, core.AIShadowModel+" · "+buildDetail(result))
+	}
 }
 
 // buildDetail 拼出给管理员看的判定说明
@@ -210,14 +388,31 @@ func buildDetail(result reviewResult) string {
 // learnKeywords 校验并采纳 AI 提取的广告词, 返回真正写入词表的词。
 // 校验是硬约束: 词必须在原文中真实出现、长度达标、非常见词、未被管理员否决。
 func learnKeywords(text string, candidates []string) []string {
-	normalizedText := moderation.Normalize(text)
-
 	var accepted []string
-	for _, candidate := range candidates {
+	for _, word := range selectKeywords(text, candidates) {
 		if len(accepted) >= maxExtractedWords {
 			break
 		}
 
+		added, err := core.DB.AddKeyword(word, core.SourceAI)
+		if err != nil {
+			log.Printf("[AIReview] 写入关键词 %q 失败: %v", word, err)
+			continue
+		}
+		if added {
+			accepted = append(accepted, word)
+			log.Printf("[AIReview] 已自动添加关键词: %q", word)
+		}
+	}
+	return accepted
+}
+
+// selectKeywords 过滤 AI 提取的候选词, 只做不依赖数据库的校验; 否决表与去重由写库时把关
+func selectKeywords(text string, candidates []string) []string {
+	normalizedText := moderation.Normalize(text)
+
+	var selected []string
+	for _, candidate := range candidates {
 		word := strings.TrimSpace(candidate)
 		normalized := moderation.Normalize(word)
 
@@ -232,19 +427,10 @@ func learnKeywords(text string, candidates []string) []string {
 			continue
 		}
 
-		if err := core.ValidateKeyword(word); err != nil {
+		if err := core.ValidateKeyword(core.KindPlain, word); err != nil {
 			continue
 		}
-
-		added, err := core.DB.AddKeyword(word, core.SourceAI)
-		if err != nil {
-			log.Printf("[AIReview] 写入关键词 %q 失败: %v", word, err)
-			continue
-		}
-		if added {
-			accepted = append(accepted, word)
-			log.Printf("[AIReview] 已自动添加关键词: %q", word)
-		}
+		selected = append(selected, word)
 	}
-	return accepted
+	return selected
 }
@@ -0,0 +1,78 @@
+package ai_review
+
+import (
+	"testing"
+	"time"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestPhotoForReview(t *testing.T) {
+	visionModel, newUserMessages := core.AIVisionModel, core.AINewUserMessages
+	core.AIVisionModel, core.AINewUserMessages = "vision-model", 3
+	t.Cleanup(func() { core.AIVisionModel, core.AINewUserMessages = visionModel, newUserMessages })
+
+	// Telegram 按从小到大给出各档尺寸
+	sizes := []tgbotapi.PhotoSize{
+		{FileID: "small", FileSize: 20 << 10},
+		{FileID: "medium", FileSize: 800 << 10},
+		{FileID: "large", FileSize: 6 << 20},
+	}
+
+	cases := []struct {
+		name   string
+		photo  []tgbotapi.PhotoSize
+		text   string
+		count  int
+		vision string
+		want   string // 期望选中的 FileID, 空表示不送审
+	}{
+		{"取不超过上限的最大一档", sizes, "", 1, "vision-model", "medium"},
+		{"最大一档正好在上限内", []tgbotapi.PhotoSize{{FileID: "exact", FileSize: maxImageBytes}}, "", 1, "vision-model", "exact"},
+		{"每档都超过上限", []tgbotapi.PhotoSize{{FileID: "huge", FileSize: maxImageBytes + 1}}, "", 1, "vision-model", ""},
+		{"短说明文字仍按图片判", sizes, "看图", 2, "vision-model", "medium"},
+		{"说明文字够长走文本判定", sizes, "这是一段足够长的说明", 1, "vision-model", ""},
+		{"新用户窗口最后一条", sizes, "", 3, "vision-model", "medium"},
+		{"超出新用户窗口", sizes, "", 4, "vision-model", ""},
+		{"没有发言统计", sizes, "", 0, "vision-model", ""},
+		{"没有图片", nil, "", 1, "vision-model", ""},
+		{"未配置识图模型", sizes, "", 1, "", ""},
+	}
+	for _, c := range cases {
+		core.AIVisionModel = c.vision
+		message := &tgbotapi.Message{Photo: c.photo}
+		photo, ok := photoForReview(message, c.text, c.count)
+		if ok != (c.want != "") || photo.FileID != c.want {
+			t.Errorf("%s: photoForReview = %q,%v, 期望 %q", c.name, photo.FileID, ok, c.want)
+		}
+	}
+}
+
+func TestBudget(t *testing.T) {
+	b := &budget{windowAt: time.Now()}
+	for i := 0; i < 3; i++ {
+		if !b.take(3) {
+			t.Fatalf("第 %d 次调用不该被额度拦下", i+1)
+		}
+	}
+	if b.take(3) {
+		t.Error("额度用尽后应当拒绝")
+	}
+	if got := b.remaining(3); got != 0 {
+		t.Errorf("remaining = %d, 期望 0", got)
+	}
+
+	// 窗口过期: remaining 按满额算但不重置, take 时才开新窗口
+	b.windowAt = time.Now().Add(-time.Hour)
+	if got := b.remaining(3); got != 3 {
+		t.Errorf("窗口过期后 remaining = %d, 期望 3", got)
+	}
+	if !b.take(3) {
+		t.Error("新窗口应当重新计额")
+	}
+	if got := b.remaining(3); got != 2 {
+		t.Errorf("新窗口占用一次后 remaining = %d, 期望 2", got)
+	}
+}
@@ -0,0 +1,186 @@
+package api
+
+// 管理 API: 供脚本同步词表、拉取处置记录等自动化场景使用, 不经过 Telegram。
+//
+// 路径带版本号 (/api/v1), 以后不兼容的改动另开 v2, 已有脚本不受影响。
+// 鉴权是 Bearer 口令 (API_TOKEN), 持有口令即按所有者对待; 读写一律复用 core.DB 上现成的方法,
+// 与私聊命令、网页面板走同一套校验和缓存。接口描述见 openapi.json, 服务时挂在 /api/v1/openapi.json。
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+// maxBodyBytes 请求体上限; 批量导词也用不了这么多
+const maxBodyBytes = 1 << 20
+
+// 列表接口的默认与最大条数
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// Server 管理 API 的 HTTP 处理器
+type Server struct {
+	bot   *tgbotapi.BotAPI
+	token string
+	mux   *http.ServeMux
+}
+
+// New 构造 API; bot 只在撤销处置时用到 (解封、发回原文)
+func New(bot *tgbotapi.BotAPI, token string) *Server {
+	s := &Server{bot: bot, token: token, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("GET /api/v1/openapi.json", serveSpec)
+
+	s.handle("GET /api/v1/keywords", s.listKeywords)
+	s.handle("POST /api/v1/keywords", s.createKeyword)
+	s.handle("GET /api/v1/keywords/{keyword...}", s.getKeyword)
+	s.handle("PATCH /api/v1/keywords/{keyword...}", s.updateKeyword)
+	s.handle("DELETE /api/v1/keywords/{keyword...}", s.deleteKeyword)
+
+	s.handle("GET /api/v1/rejected-keywords", s.listRejected)
+	s.handle("POST /api/v1/rejected-keywords", s.createRejected)
+	s.handle("DELETE /api/v1/rejected-keywords/{keyword...}", s.deleteRejected)
+
+	s.handle("GET /api/v1/prompts", s.listPrompts)
+	s.handle("GET /api/v1/prompts/{prompt}", s.getPrompt)
+	s.handle("PUT /api/v1/prompts/{prompt}", s.putPrompt)
+	s.handle("DELETE /api/v1/prompts/{prompt}", s.deletePrompt)
+
+	s.handle("GET /api/v1/actions", s.listActions)
+	s.handle("GET /api/v1/actions/{id}", s.getAction)
+	s.handle("POST /api/v1/actions/{id}/undo", s.undoAction)
+
+	s.handle("GET /api/v1/strikes", s.listStrikes)
+	s.handle("DELETE /api/v1/strikes/{user_id}/{chat_id}", s.resetStrikes)
+
+	s.handle("GET /api/v1/backups", s.listBackups)
+	s.handle("POST /api/v1/backups", s.createBackup)
+
+	s.handle("POST /api/v1/inspect", s.inspect)
+
+	// 其余路径统一回 JSON 的 404, 而不是默认的纯文本
+	s.mux.HandleFunc("/api/", func(w http.ResponseWriter, _ *http.Request) {
+		writeError(w, http.StatusNotFound, "没有这个接口")
+	})
+	return s
+}
+
+// ServeHTTP 实现 http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handle 注册需要鉴权的接口
+func (s *Server) handle(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			writeError(w, http.StatusUnauthorized, "缺少或错误的 Bearer 口令")
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		handler(w, r)
+	})
+}
+
+// authorized 校验 Authorization: Bearer <口令>
+func (s *Server) authorized(r *http.Request) bool {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(s.token)) == 1
+}
+
+// serveSpec 返回 OpenAPI 描述; 不要求鉴权, 方便直接导入各类客户端生成工具
+func serveSpec(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// errorBody 错误响应
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// writeJSON 写出 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("[API] 写出响应失败: %v", err)
+	}
+}
+
+// writeError 写出错误响应
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorBody{Error: message})
+}
+
+// writeInternal 记录内部错误并回 500; 错误细节只进日志, 不回给调用方
+func writeInternal(w http.ResponseWriter, what string, err error) {
+	log.Printf("[API] %s失败: %v", what, err)
+	writeError(w, http.StatusInternalServerError, what+"失败")
+}
+
+// decodeJSON 解析请求体; 不认识的字段报错, 免得拼错字段名被静默忽略
+func decodeJSON(r *http.Request, dst any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			return fmt.Errorf("请求体超过 %d 字节", maxBodyBytes)
+		case errors.Is(err, io.EOF):
+			return fmt.Errorf("请求体为空")
+		default:
+			return fmt.Errorf("请求体不是合法的 JSON: %v", err)
+		}
+	}
+	return nil
+}
+
+// queryInt64 读取整数型查询参数, 缺省为 0
+func queryInt64(r *http.Request, name string) (int64, error) {
+	raw := strings.TrimSpace(r.URL.Query().Get(name))
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("参数 %s 应当是整数", name)
+	}
+	return value, nil
+}
+
+// queryLimit 读取 limit 参数, 缺省取默认值, 超出上限按上限
+func queryLimit(r *http.Request) (int, error) {
+	limit, err := queryInt64(r, "limit")
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("参数 limit 应当是非负整数")
+	}
+	if limit == 0 {
+		return defaultLimit, nil
+	}
+	return int(min(limit, maxLimit)), nil
+}
+
+// listBody 列表响应
+type listBody[T any] struct {
+	Items []T `json:"items"`
+}
@@ -0,0 +1,343 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"SunaiForum-Bot/core"
+)
+
+const testToken = "api-test-token"
+
+// useTempDB 让 core.DB 指向临时库, 所有者设为 1, 测试结束后还原
+func useTempDB(t *testing.T) *core.Database {
+	t.Helper()
+
+	db, err := core.NewDatabaseAt(filepath.Join(t.TempDir(), "api.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	previousDB, previousAdmin := core.DB, core.AdminID
+	core.DB, core.AdminID = db, 1
+	t.Cleanup(func() {
+		core.DB, core.AdminID = previousDB, previousAdmin
+		db.Close()
+	})
+	return db
+}
+
+// client 带口令调用 API, 返回状态码与解析后的 JSON
+type client struct {
+	t      *testing.T
+	server *Server
+}
+
+func newClient(t *testing.T) client {
+	return client{t: t, server: New(nil, testToken)}
+}
+
+func (c client) do(method, target, body string, out any) int {
+	c.t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rec := httptest.NewRecorder()
+	c.server.ServeHTTP(rec, req)
+	if out != nil && rec.Body.Len() > 0 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			c.t.Fatalf("%s %s 的响应不是 JSON: %v\n%s", method, target, err, rec.Body.String())
+		}
+	}
+	return rec.Code
+}
+
+// TestAuthRequired 除接口描述外都要求正确的 Bearer 口令
+func TestAuthRequired(t *testing.T) {
+	useTempDB(t)
+	s := New(nil, testToken)
+
+	for _, header := range []string{"", "Bearer wrong", "Basic " + testToken, testToken} {
+		req := httptest.NewRequest("GET", "/api/v1/keywords", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q = %d, 期望 401", header, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("接口描述 = %d, 应当无需口令", rec.Code)
+	}
+
+	if code := newClient(t).do("GET", "/api/v1/nothing", "", nil); code != http.StatusNotFound {
+		t.Errorf("未知路径 = %d, 期望 404", code)
+	}
+}
+
+// TestKeywordCRUD 增查改删走完一轮; 删掉 AI 加的词要进否决表
+func TestKeywordCRUD(t *testing.T) {
+	db := useTempDB(t)
+	c := newClient(t)
+
+	var created keywordJSON
+	if code := c.do("POST", "/api/v1/keywords", `{"keyword":"水果特价","mode":"shadow"}`, &created); code != http.StatusCreated {
+		t.Fatalf("添加词条 = %d", code)
+	}
+	if created.Kind != core.KindPlain || created.Mode != core.ModeShadow || created.Scope != "global" || created.Source != core.SourceManual {
+		t.Errorf("添加的词条 = %+v", created)
+	}
+	if code := c.do("POST", "/api/v1/keywords", `{"keyword":"水果特价"}`, nil); code != http.StatusConflict {
+		t.Errorf("重复添加 = %d, 期望 409", code)
+	}
+	if code := c.do("POST", "/api/v1/keywords", `{"keyword":"加微信","scope":"-100123"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("未登记群的作用域 = %d, 期望 400", code)
+	}
+	if code := c.do("POST", "/api/v1/keywords", `{"keyword":"加微信(","kind":"regex"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("不合法的正则 = %d, 期望 400", code)
+	}
+	if code := c.do("POST", "/api/v1/keywords", `{"keyword":"加微信","typo":1}`, nil); code != http.StatusBadRequest {
+		t.Errorf("未知字段 = %d, 期望 400", code)
+	}
+	if code := c.do("POST", "/api/v1/keywords", `{"keyword":"v[x信]\\d+","kind":"regex"}`, nil); code != http.StatusCreated {
+		t.Errorf("添加正则规则 = %d", code)
+	}
+
+	var page keywordPage
+	if code := c.do("GET", "/api/v1/keywords?q=水果", "", &page); code != http.StatusOK || page.Total != 1 || page.Items[0].Keyword != "水果特价" {
+		t.Errorf("检索关键词 = %d %+v", code, page)
+	}
+
+	target := "/api/v1/keywords/" + url.PathEscape("水果特价")
+	var updated keywordJSON
+	if code := c.do("PATCH", target, `{"mode":"enforce"}`, &updated); code != http.StatusOK || updated.Mode != core.ModeEnforce {
+		t.Errorf("修改执行模式 = %d %+v", code, updated)
+	}
+	if code := c.do("PATCH", target, `{"mode":"loud"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("无效模式 = %d, 期望 400", code)
+	}
+	var got keywordJSON
+	if code := c.do("GET", target, "", &got); code != http.StatusOK || got.Mode != core.ModeEnforce {
+		t.Errorf("读取词条 = %d %+v", code, got)
+	}
+
+	var deleted deleteResult
+	if code := c.do("DELETE", target, "", &deleted); code != http.StatusOK || deleted.Rejected {
+		t.Errorf("删除手动添加的词 = %d %+v, 不应进否决表", code, deleted)
+	}
+	if code := c.do("GET", target, "", nil); code != http.StatusNotFound {
+		t.Errorf("删除后读取 = %d, 期望 404", code)
+	}
+
+	db.AddKeyword("刷单", core.SourceAI)
+	if code := c.do("DELETE", "/api/v1/keywords/"+url.PathEscape("刷单"), "", &deleted); code != http.StatusOK || !deleted.Rejected {
+		t.Errorf("删除 AI 加的词 = %d %+v, 应当进否决表", code, deleted)
+	}
+	if rejected, _ := db.IsKeywordRejected("刷单"); !rejected {
+		t.Error("AI 加的词删除后应在否决表里")
+	}
+}
+
+// TestRejectedKeywords 否决表可增、可列、可移出
+func TestRejectedKeywords(t *testing.T) {
+	db := useTempDB(t)
+	c := newClient(t)
+
+	if code := c.do("POST", "/api/v1/rejected-keywords", `{"keyword":" Telegram "}`, nil); code != http.StatusCreated {
+		t.Fatalf("否决 = %d", code)
+	}
+	var list listBody[rejectJSON]
+	if code := c.do("GET", "/api/v1/rejected-keywords", "", &list); code != http.StatusOK || len(list.Items) != 1 || list.Items[0].Keyword != "telegram" {
+		t.Errorf("列出否决表 = %d %+v", code, list)
+	}
+	if added, _ := db.AddKeyword("telegram", core.SourceAI); added {
+		t.Error("否决后 AI 不应能再添加")
+	}
+
+	if code := c.do("DELETE", "/api/v1/rejected-keywords/TELEGRAM", "", nil); code != http.StatusNoContent {
+		t.Errorf("移出否决表 = %d", code)
+	}
+	if code := c.do("DELETE", "/api/v1/rejected-keywords/telegram", "", nil); code != http.StatusNotFound {
+		t.Errorf("重复移出 = %d, 期望 404", code)
+	}
+}
+
+// TestPrompts 自动回复的增删改查要同步到内存映射
+func TestPrompts(t *testing.T) {
+	useTempDB(t)
+	c := newClient(t)
+
+	var saved promptJSON
+	if code := c.do("PUT", "/api/v1/prompts/"+url.PathEscape("官网"), `{"reply":"https://example.com"}`, &saved); code != http.StatusOK {
+		t.Fatalf("设置自动回复 = %d", code)
+	}
+	t.Cleanup(func() { c.do("DELETE", "/api/v1/prompts/"+url.PathEscape("官网"), "", nil) })
+
+	var got promptJSON
+	if code := c.do("GET", "/api/v1/prompts/"+url.PathEscape("官网"), "", &got); code != http.StatusOK || got.Reply != "https://example.com" {
+		t.Errorf("读取自动回复 = %d %+v", code, got)
+	}
+	var list listBody[promptJSON]
+	if code := c.do("GET", "/api/v1/prompts", "", &list); code != http.StatusOK || len(list.Items) == 0 {
+		t.Errorf("列出自动回复 = %d %+v", code, list)
+	}
+	if code := c.do("PUT", "/api/v1/prompts/x", `{"reply":""}`, nil); code != http.StatusBadRequest {
+		t.Errorf("空回复 = %d, 期望 400", code)
+	}
+
+	if code := c.do("DELETE", "/api/v1/prompts/"+url.PathEscape("官网"), "", nil); code != http.StatusNoContent {
+		t.Errorf("删除自动回复 = %d", code)
+	}
+	if code := c.do("GET", "/api/v1/prompts/"+url.PathEscape("官网"), "", nil); code != http.StatusNotFound {
+		t.Errorf("删除后读取 = %d, 期望 404", code)
+	}
+}
+
+// TestActions 处置记录按 id 倒序翻页; 试行记录与已撤销的记录不能再撤销
+func TestActions(t *testing.T) {
+	db := useTempDB(t)
+	c := newClient(t)
+
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		id, err := db.RecordModerationAction(core.ModerationAction{UserID: 9, ChatID: -100, Rule: "关键词", Penalty: core.Penalty{Action: core.PenaltyDelete}})
+		if err != nil {
+			t.Fatalf("记录处置失败: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	shadowID, _ := db.RecordModerationAction(core.ModerationAction{UserID: 8, ChatID: -100, Rule: "关键词", Shadow: true})
+
+	var page actionPage
+	if code := c.do("GET", "/api/v1/actions?user_id=9&limit=2", "", &page); code != http.StatusOK || len(page.Items) != 2 || page.NextBefore != ids[1] {
+		t.Fatalf("第一页 = %d %+v", code, page)
+	}
+	if page.Items[0].ID != ids[2] || page.Items[0].Penalty != core.PenaltyDelete {
+		t.Errorf("第一条应是最新的记录: %+v", page.Items[0])
+	}
+	if code := c.do("GET", "/api/v1/actions?user_id=9&limit=2&before="+strconv.FormatInt(page.NextBefore, 10), "", &page); code != http.StatusOK || len(page.Items) != 1 || page.NextBefore != 0 {
+		t.Errorf("第二页 = %d %+v", code, page)
+	}
+
+	var action actionJSON
+	if code := c.do("GET", "/api/v1/actions/"+strconv.FormatInt(shadowID, 10), "", &action); code != http.StatusOK || !action.Shadow {
+		t.Errorf("读取处置 = %d %+v", code, action)
+	}
+	if code := c.do("GET", "/api/v1/actions/999", "", nil); code != http.StatusNotFound {
+		t.Errorf("不存在的处置 = %d, 期望 404", code)
+	}
+	if code := c.do("POST", "/api/v1/actions/"+strconv.FormatInt(shadowID, 10)+"/undo", "", nil); code != http.StatusConflict {
+		t.Errorf("撤销试行记录 = %d, 期望 409", code)
+	}
+	db.MarkActionUndone(ids[0])
+	if code := c.do("POST", "/api/v1/actions/"+strconv.FormatInt(ids[0], 10)+"/undo", "", nil); code != http.StatusConflict {
+		t.Errorf("重复撤销 = %d, 期望 409", code)
+	}
+	if code := c.do("POST", "/api/v1/actions/999/undo", "", nil); code != http.StatusNotFound {
+		t.Errorf("撤销不存在的处置 = %d, 期望 404", code)
+	}
+}
+
+// TestStrikes 列出计分并清零; 群 ID 是负数也要能放进路径
+func TestStrikes(t *testing.T) {
+	db := useTempDB(t)
+	c := newClient(t)
+	db.AddStrike(9, -100, 2)
+
+	var list listBody[strikeJSON]
+	if code := c.do("GET", "/api/v1/strikes?user_id=9", "", &list); code != http.StatusOK || len(list.Items) != 1 || list.Items[0].Strikes != 2 {
+		t.Fatalf("列出计分 = %d %+v", code, list)
+	}
+	if code := c.do("DELETE", "/api/v1/strikes/9/-100", "", nil); code != http.StatusNoContent {
+		t.Errorf("清零计分 = %d", code)
+	}
+	if strikes, _ := db.GetStrikes(9, -100); strikes != 0 {
+		t.Errorf("清零后计分 = %d", strikes)
+	}
+}
+
+// TestBackups 触发一次快照后能在列表里看到
+func TestBackups(t *testing.T) {
+	useTempDB(t)
+	c := newClient(t)
+
+	var created backupJSON
+	if code := c.do("POST", "/api/v1/backups", "", &created); code != http.StatusCreated || created.Name == "" || created.Size == 0 {
+		t.Fatalf("生成快照 = %d %+v", code, created)
+	}
+	var list listBody[backupJSON]
+	if code := c.do("GET", "/api/v1/backups", "", &list); code != http.StatusOK || len(list.Items) != 1 || list.Items[0].Name != created.Name {
+		t.Errorf("列出快照 = %d %+v", code, list)
+	}
+}
+
+// TestInspect 试判返回实际会执行的结论, 同时列出试行规则的命中
+func TestInspect(t *testing.T) {
+	db := useTempDB(t)
+	c := newClient(t)
+	db.AddKeyword("水果", core.SourceManual)
+	db.AddKeyword("特价", core.SourceManual)
+	db.SetKeywordMode("特价", core.ModeShadow)
+
+	var result inspectResult
+	if code := c.do("POST", "/api/v1/inspect", `{"text":"水果特价了"}`, &result); code != http.StatusOK {
+		t.Fatalf("试判 = %d", code)
+	}
+	if !result.Hit || result.Shadow || result.Verdict == nil || result.Verdict.Detail != "水果" {
+		t.Errorf("结论 = %+v", result)
+	}
+	if len(result.Verdicts) != 2 {
+		t.Errorf("应列出正式与试行两条命中, 实际 %+v", result.Verdicts)
+	}
+
+	if code := c.do("POST", "/api/v1/inspect", `{"text":"你好"}`, &result); code != http.StatusOK || result.Hit || result.Verdict != nil {
+		t.Errorf("未命中 = %d %+v", code, result)
+	}
+	if code := c.do("POST", "/api/v1/inspect", `{}`, nil); code != http.StatusBadRequest {
+		t.Errorf("空文本 = %d, 期望 400", code)
+	}
+}
+
+// routePattern 从 New 的注册语句里取路径; 与 openapi.json 对照, 加了接口忘记写描述时测试会失败
+var routePattern = regexp.MustCompile(`"(?:GET|POST|PUT|PATCH|DELETE) /api/v1(/[^"]*)"`)
+
+// TestOpenAPICoversRoutes 描述文件是合法 JSON, 且每个注册的接口都有对应的路径与方法
+func TestOpenAPICoversRoutes(t *testing.T) {
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.json 不是合法 JSON: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("openapi 版本 = %q", spec.OpenAPI)
+	}
+
+	source, err := os.ReadFile("api.go")
+	if err != nil {
+		t.Fatalf("读取 api.go 失败: %v", err)
+	}
+	matches := routePattern.FindAllStringSubmatch(string(source), -1)
+	if len(matches) == 0 {
+		t.Fatal("api.go 里没有找到路由")
+	}
+	for _, match := range matches {
+		method, _, _ := strings.Cut(strings.Trim(match[0], `"`), " ")
+		path := strings.ReplaceAll(match[1], "...}", "}")
+		if _, ok := spec.Paths[path][strings.ToLower(method)]; !ok {
+			t.Errorf("openapi.json 缺少 %s %s", method, path)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package api
+
+// 词表相关接口: 关键词与规则、否决表、自动回复。
+// 校验与私聊命令一致: 词条过 core.ValidateKeyword, 非全局作用域要求群已登记, 删除 AI 加的词同时写入否决表。
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"SunaiForum-Bot/core"
+	"SunaiForum-Bot/service/prompt_reply"
+)
+
+// keywordJSON 词条的对外形态
+type keywordJSON struct {
+	Keyword  string    `json:"keyword"`
+	Kind     string    `json:"kind"`
+	Source   string    `json:"source"`
+	Mode     string    `json:"mode"`
+	Scope    string    `json:"scope"`
+	HitCount int       `json:"hit_count"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+func toKeywordJSON(k core.Keyword) keywordJSON {
+	return keywordJSON{
+		Keyword:  k.Word,
+		Kind:     k.Kind,
+		Source:   k.Source,
+		Mode:     k.Mode,
+		Scope:    k.Scope().String(),
+		HitCount: k.HitCount,
+		AddedAt:  k.AddedAt,
+	}
+}
+
+// keywordPage 关键词列表响应, total 是过滤后的总数, 配合 offset 翻页
+type keywordPage struct {
+	Items []keywordJSON `json:"items"`
+	Total int64         `json:"total"`
+}
+
+// listKeywords GET /keywords?q=&offset=&limit=
+func (s *Server) listKeywords(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryLimit(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	offset, err := queryInt64(r, "offset")
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, "参数 offset 应当是非负整数")
+		return
+	}
+
+	keywords, total, err := core.DB.ListKeywords(r.URL.Query().Get("q"), int(offset), limit)
+	if err != nil {
+		writeInternal(w, "读取关键词", err)
+		return
+	}
+	page := keywordPage{Items: make([]keywordJSON, 0, len(keywords)), Total: total}
+	for _, k := range keywords {
+		page.Items = append(page.Items, toKeywordJSON(k))
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// keywordRequest 新增词条的请求体; kind 与 scope 缺省为 plain 与 global, mode 缺省为 enforce
+type keywordRequest struct {
+	Keyword string `json:"keyword"`
+	Kind    string `json:"kind"`
+	Scope   string `json:"scope"`
+	Mode    string `json:"mode"`
+}
+
+// createKeyword POST /keywords
+func (s *Server) createKeyword(w http.ResponseWriter, r *http.Request) {
+	var req keywordRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.Keyword = strings.TrimSpace(req.Keyword)
+	if req.Kind == "" {
+		req.Kind = core.KindPlain
+	}
+	if req.Mode == "" {
+		req.Mode = core.ModeEnforce
+	}
+	if req.Kind != core.KindPlain && req.Kind != core.KindRegex && req.Kind != core.KindCombo {
+		writeError(w, http.StatusBadRequest, "kind 只能是 plain、regex 或 combo")
+		return
+	}
+	if !core.ValidMode(req.Mode) {
+		writeError(w, http.StatusBadRequest, "mode 只能是 enforce、shadow 或 off")
+		return
+	}
+	if err := core.ValidateKeyword(req.Kind, req.Keyword); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	scope, err := parseScope(req.Scope)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var added bool
+	if req.Kind == core.KindPlain {
+		added, err = core.DB.AddScopedKeyword(req.Keyword, core.SourceManual, scope)
+	} else {
+		added, err = core.DB.AddRule(req.Keyword, req.Kind, scope)
+	}
+	if err != nil {
+		writeInternal(w, "添加词条", err)
+		return
+	}
+	if !added {
+		// 一个词只能属于一个作用域, 已在别处的要说清在哪
+		if existing, ok, _ := core.DB.GetKeyword(req.Keyword); ok {
+			writeError(w, http.StatusConflict, fmt.Sprintf("词条已存在于作用域 %s", existing.Scope()))
+			return
+		}
+		writeError(w, http.StatusConflict, "词条已存在")
+		return
+	}
+	if req.Mode != core.ModeEnforce {
+		if _, err := core.DB.SetKeywordMode(req.Keyword, req.Mode); err != nil {
+			writeInternal(w, "设置执行模式", err)
+			return
+		}
+	}
+
+	keyword, _, err := core.DB.GetKeyword(req.Keyword)
+	if err != nil {
+		writeInternal(w, "读取词条", err)
+		return
+	}
+	log.Printf("[API] 添加词条 %q (%s, 作用域 %s)", req.Keyword, req.Kind, scope)
+	writeJSON(w, http.StatusCreated, toKeywordJSON(keyword))
+}
+
+// parseScope 解析作用域, 空值为全局; 非全局作用域要求群已登记, 与 /add 一致
+func parseScope(raw string) (core.Scope, error) {
+	if strings.TrimSpace(raw) == "" {
+		return core.GlobalScope, nil
+	}
+	scope, err := core.ParseScope(raw)
+	if err != nil {
+		return core.Scope{}, err
+	}
+	if !scope.IsGlobal() {
+		if _, ok := core.GroupSettings(scope.ChatID); !ok {
+			return core.Scope{}, fmt.Errorf("群 %d 没有登记", scope.ChatID)
+		}
+	}
+	return scope, nil
+}
+
+// lookupKeyword 取路径里的词条, 不存在时已回 404
+func lookupKeyword(w http.ResponseWriter, r *http.Request) (core.Keyword, bool) {
+	keyword, ok, err := core.DB.GetKeyword(r.PathValue("keyword"))
+	if err != nil {
+		writeInternal(w, "读取词条", err)
+		return core.Keyword{}, false
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "词条不存在")
+		return core.Keyword{}, false
+	}
+	return keyword, true
+}
+
+// getKeyword GET /keywords/{keyword}
+func (s *Server) getKeyword(w http.ResponseWriter, r *http.Request) {
+	if keyword, ok := lookupKeyword(w, r); ok {
+		writeJSON(w, http.StatusOK, toKeywordJSON(keyword))
+	}
+}
+
+// modeRequest 修改执行模式的请求体
+type modeRequest struct {
+	Mode string `json:"mode"`
+}
+
+// updateKeyword PATCH /keywords/{keyword}; 词条本身不可改, 改词等于删了重加, 能改的只有执行模式
+func (s *Server) updateKeyword(w http.ResponseWriter, r *http.Request) {
+	keyword, ok := lookupKeyword(w, r)
+	if !ok {
+		return
+	}
+	var req modeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !core.ValidMode(req.Mode) {
+		writeError(w, http.StatusBadRequest, "mode 只能是 enforce、shadow 或 off")
+		return
+	}
+	if _, err := core.DB.SetKeywordMode(keyword.Word, req.Mode); err != nil {
+		writeInternal(w, "设置执行模式", err)
+		return
+	}
+	keyword.Mode = req.Mode
+	log.Printf("[API] 词条 %q 改为 %s", keyword.Word, req.Mode)
+	writeJSON(w, http.StatusOK, toKeywordJSON(keyword))
+}
+
+// deleteResult 删除词条的结果; rejected 表示删的是 AI 加的词, 已一并写入否决表
+type deleteResult struct {
+	Keyword  string `json:"keyword"`
+	Rejected bool   `json:"rejected"`
+}
+
+// deleteKeyword DELETE /keywords/{keyword}
+func (s *Server) deleteKeyword(w http.ResponseWriter, r *http.Request) {
+	word := r.PathValue("keyword")
+	removed, source, err := core.DB.RemoveKeyword(word)
+	if err != nil {
+		writeInternal(w, "删除词条", err)
+		return
+	}
+	if !removed {
+		writeError(w, http.StatusNotFound, "词条不存在")
+		return
+	}
+
+	result := deleteResult{Keyword: word}
+	// 与 /delete 一致: 删掉 AI 加的词即否决, AI 不得再添加
+	if source == core.SourceAI {
+		if err := core.DB.RejectKeyword(word); err != nil {
+			log.Printf("[API] 否决关键词 %q 失败: %v", word, err)
+		} else {
+			result.Rejected = true
+		}
+	}
+	log.Printf("[API] 删除词条 %q", word)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// rejectJSON 否决表条目的对外形态
+type rejectJSON struct {
+	Keyword    string    `json:"keyword"`
+	RejectedAt time.Time `json:"rejected_at"`
+}
+
+// listRejected GET /rejected-keywords
+func (s *Server) listRejected(w http.ResponseWriter, _ *http.Request) {
+	rows, err := core.DB.GetRejectedKeywords()
+	if err != nil {
+		writeInternal(w, "读取否决表", err)
+		return
+	}
+	body := listBody[rejectJSON]{Items: make([]rejectJSON, 0, len(rows))}
+	for _, row := range rows {
+		body.Items = append(body.Items, rejectJSON{Keyword: row.Word, RejectedAt: row.RejectedAt})
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// rejectRequest 否决一个词的请求体
+type rejectRequest struct {
+	Keyword string `json:"keyword"`
+}
+
+// createRejected POST /rejected-keywords; 只拦住 AI 以后再加, 已在词表里的词不动
+func (s *Server) createRejected(w http.ResponseWriter, r *http.Request) {
+	var req rejectRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := core.ValidateKeyword(core.KindPlain, req.Keyword); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := core.DB.RejectKeyword(req.Keyword); err != nil {
+		writeInternal(w, "写入否决表", err)
+		return
+	}
+	log.Printf("[API] 否决关键词 %q", req.Keyword)
+	writeJSON(w, http.StatusCreated, rejectJSON{Keyword: strings.ToLower(strings.TrimSpace(req.Keyword)), RejectedAt: time.Now()})
+}
+
+// deleteRejected DELETE /rejected-keywords/{keyword}
+func (s *Server) deleteRejected(w http.ResponseWriter, r *http.Request) {
+	word := r.PathValue("keyword")
+	removed, err := core.DB.UnrejectKeyword(word)
+	if err != nil {
+		writeInternal(w, "移出否决表", err)
+		return
+	}
+	if !removed {
+		writeError(w, http.StatusNotFound, "该词不在否决表里")
+		return
+	}
+	log.Printf("[API] 移出否决表 %q", word)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// promptJSON 自动回复的对外形态
+type promptJSON struct {
+	Prompt string `json:"prompt"`
+	Reply  string `json:"reply"`
+}
+
+// listPrompts GET /prompts
+func (s *Server) listPrompts(w http.ResponseWriter, _ *http.Request) {
+	replies := prompt_reply.All()
+	body := listBody[promptJSON]{Items: make([]promptJSON, 0, len(replies))}
+	for prompt, reply := range replies {
+		body.Items = append(body.Items, promptJSON{Prompt: prompt, Reply: reply})
+	}
+	sort.Slice(body.Items, func(i, j int) bool { return body.Items[i].Prompt < body.Items[j].Prompt })
+	writeJSON(w, http.StatusOK, body)
+}
+
+// getPrompt GET /prompts/{prompt}; 触发词按小写存储, 查询时同样转小写
+func (s *Server) getPrompt(w http.ResponseWriter, r *http.Request) {
+	prompt := strings.ToLower(strings.TrimSpace(r.PathValue("prompt")))
+	reply, ok := prompt_reply.All()[prompt]
+	if !ok {
+		writeError(w, http.StatusNotFound, "没有这条自动回复")
+		return
+	}
+	writeJSON(w, http.StatusOK, promptJSON{Prompt: prompt, Reply: reply})
+}
+
+// replyRequest 设置自动回复的请求体
+type replyRequest struct {
+	Reply string `json:"reply"`
+}
+
+// putPrompt PUT /prompts/{prompt}; 新增或覆盖, 走 prompt_reply 包以同步内存映射
+func (s *Server) putPrompt(w http.ResponseWriter, r *http.Request) {
+	var req replyRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	prompt := strings.TrimSpace(r.PathValue("prompt"))
+	if err := prompt_reply.SetPromptReply(prompt, req.Reply); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	log.Printf("[API] 设置自动回复 %q", prompt)
+	writeJSON(w, http.StatusOK, promptJSON{Prompt: strings.ToLower(prompt), Reply: strings.TrimSpace(req.Reply)})
+}
+
+// deletePrompt DELETE /prompts/{prompt}
+func (s *Server) deletePrompt(w http.ResponseWriter, r *http.Request) {
+	prompt := strings.ToLower(strings.TrimSpace(r.PathValue("prompt")))
+	if _, ok := prompt_reply.All()[prompt]; !ok {
+		writeError(w, http.StatusNotFound, "没有这条自动回复")
+		return
+	}
+	if err := prompt_reply.DeletePromptReply(prompt); err != nil {
+		writeInternal(w, "删除自动回复", err)
+		return
+	}
+	log.Printf("[API] 删除自动回复 %q", prompt)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,296 @@
+package api
+
+// 处置相关接口: 处置记录与撤销、违规计分、数据库快照, 以及不落库的试判。
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"SunaiForum-Bot/core"
+	"SunaiForum-Bot/service/moderation"
+)
+
+// actionJSON 处置记录的对外形态
+type actionJSON struct {
+	ID           int64     `json:"id"`
+	UserID       int64     `json:"user_id"`
+	ChatID       int64     `json:"chat_id"`
+	UserName     string    `json:"user_name"`
+	MessageText  string    `json:"message_text"`
+	Rule         string    `json:"rule"`
+	Detail       string    `json:"detail"`
+	LearnedWords []string  `json:"learned_words"`
+	Penalty      string    `json:"penalty"`
+	Weight       int       `json:"weight"`
+	Banned       bool      `json:"banned"`
+	Undone       bool      `json:"undone"`
+	Shadow       bool      `json:"shadow"`
+	SenderChat   bool      `json:"sender_chat"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func toActionJSON(a core.ModerationAction) actionJSON {
+	learned := a.LearnedWords
+	if learned == nil {
+		learned = []string{}
+	}
+	return actionJSON{
+		ID:           a.ID,
+		UserID:       a.UserID,
+		ChatID:       a.ChatID,
+		UserName:     a.UserName,
+		MessageText:  a.MessageText,
+		Rule:         a.Rule,
+		Detail:       a.Detail,
+		LearnedWords: learned,
+		Penalty:      a.Penalty.String(),
+		Weight:       a.Weight,
+		Banned:       a.Banned,
+		Undone:       a.Undone,
+		Shadow:       a.Shadow,
+		SenderChat:   a.SenderChat,
+		CreatedAt:    a.CreatedAt,
+	}
+}
+
+// actionPage 处置记录列表响应; next_before 非零时带上它再请求一次即是下一页
+type actionPage struct {
+	Items      []actionJSON `json:"items"`
+	NextBefore int64        `json:"next_before"`
+}
+
+// listActions GET /actions?user_id=&before=&limit=, 按 id 倒序
+func (s *Server) listActions(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryLimit(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	userID, err := queryInt64(r, "user_id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	before, err := queryInt64(r, "before")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	actions, err := core.DB.GetRecentActions(userID, before, limit)
+	if err != nil {
+		writeInternal(w, "读取处置记录", err)
+		return
+	}
+	page := actionPage{Items: make([]actionJSON, 0, len(actions))}
+	for _, action := range actions {
+		page.Items = append(page.Items, toActionJSON(action))
+	}
+	if len(actions) == limit {
+		page.NextBefore = actions[len(actions)-1].ID
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// pathID 解析路径里的正整数 ID, 不合法时已回 400
+func pathID(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "无效的 "+name)
+		return 0, false
+	}
+	return id, true
+}
+
+// getAction GET /actions/{id}
+func (s *Server) getAction(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	action, err := core.DB.GetModerationAction(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, moderation.ErrActionNotFound.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, toActionJSON(action))
+}
+
+// undoResult 撤销结果, summary 与通知里"误判，恢复"按钮回报的内容一致
+type undoResult struct {
+	ID      int64  `json:"id"`
+	Summary string `json:"summary"`
+}
+
+// undoAction POST /actions/{id}/undo; 以所有者身份撤销, 与通知里的按钮等效
+func (s *Server) undoAction(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	summary, err := moderation.Undo(s.bot, id, core.AdminID)
+	switch {
+	case errors.Is(err, moderation.ErrActionNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, moderation.ErrAlreadyUndone), errors.Is(err, moderation.ErrShadowAction):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeInternal(w, "撤销处置", err)
+	default:
+		log.Printf("[API] 撤销处置 #%d: %s", id, summary)
+		writeJSON(w, http.StatusOK, undoResult{ID: id, Summary: summary})
+	}
+}
+
+// strikeJSON 违规计分的对外形态
+type strikeJSON struct {
+	UserID    int64     `json:"user_id"`
+	ChatID    int64     `json:"chat_id"`
+	Strikes   int       `json:"strikes"`
+	LastHitAt time.Time `json:"last_hit_at"`
+}
+
+// listStrikes GET /strikes?user_id=&limit=, 只列仍有计分的记录
+func (s *Server) listStrikes(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryLimit(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	userID, err := queryInt64(r, "user_id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	strikes, err := core.DB.GetStrikeRecords(userID, limit)
+	if err != nil {
+		writeInternal(w, "读取违规计分", err)
+		return
+	}
+	body := listBody[strikeJSON]{Items: make([]strikeJSON, 0, len(strikes))}
+	for _, strike := range strikes {
+		body.Items = append(body.Items, strikeJSON{UserID: strike.UserID, ChatID: strike.ChatID, Strikes: strike.Strikes, LastHitAt: strike.LastHitAt})
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// resetStrikes DELETE /strikes/{user_id}/{chat_id}; 与 /unban 一样只清计分, 不动封禁状态。
+// 群 ID 是负数, 不能套用 pathID 的正数校验
+func (s *Server) resetStrikes(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(r.PathValue("user_id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "无效的 user_id")
+		return
+	}
+	chatID, err := strconv.ParseInt(r.PathValue("chat_id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "无效的 chat_id")
+		return
+	}
+	if err := core.DB.ResetStrikes(userID, chatID); err != nil {
+		writeInternal(w, "清零违规计分", err)
+		return
+	}
+	log.Printf("[API] 清零用户 %d 在群 %d 的违规计分", userID, chatID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// backupJSON 快照的对外形态; 只给文件名, 下载走网页面板
+type backupJSON struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// listBackups GET /backups, 最新的排前面
+func (s *Server) listBackups(w http.ResponseWriter, _ *http.Request) {
+	files, err := core.DB.ListBackups()
+	if err != nil {
+		writeInternal(w, "读取快照目录", err)
+		return
+	}
+	body := listBody[backupJSON]{Items: make([]backupJSON, 0, len(files))}
+	for _, file := range files {
+		body.Items = append(body.Items, backupJSON{Name: file.Name, Size: file.Size, CreatedAt: file.ModTime})
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// createBackup POST /backups; 立即生成一份快照, 与每日快照共用轮转
+func (s *Server) createBackup(w http.ResponseWriter, _ *http.Request) {
+	path, err := core.DB.Backup("api", core.BackupKeep)
+	if err != nil {
+		writeInternal(w, "生成快照", err)
+		return
+	}
+	backup := backupJSON{Name: filepath.Base(path), CreatedAt: time.Now()}
+	if info, err := os.Stat(path); err == nil {
+		backup.Size, backup.CreatedAt = info.Size(), info.ModTime()
+	}
+	log.Printf("[API] 生成快照 %s", backup.Name)
+	writeJSON(w, http.StatusCreated, backup)
+}
+
+// inspectRequest 试判的请求体; chat_id 为 0 时只用全局词表, 与 /check 一致
+type inspectRequest struct {
+	Text        string `json:"text"`
+	DisplayName string `json:"display_name"`
+	ChatID      int64  `json:"chat_id"`
+	TopicID     int    `json:"topic_id"`
+}
+
+// verdictJSON 一条审核结论
+type verdictJSON struct {
+	Rule     string   `json:"rule"`
+	Detail   string   `json:"detail"`
+	Keywords []string `json:"keywords"`
+	Shadow   bool     `json:"shadow"`
+	Part     string   `json:"part,omitempty"`
+}
+
+func toVerdictJSON(v moderation.Verdict) verdictJSON {
+	keywords := v.Keywords
+	if keywords == nil {
+		keywords = []string{}
+	}
+	return verdictJSON{Rule: v.Rule, Detail: v.Detail, Keywords: keywords, Shadow: v.Shadow, Part: v.Part}
+}
+
+// inspectResult 试判结果: verdict 是实际会执行的那条结论, verdicts 是全部命中 (含试行规则)
+type inspectResult struct {
+	Hit      bool          `json:"hit"`
+	Shadow   bool          `json:"shadow"`
+	Verdict  *verdictJSON  `json:"verdict"`
+	Verdicts []verdictJSON `json:"verdicts"`
+}
+
+// inspect POST /inspect; 只跑文本类规则, 不处置、不记命中次数, 适合上线新词前批量回放历史消息
+func (s *Server) inspect(w http.ResponseWriter, r *http.Request) {
+	var req inspectRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" && strings.TrimSpace(req.DisplayName) == "" {
+		writeError(w, http.StatusBadRequest, "text 与 display_name 不能都为空")
+		return
+	}
+
+	verdict := moderation.Inspect(req.ChatID, req.TopicID, req.Text, req.DisplayName, 0)
+	all := moderation.InspectAll(req.ChatID, req.TopicID, req.Text, req.DisplayName, 0)
+	result := inspectResult{Hit: verdict.Hit, Shadow: verdict.Shadow, Verdicts: make([]verdictJSON, 0, len(all))}
+	if verdict.Hit {
+		v := toVerdictJSON(verdict)
+		result.Verdict = &v
+	}
+	for _, v := range all {
+		result.Verdicts = append(result.Verdicts, toVerdictJSON(v))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"log"
 	"strconv"
-	"sync"
 	"time"
 
 	"SunaiForum-Bot/core"
@@ -15,64 +14,52 @@ import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-var (
-	chatID    int64
-	symbols   []string
-	bot       *tgbotapi.BotAPI
-	lastMsgID int
-	lastMsgMu sync.Mutex // 保护lastMsgID的并发访问
-)
+var bot *tgbotapi.BotAPI
 
+// lastMsgIDConfigKey 每个群上一条行情消息的 id, 按群分键存储, 推送新行情前先删掉旧的
 const lastMsgIDConfigKey = "binance_last_msg_id"
 
-// 从数据库加载lastMsgID
-func loadLastMsgID() {
-	lastMsgMu.Lock()
-	defer lastMsgMu.Unlock()
-	
-	value, err := core.DB.GetConfig(lastMsgIDConfigKey)
+// lastMsgKey 返回某个群的 lastMsgID 配置键
+func lastMsgKey(chatID int64) string {
+	return fmt.Sprintf("%s:%d", lastMsgIDConfigKey, chatID)
+}
+
+// 从数据库加载某个群的lastMsgID, 不存在返回0。
+// 多群改造前只有一个群, 用的是不带群号的旧键; CHAT_ID 对应的群读不到新键时回落旧键, 避免升级后残留一条旧行情
+func loadLastMsgID(chatID int64) int {
+	value, err := core.DB.GetConfig(lastMsgKey(chatID))
+	if err == nil && value == "" && chatID == core.ChatID {
+		value, err = core.DB.GetConfig(lastMsgIDConfigKey)
+	}
 	if err != nil {
-		log.Printf("[Binance] 加载lastMsgID失败: %v", err)
-		lastMsgID = 0
-		return
+		log.Printf("[Binance] 加载群 %d 的lastMsgID失败: %v", chatID, err)
+		return 0
 	}
-	
 	if value == "" {
-		lastMsgID = 0
-		log.Printf("[Binance] 未找到保存的lastMsgID，设置为0")
-		return
+		return 0
 	}
-	
+
 	msgID, err := strconv.Atoi(value)
 	if err != nil {
-		log.Printf("[Binance] 解析lastMsgID失败: %v", err)
-		lastMsgID = 0
-		return
+		log.Printf("[Binance] 解析群 %d 的lastMsgID失败: %v", chatID, err)
+		return 0
 	}
-	
-	lastMsgID = msgID
-	log.Printf("[Binance] 从数据库加载lastMsgID: %d", lastMsgID)
+	return msgID
 }
 
-// 保存lastMsgID到数据库
-func saveLastMsgID(msgID int) {
-	lastMsgMu.Lock()
-	defer lastMsgMu.Unlock()
-	
-	lastMsgID = msgID
-	err := core.DB.SetConfig(lastMsgIDConfigKey, strconv.Itoa(msgID))
-	if err != nil {
-		log.Printf("[Binance] 保存lastMsgID失败: %v", err)
-	} else {
-		log.Printf("[Binance] 保存lastMsgID到数据库: %d", msgID)
+// 保存某个群的lastMsgID到数据库
+func saveLastMsgID(chatID int64, msgID int) {
+	if err := core.DB.SetConfig(lastMsgKey(chatID), strconv.Itoa(msgID)); err != nil {
+		log.Printf("[Binance] 保存群 %d 的lastMsgID失败: %v", chatID, err)
+		return
 	}
-}
-
-// 获取当前的lastMsgID（线程安全）
-func getLastMsgID() int {
-	lastMsgMu.Lock()
-	defer lastMsgMu.Unlock()
-	return lastMsgID
+	if chatID == core.ChatID {
+		// 新键写入后旧键就没用了, 删掉防止以后误读
+		if err := core.DB.DeleteConfig(lastMsgIDConfigKey); err != nil {
+			log.Printf("[Binance] 删除旧的lastMsgID键失败: %v", err)
+		}
+	}
+	log.Printf("[Binance] 保存群 %d 的lastMsgID到数据库: %d", chatID, msgID)
 }
 
 type tickerInfo struct {
@@ -81,7 +68,17 @@ type tickerInfo struct {
 	changePercent float64
 }
 
+// getTickerInfo 查询行情, 失败计入 sunai_binance_fetch_errors_total
 func getTickerInfo(symbol string) (tickerInfo, error) {
+	info, err := fetchTickerInfo(symbol)
+	if err != nil {
+		fetchErrors.WithLabelValues("ticker").Inc()
+	}
+	return info, err
+}
+
+// fetchTickerInfo 向币安查询一个交易对的最新价与 24 小时涨跌
+func fetchTickerInfo(symbol string) (tickerInfo, error) {
 	client := binance.NewClient("", "")
 
 	ticker, err := client.NewListPricesService().Symbol(symbol).Do(context.Background())
@@ -124,13 +121,22 @@ func formatChange(changePercent float64) string {
 	return fmt.Sprintf("◀▶ %.2f%%", changePercent)
 }
 
-func sendPriceUpdate() {
-	// 如果没有配置交易对，跳过推送
-	if len(symbols) == 0 {
-		log.Println("[Binance] 未配置交易对，跳过价格推送")
+// sendPriceUpdates 给每个配置了交易对的受管群推送一次行情
+func sendPriceUpdates() {
+	groups, err := core.DB.GetGroups()
+	if err != nil {
+		log.Printf("[Binance] 读取受管群列表失败: %v", err)
 		return
 	}
 
+	for _, group := range groups {
+		if symbols := group.SymbolList(); len(symbols) > 0 {
+			sendPriceUpdate(group.ChatID, symbols)
+		}
+	}
+}
+
+func sendPriceUpdate(chatID int64, symbols []string) {
 	now := time.Now()
 	message := fmt.Sprintf("市场更新 - %s (SGT)\n\n", now.Format("2006-01-02 15:04:05"))
 
@@ -149,14 +155,13 @@ func sendPriceUpdate() {
 	}
 
 	// 删除之前的消息（如果存在）
-	currentLastMsgID := getLastMsgID()
-	if currentLastMsgID != 0 {
-		deleteMsg := tgbotapi.NewDeleteMessage(chatID, currentLastMsgID)
+	if lastMsgID := loadLastMsgID(chatID); lastMsgID != 0 {
+		deleteMsg := tgbotapi.NewDeleteMessage(chatID, lastMsgID)
 		_, err := bot.Request(deleteMsg)
 		if err != nil {
-			log.Printf("[Binance] 删除前一条消息 %d 时出错: %v", currentLastMsgID, err)
+			log.Printf("[Binance] 删除群 %d 前一条消息 %d 时出错: %v", chatID, lastMsgID, err)
 		} else {
-			log.Printf("[Binance] 成功删除前一条消息: %d", currentLastMsgID)
+			log.Printf("[Binance] 成功删除群 %d 前一条消息: %d", chatID, lastMsgID)
 		}
 	}
 
@@ -170,19 +175,15 @@ func sendPriceUpdate() {
 	}
 
 	// 保存新消息ID到数据库
-	saveLastMsgID(sentMsg.MessageID)
+	saveLastMsgID(chatID, sentMsg.MessageID)
 }
 
+// RunBinance 启动行情服务: 交易对缓存供查询使用, 整点给各受管群推送行情。
+// 推送的交易对按群配置, 每次推送时现读, 因此管理员改完设置下个整点即生效, 不需要重启
 func RunBinance() {
 	log.Println("[Binance]", "启动币安服务...")
 
-	// 初始化必要的变量
 	bot = core.Bot
-	chatID = core.ChatID
-	symbols = core.Symbols
-
-	// 从数据库加载lastMsgID（容器重启时恢复）
-	loadLastMsgID()
 
 	// 初始化并加载所有交易对
 	if err := LoadAllSymbols(); err != nil {
@@ -193,15 +194,8 @@ func RunBinance() {
 	go StartSymbolRefresh(1 * time.Hour)
 	log.Println("[Binance]", "启动每小时刷新交易对缓存...")
 
-	// 检查是否配置了交易对
-	if len(symbols) == 0 {
-		log.Println("[Binance]", "未配置交易对（SYMBOLS环境变量为空），仅启用查询功能，不主动推送价格")
-		// 不启动定时推送，但保持服务运行以支持查询功能
-		select {} // 阻塞主goroutine，保持服务运行
-	}
-
 	// 立即发送一次价格更新（会删除之前的消息如果存在）
-	sendPriceUpdate()
+	sendPriceUpdates()
 
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
@@ -210,7 +204,7 @@ func RunBinance() {
 		now := time.Now()
 		if now.Minute() == 0 {
 			log.Println("[Binance]", "发送每小时价格更新...")
-			sendPriceUpdate()
+			sendPriceUpdates()
 		}
 	}
 }
@@ -0,0 +1,13 @@
+package binance
+
+// 行情查询的 Prometheus 指标, 注册说明见 core/metrics.go
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// fetchErrors 请求币安失败的次数; call 为 ticker (查价) 或 symbols (刷新交易对列表)
+var fetchErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "sunai_binance_fetch_errors_total",
+	Help: "请求币安接口失败次数, 按调用",
+}, []string{"call"})
@@ -22,6 +22,7 @@ func LoadAllSymbols() error {
 	client := binance.NewClient("", "")
 	exchangeInfo, err := client.NewExchangeInfoService().Do(context.Background())
 	if err != nil {
+		fetchErrors.WithLabelValues("symbols").Inc()
 		return err
 	}
 
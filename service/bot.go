@@ -1,13 +1,15 @@
 package service
 
-// 机器人生命周期: 注册命令、拉取更新、断线重连
+// 机器人生命周期: 注册命令、拉取更新、断线重连; webhook 模式见 webhook.go
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"time"
 
 	"SunaiForum-Bot/core"
 	"SunaiForum-Bot/service/command"
+	"SunaiForum-Bot/service/moderation"
 	"SunaiForum-Bot/service/prompt_reply"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -23,7 +25,7 @@ const (
 	healthyRunThreshold = time.Minute
 )
 
-// RunMessageHandler 阻塞运行消息处理主循环, 仅在无法恢复的初始化错误时返回
+// RunMessageHandler 阻塞运行消息处理主循环, 仅在无法恢复的初始化错误时返回; webhook 模式下收到退出信号时正常返回
 func RunMessageHandler() error {
 	log.Println("[MessageHandler] 消息处理器启动...")
 
@@ -32,6 +34,8 @@ func RunMessageHandler() error {
 		log.Printf("[MessageHandler] 加载提示回复数据失败, 自动回复暂不可用: %v", err)
 	}
 
+	moderation.ConfigureShortLinks()
+
 	bot := core.Bot
 	if err := registerCommands(bot); err != nil {
 		return fmt.Errorf("注册机器人命令失败: %w", err)
@@ -39,17 +43,29 @@ func RunMessageHandler() error {
 	log.Printf("[MessageHandler] 已授权账户 %s", bot.Self.UserName)
 
 	rateLimiter := core.NewRateLimiter()
+	if core.WebhookURL != "" {
+		return runWebhook(bot, rateLimiter)
+	}
+
+	// 之前跑过 webhook 模式又没能正常注销的, getUpdates 会一直报 409, 先清掉; 攒着的更新不丢
+	if _, err := bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		log.Printf("[MessageHandler] 清除残留 webhook 失败: %v", err)
+	}
+
 	delay := reconnectBaseDelay
+	// offset 跨重连保留: 从 0 重新拉会把断线前已经处理过、但还没来得及确认的那批更新再处理一遍
+	offset := 0
 
 	for {
 		startedAt := time.Now()
-		consumeUpdates(bot, rateLimiter)
+		consumeUpdates(bot, rateLimiter, &offset)
 
 		// 正常跑过一段时间才断开的, 视为偶发断线, 退避重新从最小值开始
 		if time.Since(startedAt) > healthyRunThreshold {
 			delay = reconnectBaseDelay
 		}
 
+		reconnectsTotal.Inc()
 		log.Printf("[MessageHandler] 更新通道已关闭, %v 后重连...", delay)
 		time.Sleep(delay)
 
@@ -73,20 +89,59 @@ func registerCommands(bot *tgbotapi.BotAPI) error {
 	return nil
 }
 
-// consumeUpdates 建立更新通道并分发消息, 通道关闭后返回交由上层重连
-func consumeUpdates(bot *tgbotapi.BotAPI, rateLimiter *core.RateLimiter) {
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = updateTimeout
-
-	for update := range bot.GetUpdatesChan(u) {
-		go func() {
-			// 单条消息的处理失败不允许拖垮整个进程
-			defer func() {
-				if r := recover(); r != nil {
-					log.Printf("[MessageHandler] 处理更新 %d 时 panic: %v", update.UpdateID, r)
-				}
-			}()
-			handleUpdate(bot, update, rateLimiter)
-		}()
+// consumeUpdates 长轮询拉取更新并分发, 请求出错时返回交由上层退避重连。
+//
+// 不用库自带的 GetUpdatesChan: 它把原始 JSON 直接解进库的结构体, 论坛话题 ID 随之丢失,
+// 这里拿到原始 JSON 后交给 core.DecodeUpdate 解码 (见 core/topic.go)。
+func consumeUpdates(bot *tgbotapi.BotAPI, rateLimiter *core.RateLimiter, offset *int) {
+	config := tgbotapi.NewUpdate(*offset)
+	config.Timeout = updateTimeout
+
+	for {
+		config.Offset = *offset
+		resp, err := bot.Request(config)
+		if err != nil {
+			log.Printf("[MessageHandler] 拉取更新失败: %v", err)
+			return
+		}
+
+		var raws []json.RawMessage
+		if err := json.Unmarshal(resp.Result, &raws); err != nil {
+			log.Printf("[MessageHandler] 解析更新列表失败: %v", err)
+			return
+		}
+
+		for _, raw := range raws {
+			// 先单独取 update_id 推进 offset: 即便这条解不出来, 也不能卡住后面所有更新
+			var header struct {
+				UpdateID int `json:"update_id"`
+			}
+			if err := json.Unmarshal(raw, &header); err == nil && header.UpdateID >= *offset {
+				*offset = header.UpdateID + 1
+			}
+
+			update, err := core.DecodeUpdate(raw)
+			if err != nil {
+				log.Printf("[MessageHandler] 解码更新 %d 失败, 跳过: %v", header.UpdateID, err)
+				continue
+			}
+			dispatchUpdate(bot, update, rateLimiter)
+		}
 	}
 }
+
+// dispatchUpdate 异步处理一条更新
+func dispatchUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update, rateLimiter *core.RateLimiter) {
+	go processUpdate(bot, update, rateLimiter)
+}
+
+// processUpdate 处理一条更新, 长轮询与 webhook 共用
+func processUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update, rateLimiter *core.RateLimiter) {
+	// 单条消息的处理失败不允许拖垮整个进程
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[MessageHandler] 处理更新 %d 时 panic: %v", update.UpdateID, r)
+		}
+	}()
+	handleUpdate(bot, update, rateLimiter)
+}
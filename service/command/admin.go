@@ -0,0 +1,105 @@
+package command
+
+// 管理员名册的维护命令, 只有所有者可用。
+// 目标用户用数字 ID 指定: 私聊里拿不到群成员列表, 用户名又可以随时改, 只有 ID 是稳定的。
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// grantAdmin 任命管理员或修改其角色: "<用户 ID> <角色>"
+func grantAdmin(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	fields := strings.Fields(args)
+	if len(fields) != 2 {
+		core.SendErrorMessage(bot, message.Chat.ID, "格式：/grant 用户ID 角色，例如 /grant 123456789 moderator")
+		return
+	}
+
+	userID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, fmt.Sprintf("用户 ID 不是数字：%s", fields[0]))
+		return
+	}
+	role := strings.ToLower(fields[1])
+	if !core.ValidRole(role) {
+		core.SendErrorMessage(bot, message.Chat.ID,
+			fmt.Sprintf("未知角色 %s，可选：%s", fields[1], strings.Join(core.RoleNames(), "、")))
+		return
+	}
+	if userID == core.AdminID {
+		core.SendErrorMessage(bot, message.Chat.ID, "ADMIN_ID 指定的所有者角色固定，不能修改。")
+		return
+	}
+
+	if err := core.DB.SetAdminRole(userID, role, message.From.ID); err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, "写入名册失败。")
+		log.Printf("[Command] 任命管理员 %d 失败: %v", userID, err)
+		return
+	}
+
+	log.Printf("[Command] 管理员 %d 任命 %d 为 %s", message.From.ID, userID, role)
+	core.SendMessage(bot, message.Chat.ID, fmt.Sprintf("已任命 %d 为%s。\n对方需要先私聊机器人发一条消息，机器人才能给对方推送通知。",
+		userID, core.RoleLabel(role)))
+}
+
+// revokeAdmin 撤销管理员, 支持一次多行批量
+func revokeAdmin(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	var report batchReport
+
+	for _, line := range splitLines(args) {
+		userID, err := strconv.ParseInt(line, 10, 64)
+		if err != nil {
+			report.failed = append(report.failed, fmt.Sprintf("%s（不是数字）", line))
+			continue
+		}
+		if userID == core.AdminID {
+			report.failed = append(report.failed, fmt.Sprintf("%s（ADMIN_ID 指定的所有者不能撤销）", line))
+			continue
+		}
+
+		removed, err := core.DB.RemoveAdmin(userID)
+		if err != nil {
+			report.failed = append(report.failed, fmt.Sprintf("%s（删除失败）", line))
+			log.Printf("[Command] 撤销管理员 %d 失败: %v", userID, err)
+			continue
+		}
+		if removed {
+			report.succeeded = append(report.succeeded, line)
+			log.Printf("[Command] 管理员 %d 撤销了 %d", message.From.ID, userID)
+		} else {
+			report.skipped = append(report.skipped, line)
+		}
+	}
+
+	core.SendMessage(bot, message.Chat.ID, report.render("已撤销", "不在名册，跳过"))
+}
+
+// listAdmins 列出名册; ADMIN_ID 不在表里, 单独排在第一位
+func listAdmins(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+	admins, err := core.DB.GetAdmins()
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, "读取名册时发生错误。")
+		log.Printf("[Command] 读取管理员名册失败: %v", err)
+		return
+	}
+
+	items := []string{fmt.Sprintf("%d · %s（ADMIN_ID）", core.AdminID, core.RoleLabel(core.RoleOwner))}
+	for _, admin := range admins {
+		if admin.UserID == core.AdminID {
+			continue
+		}
+		items = append(items, fmt.Sprintf("%d · %s（%s 由 %d 任命）",
+			admin.UserID, core.RoleLabel(admin.Role), admin.GrantedAt.Format("2006-01-02"), admin.GrantedBy))
+	}
+
+	if err := core.SendLongMessage(bot, message.Chat.ID,
+		fmt.Sprintf("管理员（%d 人）：", len(items)), items); err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, "发送名册时发生错误。")
+	}
+}
@@ -0,0 +1,162 @@
+package command
+
+// 跨群封禁名单的维护命令: 查看与搜索、批量导入、移出。名单规则见 core/federation.go。
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// banListLimit 列表与搜索最多展示的条数; 名单可能很长, 全量刷屏没有意义, 找具体的人用搜索
+const banListLimit = 50
+
+// listBannedUsers 不带参数列出最近入名单的账号; 带参数按用户 ID、昵称或原因搜索
+func listBannedUsers(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	query := strings.TrimSpace(args)
+
+	var (
+		entries []core.BannedUser
+		header  string
+		err     error
+	)
+	if query == "" {
+		var total int64
+		entries, total, err = core.DB.ListBannedUsers(banListLimit)
+		header = fmt.Sprintf("封禁名单共 %d 个账号，最近 %d 个：", total, len(entries))
+	} else {
+		entries, err = core.DB.SearchBannedUsers(query, banListLimit)
+		header = fmt.Sprintf("与「%s」匹配的账号（%d 个）：", query, len(entries))
+	}
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, "读取封禁名单时发生错误。")
+		log.Printf("[Command] 读取封禁名单失败: %v", err)
+		return
+	}
+	if len(entries) == 0 {
+		core.SendMessage(bot, message.Chat.ID, "没有找到。")
+		return
+	}
+
+	items := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		items = append(items, describeBannedUser(entry))
+	}
+	if err := core.SendLongMessage(bot, message.Chat.ID, header, items); err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, "发送封禁名单时发生错误。")
+	}
+}
+
+// describeBannedUser 名单条目的一行展示
+func describeBannedUser(entry core.BannedUser) string {
+	name := entry.UserName
+	if name == "" {
+		name = "（未知）"
+	}
+	line := fmt.Sprintf("%d %s｜%s｜%s", entry.UserID, name, orNone(entry.Reason), entry.BannedAt.Format("2006-01-02"))
+	if entry.ChatID != 0 {
+		line += fmt.Sprintf("｜群 %d", entry.ChatID)
+	}
+	return line
+}
+
+// importBannedUsers 批量导入名单, 每行 "用户ID 原因", ID 与原因以空白分隔, 原因可省略。
+// 只入名单不主动封禁: 导入的名单动辄上千条, 逐群逐个调接口会撞上 Telegram 的频率限制,
+// 而这些账号多半根本不在群里, 等它们进群时再踢即可。
+func importBannedUsers(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	entries, invalid := parseBanImport(args)
+	if len(entries) == 0 {
+		core.SendErrorMessage(bot, message.Chat.ID, "没有可导入的行，格式为每行「用户ID 原因」。")
+		return
+	}
+
+	added, err := core.DB.ImportBannedUsers(entries)
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, "导入封禁名单时发生错误。")
+		log.Printf("[Command] 导入封禁名单失败: %v", err)
+		return
+	}
+
+	log.Printf("[Command] 管理员 %d 导入封禁名单 %d 条, 新增 %d 条", message.From.ID, len(entries), added)
+	result := fmt.Sprintf("已导入 %d 条，其中 %d 条已在名单上。\n名单上的账号进入任一成员群时会被踢出。", added, len(entries)-added)
+	if len(invalid) > 0 {
+		result += fmt.Sprintf("\n\n以下 %d 行无法识别，已跳过：\n%s", len(invalid), strings.Join(invalid, "\n"))
+	}
+	core.SendMessage(bot, message.Chat.ID, result)
+}
+
+// parseBanImport 解析导入内容, 返回可导入的条目与无法识别的行; 同一 ID 重复出现只取第一行
+func parseBanImport(args string) ([]core.BannedUser, []string) {
+	var (
+		entries []core.BannedUser
+		invalid []string
+		seen    = make(map[int64]bool)
+	)
+	for _, line := range strings.Split(args, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		// ID 与原因之间可以是空格也可以是制表符, 从表格里复制出来的多是后者
+		idText := strings.Fields(line)[0]
+		reason := line[len(idText):]
+		userID, err := strconv.ParseInt(idText, 10, 64)
+		if err != nil || userID <= 0 {
+			invalid = append(invalid, line)
+			continue
+		}
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		reason = strings.TrimSpace(reason)
+		if reason == "" {
+			reason = "导入"
+		}
+		entries = append(entries, core.BannedUser{UserID: userID, Reason: reason})
+	}
+	return entries, invalid
+}
+
+// removeBannedUsers 把账号移出名单, 并在全部成员群解封; 可一次多个, 空白或换行分隔
+func removeBannedUsers(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	var removed, missing []string
+	for _, field := range strings.Fields(args) {
+		userID, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			missing = append(missing, field)
+			continue
+		}
+
+		ok, err := core.LiftFederatedBan(bot, userID)
+		if err != nil {
+			log.Printf("[Command] 移出封禁名单失败 (用户 %d): %v", userID, err)
+			missing = append(missing, field)
+			continue
+		}
+		if !ok {
+			missing = append(missing, field)
+			continue
+		}
+		removed = append(removed, field)
+	}
+
+	log.Printf("[Command] 管理员 %d 移出封禁名单: %v", message.From.ID, removed)
+	var b strings.Builder
+	if len(removed) > 0 {
+		fmt.Fprintf(&b, "已移出名单并在各成员群解封：%s", strings.Join(removed, "、"))
+	}
+	if len(missing) > 0 {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "不在名单上或无法识别：%s", strings.Join(missing, "、"))
+	}
+	core.SendMessage(bot, message.Chat.ID, b.String())
+}
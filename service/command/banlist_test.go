@@ -0,0 +1,17 @@
+package command
+
+import "testing"
+
+func TestParseBanImport(t *testing.T) {
+	entries, invalid := parseBanImport("123 刷单广告\n\n456\n123 重复\nabc 无效\n-5 负数\n789\t表格导出")
+
+	if len(entries) != 3 || entries[0].UserID != 123 || entries[0].Reason != "刷单广告" || entries[1].Reason != "导入" {
+		t.Errorf("解析结果 = %+v", entries)
+	}
+	if len(entries) == 3 && (entries[2].UserID != 789 || entries[2].Reason != "表格导出") {
+		t.Errorf("制表符分隔的行 = %+v", entries[2])
+	}
+	if len(invalid) != 2 {
+		t.Errorf("无法识别的行 = %v, 期望 2 行", invalid)
+	}
+}
@@ -0,0 +1,154 @@
+package command
+
+// /channel: 维护来源频道的黑白名单。
+// 黑名单上的频道, 转发即撤回、以其身份发言即封禁该频道身份; 白名单上的频道不做来源检查。
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// channelUsage /channel 的用法说明
+const channelUsage = "用法：\n" +
+	"/channel block @频道 … 加入黑名单\n" +
+	"/channel allow @频道 … 加入白名单\n" +
+	"/channel remove @频道 … 移出名单\n" +
+	"频道可以写 @用户名、t.me 链接或 -100 开头的 ID，一次可写多个。"
+
+// channelLists 名单种类的中文展示
+var channelLists = map[string]string{
+	core.ChannelBlock: "黑名单",
+	core.ChannelAllow: "白名单",
+}
+
+// manageChannels 不带参数时列出名单; 带参数时按子命令修改
+func manageChannels(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		core.SendMessage(bot, message.Chat.ID, describeChannels()+"\n\n"+channelUsage)
+		return
+	}
+
+	action := strings.ToLower(fields[0])
+	refs, invalid := parseChannelRefs(fields[1:])
+	if len(refs) == 0 && len(invalid) == 0 {
+		core.SendErrorMessage(bot, message.Chat.ID, "需要至少一个频道。\n"+channelUsage)
+		return
+	}
+
+	var done []string
+	switch action {
+	case core.ChannelBlock, core.ChannelAllow:
+		for _, ref := range refs {
+			err := core.DB.SetSourceChannel(core.SourceChannel{
+				Ref:     ref,
+				List:    action,
+				Note:    channelTitle(bot, ref),
+				AddedBy: message.From.ID,
+			})
+			if err != nil {
+				log.Printf("[Command] 登记来源频道 %s 失败: %v", ref, err)
+				invalid = append(invalid, ref)
+				continue
+			}
+			done = append(done, ref)
+		}
+	case "remove":
+		for _, ref := range refs {
+			removed, err := core.DB.RemoveSourceChannel(ref)
+			if err != nil {
+				log.Printf("[Command] 移除来源频道 %s 失败: %v", ref, err)
+			}
+			if err != nil || !removed {
+				invalid = append(invalid, ref)
+				continue
+			}
+			done = append(done, ref)
+		}
+	default:
+		core.SendErrorMessage(bot, message.Chat.ID, fmt.Sprintf("未知的操作 %q。\n%s", fields[0], channelUsage))
+		return
+	}
+
+	log.Printf("[Command] 管理员 %d 来源频道 %s: %v", message.From.ID, action, done)
+	var b strings.Builder
+	if len(done) > 0 {
+		if label, ok := channelLists[action]; ok {
+			fmt.Fprintf(&b, "已加入%s：%s", label, strings.Join(done, "、"))
+		} else {
+			fmt.Fprintf(&b, "已移出名单：%s", strings.Join(done, "、"))
+		}
+	}
+	if len(invalid) > 0 {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "无法识别或不在名单上：%s", strings.Join(invalid, "、"))
+	}
+	core.SendMessage(bot, message.Chat.ID, b.String())
+}
+
+// parseChannelRefs 规整一组频道写法, 无法识别的原样放进第二个返回值
+func parseChannelRefs(fields []string) (refs, invalid []string) {
+	for _, field := range fields {
+		ref, err := core.ParseChannelRef(field)
+		if err != nil {
+			invalid = append(invalid, field)
+			continue
+		}
+		refs = append(refs, ref)
+	}
+	return refs, invalid
+}
+
+// channelTitle 取频道名只为展示; 机器人看不到的私有频道取不到, 不影响登记
+func channelTitle(bot *tgbotapi.BotAPI, ref string) string {
+	config := tgbotapi.ChatConfig{SuperGroupUsername: "@" + ref}
+	if chatID, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		config = tgbotapi.ChatConfig{ChatID: chatID}
+	}
+	chat, err := bot.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: config})
+	if err != nil {
+		return ""
+	}
+	return chat.Title
+}
+
+// describeChannels 列出黑白名单
+func describeChannels() string {
+	entries, err := core.DB.ListSourceChannels()
+	if err != nil {
+		log.Printf("[Command] 读取来源频道名单失败: %v", err)
+		return "读取名单失败。"
+	}
+	if len(entries) == 0 {
+		return "来源频道名单为空。"
+	}
+
+	var b strings.Builder
+	current := ""
+	for _, entry := range entries {
+		if entry.List != current {
+			if current != "" {
+				b.WriteString("\n")
+			}
+			current = entry.List
+			fmt.Fprintf(&b, "%s：\n", channelLists[entry.List])
+		}
+		ref := entry.Ref
+		if !strings.HasPrefix(ref, "-") {
+			ref = "@" + ref
+		}
+		if entry.Note != "" {
+			fmt.Fprintf(&b, "- %s（%s）\n", ref, entry.Note)
+		} else {
+			fmt.Fprintf(&b, "- %s\n", ref)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
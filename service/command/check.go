@@ -0,0 +1,192 @@
+package command
+
+// /check: 审核预演。
+//
+// 把一段文本按真实审核流程走一遍, 列出会触发的全部规则、命中的词、会不会送 AI, 但不执行任何处置。
+// 用途是加词之前试一试会不会误伤, 或者用户申诉时复盘当时为什么被删。
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"SunaiForum-Bot/core"
+	"SunaiForum-Bot/service/ai_review"
+	"SunaiForum-Bot/service/moderation"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// checkNamePrefixes 指定昵称的行前缀, 全角半角冒号都认
+var checkNamePrefixes = []string{"昵称:", "昵称：", "name:"}
+
+// checkInput 解析后的预演参数
+type checkInput struct {
+	scope       core.Scope
+	displayName string
+	text        string
+}
+
+// parseCheckInput 拆出可选的作用域、昵称行和正文:
+//
+//	@-1001234567890/15
+//	昵称: 水果机批发
+//	正文……
+func parseCheckInput(args string) (checkInput, error) {
+	scope, _, rest, err := splitScope(args)
+	if err != nil {
+		return checkInput{}, err
+	}
+
+	input := checkInput{scope: scope}
+	rest = strings.TrimSpace(rest)
+	first, remaining, _ := strings.Cut(rest, "\n")
+	for _, prefix := range checkNamePrefixes {
+		if name, ok := strings.CutPrefix(strings.TrimSpace(first), prefix); ok {
+			input.displayName = strings.TrimSpace(name)
+			rest = remaining
+			break
+		}
+	}
+	input.text = strings.TrimSpace(rest)
+
+	if input.text == "" && input.displayName == "" {
+		return checkInput{}, fmt.Errorf("没有要检查的内容")
+	}
+	return input, nil
+}
+
+// checkText 预演一段文本的审核结果
+func checkText(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	runCheck(bot, message, args, false)
+}
+
+// checkTextWithAI 预演并额外做一次真实的 AI 判定, 同样不执行处置
+func checkTextWithAI(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	runCheck(bot, message, args, true)
+}
+
+func runCheck(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string, withAI bool) {
+	input, err := parseCheckInput(args)
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, err.Error())
+		return
+	}
+
+	report := describeCheck(input)
+	if withAI {
+		report += "\n\n" + describeAIVerdict(input)
+	}
+	core.SendMessage(bot, message.Chat.ID, report)
+}
+
+// describeCheck 生成预演报告; 只读, 不计刷屏、不记命中、不动任何状态
+func describeCheck(input checkInput) string {
+	var b strings.Builder
+	b.WriteString("🔍 审核预演（不会执行任何处置）\n\n")
+
+	fmt.Fprintf(&b, "词表范围：%s\n", checkScopeLabel(input.scope))
+	if input.displayName != "" {
+		fmt.Fprintf(&b, "昵称：%s\n", input.displayName)
+	}
+	fmt.Fprintf(&b, "归一化正文：%s\n", orNone(moderation.Normalize(input.text)))
+	if input.displayName != "" {
+		fmt.Fprintf(&b, "归一化昵称：%s\n", orNone(moderation.Normalize(input.displayName)))
+	}
+
+	verdicts := moderation.InspectAll(input.scope.ChatID, input.scope.TopicID, input.text, input.displayName, 0)
+	b.WriteString("\n规则判定：")
+	switch {
+	case len(verdicts) == 0:
+		b.WriteString("无规则命中，放行\n")
+	case slices.ContainsFunc(verdicts, func(v moderation.Verdict) bool { return !v.Shadow }):
+		b.WriteString("会被拦截\n")
+	default:
+		b.WriteString("只命中试行规则，放行并记录\n")
+	}
+	for _, verdict := range verdicts {
+		fmt.Fprintf(&b, "• %s", verdict.Rule)
+		if verdict.Detail != "" {
+			fmt.Fprintf(&b, "：%s", verdict.Detail)
+		}
+		if verdict.Shadow {
+			b.WriteString("（试行）")
+		}
+		b.WriteString("\n")
+	}
+
+	// 规则层命中的消息在真实流程里走不到 AI, 但这里照样给出, 方便判断放宽规则后会怎样
+	fmt.Fprintf(&b, "\n弱信号：正文%s，昵称%s\n",
+		yesNo(moderation.HasWeakSignal(input.text)), yesNo(moderation.HasWeakSignal(input.displayName)))
+	fmt.Fprintf(&b, "送 AI 复核：新用户（前 %d 条）%s，老用户%s",
+		core.AINewUserMessages,
+		reviewLabel(ai_review.WouldReview(input.text, input.displayName, 1)),
+		reviewLabel(ai_review.WouldReview(input.text, input.displayName, core.AINewUserMessages+1)))
+	if !core.AIEnabled {
+		b.WriteString("\n（AI 审核未启用）")
+	} else if group, ok := core.GroupSettings(input.scope.ChatID); ok && !group.AIEnabled {
+		b.WriteString("\n（该群已关闭 AI 审核）")
+	}
+	return b.String()
+}
+
+// describeAIVerdict 做一次真实的 AI 判定并描述结果
+func describeAIVerdict(input checkInput) string {
+	if !core.AIEnabled {
+		return "AI 判定：AI 审核未启用，跳过。"
+	}
+
+	result, err := ai_review.DryRun(input.text, input.displayName)
+	if err != nil {
+		log.Printf("[Command] AI 预演失败: %v", err)
+		return fmt.Sprintf("AI 判定：调用失败（%v）", err)
+	}
+
+	var b strings.Builder
+	verdict := "正常"
+	if result.IsSpam {
+		verdict = "广告"
+	}
+	fmt.Fprintf(&b, "AI 判定：%s，置信度 %.2f（拦截阈值 %.2f）\n", verdict, result.Confidence, core.AIMinConfidence)
+	fmt.Fprintf(&b, "理由：%s\n", orNone(result.Reason))
+	if result.WouldEnforce && moderation.RuleMode(moderation.RuleIDAI) == core.ModeShadow {
+		b.WriteString("AI 规则试行中，真实消息会被放行并记录")
+	} else if result.WouldEnforce {
+		b.WriteString("真实消息会被拦截")
+		if len(result.WouldLearn) > 0 {
+			fmt.Fprintf(&b, "，并学习关键词：%s", strings.Join(result.WouldLearn, "、"))
+		}
+	} else {
+		b.WriteString("真实消息会被放行")
+	}
+	return b.String()
+}
+
+// checkScopeLabel 预演使用的词表范围; 不指定群时只用全局词表
+func checkScopeLabel(scope core.Scope) string {
+	if scope.IsGlobal() {
+		return "仅全局词表（可在第一行用 @群ID 或 @群ID/话题ID 模拟某个群）"
+	}
+	return scopeLabel(scope) + "实际生效的全部词表"
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "有"
+	}
+	return "无"
+}
+
+func reviewLabel(b bool) string {
+	if b {
+		return "会送审"
+	}
+	return "不送审"
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "（空）"
+	}
+	return s
+}
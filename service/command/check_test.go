@@ -0,0 +1,23 @@
+package command
+
+import "testing"
+
+func TestParseCheckInput(t *testing.T) {
+	input, err := parseCheckInput("昵称: 水果机批发\n你好\n请问群规在哪")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if !input.scope.IsGlobal() || input.displayName != "水果机批发" || input.text != "你好\n请问群规在哪" {
+		t.Errorf("解析结果 = %+v", input)
+	}
+
+	// 不写昵称行时整段都是正文, 正文里出现的冒号不能被误认作昵称
+	input, _ = parseCheckInput("价格：5000 元\n昵称: 不是第一行")
+	if input.displayName != "" || input.text != "价格：5000 元\n昵称: 不是第一行" {
+		t.Errorf("解析结果 = %+v", input)
+	}
+
+	if _, err := parseCheckInput("  \n "); err == nil {
+		t.Error("空内容应当报错")
+	}
+}
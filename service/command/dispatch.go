@@ -20,50 +20,176 @@ type spec struct {
 	order     int    // 菜单里的排列顺序
 	needsArgs bool   // 是否需要参数; 为 true 且用户没带参数时会追问
 	askFor    string // 追问的提示语
-	handle    func(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string)
+	// perm 执行所需的权限; 零值 PermAdmin 表示在册管理员都能用
+	perm   core.Permission
+	handle func(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string)
 }
 
 // specs 全部管理员命令。新增命令只需要在这里加一行。
 var specs = map[string]spec{
 	"add": {
 		desc: "添加过滤关键词", order: 1, needsArgs: true,
-		askFor: "请发送要添加的关键词。\n可以一次发多个，每行一个。\n\n发送 /cancel 取消。",
+		askFor: "请发送要添加的关键词。\n可以一次发多个，每行一个。\n" + scopeHelp + "\n\n发送 /cancel 取消。",
+		perm:   core.PermKeywords,
 		handle: addKeywords,
 	},
 	"delete": {
 		desc: "删除过滤关键词", order: 2, needsArgs: true,
-		askFor: "请发送要删除的关键词。\n可以一次发多个，每行一个。\n\n发送 /cancel 取消。",
+		askFor: "请发送要删除的关键词。\n可以一次发多个，每行一个。\n" + scopeHelp + "\n\n发送 /cancel 取消。",
+		perm:   core.PermKeywords,
 		handle: deleteKeywords,
 	},
 	"deletecontaining": {
 		desc: "删除所有包含指定词语的关键词", order: 3, needsArgs: true,
 		askFor: "请发送要匹配的词语，所有包含它的关键词都会被删除。\n\n发送 /cancel 取消。",
+		perm:   core.PermKeywords,
 		handle: deleteKeywordsContaining,
 	},
+	"addrule": {
+		desc: "添加正则或组合规则", order: 4, needsArgs: true,
+		askFor: "请发送要添加的规则，每行一条，格式为「类型 表达式」，例如：\n" +
+			"regex \\d{7,}微信\ncombo 水果 NEAR/10 特价\ncombo 出售 AND 私聊\n\n" +
+			"regex 是 RE2 正则；combo 的 AND 要求几个词同时出现，NEAR/距离 要求两个词相距不超过该字数。\n" +
+			scopeHelp + "\n\n发送 /cancel 取消。",
+		perm:   core.PermKeywords,
+		handle: addRules,
+	},
 	"list": {
-		desc: "列出所有关键词", order: 4,
-		handle: func(bot *tgbotapi.BotAPI, message *tgbotapi.Message, _ string) { listKeywords(bot, message) },
+		desc: "列出所有关键词与规则", order: 5,
+		handle: listKeywords,
+	},
+	"mode": {
+		desc: "设置规则的执行模式（执行/试行/停用）", order: 6,
+		perm:   core.PermKeywords,
+		handle: setMode,
+	},
+	"weight": {
+		desc: "设置规则的违规权重", order: 7,
+		perm:   core.PermManageGroups,
+		handle: setWeight,
+	},
+	"channel": {
+		desc: "管理来源频道黑白名单", order: 8,
+		perm:   core.PermModerate,
+		handle: manageChannels,
+	},
+	"domain": {
+		desc: "管理链接域名黑白名单", order: 9,
+		perm:   core.PermModerate,
+		handle: manageDomains,
+	},
+	"check": {
+		desc: "预演一段文本会不会被拦截", order: 10, needsArgs: true,
+		askFor: checkHelp,
+		handle: checkText,
+	},
+	"checkai": {
+		desc: "预演并让 AI 实际判一次", order: 11, needsArgs: true,
+		askFor: checkHelp,
+		handle: checkTextWithAI,
 	},
 	"setprompt": {
-		desc: "设置自动回复", order: 5, needsArgs: true,
+		desc: "设置自动回复", order: 12, needsArgs: true,
 		askFor: "请发送触发词和回复内容。\n第一行是触发词，之后所有行是回复内容。\n\n发送 /cancel 取消。",
+		perm:   core.PermKeywords,
 		handle: setPrompt,
 	},
 	"delprompt": {
-		desc: "删除自动回复", order: 6, needsArgs: true,
+		desc: "删除自动回复", order: 13, needsArgs: true,
 		askFor: "请发送要删除的触发词。\n\n发送 /cancel 取消。",
+		perm:   core.PermKeywords,
 		handle: deletePrompt,
 	},
 	"listprompt": {
-		desc: "列出所有自动回复", order: 7,
+		desc: "列出所有自动回复", order: 14,
 		handle: func(bot *tgbotapi.BotAPI, message *tgbotapi.Message, _ string) { listPrompts(bot, message) },
 	},
+	"grant": {
+		desc: "任命管理员或修改角色", order: 15, needsArgs: true,
+		askFor: "请发送用户 ID 和角色，用空格隔开，例如：\n123456789 moderator\n\n" +
+			"可选角色：owner（所有者）、moderator（版主）、keyword_editor（词表编辑）\n\n发送 /cancel 取消。",
+		perm:   core.PermManageAdmins,
+		handle: grantAdmin,
+	},
+	"revoke": {
+		desc: "撤销管理员", order: 16, needsArgs: true,
+		askFor: "请发送要撤销的管理员用户 ID。\n\n发送 /cancel 取消。",
+		perm:   core.PermManageAdmins,
+		handle: revokeAdmin,
+	},
+	"admins": {
+		desc: "列出所有管理员", order: 17,
+		handle: func(bot *tgbotapi.BotAPI, message *tgbotapi.Message, _ string) { listAdmins(bot, message) },
+	},
+	"groups": {
+		desc: "列出受管群及其设置", order: 18,
+		handle: func(bot *tgbotapi.BotAPI, message *tgbotapi.Message, _ string) { listGroups(bot, message) },
+	},
+	"addgroup": {
+		desc: "登记受管群", order: 19, needsArgs: true,
+		askFor: "请发送要登记的群 ID（形如 -100xxxx）。\n未登记的群机器人一律不处理。\n\n发送 /cancel 取消。",
+		perm:   core.PermManageGroups,
+		handle: addGroup,
+	},
+	"removegroup": {
+		desc: "取消登记受管群", order: 20, needsArgs: true,
+		askFor: "请发送要取消登记的群 ID。\n\n发送 /cancel 取消。",
+		perm:   core.PermManageGroups,
+		handle: removeGroup,
+	},
+	"groupset": {
+		desc: "修改某个群的设置", order: 21, needsArgs: true,
+		askFor: "请发送：群ID 设置项 值，例如：\n-1001234567890 ban 5\n\n" + groupSettingHelp() + "\n\n发送 /cancel 取消。",
+		perm:   core.PermManageGroups,
+		handle: setGroupOption,
+	},
+	"banlist": {
+		desc: "查看或搜索跨群封禁名单", order: 22,
+		perm:   core.PermModerate,
+		handle: listBannedUsers,
+	},
+	"banimport": {
+		desc: "批量导入封禁名单", order: 23, needsArgs: true,
+		askFor: "请发送要导入的账号，每行一个，格式为「用户ID 原因」，原因可省略。\n" +
+			"只写入名单，账号进入任一成员群时会被踢出。\n\n发送 /cancel 取消。",
+		perm:   core.PermModerate,
+		handle: importBannedUsers,
+	},
+	"banremove": {
+		desc: "把账号移出封禁名单", order: 24, needsArgs: true,
+		askFor: "请发送要移出名单的用户 ID，可以一次多个。\n移出后会在全部成员群解封。\n\n发送 /cancel 取消。",
+		perm:   core.PermModerate,
+		handle: removeBannedUsers,
+	},
+	"release": {
+		desc: "提前解除新成员限制", order: 25, needsArgs: true,
+		askFor: "请发送要解除新成员限制的用户 ID，可以一次多个。\n解除后在全部受管群都可以正常发链接和媒体，也不再只限白名单域名。\n\n发送 /cancel 取消。",
+		perm:   core.PermModerate,
+		handle: releaseProbation,
+	},
+	"trust": {
+		desc: "把用户指定为可信", order: 26, needsArgs: true,
+		askFor: "请发送：用户ID [群ID]\n可信用户跳过 AI 审核与昵称检查。不写群 ID 即对全部受管群生效；在用户 ID 前加 reset 清除指定、恢复按统计计算。\n\n发送 /cancel 取消。",
+		perm:   core.PermModerate,
+		handle: trustUser,
+	},
+	"distrust": {
+		desc: "把用户指定为低信任", order: 27, needsArgs: true,
+		askFor: "请发送：用户ID [群ID]\n低信任用户在开启 strictlinks 的群里只能发白名单链接。不写群 ID 即对全部受管群生效；在用户 ID 前加 reset 清除指定。\n\n发送 /cancel 取消。",
+		perm:   core.PermModerate,
+		handle: distrustUser,
+	},
 	"cancel": {
-		desc: "取消当前正在输入的命令", order: 8,
+		desc: "取消当前正在输入的命令", order: 28,
 		handle: cancelPending,
 	},
 }
 
+// checkHelp /check 与 /checkai 共用的追问提示
+const checkHelp = "请粘贴要检查的文本。\n" +
+	"第一行可以用 @群ID 或 @群ID/话题ID 模拟某个群的词表；接下来一行可以写「昵称: xxx」一并检查昵称。\n" +
+	"只做预演，不会删消息、记分或学习关键词。\n\n发送 /cancel 取消。"
+
 // MenuCommands 按定义表生成 Telegram 命令菜单
 func MenuCommands() []tgbotapi.BotCommand {
 	names := make([]string, 0, len(specs))
@@ -109,6 +235,10 @@ func HandleAdmin(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
 		core.SendErrorMessage(bot, message.Chat.ID, "未知命令，点击菜单看看有哪些可用。")
 		return
 	}
+	// 先查权限再追问, 否则没权限的人要白输一遍参数才被拒
+	if !permitted(bot, message, cmd) {
+		return
+	}
 
 	args := strings.TrimSpace(message.CommandArguments())
 	if cmd.needsArgs && args == "" {
@@ -128,6 +258,10 @@ func runCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message, name, args stri
 		return
 	}
 
+	if !permitted(bot, message, cmd) {
+		return
+	}
+
 	args = strings.TrimSpace(args)
 	if cmd.needsArgs && args == "" {
 		core.SendErrorMessage(bot, message.Chat.ID, "内容不能为空，请重新发送命令。")
@@ -136,6 +270,16 @@ func runCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message, name, args stri
 	cmd.handle(bot, message, args)
 }
 
+// permitted 检查发送者的角色能否执行该命令, 不能时直接回复原因
+func permitted(bot *tgbotapi.BotAPI, message *tgbotapi.Message, cmd spec) bool {
+	if core.Can(message.From.ID, cmd.perm) {
+		return true
+	}
+	core.SendErrorMessage(bot, message.Chat.ID,
+		fmt.Sprintf("你的角色（%s）不能使用这个命令。", core.RoleLabel(core.RoleOf(message.From.ID))))
+	return false
+}
+
 // ask 发出追问, 并让客户端自动聚焦输入框
 func ask(bot *tgbotapi.BotAPI, chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
@@ -0,0 +1,127 @@
+package command
+
+// /domain: 维护链接域名的黑白名单。
+// 黑名单上的域名, 出现在正文、超链接或按钮里即按违规处置; 开启 strictlinks 的群里, 新成员只能发白名单上的域名。
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// domainUsage /domain 的用法说明
+const domainUsage = "用法：\n" +
+	"/domain block 域名 … 加入黑名单\n" +
+	"/domain allow 域名 … 加入白名单\n" +
+	"/domain remove 域名 … 移出名单\n" +
+	"登记 example.com 时其下全部子域名一并生效；可以直接粘贴链接，一次可写多个。"
+
+// domainLists 名单种类的中文展示
+var domainLists = map[string]string{
+	core.DomainBlock: "黑名单",
+	core.DomainAllow: "白名单",
+}
+
+// manageDomains 不带参数时列出名单; 带参数时按子命令修改
+func manageDomains(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		core.SendMessage(bot, message.Chat.ID, describeDomains()+"\n\n"+domainUsage)
+		return
+	}
+
+	action := strings.ToLower(fields[0])
+	domains, invalid := parseDomains(fields[1:])
+	if len(domains) == 0 && len(invalid) == 0 {
+		core.SendErrorMessage(bot, message.Chat.ID, "需要至少一个域名。\n"+domainUsage)
+		return
+	}
+
+	var done []string
+	switch action {
+	case core.DomainBlock, core.DomainAllow:
+		for _, domain := range domains {
+			err := core.DB.SetLinkDomain(core.LinkDomain{Domain: domain, List: action, AddedBy: message.From.ID})
+			if err != nil {
+				log.Printf("[Command] 登记域名 %s 失败: %v", domain, err)
+				invalid = append(invalid, domain)
+				continue
+			}
+			done = append(done, domain)
+		}
+	case "remove":
+		for _, domain := range domains {
+			removed, err := core.DB.RemoveLinkDomain(domain)
+			if err != nil {
+				log.Printf("[Command] 移除域名 %s 失败: %v", domain, err)
+			}
+			if err != nil || !removed {
+				invalid = append(invalid, domain)
+				continue
+			}
+			done = append(done, domain)
+		}
+	default:
+		core.SendErrorMessage(bot, message.Chat.ID, fmt.Sprintf("未知的操作 %q。\n%s", fields[0], domainUsage))
+		return
+	}
+
+	log.Printf("[Command] 管理员 %d 域名名单 %s: %v", message.From.ID, action, done)
+	var b strings.Builder
+	if len(done) > 0 {
+		if label, ok := domainLists[action]; ok {
+			fmt.Fprintf(&b, "已加入%s：%s", label, strings.Join(done, "、"))
+		} else {
+			fmt.Fprintf(&b, "已移出名单：%s", strings.Join(done, "、"))
+		}
+	}
+	if len(invalid) > 0 {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "无法识别或不在名单上：%s", strings.Join(invalid, "、"))
+	}
+	core.SendMessage(bot, message.Chat.ID, b.String())
+}
+
+// parseDomains 规整一组域名写法, 无法识别的原样放进第二个返回值
+func parseDomains(fields []string) (domains, invalid []string) {
+	for _, field := range fields {
+		domain, err := core.ParseDomain(field)
+		if err != nil {
+			invalid = append(invalid, field)
+			continue
+		}
+		domains = append(domains, domain)
+	}
+	return domains, invalid
+}
+
+// describeDomains 列出黑白名单
+func describeDomains() string {
+	entries, err := core.DB.ListLinkDomains()
+	if err != nil {
+		log.Printf("[Command] 读取域名名单失败: %v", err)
+		return "读取名单失败。"
+	}
+	if len(entries) == 0 {
+		return "域名名单为空。"
+	}
+
+	var b strings.Builder
+	current := ""
+	for _, entry := range entries {
+		if entry.List != current {
+			if current != "" {
+				b.WriteString("\n")
+			}
+			current = entry.List
+			fmt.Fprintf(&b, "%s：\n", domainLists[entry.List])
+		}
+		fmt.Fprintf(&b, "- %s\n", entry.Domain)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
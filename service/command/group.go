@@ -0,0 +1,445 @@
+package command
+
+// 受管群的登记与设置命令, 只有所有者可用。
+// 群用数字 ID 指定: 私聊里无法"选中"一个群, 而机器人被拉进未登记的群时会把 ID 推给管理员, 照抄即可。
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// groupSetting 一项可通过 /groupset 修改的群设置
+type groupSetting struct {
+	desc  string
+	apply func(group *core.ManagedGroup, value string) error
+}
+
+// groupSettings 全部可修改的群设置, 键名即 /groupset 的第二个参数
+var groupSettings = map[string]groupSetting{
+	"symbols": {
+		desc: "行情推送的交易对，逗号分隔，如 DOGS/USDT,TON/USDT；填 - 关闭推送",
+		apply: func(group *core.ManagedGroup, value string) error {
+			if value == "-" {
+				value = ""
+			}
+			group.Symbols = core.NormalizeSymbols(value)
+			return nil
+		},
+	},
+	"ban": {
+		desc: "累计违规几次自动封禁，0 表示只删消息不封禁；设置了 ladder 时不生效",
+		apply: func(group *core.ManagedGroup, value string) error {
+			threshold, err := strconv.Atoi(value)
+			if err != nil || threshold < 0 {
+				return fmt.Errorf("需要一个不小于 0 的整数")
+			}
+			group.AutoBanThreshold = threshold
+			return nil
+		},
+	},
+	"ai": {
+		desc: "是否启用 AI 审核，on / off",
+		apply: func(group *core.ManagedGroup, value string) error {
+			enabled, err := parseSwitch(value)
+			group.AIEnabled = enabled
+			return err
+		},
+	},
+	"cleanup": {
+		desc: "是否清理加入/退出等群务通知，on / off",
+		apply: func(group *core.ManagedGroup, value string) error {
+			enabled, err := parseSwitch(value)
+			group.DeleteServiceMessages = enabled
+			return err
+		},
+	},
+	"ladder": {
+		desc: "处罚阶梯，按累计违规次数逐级执行，如 warn,mute:1h,mute:24h,ban；可选 delete、warn、mute:时长（30m/1h/7d）、ban，超出阶梯的按最后一级；填 - 恢复按 ban 阈值处理",
+		apply: func(group *core.ManagedGroup, value string) error {
+			if strings.TrimSpace(value) == "-" {
+				group.PenaltyLadder = ""
+				return nil
+			}
+			ladder, err := core.ParseLadder(value)
+			if err != nil {
+				return err
+			}
+			group.PenaltyLadder = core.FormatLadder(ladder)
+			return nil
+		},
+	},
+	"federation": {
+		desc: "是否加入跨群封禁名单，on / off；加入后本群的封禁同步到其他成员群，名单上的账号进群即被踢出",
+		apply: func(group *core.ManagedGroup, value string) error {
+			enabled, err := parseSwitch(value)
+			group.Federation = enabled
+			return err
+		},
+	},
+	"captcha": {
+		desc: "入群验证题型，math（算术题）/ emoji（点选表情）/ off；新成员进群先禁言，答对才解除，答错或超时移出",
+		apply: func(group *core.ManagedGroup, value string) error {
+			value = strings.ToLower(value)
+			if value == "off" {
+				group.Captcha = ""
+				return nil
+			}
+			if !core.ValidCaptcha(value) {
+				return fmt.Errorf("只能是 math、emoji 或 off")
+			}
+			group.Captcha = value
+			return nil
+		},
+	},
+	"captchatime": {
+		desc: fmt.Sprintf("入群验证时限（秒），%d 到 %d；填 0 恢复默认 2 分钟", core.MinCaptchaTimeout, core.MaxCaptchaTimeout),
+		apply: func(group *core.ManagedGroup, value string) error {
+			seconds, err := strconv.Atoi(value)
+			if err != nil || (seconds != 0 && (seconds < core.MinCaptchaTimeout || seconds > core.MaxCaptchaTimeout)) {
+				return fmt.Errorf("需要 %d 到 %d 之间的秒数，或 0", core.MinCaptchaTimeout, core.MaxCaptchaTimeout)
+			}
+			group.CaptchaTimeout = seconds
+			return nil
+		},
+	},
+	"probation": {
+		desc: fmt.Sprintf("新成员限制时长（小时），入群后这段时间内不能发 probationblock 里的内容；0 关闭，最多 %d", core.MaxProbationHours),
+		apply: func(group *core.ManagedGroup, value string) error {
+			hours, err := strconv.Atoi(value)
+			if err != nil || hours < 0 || hours > core.MaxProbationHours {
+				return fmt.Errorf("需要 0 到 %d 之间的小时数", core.MaxProbationHours)
+			}
+			group.ProbationHours = hours
+			return nil
+		},
+	},
+	"probationblock": {
+		desc: "新成员限制期内禁发的内容，逗号分隔，可选 " + strings.Join(core.ProbationTypes(), "、") + "；填 - 恢复全部禁发",
+		apply: func(group *core.ManagedGroup, value string) error {
+			if strings.TrimSpace(value) == "-" {
+				group.ProbationBlock = ""
+				return nil
+			}
+			block, err := core.ParseProbationBlock(value)
+			if err != nil {
+				return err
+			}
+			group.ProbationBlock = block
+			return nil
+		},
+	},
+	"strictlinks": {
+		desc: fmt.Sprintf("新成员只能发白名单域名链接的时长（小时），见 /domain；0 关闭，最多 %d", core.MaxProbationHours),
+		apply: func(group *core.ManagedGroup, value string) error {
+			hours, err := strconv.Atoi(value)
+			if err != nil || hours < 0 || hours > core.MaxProbationHours {
+				return fmt.Errorf("需要 0 到 %d 之间的小时数", core.MaxProbationHours)
+			}
+			group.StrictLinkHours = hours
+			return nil
+		},
+	},
+	"flood": {
+		desc: fmt.Sprintf("单人发言频率上限，条数/秒数，如 8/10 表示 10 秒内超过 8 条即临时禁言；填 off 关闭，秒数最多 %d", core.MaxFloodSeconds),
+		apply: func(group *core.ManagedGroup, value string) error {
+			if isOff(value) {
+				group.FloodMessages, group.FloodSeconds = 0, 0
+				return nil
+			}
+			count, seconds, err := core.ParseRate(value, core.MinFloodMessages, core.MaxFloodMessages, core.MinFloodSeconds, core.MaxFloodSeconds)
+			if err != nil {
+				return err
+			}
+			group.FloodMessages, group.FloodSeconds = count, seconds
+			return nil
+		},
+	},
+	"raid": {
+		desc: fmt.Sprintf("突袭检测，人数/秒数，如 10/60 表示 60 秒内有 10 个新账号入群或发言即开启突袭模式；填 off 关闭，人数至少 %d", core.MinRaidMembers),
+		apply: func(group *core.ManagedGroup, value string) error {
+			if isOff(value) {
+				group.RaidMembers, group.RaidSeconds = 0, 0
+				return nil
+			}
+			count, seconds, err := core.ParseRate(value, core.MinRaidMembers, core.MaxRaidMembers, core.MinRaidSeconds, core.MaxRaidSeconds)
+			if err != nil {
+				return err
+			}
+			group.RaidMembers, group.RaidSeconds = count, seconds
+			return nil
+		},
+	},
+	"duplicate": {
+		desc: fmt.Sprintf("多账号重复内容，账号数/秒数，如 3/600 表示 600 秒内有 3 个账号发出近似内容即成批撤回；填 - 恢复默认 3/600，账号数至少 %d", core.MinWaveAccounts),
+		apply: func(group *core.ManagedGroup, value string) error {
+			if strings.TrimSpace(value) == "-" {
+				group.WaveAccounts, group.WaveSeconds = 0, 0
+				return nil
+			}
+			count, seconds, err := core.ParseRate(value, core.MinWaveAccounts, core.MaxWaveAccounts, core.MinWaveSeconds, core.MaxWaveSeconds)
+			if err != nil {
+				return err
+			}
+			group.WaveAccounts, group.WaveSeconds = count, seconds
+			return nil
+		},
+	},
+	"keywords": {
+		desc: "关键词作用域，global（全局词表 + 本群专属词）/ local（只用本群专属词）",
+		apply: func(group *core.ManagedGroup, value string) error {
+			value = strings.ToLower(value)
+			if !core.ValidKeywordScope(value) {
+				return fmt.Errorf("只能是 global 或 local")
+			}
+			group.KeywordScope = value
+			return nil
+		},
+	},
+}
+
+// addGroup 登记受管群, 设置取环境变量里的默认值; 已登记的群保持原设置
+func addGroup(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	chatID, err := parseChatID(args)
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, err.Error())
+		return
+	}
+
+	// 取群名只为展示; 机器人还没进群时取不到, 不影响登记
+	title := ""
+	if chat, err := bot.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: chatID}}); err == nil {
+		title = chat.Title
+	}
+
+	added, err := core.DB.EnsureGroup(core.DefaultGroup(chatID, title))
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, "登记失败。")
+		log.Printf("[Command] 登记受管群 %d 失败: %v", chatID, err)
+		return
+	}
+	if !added {
+		core.SendMessage(bot, message.Chat.ID, fmt.Sprintf("群 %d 已经登记过了，设置保持不变。", chatID))
+		return
+	}
+
+	log.Printf("[Command] 管理员 %d 登记了受管群 %d (%s)", message.From.ID, chatID, title)
+	group, _ := core.GroupSettings(chatID)
+	core.SendMessage(bot, message.Chat.ID, "已登记，当前设置：\n\n"+describeGroup(group)+
+		"\n\n用 /groupset 修改设置。记得把机器人设为该群管理员，否则无法删消息和封禁。")
+}
+
+// removeGroup 取消登记, 之后机器人不再处理该群的任何消息
+func removeGroup(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	chatID, err := parseChatID(args)
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, err.Error())
+		return
+	}
+
+	removed, err := core.DB.RemoveGroup(chatID)
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, "取消登记失败。")
+		log.Printf("[Command] 取消登记受管群 %d 失败: %v", chatID, err)
+		return
+	}
+	if !removed {
+		core.SendMessage(bot, message.Chat.ID, fmt.Sprintf("群 %d 本来就没有登记。", chatID))
+		return
+	}
+
+	log.Printf("[Command] 管理员 %d 取消登记了受管群 %d", message.From.ID, chatID)
+	core.SendMessage(bot, message.Chat.ID, fmt.Sprintf("已取消登记群 %d，机器人不会再处理该群的消息。", chatID))
+}
+
+// setGroupOption 修改一项群设置: "<群 ID> <设置项> <值>"
+func setGroupOption(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	fields := strings.Fields(args)
+	if len(fields) < 3 {
+		core.SendErrorMessage(bot, message.Chat.ID, "格式：/groupset 群ID 设置项 值\n\n"+groupSettingHelp())
+		return
+	}
+
+	chatID, err := parseChatID(fields[0])
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, err.Error())
+		return
+	}
+	setting, ok := groupSettings[strings.ToLower(fields[1])]
+	if !ok {
+		core.SendErrorMessage(bot, message.Chat.ID, fmt.Sprintf("没有设置项 %s。\n\n%s", fields[1], groupSettingHelp()))
+		return
+	}
+
+	group, ok := core.GroupSettings(chatID)
+	if !ok {
+		core.SendErrorMessage(bot, message.Chat.ID, fmt.Sprintf("群 %d 没有登记，先用 /addgroup 登记。", chatID))
+		return
+	}
+
+	// 交易对之间允许带空格, 值取设置项之后的全部内容
+	value := strings.Join(fields[2:], " ")
+	if err := setting.apply(&group, value); err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, fmt.Sprintf("%s 的值无效：%v", fields[1], err))
+		return
+	}
+	if err := core.DB.SaveGroup(group); err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, "保存失败。")
+		log.Printf("[Command] 保存群 %d 的设置失败: %v", chatID, err)
+		return
+	}
+
+	log.Printf("[Command] 管理员 %d 把群 %d 的 %s 改为 %q", message.From.ID, chatID, fields[1], value)
+	core.SendMessage(bot, message.Chat.ID, "已保存，当前设置：\n\n"+describeGroup(group))
+}
+
+// listGroups 列出全部受管群及其设置
+func listGroups(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+	groups, err := core.DB.GetGroups()
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, "读取受管群列表时发生错误。")
+		log.Printf("[Command] 读取受管群列表失败: %v", err)
+		return
+	}
+	if len(groups) == 0 {
+		core.SendMessage(bot, message.Chat.ID, "还没有登记任何群，用 /addgroup 群ID 登记。")
+		return
+	}
+
+	items := make([]string, 0, len(groups))
+	for _, group := range groups {
+		items = append(items, "\n"+describeGroup(group))
+	}
+	if err := core.SendLongMessage(bot, message.Chat.ID,
+		fmt.Sprintf("受管群（%d 个）：", len(groups)), items); err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, "发送列表时发生错误。")
+	}
+}
+
+// describeGroup 把一个群的设置渲染成多行文本
+func describeGroup(group core.ManagedGroup) string {
+	var b strings.Builder
+
+	title := group.Title
+	if title == "" {
+		title = "（未知群名）"
+	}
+	fmt.Fprintf(&b, "%s\nID: %d\n", title, group.ChatID)
+
+	symbols := group.Symbols
+	if symbols == "" {
+		symbols = "不推送"
+	}
+	fmt.Fprintf(&b, "行情推送: %s\n", symbols)
+	if group.PenaltyLadder != "" {
+		fmt.Fprintf(&b, "处罚阶梯: %s\n", describeLadder(group.Ladder()))
+	} else if group.AutoBanThreshold > 0 {
+		fmt.Fprintf(&b, "自动封禁: 累计 %d 次\n", group.AutoBanThreshold)
+	} else {
+		b.WriteString("自动封禁: 关闭\n")
+	}
+	fmt.Fprintf(&b, "AI 审核: %s\n", switchLabel(group.AIEnabled))
+	fmt.Fprintf(&b, "清理群务通知: %s\n", switchLabel(group.DeleteServiceMessages))
+	fmt.Fprintf(&b, "封禁名单: %s\n", switchLabel(group.Federation))
+	if group.Captcha != "" {
+		fmt.Fprintf(&b, "入群验证: %s，时限 %d 秒\n", group.Captcha, int(group.CaptchaWindow().Seconds()))
+	} else {
+		b.WriteString("入群验证: 关闭\n")
+	}
+	if group.ProbationHours > 0 {
+		labels := make([]string, 0, len(core.ProbationTypes()))
+		for _, kind := range group.ProbationBlocks() {
+			labels = append(labels, core.ProbationLabel(kind))
+		}
+		fmt.Fprintf(&b, "新成员限制: 入群 %d 小时内禁发%s\n", group.ProbationHours, strings.Join(labels, "、"))
+	} else {
+		b.WriteString("新成员限制: 关闭\n")
+	}
+	if group.StrictLinkHours > 0 {
+		fmt.Fprintf(&b, "链接白名单: 入群 %d 小时内只能发白名单域名\n", group.StrictLinkHours)
+	} else {
+		b.WriteString("链接白名单: 关闭\n")
+	}
+	if count, window := group.FloodLimit(); count > 0 {
+		fmt.Fprintf(&b, "发言频率: %d 秒内超过 %d 条临时禁言\n", int(window.Seconds()), count)
+	} else {
+		b.WriteString("发言频率: 不限制\n")
+	}
+	if count, window := group.RaidLimit(); count > 0 {
+		fmt.Fprintf(&b, "突袭检测: %d 秒内 %d 个新账号活跃时开启突袭模式\n", int(window.Seconds()), count)
+	} else {
+		b.WriteString("突袭检测: 关闭\n")
+	}
+	count, window := group.WaveLimit()
+	fmt.Fprintf(&b, "多账号重复内容: %d 秒内 %d 个账号发近似内容时成批撤回\n", int(window.Seconds()), count)
+	fmt.Fprintf(&b, "关键词作用域: %s", group.KeywordScope)
+	return b.String()
+}
+
+// describeLadder 阶梯的中文展示, 如 "第1次 警告 → 第2次 禁言 1 小时 → 第3次起 封禁"
+func describeLadder(ladder []core.Penalty) string {
+	parts := make([]string, 0, len(ladder))
+	for i, penalty := range ladder {
+		step := fmt.Sprintf("第%d次", i+1)
+		if i == len(ladder)-1 {
+			step += "起"
+		}
+		parts = append(parts, step+" "+penalty.Label())
+	}
+	return strings.Join(parts, " → ")
+}
+
+// groupSettingHelp 列出全部设置项的说明
+func groupSettingHelp() string {
+	var b strings.Builder
+	b.WriteString("可用设置项：")
+	for _, name := range []string{"symbols", "ban", "ladder", "ai", "cleanup", "federation", "captcha", "captchatime", "probation", "probationblock", "strictlinks", "flood", "raid", "duplicate", "keywords"} {
+		fmt.Fprintf(&b, "\n%s — %s", name, groupSettings[name].desc)
+	}
+	return b.String()
+}
+
+// parseChatID 解析群 ID; 群 ID 一定是负数, 顺手拦住把用户 ID 错填进来的情况
+func parseChatID(raw string) (int64, error) {
+	chatID, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("群 ID 不是数字：%s", raw)
+	}
+	if chatID >= 0 {
+		return 0, fmt.Errorf("群 ID 应当是负数（形如 -100xxxx），%d 看起来是用户 ID", chatID)
+	}
+	return chatID, nil
+}
+
+// parseSwitch 解析 on / off 一类的开关值
+func parseSwitch(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "on", "true", "1", "yes", "开":
+		return true, nil
+	case "off", "false", "0", "no", "关":
+		return false, nil
+	default:
+		return false, fmt.Errorf("只能是 on 或 off")
+	}
+}
+
+// isOff 判断是否为关闭某项设置的取值
+func isOff(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "off", "0", "-", "关":
+		return true
+	}
+	return false
+}
+
+// switchLabel 开关状态的中文展示
+func switchLabel(enabled bool) string {
+	if enabled {
+		return "开启"
+	}
+	return "关闭"
+}
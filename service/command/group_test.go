@@ -0,0 +1,82 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"SunaiForum-Bot/core"
+)
+
+func TestParseChatID(t *testing.T) {
+	if id, err := parseChatID(" -1001234567890 "); err != nil || id != -1001234567890 {
+		t.Errorf("parseChatID = %d,%v, 期望 -1001234567890", id, err)
+	}
+	// 用户 ID 是正数, 填错了要当场拦住, 不然会登记一个永远收不到消息的"群"
+	for _, raw := range []string{"5912366993", "abc", ""} {
+		if _, err := parseChatID(raw); err == nil {
+			t.Errorf("parseChatID(%q) 应当报错", raw)
+		}
+	}
+}
+
+func TestGroupSettingsApply(t *testing.T) {
+	group := core.ManagedGroup{AIEnabled: true, AutoBanThreshold: 3, KeywordScope: core.KeywordScopeGlobal}
+
+	steps := []struct {
+		key, value string
+		wantErr    bool
+	}{
+		{"ai", "off", false},
+		{"ban", "0", false},
+		{"ban", "-1", true},
+		{"symbols", "dogs/usdt, ton/usdt", false},
+		{"keywords", "LOCAL", false},
+		{"keywords", "everything", true},
+		{"cleanup", "maybe", true},
+		{"ladder", "warn, MUTE:60m ,ban", false},
+		{"ladder", "ban,warn", true},
+		{"captcha", "EMOJI", false},
+		{"captcha", "photo", true},
+		{"captchatime", "90", false},
+		{"captchatime", "5", true},
+		{"probation", "24", false},
+		{"probation", "99999", true},
+		{"probationblock", "media,LINK", false},
+		{"probationblock", "sticker", true},
+		{"strictlinks", "48", false},
+		{"strictlinks", "-1", true},
+		{"flood", "8/10", false},
+		{"flood", "1/10", true},
+		{"raid", "10/60s", false},
+		{"raid", "10/60", false},
+		{"raid", "2/60", true},
+		{"raid", "off", false},
+		{"duplicate", "5/300", false},
+		{"duplicate", "2/300", true},
+		{"duplicate", "5/30", true},
+	}
+	for _, step := range steps {
+		err := groupSettings[step.key].apply(&group, step.value)
+		if (err != nil) != step.wantErr {
+			t.Errorf("设置 %s=%q 的错误 = %v, 期望报错 %v", step.key, step.value, err, step.wantErr)
+		}
+	}
+
+	if group.AIEnabled || group.AutoBanThreshold != 0 || group.Symbols != "DOGSUSDT,TONUSDT" || group.KeywordScope != core.KeywordScopeLocal ||
+		group.PenaltyLadder != "warn,mute:1h,ban" || group.Captcha != core.CaptchaEmoji || group.CaptchaTimeout != 90 ||
+		group.ProbationHours != 24 || group.ProbationBlock != "link,media" ||
+		group.FloodMessages != 8 || group.FloodSeconds != 10 || group.RaidMembers != 0 ||
+		group.WaveAccounts != 5 || group.WaveSeconds != 300 {
+		t.Errorf("设置结果不符: %+v", group)
+	}
+}
+
+// TestGroupSettingHelpCoversAll 帮助文本漏掉的设置项, 管理员就不知道它存在
+func TestGroupSettingHelpCoversAll(t *testing.T) {
+	help := groupSettingHelp()
+	for name := range groupSettings {
+		if !strings.Contains(help, name+" — ") {
+			t.Errorf("帮助文本缺少设置项 %s", name)
+		}
+	}
+}
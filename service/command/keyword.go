@@ -7,23 +7,70 @@ import (
 	"log"
 	"sort"
 	"strings"
+	"unicode"
 
 	"SunaiForum-Bot/core"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// scopeHelp 作用域写法说明, 供追问提示与报错复用
+const scopeHelp = "第一行可以用 @ 指定作用域：@global（默认）、@群ID、@群ID/话题ID。"
+
+// splitScope 拆出参数开头可选的 "@作用域" 标记, 返回作用域、是否显式指定, 以及剩余参数。
+// 标记之后同一行还有内容的, 算作第一条关键词, 这样一行写法 "/add @-100123 水果机" 也能用。
+func splitScope(args string) (core.Scope, bool, string, error) {
+	trimmed := strings.TrimSpace(args)
+	if !strings.HasPrefix(trimmed, "@") {
+		return core.GlobalScope, false, args, nil
+	}
+
+	token, rest := trimmed, ""
+	if idx := strings.IndexFunc(trimmed, unicode.IsSpace); idx >= 0 {
+		token, rest = trimmed[:idx], trimmed[idx:]
+	}
+
+	scope, err := core.ParseScope(strings.TrimPrefix(token, "@"))
+	if err != nil {
+		return core.Scope{}, true, "", err
+	}
+	if !scope.IsGlobal() {
+		if _, ok := core.GroupSettings(scope.ChatID); !ok {
+			return core.Scope{}, true, "", fmt.Errorf("群 %d 没有登记，先用 /addgroup 登记", scope.ChatID)
+		}
+	}
+	return scope, true, rest, nil
+}
+
+// scopeLabel 作用域的中文展示
+func scopeLabel(scope core.Scope) string {
+	switch {
+	case scope.IsGlobal():
+		return "全局"
+	case scope.TopicID != 0:
+		return fmt.Sprintf("群 %d 话题 %d", scope.ChatID, scope.TopicID)
+	default:
+		return fmt.Sprintf("群 %d", scope.ChatID)
+	}
+}
+
 // addKeywords 批量添加关键词, 逐条校验, 汇总回报
 func addKeywords(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	scope, _, args, err := splitScope(args)
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, err.Error())
+		return
+	}
+
 	var report batchReport
 
 	for _, keyword := range splitLines(args) {
-		if err := core.ValidateKeyword(keyword); err != nil {
+		if err := core.ValidateKeyword(core.KindPlain, keyword); err != nil {
 			report.failed = append(report.failed, fmt.Sprintf("%s（%v）", keyword, err))
 			continue
 		}
 
-		added, err := core.DB.AddKeyword(keyword, core.SourceManual)
+		added, err := core.DB.AddScopedKeyword(keyword, core.SourceManual, scope)
 		if err != nil {
 			report.failed = append(report.failed, fmt.Sprintf("%s（写入失败）", keyword))
 			log.Printf("[Command] 添加关键词 %q 失败: %v", keyword, err)
@@ -31,22 +78,98 @@ func addKeywords(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
 		}
 		if added {
 			report.succeeded = append(report.succeeded, keyword)
+			continue
+		}
+
+		// 一个词只能属于一个作用域, 已在别处的要说清在哪, 否则管理员会以为加上了
+		if existing, ok, _ := core.DB.GetKeyword(keyword); ok && existing.Scope() != scope {
+			report.skipped = append(report.skipped, fmt.Sprintf("%s（已在%s）", keyword, scopeLabel(existing.Scope())))
 		} else {
 			report.skipped = append(report.skipped, keyword)
 		}
 	}
 
-	core.SendMessage(bot, message.Chat.ID, report.render("已添加", "已存在，跳过"))
+	core.SendMessage(bot, message.Chat.ID, fmt.Sprintf("作用域：%s\n\n%s", scopeLabel(scope), report.render("已添加", "已存在，跳过")))
+}
+
+// ruleKinds /addrule 每行开头的类型写法
+var ruleKinds = map[string]string{
+	"regex": core.KindRegex,
+	"combo": core.KindCombo,
+}
+
+// addRules 批量添加规则, 每行一条, 写法为 "类型 表达式", 例如:
+//
+//	regex \d{7,}微信
+//	combo 水果 NEAR/10 特价
+func addRules(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	scope, _, args, err := splitScope(args)
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, err.Error())
+		return
+	}
+
+	var report batchReport
+
+	for _, line := range splitLines(args) {
+		kind, expr, err := parseRuleLine(line)
+		if err == nil {
+			err = core.ValidateKeyword(kind, expr)
+		}
+		if err != nil {
+			report.failed = append(report.failed, fmt.Sprintf("%s（%v）", line, err))
+			continue
+		}
+
+		added, err := core.DB.AddRule(expr, kind, scope)
+		if err != nil {
+			report.failed = append(report.failed, fmt.Sprintf("%s（写入失败）", expr))
+			log.Printf("[Command] 添加规则 %q 失败: %v", expr, err)
+			continue
+		}
+		if added {
+			report.succeeded = append(report.succeeded, expr)
+		} else {
+			report.skipped = append(report.skipped, expr)
+		}
+	}
+
+	core.SendMessage(bot, message.Chat.ID, fmt.Sprintf("作用域：%s\n\n%s", scopeLabel(scope), report.render("已添加", "已存在，跳过")))
+}
+
+// parseRuleLine 拆出一行规则的类型与表达式
+func parseRuleLine(line string) (string, string, error) {
+	name, expr, _ := strings.Cut(strings.TrimSpace(line), " ")
+	kind, ok := ruleKinds[strings.ToLower(name)]
+	if !ok {
+		return "", "", fmt.Errorf("开头应为 regex 或 combo")
+	}
+	return kind, strings.TrimSpace(expr), nil
 }
 
-// deleteKeywords 批量删除关键词; 删掉的若是 AI 加的词, 顺带写入否决表永久拦住它
+// deleteKeywords 批量删除关键词; 删掉的若是 AI 加的词, 顺带写入否决表永久拦住它。
+// 显式指定了作用域时, 只删确实属于该作用域的词, 防止在一个群的清单里误删全局词。
 func deleteKeywords(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	scope, scoped, args, err := splitScope(args)
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, err.Error())
+		return
+	}
+
 	var (
 		report   batchReport
 		rejected []string
 	)
 
 	for _, keyword := range splitLines(args) {
+		if scoped {
+			existing, ok, err := core.DB.GetKeyword(keyword)
+			if err == nil && ok && existing.Scope() != scope {
+				report.failed = append(report.failed, fmt.Sprintf("%s（不在%s，而在%s）", keyword, scopeLabel(scope), scopeLabel(existing.Scope())))
+				continue
+			}
+		}
+
 		removed, source, err := core.DB.RemoveKeyword(keyword)
 		if err != nil {
 			report.failed = append(report.failed, fmt.Sprintf("%s（删除失败）", keyword))
@@ -103,7 +226,7 @@ func suggestSimilar(missing []string) string {
 
 // deleteKeywordsContaining 删除所有包含指定子串的关键词
 func deleteKeywordsContaining(bot *tgbotapi.BotAPI, message *tgbotapi.Message, substring string) {
-	if err := core.ValidateKeyword(substring); err != nil {
+	if err := core.ValidateKeyword(core.KindPlain, substring); err != nil {
 		core.SendErrorMessage(bot, message.Chat.ID, fmt.Sprintf("输入验证失败: %v", err))
 		return
 	}
@@ -123,8 +246,15 @@ func deleteKeywordsContaining(bot *tgbotapi.BotAPI, message *tgbotapi.Message, s
 }
 
 // listKeywords 分来源展示词表: 手工词按字母序, AI 词按命中次数降序,
-// 让管理员一眼看出哪些 AI 词在真正起作用、哪些是零命中该清理的
-func listKeywords(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+// 让管理员一眼看出哪些 AI 词在真正起作用、哪些是零命中该清理的。
+// 带 "@作用域" 参数时只列该作用域的词; 不带时列出全部, 非全局词后面注明作用域。
+func listKeywords(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	scope, scoped, _, err := splitScope(args)
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, err.Error())
+		return
+	}
+
 	manual, err := core.DB.GetKeywordsBySource(core.SourceManual)
 	if err != nil {
 		core.SendErrorMessage(bot, message.Chat.ID, "获取关键词列表时发生错误。")
@@ -138,7 +268,12 @@ func listKeywords(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
 		return
 	}
 
-	if len(manual) == 0 && len(aiKeywords) == 0 {
+	if scoped {
+		manual, aiKeywords = filterScope(manual, scope), filterScope(aiKeywords, scope)
+	}
+	manual, rules := splitRules(manual)
+
+	if len(manual) == 0 && len(rules) == 0 && len(aiKeywords) == 0 {
 		core.SendMessage(bot, message.Chat.ID, "关键词列表为空。")
 		return
 	}
@@ -146,7 +281,7 @@ func listKeywords(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
 	if len(manual) > 0 {
 		words := make([]string, 0, len(manual))
 		for _, k := range manual {
-			words = append(words, k.Word)
+			words = append(words, k.Word+modeSuffix(k)+scopeSuffix(k, scoped))
 		}
 		sort.Strings(words)
 
@@ -157,10 +292,22 @@ func listKeywords(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
 		}
 	}
 
+	if len(rules) > 0 {
+		lines := make([]string, 0, len(rules))
+		for _, k := range rules {
+			lines = append(lines, fmt.Sprintf("[%s] %s（命中 %d 次）%s%s", core.KindLabel(k.Kind), k.Word, k.HitCount, modeSuffix(k), scopeSuffix(k, scoped)))
+		}
+		if err := core.SendLongMessage(bot, message.Chat.ID,
+			fmt.Sprintf("规则（%d 条，用 /delete 加规则原文删除）：", len(lines)), lines); err != nil {
+			core.SendErrorMessage(bot, message.Chat.ID, "发送规则列表时发生错误。")
+			return
+		}
+	}
+
 	if len(aiKeywords) > 0 {
 		words := make([]string, 0, len(aiKeywords))
 		for _, k := range aiKeywords {
-			words = append(words, fmt.Sprintf("%s（命中 %d 次）", k.Word, k.HitCount))
+			words = append(words, fmt.Sprintf("%s（命中 %d 次）%s%s", k.Word, k.HitCount, modeSuffix(k), scopeSuffix(k, scoped)))
 		}
 		if err := core.SendLongMessage(bot, message.Chat.ID,
 			fmt.Sprintf("AI 关键词（%d 条，按命中次数排序，用 /delete 删除即永久否决）：", len(words)), words); err != nil {
@@ -168,3 +315,42 @@ func listKeywords(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
 		}
 	}
 }
+
+// splitRules 把手工词条拆成纯文本词与规则两组, 分开展示
+func splitRules(entries []core.Keyword) (plain, rules []core.Keyword) {
+	for _, k := range entries {
+		if k.Kind == core.KindPlain {
+			plain = append(plain, k)
+		} else {
+			rules = append(rules, k)
+		}
+	}
+	return plain, rules
+}
+
+// filterScope 只保留属于指定作用域的词
+func filterScope(keywords []core.Keyword, scope core.Scope) []core.Keyword {
+	kept := keywords[:0]
+	for _, k := range keywords {
+		if k.Scope() == scope {
+			kept = append(kept, k)
+		}
+	}
+	return kept
+}
+
+// scopeSuffix 非全局词在列表里注明作用域; 已按作用域筛选时不必重复
+func scopeSuffix(k core.Keyword, scoped bool) string {
+	if scoped || k.Scope().IsGlobal() {
+		return ""
+	}
+	return " [" + scopeLabel(k.Scope()) + "]"
+}
+
+// modeSuffix 非执行状态的词条在列表里注明试行或停用
+func modeSuffix(k core.Keyword) string {
+	if k.Mode == "" || k.Mode == core.ModeEnforce {
+		return ""
+	}
+	return "（" + core.ModeLabel(k.Mode) + "）"
+}
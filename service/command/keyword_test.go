@@ -0,0 +1,23 @@
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitScopeWithoutMarker(t *testing.T) {
+	// 不带 @ 时原样返回, 词表内容一个字都不能动
+	scope, scoped, rest, err := splitScope("水果机\n@不是作用域")
+	if err != nil || scoped || !scope.IsGlobal() || rest != "水果机\n@不是作用域" {
+		t.Errorf("splitScope = %v,%v,%q,%v", scope, scoped, rest, err)
+	}
+
+	scope, scoped, rest, err = splitScope("@global 水果机\n菠菜")
+	if err != nil || !scoped || !scope.IsGlobal() || strings.TrimSpace(rest) != "水果机\n菠菜" {
+		t.Errorf("splitScope(@global) = %v,%v,%q,%v", scope, scoped, rest, err)
+	}
+
+	if _, _, _, err := splitScope("@abc\n水果机"); err == nil {
+		t.Error("无效作用域应当报错")
+	}
+}
@@ -0,0 +1,116 @@
+package command
+
+// /mode: 切换规则的执行模式。
+// 新词、新正则先以试行上线, 看过每日汇总确认没有误伤再转为执行; 出问题的规则可以先停用而不必删掉。
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"SunaiForum-Bot/core"
+	"SunaiForum-Bot/service/moderation"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// modeUsage /mode 的用法说明, 不带参数时附在当前状态后面
+const modeUsage = "用法：/mode <enforce|shadow|off> <目标>\n" +
+	"enforce 执行，shadow 试行（只记录、每日汇总），off 停用。\n" +
+	"目标可以是关键词或规则原文，也可以是内置规则："
+
+// setMode 不带参数时列出非执行状态的规则; 带参数时修改一条规则的模式
+func setMode(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		core.SendMessage(bot, message.Chat.ID, describeModes())
+		return
+	}
+
+	mode, target, err := parseModeArgs(args)
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, err.Error())
+		return
+	}
+
+	if label, ok := moderation.BuiltinRule(strings.ToLower(target)); ok {
+		if err := core.DB.SetRuleMode(strings.ToLower(target), mode); err != nil {
+			core.SendErrorMessage(bot, message.Chat.ID, "修改执行模式时发生错误。")
+			log.Printf("[Command] 修改规则 %s 的执行模式失败: %v", target, err)
+			return
+		}
+		log.Printf("[Command] 管理员 %d 将内置规则 %s 设为 %s", message.From.ID, target, mode)
+		core.SendMessage(bot, message.Chat.ID, fmt.Sprintf("内置规则「%s」已设为%s。", label, core.ModeLabel(mode)))
+		return
+	}
+
+	found, err := core.DB.SetKeywordMode(target, mode)
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, "修改执行模式时发生错误。")
+		log.Printf("[Command] 修改词条 %q 的执行模式失败: %v", target, err)
+		return
+	}
+	if !found {
+		core.SendErrorMessage(bot, message.Chat.ID,
+			fmt.Sprintf("没有找到「%s」，既不是内置规则也不在词表里。\n%s", target, suggestSimilar([]string{target})))
+		return
+	}
+	log.Printf("[Command] 管理员 %d 将词条 %q 设为 %s", message.From.ID, target, mode)
+	core.SendMessage(bot, message.Chat.ID, fmt.Sprintf("「%s」已设为%s。", target, core.ModeLabel(mode)))
+}
+
+// parseModeArgs 拆出模式与目标; 目标取第一个空白之后的全部内容, 规则原文里可以带空格
+func parseModeArgs(args string) (string, string, error) {
+	fields := strings.Fields(args)
+	if len(fields) < 2 {
+		return "", "", fmt.Errorf("需要模式和目标两个参数。\n%s%s", modeUsage, builtinRuleList())
+	}
+
+	mode := strings.ToLower(fields[0])
+	if !core.ValidMode(mode) {
+		return "", "", fmt.Errorf("未知的模式 %q，只能是 enforce、shadow 或 off", fields[0])
+	}
+	target := strings.TrimSpace(strings.TrimPrefix(args, fields[0]))
+	return mode, target, nil
+}
+
+// describeModes 列出内置规则的当前模式与全部非执行状态的词条
+func describeModes() string {
+	var b strings.Builder
+
+	b.WriteString("内置规则：\n")
+	for _, id := range moderation.BuiltinRuleIDs() {
+		label, _ := moderation.BuiltinRule(id)
+		fmt.Fprintf(&b, "• %s（%s）：%s\n", id, label, core.ModeLabel(moderation.RuleMode(id)))
+	}
+
+	var entries []string
+	for _, source := range []string{core.SourceManual, core.SourceAI} {
+		keywords, err := core.DB.GetKeywordsBySource(source)
+		if err != nil {
+			log.Printf("[Command] 获取关键词失败: %v", err)
+			continue
+		}
+		for _, k := range keywords {
+			if k.Mode != "" && k.Mode != core.ModeEnforce {
+				entries = append(entries, fmt.Sprintf("• %s：%s%s", k.Word, core.ModeLabel(k.Mode), scopeSuffix(k, false)))
+			}
+		}
+	}
+	sort.Strings(entries)
+
+	b.WriteString("\n试行或停用中的词条：")
+	if len(entries) == 0 {
+		b.WriteString("无\n")
+	} else {
+		b.WriteString("\n" + strings.Join(entries, "\n") + "\n")
+	}
+
+	b.WriteString("\n" + modeUsage + builtinRuleList())
+	return b.String()
+}
+
+// builtinRuleList 内置规则 ID 清单
+func builtinRuleList() string {
+	return strings.Join(moderation.BuiltinRuleIDs(), "、")
+}
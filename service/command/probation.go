@@ -0,0 +1,56 @@
+package command
+
+// 新成员限制的提前解除, 版主与所有者可用。
+// 限制按群计算, 但"这个人可信"的判断与群无关, 所以一次解除全部受管群。
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// releaseProbation 在全部受管群提前解除给定用户的新成员限制
+func releaseProbation(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	groups, err := core.DB.GetGroups()
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, "读取受管群失败。")
+		log.Printf("[Command] 读取受管群失败: %v", err)
+		return
+	}
+	chatIDs := make([]int64, 0, len(groups))
+	for _, group := range groups {
+		chatIDs = append(chatIDs, group.ChatID)
+	}
+
+	var released, invalid []string
+	for _, field := range strings.Fields(args) {
+		userID, err := strconv.ParseInt(field, 10, 64)
+		if err != nil || userID <= 0 {
+			invalid = append(invalid, field)
+			continue
+		}
+		if err := core.DB.ReleaseProbation(userID, chatIDs); err != nil {
+			log.Printf("[Command] 解除用户 %d 的新成员限制失败: %v", userID, err)
+			invalid = append(invalid, field)
+			continue
+		}
+		released = append(released, field)
+	}
+
+	log.Printf("[Command] 管理员 %d 解除了新成员限制: %v", message.From.ID, released)
+	var b strings.Builder
+	if len(released) > 0 {
+		fmt.Fprintf(&b, "已在 %d 个受管群解除新成员限制：%s", len(chatIDs), strings.Join(released, "、"))
+	}
+	if len(invalid) > 0 {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "无法识别或操作失败：%s", strings.Join(invalid, "、"))
+	}
+	core.SendMessage(bot, message.Chat.ID, b.String())
+}
@@ -0,0 +1,128 @@
+package command
+
+// 信任等级的手动指定, 版主与所有者可用。
+// 不带群 ID 时对全部受管群生效, 与 /release 一致; 指定后回报各群的分数与最终等级, 便于核对。
+// 等级的计算方式见 core/trust.go。
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// trustResetWord 写在用户 ID 前面, 表示清除指定、恢复按统计计算
+const trustResetWord = "reset"
+
+// trustRequest 一次 /trust 或 /distrust 的参数
+type trustRequest struct {
+	reset  bool
+	userID int64
+	chatID int64 // 0 表示全部受管群
+}
+
+// parseTrustArgs 解析 "[reset] 用户ID [群ID]"
+func parseTrustArgs(args string) (trustRequest, error) {
+	var req trustRequest
+	fields := strings.Fields(args)
+	if len(fields) > 0 && strings.EqualFold(fields[0], trustResetWord) {
+		req.reset = true
+		fields = fields[1:]
+	}
+	if len(fields) == 0 || len(fields) > 2 {
+		return trustRequest{}, fmt.Errorf("格式为：[reset] 用户ID [群ID]")
+	}
+	userID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || userID <= 0 {
+		return trustRequest{}, fmt.Errorf("用户 ID 应当是正整数：%s", fields[0])
+	}
+	req.userID = userID
+	if len(fields) == 2 {
+		if req.chatID, err = parseChatID(fields[1]); err != nil {
+			return trustRequest{}, err
+		}
+	}
+	return req, nil
+}
+
+// trustUser 把用户指定为可信; reset 时清除指定
+func trustUser(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	setTrust(bot, message, args, core.TrustOverrideTrusted)
+}
+
+// distrustUser 把用户指定为低信任; reset 时清除指定
+func distrustUser(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	setTrust(bot, message, args, core.TrustOverrideDistrusted)
+}
+
+// setTrust 在指定的群 (或全部受管群) 写入信任等级, 再回报各群的结果
+func setTrust(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args, override string) {
+	req, err := parseTrustArgs(args)
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, err.Error())
+		return
+	}
+	if req.reset {
+		override = ""
+	}
+
+	var groups []core.ManagedGroup
+	if req.chatID != 0 {
+		group, ok, err := core.DB.GetGroup(req.chatID)
+		if err != nil {
+			core.SendErrorMessage(bot, message.Chat.ID, "读取群设置失败。")
+			log.Printf("[Command] 读取群 %d 失败: %v", req.chatID, err)
+			return
+		}
+		if !ok {
+			core.SendErrorMessage(bot, message.Chat.ID, fmt.Sprintf("群 %d 未登记为受管群。", req.chatID))
+			return
+		}
+		groups = []core.ManagedGroup{group}
+	} else if groups, err = core.DB.GetGroups(); err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, "读取受管群失败。")
+		log.Printf("[Command] 读取受管群失败: %v", err)
+		return
+	}
+	if len(groups) == 0 {
+		core.SendErrorMessage(bot, message.Chat.ID, "还没有受管群。")
+		return
+	}
+
+	chatIDs := make([]int64, 0, len(groups))
+	for _, group := range groups {
+		chatIDs = append(chatIDs, group.ChatID)
+	}
+	if err := core.DB.SetTrustOverride(req.userID, chatIDs, override); err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, "保存信任等级失败。")
+		log.Printf("[Command] 设置用户 %d 的信任等级失败: %v", req.userID, err)
+		return
+	}
+	log.Printf("[Command] 管理员 %d 把用户 %d 在 %d 个群的信任等级指定为 %q", message.From.ID, req.userID, len(chatIDs), override)
+
+	var b strings.Builder
+	switch override {
+	case core.TrustOverrideTrusted:
+		fmt.Fprintf(&b, "已把用户 %d 指定为可信：", req.userID)
+	case core.TrustOverrideDistrusted:
+		fmt.Fprintf(&b, "已把用户 %d 指定为低信任：", req.userID)
+	default:
+		fmt.Fprintf(&b, "已清除用户 %d 的指定，恢复按统计计算：", req.userID)
+	}
+	for _, group := range groups {
+		title := group.Title
+		if title == "" {
+			title = strconv.FormatInt(group.ChatID, 10)
+		}
+		rep, err := core.DB.GetReputation(req.userID, group.ChatID)
+		if err != nil {
+			fmt.Fprintf(&b, "\n• %s：读取失败", title)
+			continue
+		}
+		fmt.Fprintf(&b, "\n• %s：%s（计算分 %d）", title, rep.Level, rep.Score)
+	}
+	core.SendMessage(bot, message.Chat.ID, b.String())
+}
@@ -0,0 +1,19 @@
+package command
+
+import "testing"
+
+func TestParseTrustArgs(t *testing.T) {
+	req, err := parseTrustArgs("123")
+	if err != nil || req.reset || req.userID != 123 || req.chatID != 0 {
+		t.Errorf("只给用户 ID = %+v,%v", req, err)
+	}
+	req, err = parseTrustArgs("RESET 123 -100200")
+	if err != nil || !req.reset || req.userID != 123 || req.chatID != -100200 {
+		t.Errorf("reset 加群 ID = %+v,%v", req, err)
+	}
+	for _, raw := range []string{"", "reset", "abc", "-5", "123 456", "123 -100 -200"} {
+		if _, err := parseTrustArgs(raw); err == nil {
+			t.Errorf("parseTrustArgs(%q) 应当报错", raw)
+		}
+	}
+}
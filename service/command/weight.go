@@ -0,0 +1,68 @@
+package command
+
+// /weight: 设置规则的违规权重。
+// 权重决定每次命中记几分, 处罚阶梯按累计分数取级; 设为 ban 的规则命中即封禁。
+// 直接关系到封禁, 与处罚阶梯一样只有所有者可以修改。
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"SunaiForum-Bot/core"
+	"SunaiForum-Bot/service/moderation"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// weightUsage /weight 的用法说明
+const weightUsage = "用法：/weight <规则> <权重>\n权重为 1 到 10 的整数（每次命中记几分），或 ban（命中即封禁）。\n可选规则："
+
+// setWeight 不带参数时列出全部规则的权重; 带参数时修改一条规则的权重
+func setWeight(bot *tgbotapi.BotAPI, message *tgbotapi.Message, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		core.SendMessage(bot, message.Chat.ID, describeWeights())
+		return
+	}
+	if len(fields) != 2 {
+		core.SendErrorMessage(bot, message.Chat.ID, "需要规则和权重两个参数。\n"+weightUsage+weightedRuleList())
+		return
+	}
+
+	id := strings.ToLower(fields[0])
+	label, ok := moderation.WeightedRule(id)
+	if !ok {
+		core.SendErrorMessage(bot, message.Chat.ID, fmt.Sprintf("没有规则 %s。\n%s%s", fields[0], weightUsage, weightedRuleList()))
+		return
+	}
+	weight, err := core.ParseWeight(fields[1])
+	if err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, err.Error())
+		return
+	}
+
+	if err := core.DB.SetRuleWeight(id, weight); err != nil {
+		core.SendErrorMessage(bot, message.Chat.ID, "保存权重时发生错误。")
+		log.Printf("[Command] 修改规则 %s 的权重失败: %v", id, err)
+		return
+	}
+	log.Printf("[Command] 管理员 %d 将规则 %s 的权重设为 %d", message.From.ID, id, weight)
+	core.SendMessage(bot, message.Chat.ID, fmt.Sprintf("规则「%s」的权重已设为%s。", label, core.WeightLabel(weight)))
+}
+
+// describeWeights 列出全部规则的当前权重
+func describeWeights() string {
+	var b strings.Builder
+	b.WriteString("规则权重：\n")
+	for _, id := range moderation.WeightedRuleIDs() {
+		label, _ := moderation.WeightedRule(id)
+		fmt.Fprintf(&b, "• %s（%s）：%s\n", id, label, core.WeightLabel(moderation.RuleWeight(id)))
+	}
+	b.WriteString("\n" + weightUsage + weightedRuleList())
+	return b.String()
+}
+
+// weightedRuleList 可设置权重的规则 ID 清单
+func weightedRuleList() string {
+	return strings.Join(moderation.WeightedRuleIDs(), "、")
+}
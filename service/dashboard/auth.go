@@ -0,0 +1,258 @@
+package dashboard
+
+// 面板的登录与会话。
+//
+// 两种登录方式:
+//   - 口令: DASHBOARD_TOKEN, 登录后按所有者 (ADMIN_ID) 对待, 适合只有所有者一人使用的部署
+//   - Telegram 登录组件: 按 Telegram 官方算法校验签名, 登录后按此人在名册里的角色授权
+//
+// 会话是签名的 cookie (用户 ID + 过期时间), 不落库; 每次请求都重新查名册, 被撤销的管理员立即失去权限。
+// 表单另带一个由会话派生的 CSRF 口令, 防止别的站点借着 cookie 替管理员点撤销。
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"SunaiForum-Bot/core"
+)
+
+const (
+	// sessionCookie 会话 cookie 的名字
+	sessionCookie = "sunai_dashboard"
+	// sessionTTL 会话有效期, 过期需重新登录
+	sessionTTL = 12 * time.Hour
+	// telegramAuthMaxAge Telegram 登录数据的有效期; 防止截获的旧链接被拿来重放
+	telegramAuthMaxAge = 24 * time.Hour
+)
+
+// session 一个已登录的会话
+type session struct {
+	userID  int64
+	expires time.Time
+}
+
+// deriveKey 从机器人 token 派生用途各异的密钥, 一个 token 管到底, 不必另配密钥
+func deriveKey(purpose, botToken string) []byte {
+	mac := hmac.New(sha256.New, []byte(botToken))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// sign 用会话密钥签名
+func (s *Server) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// encodeSession 会话的 cookie 值: 用户ID.过期时间戳.签名
+func (s *Server) encodeSession(sess session) string {
+	payload := fmt.Sprintf("%d.%d", sess.userID, sess.expires.Unix())
+	return payload + "." + s.sign(payload)
+}
+
+// decodeSession 校验并解析 cookie 值; 签名不对或已过期都视为未登录
+func (s *Server) decodeSession(value string, now time.Time) (session, bool) {
+	idx := strings.LastIndexByte(value, '.')
+	if idx < 0 {
+		return session{}, false
+	}
+	payload, sig := value[:idx], value[idx+1:]
+	if !hmac.Equal([]byte(sig), []byte(s.sign(payload))) {
+		return session{}, false
+	}
+
+	rawID, rawExpires, ok := strings.Cut(payload, ".")
+	if !ok {
+		return session{}, false
+	}
+	userID, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		return session{}, false
+	}
+	unix, err := strconv.ParseInt(rawExpires, 10, 64)
+	if err != nil {
+		return session{}, false
+	}
+	sess := session{userID: userID, expires: time.Unix(unix, 0)}
+	if !now.Before(sess.expires) {
+		return session{}, false
+	}
+	return sess, true
+}
+
+// session 读取请求里的会话
+func (s *Server) session(r *http.Request) (session, bool) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return session{}, false
+	}
+	return s.decodeSession(cookie.Value, time.Now())
+}
+
+// csrfToken 由会话派生的表单口令; 会话换了口令跟着换
+func (s *Server) csrfToken(sess session) string {
+	return s.sign("csrf:" + s.encodeSession(sess))[:32]
+}
+
+// require 包装需要登录且具备某项权限的页面; 写操作额外校验 CSRF 口令
+func (s *Server) require(perm core.Permission, handle func(http.ResponseWriter, *http.Request, int64)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sess, ok := s.session(r)
+		if !ok {
+			if r.Method == http.MethodGet {
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return
+			}
+			s.renderError(w, r, http.StatusUnauthorized, "登录已过期，请重新登录。")
+			return
+		}
+		if !core.Can(sess.userID, perm) {
+			s.renderError(w, r, http.StatusForbidden, "当前角色没有权限访问这个页面。")
+			return
+		}
+		if r.Method != http.MethodGet {
+			if subtle.ConstantTimeCompare([]byte(r.FormValue("csrf")), []byte(s.csrfToken(sess))) != 1 {
+				s.renderError(w, r, http.StatusForbidden, "表单已失效，请刷新页面后重试。")
+				return
+			}
+		}
+		handle(w, r, sess.userID)
+	}
+}
+
+// startSession 登录成功后写入会话 cookie
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request, userID int64) {
+	sess := session{userID: userID, expires: time.Now().Add(sessionTTL)}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    s.encodeSession(sess),
+		Path:     "/",
+		Expires:  sess.expires,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// isHTTPS 判断请求是否经由 HTTPS 到达; 放在反向代理后面时看代理加的头
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// loginView 登录页的数据
+type loginView struct {
+	Token    bool
+	Telegram bool
+	BotName  string
+	Error    string
+}
+
+// loginPage 登录页; 已登录的直接进首页
+func (s *Server) loginPage(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.session(r); ok {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	s.render(w, r, http.StatusOK, "login", "登录", s.loginView(""))
+}
+
+func (s *Server) loginView(message string) loginView {
+	return loginView{
+		Token:    s.opts.Token != "",
+		Telegram: s.opts.TelegramLogin && s.opts.BotName != "",
+		BotName:  s.opts.BotName,
+		Error:    message,
+	}
+}
+
+// tokenLogin 口令登录, 登录后按所有者对待
+func (s *Server) tokenLogin(w http.ResponseWriter, r *http.Request) {
+	if s.opts.Token == "" {
+		s.renderError(w, r, http.StatusNotFound, "未开启口令登录。")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(r.FormValue("token"))), []byte(s.opts.Token)) != 1 {
+		log.Printf("[Dashboard] 口令登录失败, 来自 %s", r.RemoteAddr)
+		s.render(w, r, http.StatusUnauthorized, "login", "登录", s.loginView("口令不正确。"))
+		return
+	}
+	log.Printf("[Dashboard] 所有者以口令登录, 来自 %s", r.RemoteAddr)
+	s.startSession(w, r, core.AdminID)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// telegramLogin Telegram 登录组件的回调; 只有名册上的管理员能登录
+func (s *Server) telegramLogin(w http.ResponseWriter, r *http.Request) {
+	if !s.opts.TelegramLogin {
+		s.renderError(w, r, http.StatusNotFound, "未开启 Telegram 登录。")
+		return
+	}
+	userID, err := verifyTelegramLogin(r.URL.Query(), s.opts.BotToken, time.Now())
+	if err != nil {
+		log.Printf("[Dashboard] Telegram 登录校验失败: %v", err)
+		s.render(w, r, http.StatusUnauthorized, "login", "登录", s.loginView("登录数据校验失败，请重试。"))
+		return
+	}
+	if !core.IsAdmin(userID) {
+		log.Printf("[Dashboard] 非管理员 %d 尝试登录面板", userID)
+		s.render(w, r, http.StatusForbidden, "login", "登录", s.loginView("这个账号不是管理员。"))
+		return
+	}
+	log.Printf("[Dashboard] 管理员 %d 以 Telegram 登录", userID)
+	s.startSession(w, r, userID)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// logout 清掉会话 cookie; 不校验 CSRF, 被人诱导退出无伤大雅
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// verifyTelegramLogin 按 Telegram 登录组件的规则校验回调参数, 返回用户 ID。
+// 规则: 除 hash 外的字段按键名排序拼成 "k=v\n..." , 以 SHA256(bot token) 为密钥做 HMAC-SHA256, 结果应等于 hash。
+func verifyTelegramLogin(query url.Values, botToken string, now time.Time) (int64, error) {
+	hash := query.Get("hash")
+	if hash == "" {
+		return 0, fmt.Errorf("缺少 hash")
+	}
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if key != "hash" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, key+"="+query.Get(key))
+	}
+
+	secret := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	if !hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(strings.ToLower(hash))) {
+		return 0, fmt.Errorf("签名不匹配")
+	}
+
+	authDate, err := strconv.ParseInt(query.Get("auth_date"), 10, 64)
+	if err != nil || now.Sub(time.Unix(authDate, 0)) > telegramAuthMaxAge {
+		return 0, fmt.Errorf("登录数据已过期")
+	}
+	userID, err := strconv.ParseInt(query.Get("id"), 10, 64)
+	if err != nil || userID <= 0 {
+		return 0, fmt.Errorf("无效的用户 ID")
+	}
+	return userID, nil
+}
@@ -0,0 +1,173 @@
+package dashboard
+
+// 网页管理面板, 与机器人同进程运行。
+//
+// Telegram 私聊适合零碎操作, 但词表和处置记录一长, 分段发来的消息就没法翻了。面板提供
+// 关键词检索、处置记录与撤销、自动回复编辑、违规计分查看和数据库快照下载, 读写一律复用
+// core.DB 上现成的方法, 与私聊命令走同一套逻辑和缓存。
+//
+// 默认关闭: 只有设置了 HTTP_ADDR 且至少开了一种登录方式 (口令或 Telegram 登录) 才挂载, 监听见 service/http_server.go。
+// 面板本身只讲 HTTP, 对公网开放时应放在反向代理的 HTTPS 后面。
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// pageNames 全部页面模板, 每个都和 layout.html 拼在一起解析
+var pageNames = []string{"login", "error", "keywords", "actions", "prompts", "strikes", "backups"}
+
+// Options 面板的配置; 与 core 的全局变量分开, 测试时不必动全局状态
+type Options struct {
+	Token         string // 口令登录, 为空表示不开
+	TelegramLogin bool   // 是否开启 Telegram 登录
+	BotToken      string // 用于校验 Telegram 登录数据与签发会话
+	BotName       string // Telegram 登录组件要填机器人用户名
+}
+
+// Server 面板的 HTTP 处理器
+type Server struct {
+	bot     *tgbotapi.BotAPI
+	opts    Options
+	secret  []byte // 会话签名密钥, 由 BotToken 派生, 重启后已登录的会话仍然有效
+	pages   map[string]*template.Template
+	handler http.Handler
+}
+
+// New 构造面板; bot 只在撤销处置时用到 (解封、发回原文)
+func New(bot *tgbotapi.BotAPI, opts Options) (*Server, error) {
+	if opts.BotToken == "" {
+		return nil, fmt.Errorf("缺少 BotToken, 无法签发会话")
+	}
+
+	s := &Server{
+		bot:    bot,
+		opts:   opts,
+		secret: deriveKey("dashboard-session", opts.BotToken),
+		pages:  make(map[string]*template.Template, len(pageNames)),
+	}
+	for _, name := range pageNames {
+		page, err := template.New(name).Funcs(templateFuncs).ParseFS(templateFiles, "templates/layout.html", "templates/"+name+".html")
+		if err != nil {
+			return nil, fmt.Errorf("解析模板 %s 失败: %w", name, err)
+		}
+		s.pages[name] = page
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /login", s.loginPage)
+	mux.HandleFunc("POST /login", s.tokenLogin)
+	mux.HandleFunc("GET /login/telegram", s.telegramLogin)
+	mux.HandleFunc("POST /logout", s.logout)
+
+	mux.HandleFunc("GET /{$}", s.require(core.PermAdmin, s.home))
+	mux.HandleFunc("GET /keywords", s.require(core.PermKeywords, s.keywordsPage))
+	mux.HandleFunc("GET /actions", s.require(core.PermModerate, s.actionsPage))
+	mux.HandleFunc("POST /actions/undo", s.require(core.PermModerate, s.undoAction))
+	mux.HandleFunc("GET /prompts", s.require(core.PermKeywords, s.promptsPage))
+	mux.HandleFunc("POST /prompts", s.require(core.PermKeywords, s.savePrompt))
+	mux.HandleFunc("POST /prompts/delete", s.require(core.PermKeywords, s.deletePrompt))
+	mux.HandleFunc("GET /strikes", s.require(core.PermModerate, s.strikesPage))
+	// 快照里是整个库, 含管理员名册, 只给所有者
+	mux.HandleFunc("GET /backups", s.require(core.PermManageAdmins, s.backupsPage))
+	mux.HandleFunc("GET /backups/file", s.require(core.PermManageAdmins, s.downloadBackup))
+	mux.HandleFunc("POST /backups", s.require(core.PermManageAdmins, s.createBackup))
+	s.handler = mux
+	return s, nil
+}
+
+// ServeHTTP 实现 http.Handler; 面板页面一律不缓存, 也不许被别的站点嵌进 iframe
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	s.handler.ServeHTTP(w, r)
+}
+
+// navLink 导航栏的一项
+type navLink struct {
+	Path, Label string
+	Perm        core.Permission
+}
+
+// navLinks 导航栏, 只显示当前管理员有权限的页面
+var navLinks = []navLink{
+	{"/actions", "处置记录", core.PermModerate},
+	{"/keywords", "关键词", core.PermKeywords},
+	{"/prompts", "自动回复", core.PermKeywords},
+	{"/strikes", "违规计分", core.PermModerate},
+	{"/backups", "数据库快照", core.PermManageAdmins},
+}
+
+// view 渲染页面时传给模板的数据
+type view struct {
+	Title  string
+	Path   string
+	Nav    []navLink
+	UserID int64
+	Role   string
+	CSRF   string
+	Flash  string
+	Data   any
+}
+
+// render 以 layout 渲染页面; 导航与 CSRF 口令按当前会话填好
+func (s *Server) render(w http.ResponseWriter, r *http.Request, status int, name, title string, data any) {
+	v := view{Title: title, Path: r.URL.Path, Flash: r.URL.Query().Get("msg"), Data: data}
+	if sess, ok := s.session(r); ok {
+		v.UserID = sess.userID
+		v.Role = core.RoleLabel(core.RoleOf(sess.userID))
+		v.CSRF = s.csrfToken(sess)
+		for _, link := range navLinks {
+			if core.Can(sess.userID, link.Perm) {
+				v.Nav = append(v.Nav, link)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := s.pages[name].ExecuteTemplate(w, "layout", v); err != nil {
+		log.Printf("[Dashboard] 渲染页面 %s 失败: %v", name, err)
+	}
+}
+
+// renderError 渲染一条错误提示
+func (s *Server) renderError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	s.render(w, r, status, "error", "出错了", message)
+}
+
+// redirectWithFlash 表单提交后跳回列表页, 把结果放在 msg 参数里显示一次
+func redirectWithFlash(w http.ResponseWriter, r *http.Request, path, message string) {
+	target := path
+	if message != "" {
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		target += sep + "msg=" + url.QueryEscape(message)
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// home 首页跳到第一个有权限的页面
+func (s *Server) home(w http.ResponseWriter, r *http.Request, userID int64) {
+	for _, link := range navLinks {
+		if core.Can(userID, link.Perm) {
+			http.Redirect(w, r, link.Path, http.StatusSeeOther)
+			return
+		}
+	}
+	s.renderError(w, r, http.StatusForbidden, "当前角色没有可用的面板页面。")
+}
@@ -0,0 +1,215 @@
+package dashboard
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"SunaiForum-Bot/core"
+	"SunaiForum-Bot/service/prompt_reply"
+)
+
+const testBotToken = "123456:test-token"
+
+// signTelegramLogin 按 Telegram 的算法给登录参数签名
+func signTelegramLogin(values url.Values, botToken string) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, key+"="+values.Get(key))
+	}
+	secret := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	values.Set("hash", hex.EncodeToString(mac.Sum(nil)))
+}
+
+// TestVerifyTelegramLogin 签名正确才放行; 改过字段、换了 token 或太旧的登录数据一律拒绝
+func TestVerifyTelegramLogin(t *testing.T) {
+	now := time.Now()
+	login := func(authDate time.Time) url.Values {
+		values := url.Values{
+			"id":         {"42"},
+			"first_name": {"管理员"},
+			"username":   {"admin"},
+			"auth_date":  {strconv.FormatInt(authDate.Unix(), 10)},
+		}
+		signTelegramLogin(values, testBotToken)
+		return values
+	}
+
+	if id, err := verifyTelegramLogin(login(now), testBotToken, now); err != nil || id != 42 {
+		t.Errorf("正确签名 = %d,%v", id, err)
+	}
+
+	tampered := login(now)
+	tampered.Set("id", "43")
+	if _, err := verifyTelegramLogin(tampered, testBotToken, now); err == nil {
+		t.Error("改过 id 的登录数据应当被拒绝")
+	}
+	if _, err := verifyTelegramLogin(login(now), "654321:other", now); err == nil {
+		t.Error("别的机器人签发的登录数据应当被拒绝")
+	}
+	if _, err := verifyTelegramLogin(login(now.Add(-48*time.Hour)), testBotToken, now); err == nil {
+		t.Error("过期的登录数据应当被拒绝")
+	}
+}
+
+// TestSessionCookie 会话能原样解出; 签名被改或已过期的视为未登录
+func TestSessionCookie(t *testing.T) {
+	s, err := New(nil, Options{Token: "pw", BotToken: testBotToken})
+	if err != nil {
+		t.Fatalf("New 失败: %v", err)
+	}
+	now := time.Now()
+	value := s.encodeSession(session{userID: 7, expires: now.Add(time.Hour)})
+
+	if sess, ok := s.decodeSession(value, now); !ok || sess.userID != 7 {
+		t.Errorf("解析会话 = %+v,%v", sess, ok)
+	}
+	if _, ok := s.decodeSession(strings.Replace(value, "7.", "1.", 1), now); ok {
+		t.Error("改过用户 ID 的会话应当无效")
+	}
+	if _, ok := s.decodeSession(value, now.Add(2*time.Hour)); ok {
+		t.Error("过期的会话应当无效")
+	}
+}
+
+// useTempDB 让 core.DB 指向临时库, 所有者设为 1, 测试结束后还原
+func useTempDB(t *testing.T) *core.Database {
+	t.Helper()
+
+	db, err := core.NewDatabaseAt(filepath.Join(t.TempDir(), "dashboard.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	previousDB, previousAdmin := core.DB, core.AdminID
+	core.DB, core.AdminID = db, 1
+	t.Cleanup(func() {
+		core.DB, core.AdminID = previousDB, previousAdmin
+		db.Close()
+	})
+	return db
+}
+
+// csrfPattern 从页面里取 CSRF 口令
+var csrfPattern = regexp.MustCompile(`name="csrf" value="([0-9a-f]+)"`)
+
+// TestDashboardFlow 未登录跳登录页, 口令登录后能检索关键词, 写操作要带 CSRF 口令
+func TestDashboardFlow(t *testing.T) {
+	db := useTempDB(t)
+	db.AddKeyword("水果特价", core.SourceManual)
+	db.AddKeyword("加微信", core.SourceAI)
+
+	s, err := New(nil, Options{Token: "pw", BotToken: testBotToken})
+	if err != nil {
+		t.Fatalf("New 失败: %v", err)
+	}
+	do := func(method, target string, form url.Values, cookie *http.Cookie) *httptest.ResponseRecorder {
+		var req *http.Request
+		if form != nil {
+			req = httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		} else {
+			req = httptest.NewRequest(method, target, nil)
+		}
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do("GET", "/keywords", nil, nil); rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/login" {
+		t.Fatalf("未登录访问 = %d %s, 期望跳到登录页", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := do("POST", "/login", url.Values{"token": {"wrong"}}, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("错误口令 = %d", rec.Code)
+	}
+
+	rec := do("POST", "/login", url.Values{"token": {"pw"}}, nil)
+	cookies := rec.Result().Cookies()
+	if rec.Code != http.StatusSeeOther || len(cookies) == 0 {
+		t.Fatalf("口令登录 = %d, cookie %v", rec.Code, cookies)
+	}
+	cookie := cookies[0]
+
+	rec = do("GET", "/keywords?q=水果", nil, cookie)
+	if body := rec.Body.String(); rec.Code != http.StatusOK || !strings.Contains(body, "水果特价") || strings.Contains(body, "加微信") {
+		t.Errorf("检索关键词 = %d, 页面里应只有匹配的词", rec.Code)
+	}
+
+	if rec := do("POST", "/prompts", url.Values{"prompt": {"官网"}, "reply": {"https://example.com"}}, cookie); rec.Code != http.StatusForbidden {
+		t.Errorf("不带 CSRF 口令的提交 = %d, 期望拒绝", rec.Code)
+	}
+	match := csrfPattern.FindStringSubmatch(do("GET", "/prompts", nil, cookie).Body.String())
+	if match == nil {
+		t.Fatal("自动回复页里没有 CSRF 口令")
+	}
+	form := url.Values{"csrf": {match[1]}, "prompt": {"官网"}, "reply": {"https://example.com"}}
+	if rec := do("POST", "/prompts", form, cookie); rec.Code != http.StatusSeeOther {
+		t.Errorf("保存自动回复 = %d", rec.Code)
+	}
+	if reply := prompt_reply.All()["官网"]; reply != "https://example.com" {
+		t.Errorf("自动回复没有写进内存映射: %q", reply)
+	}
+	prompt_reply.DeletePromptReply("官网")
+
+	// 试行记录没有可撤销的处置
+	actionID, _ := db.RecordModerationAction(core.ModerationAction{UserID: 9, ChatID: -100, Rule: "关键词", Shadow: true})
+	rec = do("POST", "/actions/undo", url.Values{"csrf": {match[1]}, "id": {strconv.FormatInt(actionID, 10)}}, cookie)
+	if location := rec.Header().Get("Location"); !strings.Contains(location, "msg=") {
+		t.Errorf("撤销试行记录应带回提示, 实际跳转到 %q", location)
+	}
+	if action, _ := db.GetModerationAction(actionID); action.Undone {
+		t.Error("试行记录不应被标记为已撤销")
+	}
+
+	if rec := do("GET", "/backups/file?name=../dashboard.db", nil, cookie); rec.Code != http.StatusNotFound {
+		t.Errorf("路径穿越 = %d, 期望 404", rec.Code)
+	}
+}
+
+// TestDashboardPermissions 词表编辑看不到处置记录与快照
+func TestDashboardPermissions(t *testing.T) {
+	db := useTempDB(t)
+	if err := db.SetAdminRole(5, core.RoleKeywordEditor, 1); err != nil {
+		t.Fatalf("登记管理员失败: %v", err)
+	}
+
+	s, err := New(nil, Options{TelegramLogin: true, BotToken: testBotToken, BotName: "test_bot"})
+	if err != nil {
+		t.Fatalf("New 失败: %v", err)
+	}
+	cookie := &http.Cookie{Name: sessionCookie, Value: s.encodeSession(session{userID: 5, expires: time.Now().Add(time.Hour)})}
+
+	for target, want := range map[string]int{
+		"/keywords": http.StatusOK,
+		"/prompts":  http.StatusOK,
+		"/actions":  http.StatusForbidden,
+		"/backups":  http.StatusForbidden,
+	} {
+		req := httptest.NewRequest("GET", target, nil)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("词表编辑访问 %s = %d, 期望 %d", target, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package dashboard
+
+// 面板的各个页面。列表一律分页或限量, 表单提交后重定向回列表页 (PRG), 刷新不会重复提交。
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"SunaiForum-Bot/core"
+	"SunaiForum-Bot/service/moderation"
+	"SunaiForum-Bot/service/prompt_reply"
+)
+
+// 每页条数
+const (
+	keywordsPerPage = 50
+	actionsPerPage  = 50
+	strikesLimit    = 200
+)
+
+// templateFuncs 模板里用到的格式化函数
+var templateFuncs = template.FuncMap{
+	"time": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.In(time.Local).Format("2006-01-02 15:04")
+	},
+	"truncate": func(s string, limit int) string {
+		runes := []rune(s)
+		if len(runes) <= limit {
+			return s
+		}
+		return string(runes[:limit]) + "…"
+	},
+	"kind":  core.KindLabel,
+	"mode":  core.ModeLabel,
+	"scope": func(k core.Keyword) string { return k.Scope().String() },
+	"size": func(n int64) string {
+		switch {
+		case n >= 1<<20:
+			return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+		case n >= 1<<10:
+			return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+		default:
+			return fmt.Sprintf("%d B", n)
+		}
+	},
+}
+
+// keywordsView 关键词页的数据
+type keywordsView struct {
+	Query    string
+	Keywords []core.Keyword
+	Total    int64
+	Page     int
+	Prev     string // 上一页链接, 为空表示没有
+	Next     string
+}
+
+// keywordsPage 浏览与检索关键词, 带来源与命中次数
+func (s *Server) keywordsPage(w http.ResponseWriter, r *http.Request, _ int64) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	page := max(atoiOr(r.URL.Query().Get("page"), 1), 1)
+
+	keywords, total, err := core.DB.ListKeywords(query, (page-1)*keywordsPerPage, keywordsPerPage)
+	if err != nil {
+		log.Printf("[Dashboard] 读取关键词失败: %v", err)
+		s.renderError(w, r, http.StatusInternalServerError, "读取关键词失败。")
+		return
+	}
+
+	v := keywordsView{Query: query, Keywords: keywords, Total: total, Page: page}
+	link := func(p int) string {
+		return "/keywords?" + url.Values{"q": {query}, "page": {strconv.Itoa(p)}}.Encode()
+	}
+	if page > 1 {
+		v.Prev = link(page - 1)
+	}
+	if int64(page*keywordsPerPage) < total {
+		v.Next = link(page + 1)
+	}
+	s.render(w, r, http.StatusOK, "keywords", "关键词", v)
+}
+
+// actionsView 处置记录页的数据
+type actionsView struct {
+	UserID  string
+	Actions []core.ModerationAction
+	Groups  map[int64]string // 群 ID -> 群名, 没登记的显示 ID
+	Next    string
+}
+
+// actionsPage 按时间倒序翻阅处置记录, 可按用户过滤
+func (s *Server) actionsPage(w http.ResponseWriter, r *http.Request, _ int64) {
+	rawUser := strings.TrimSpace(r.URL.Query().Get("user"))
+	userID, _ := strconv.ParseInt(rawUser, 10, 64)
+	before, _ := strconv.ParseInt(r.URL.Query().Get("before"), 10, 64)
+
+	actions, err := core.DB.GetRecentActions(userID, before, actionsPerPage)
+	if err != nil {
+		log.Printf("[Dashboard] 读取处置记录失败: %v", err)
+		s.renderError(w, r, http.StatusInternalServerError, "读取处置记录失败。")
+		return
+	}
+
+	v := actionsView{UserID: rawUser, Actions: actions, Groups: groupTitles()}
+	if len(actions) == actionsPerPage {
+		next := url.Values{"before": {strconv.FormatInt(actions[len(actions)-1].ID, 10)}}
+		if rawUser != "" {
+			next.Set("user", rawUser)
+		}
+		v.Next = "/actions?" + next.Encode()
+	}
+	s.render(w, r, http.StatusOK, "actions", "处置记录", v)
+}
+
+// undoAction 撤销一次处置, 与通知里的"误判，恢复"按钮等效
+func (s *Server) undoAction(w http.ResponseWriter, r *http.Request, adminID int64) {
+	back := "/actions"
+	if ref := r.FormValue("back"); strings.HasPrefix(ref, "/actions") {
+		back = ref
+	}
+
+	actionID, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		redirectWithFlash(w, r, back, "无效的处置 ID")
+		return
+	}
+	summary, err := moderation.Undo(s.bot, actionID, adminID)
+	switch {
+	case errors.Is(err, moderation.ErrActionNotFound), errors.Is(err, moderation.ErrAlreadyUndone), errors.Is(err, moderation.ErrShadowAction):
+		redirectWithFlash(w, r, back, fmt.Sprintf("#%d：%v", actionID, err))
+	case err != nil:
+		redirectWithFlash(w, r, back, fmt.Sprintf("#%d：操作失败", actionID))
+	default:
+		redirectWithFlash(w, r, back, fmt.Sprintf("#%d 已恢复：%s", actionID, summary))
+	}
+}
+
+// promptEntry 一条自动回复
+type promptEntry struct {
+	Prompt, Reply string
+}
+
+// promptsPage 列出并编辑自动回复
+func (s *Server) promptsPage(w http.ResponseWriter, r *http.Request, _ int64) {
+	replies := prompt_reply.All()
+	entries := make([]promptEntry, 0, len(replies))
+	for prompt, reply := range replies {
+		entries = append(entries, promptEntry{prompt, reply})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Prompt < entries[j].Prompt })
+	s.render(w, r, http.StatusOK, "prompts", "自动回复", entries)
+}
+
+// savePrompt 新增或修改一条自动回复; 走 prompt_reply 包, 内存里的映射同步更新
+func (s *Server) savePrompt(w http.ResponseWriter, r *http.Request, adminID int64) {
+	prompt := strings.TrimSpace(r.FormValue("prompt"))
+	reply := strings.TrimSpace(r.FormValue("reply"))
+	if prompt == "" || reply == "" {
+		redirectWithFlash(w, r, "/prompts", "触发词和回复内容都不能为空")
+		return
+	}
+	// 改了触发词的, 把旧的那条删掉, 否则编辑就变成了新增
+	if original := strings.TrimSpace(r.FormValue("original")); original != "" && !strings.EqualFold(original, prompt) {
+		if err := prompt_reply.DeletePromptReply(original); err != nil {
+			log.Printf("[Dashboard] 删除旧触发词 %q 失败: %v", original, err)
+		}
+	}
+	if err := prompt_reply.SetPromptReply(prompt, reply); err != nil {
+		redirectWithFlash(w, r, "/prompts", fmt.Sprintf("保存失败：%v", err))
+		return
+	}
+	log.Printf("[Dashboard] 管理员 %d 设置了自动回复 %q", adminID, prompt)
+	redirectWithFlash(w, r, "/prompts", fmt.Sprintf("已保存「%s」", prompt))
+}
+
+// deletePrompt 删除一条自动回复
+func (s *Server) deletePrompt(w http.ResponseWriter, r *http.Request, adminID int64) {
+	prompt := strings.TrimSpace(r.FormValue("prompt"))
+	if err := prompt_reply.DeletePromptReply(prompt); err != nil {
+		redirectWithFlash(w, r, "/prompts", fmt.Sprintf("删除失败：%v", err))
+		return
+	}
+	log.Printf("[Dashboard] 管理员 %d 删除了自动回复 %q", adminID, prompt)
+	redirectWithFlash(w, r, "/prompts", fmt.Sprintf("已删除「%s」", prompt))
+}
+
+// strikesView 违规计分页的数据
+type strikesView struct {
+	UserID  string
+	Strikes []core.UserStrike
+	Groups  map[int64]string
+	Limit   int
+}
+
+// strikesPage 查看仍有计分的用户, 可按用户过滤
+func (s *Server) strikesPage(w http.ResponseWriter, r *http.Request, _ int64) {
+	rawUser := strings.TrimSpace(r.URL.Query().Get("user"))
+	userID, _ := strconv.ParseInt(rawUser, 10, 64)
+
+	strikes, err := core.DB.GetStrikeRecords(userID, strikesLimit)
+	if err != nil {
+		log.Printf("[Dashboard] 读取违规计分失败: %v", err)
+		s.renderError(w, r, http.StatusInternalServerError, "读取违规计分失败。")
+		return
+	}
+	s.render(w, r, http.StatusOK, "strikes", "违规计分", strikesView{UserID: rawUser, Strikes: strikes, Groups: groupTitles(), Limit: strikesLimit})
+}
+
+// backupsPage 列出已有快照
+func (s *Server) backupsPage(w http.ResponseWriter, r *http.Request, _ int64) {
+	files, err := core.DB.ListBackups()
+	if err != nil {
+		log.Printf("[Dashboard] 读取快照目录失败: %v", err)
+		s.renderError(w, r, http.StatusInternalServerError, "读取快照目录失败。")
+		return
+	}
+	s.render(w, r, http.StatusOK, "backups", "数据库快照", files)
+}
+
+// createBackup 立即生成一份快照, 与每日快照共用轮转
+func (s *Server) createBackup(w http.ResponseWriter, r *http.Request, adminID int64) {
+	path, err := core.DB.Backup("manual", core.BackupKeep)
+	if err != nil {
+		log.Printf("[Dashboard] 生成快照失败: %v", err)
+		redirectWithFlash(w, r, "/backups", "生成快照失败")
+		return
+	}
+	log.Printf("[Dashboard] 管理员 %d 生成了快照 %s", adminID, filepath.Base(path))
+	redirectWithFlash(w, r, "/backups", "已生成 "+filepath.Base(path))
+}
+
+// downloadBackup 下载一份快照
+func (s *Server) downloadBackup(w http.ResponseWriter, r *http.Request, adminID int64) {
+	name := r.URL.Query().Get("name")
+	path, err := core.DB.BackupPath(name)
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "找不到这份快照。")
+		return
+	}
+	log.Printf("[Dashboard] 管理员 %d 下载了快照 %s", adminID, name)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeFile(w, r, path)
+}
+
+// groupTitles 受管群 ID -> 群名, 读不到时返回空表, 页面上显示 ID
+func groupTitles() map[int64]string {
+	titles := make(map[int64]string)
+	groups, err := core.DB.GetGroups()
+	if err != nil {
+		log.Printf("[Dashboard] 读取受管群失败: %v", err)
+		return titles
+	}
+	for _, group := range groups {
+		if group.Title != "" {
+			titles[group.ChatID] = group.Title
+		}
+	}
+	return titles
+}
+
+// atoiOr 解析整数, 失败时返回默认值
+func atoiOr(raw string, fallback int) int {
+	if n, err := strconv.Atoi(raw); err == nil {
+		return n
+	}
+	return fallback
+}
@@ -0,0 +1,312 @@
+package group_member_management
+
+// 入群验证: 新成员一进群先被禁言, 在时限内点对验证题的按钮才解除; 答错或超时即移出群 (之后仍可重新加入)。
+//
+// 批量注册的广告号往往进群几秒内就发广告, 也很少会去处理按钮, 这一步能在发第一条消息之前把它们挡在外面。
+// 通过验证会记入发言统计并把计数清零, AI 审核的新用户窗口从验证通过时才开始算。
+// 进行中的验证题落库 (见 core/db_challenge.go), 重启之后照常判定答案、照常清理超时未答的人。
+import (
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"SunaiForum-Bot/core"
+	"SunaiForum-Bot/service/moderation"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// captchaCallbackPrefix 验证按钮的 callback_data 前缀, 完整格式为 "cap:<用户 ID>:<选项下标>"。
+// 群 ID 取自按钮所在的消息, 不必占 callback_data 的 64 字节。
+const captchaCallbackPrefix = "cap:"
+
+const (
+	// mathOptions 算术题的选项数
+	mathOptions = 4
+	// emojiOptions 表情题的选项数, 每行 3 个
+	emojiOptions = 6
+	emojiPerRow  = 3
+)
+
+// captchaEmojis 表情题的题库; 名称要一眼就能对上, 避免"狗"和"狼"这类容易点错的组合
+var captchaEmojis = []struct{ emoji, name string }{
+	{"🍎", "苹果"}, {"🐶", "小狗"}, {"🚗", "汽车"}, {"🌙", "月亮"},
+	{"⚽", "足球"}, {"🎸", "吉他"}, {"🐟", "鱼"}, {"🌲", "树"},
+	{"🔑", "钥匙"}, {"🍉", "西瓜"}, {"✈️", "飞机"}, {"🐱", "小猫"},
+}
+
+// challengeQuestion 一道验证题; prompt 接在"请在 X 内"之后
+type challengeQuestion struct {
+	prompt  string
+	options []string
+	answer  int
+}
+
+// newQuestion 按题型出题; 未知题型按算术题处理
+func newQuestion(kind string) challengeQuestion {
+	if kind == core.CaptchaEmoji {
+		return newEmojiQuestion()
+	}
+	return newMathQuestion()
+}
+
+// newMathQuestion 两位数加法, 干扰项取正确答案附近的数, 不能靠"最大的那个"之类的规律蒙对
+func newMathQuestion() challengeQuestion {
+	a, b := 10+rand.IntN(40), 10+rand.IntN(40)
+	sum := a + b
+
+	values := []int{sum}
+	for len(values) < mathOptions {
+		candidate := sum + rand.IntN(21) - 10
+		if !slices.Contains(values, candidate) {
+			values = append(values, candidate)
+		}
+	}
+	rand.Shuffle(len(values), func(i, j int) { values[i], values[j] = values[j], values[i] })
+
+	question := challengeQuestion{prompt: fmt.Sprintf("算出 %d + %d 等于几", a, b)}
+	for i, value := range values {
+		if value == sum {
+			question.answer = i
+		}
+		question.options = append(question.options, strconv.Itoa(value))
+	}
+	return question
+}
+
+// newEmojiQuestion 从题库里抽 6 个不重复的表情, 让用户点出指定名称的那个
+func newEmojiQuestion() challengeQuestion {
+	picked := rand.Perm(len(captchaEmojis))[:emojiOptions]
+	answer := rand.IntN(emojiOptions)
+
+	question := challengeQuestion{
+		prompt: fmt.Sprintf("点出「%s」", captchaEmojis[picked[answer]].name),
+		answer: answer,
+	}
+	for _, index := range picked {
+		question.options = append(question.options, captchaEmojis[index].emoji)
+	}
+	return question
+}
+
+// HandleJoins 处理入群通知里的新成员: 在封禁名单上的直接踢出, 其余记下入群时间、计入突袭检测并按群设置出入群验证。
+// 不处理通知本身, 通知的清理仍交给 CleanServiceMessage。
+func HandleJoins(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+	if len(message.NewChatMembers) == 0 {
+		return
+	}
+	group, ok := core.GroupSettings(message.Chat.ID)
+	if !ok {
+		return
+	}
+
+	for _, member := range message.NewChatMembers {
+		if kickIfListed(bot, group, member) || member.IsBot {
+			continue
+		}
+		// 新成员限制从入群时算起, 而不是等到第一次发言
+		firstJoin, err := core.DB.RecordJoin(member.ID, group.ChatID)
+		if err != nil {
+			log.Printf("[GroupMemberManagement] 记录用户 %d 入群失败: %v", member.ID, err)
+		}
+		// 管理员亲手拉进来的人已经有人担保, 不计入突袭检测, 也不必再验证
+		if message.From != nil && message.From.ID != member.ID && core.IsAdmin(message.From.ID) {
+			continue
+		}
+		moderation.ObserveJoin(bot, group, member)
+		if group.Captcha != "" {
+			startChallenge(bot, group, member, firstJoin)
+		}
+	}
+}
+
+// startChallenge 禁言新成员并发出验证题。
+// 禁言失败 (多半是机器人没有限制成员的权限) 时不出题: 发了题也拦不住对方发言, 反而让群里多一条噪音。
+// firstJoin 表示这是该成员第一次出现在本群, 通过验证后才清零发言计数。
+func startChallenge(bot *tgbotapi.BotAPI, group core.ManagedGroup, member tgbotapi.User, firstJoin bool) {
+	if err := core.MuteUser(bot, group.ChatID, member.ID, time.Time{}); err != nil {
+		log.Printf("[GroupMemberManagement] 入群验证禁言用户 %d 失败, 跳过验证: %v", member.ID, err)
+		return
+	}
+
+	window := group.CaptchaWindow()
+	question := newQuestion(group.Captcha)
+	msg := tgbotapi.NewMessage(group.ChatID, fmt.Sprintf("欢迎 %s！请在 %s 内%s完成验证，答错或超时将被移出本群。",
+		moderation.DisplayName(&member), windowLabel(window), question.prompt))
+	msg.ReplyMarkup = captchaKeyboard(member.ID, question.options)
+
+	sent, err := bot.Send(msg)
+	if err != nil {
+		// 题发不出去就不能让人一直禁言着
+		log.Printf("[GroupMemberManagement] 发送入群验证题失败 (用户 %d): %v", member.ID, err)
+		releaseMember(bot, group.ChatID, member.ID)
+		return
+	}
+
+	err = core.DB.SaveChallenge(core.JoinChallenge{
+		ChatID:    group.ChatID,
+		UserID:    member.ID,
+		UserName:  moderation.DisplayName(&member),
+		Kind:      group.Captcha,
+		Answer:    question.answer,
+		MessageID: sent.MessageID,
+		ExpiresAt: time.Now().Add(window),
+		FirstJoin: firstJoin,
+	})
+	if err != nil {
+		log.Printf("[GroupMemberManagement] 保存入群验证失败 (用户 %d): %v", member.ID, err)
+		core.DeleteMessages(bot, group.ChatID, sent.MessageID)
+		releaseMember(bot, group.ChatID, member.ID)
+		return
+	}
+	log.Printf("[GroupMemberManagement] 用户 %d 加入群 %d, 已发出 %s 验证题", member.ID, group.ChatID, group.Captcha)
+}
+
+// captchaKeyboard 构造验证题按钮
+func captchaKeyboard(userID int64, options []string) tgbotapi.InlineKeyboardMarkup {
+	perRow := len(options)
+	if perRow > emojiPerRow && perRow%emojiPerRow == 0 {
+		perRow = emojiPerRow
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for i, option := range options {
+		data := fmt.Sprintf("%s%d:%d", captchaCallbackPrefix, userID, i)
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(option, data))
+		if len(row) == perRow {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// IsCaptchaCallback 判断回调是否属于入群验证
+func IsCaptchaCallback(data string) bool {
+	return strings.HasPrefix(data, captchaCallbackPrefix)
+}
+
+// HandleCaptchaCallback 处理验证按钮点击。只有被验证的本人可以作答, 其他人点了只会收到提示。
+func HandleCaptchaCallback(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery) {
+	userID, choice, err := parseCaptchaCallback(query.Data)
+	if err != nil || query.Message == nil || query.From == nil {
+		answerCallback(bot, query.ID, "无效的操作")
+		return
+	}
+	if query.From.ID != userID {
+		answerCallback(bot, query.ID, "这不是给你的验证题")
+		return
+	}
+	chatID := query.Message.Chat.ID
+
+	challenge, ok, err := core.DB.GetChallenge(chatID, userID)
+	if err != nil {
+		log.Printf("[GroupMemberManagement] 读取入群验证失败 (用户 %d): %v", userID, err)
+		answerCallback(bot, query.ID, "操作失败，请稍后再试")
+		return
+	}
+	if !ok || !claimChallenge(challenge) {
+		answerCallback(bot, query.ID, "验证已结束")
+		return
+	}
+	core.DeleteMessages(bot, chatID, challenge.MessageID)
+
+	if choice != challenge.Answer {
+		answerCallback(bot, query.ID, "答案不正确")
+		kickChallenged(bot, challenge, "答错")
+		return
+	}
+
+	releaseMember(bot, chatID, userID)
+	if err := core.DB.RecordVerification(userID, chatID, challenge.FirstJoin); err != nil {
+		log.Printf("[GroupMemberManagement] 记录用户 %d 通过验证失败: %v", userID, err)
+	}
+	answerCallback(bot, query.ID, "验证通过，欢迎！")
+	log.Printf("[GroupMemberManagement] 用户 %d 通过了群 %d 的入群验证", userID, chatID)
+}
+
+// SweepExpiredChallenges 移出超时未作答的成员, 返回处理的人数; 由定时任务周期调用
+func SweepExpiredChallenges(bot *tgbotapi.BotAPI) int {
+	challenges, err := core.DB.ExpiredChallenges(time.Now())
+	if err != nil {
+		log.Printf("[GroupMemberManagement] 读取超时的入群验证失败: %v", err)
+		return 0
+	}
+
+	swept := 0
+	for _, challenge := range challenges {
+		if !claimChallenge(challenge) {
+			continue
+		}
+		core.DeleteMessages(bot, challenge.ChatID, challenge.MessageID)
+		kickChallenged(bot, challenge, "超时")
+		swept++
+	}
+	return swept
+}
+
+// claimChallenge 认领一道验证题; 作答与超时清理同时发生时只有一方能认领成功
+func claimChallenge(challenge core.JoinChallenge) bool {
+	claimed, err := core.DB.ClaimChallenge(challenge.ChatID, challenge.UserID)
+	if err != nil {
+		log.Printf("[GroupMemberManagement] 认领入群验证失败 (用户 %d): %v", challenge.UserID, err)
+		return false
+	}
+	return claimed
+}
+
+// kickChallenged 把没通过验证的成员移出群; 只踢不封, 真人误点了还能重新进群再答一次
+func kickChallenged(bot *tgbotapi.BotAPI, challenge core.JoinChallenge, reason string) {
+	if err := core.KickUser(bot, challenge.ChatID, challenge.UserID); err != nil {
+		log.Printf("[GroupMemberManagement] 移出未通过验证的用户 %d 失败: %v", challenge.UserID, err)
+		return
+	}
+	log.Printf("[GroupMemberManagement] 用户 %d(%s) 入群验证%s, 已移出群 %d",
+		challenge.UserID, challenge.UserName, reason, challenge.ChatID)
+}
+
+// releaseMember 解除入群验证时加的禁言
+func releaseMember(bot *tgbotapi.BotAPI, chatID, userID int64) {
+	if err := core.UnmuteUser(bot, chatID, userID); err != nil {
+		log.Printf("[GroupMemberManagement] 解除用户 %d 的禁言失败: %v", userID, err)
+	}
+}
+
+// parseCaptchaCallback 解析 "cap:<用户 ID>:<选项下标>"
+func parseCaptchaCallback(data string) (int64, int, error) {
+	userPart, choicePart, ok := strings.Cut(strings.TrimPrefix(data, captchaCallbackPrefix), ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("格式错误: %q", data)
+	}
+	userID, err := strconv.ParseInt(userPart, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	choice, err := strconv.Atoi(choicePart)
+	if err != nil {
+		return 0, 0, err
+	}
+	return userID, choice, nil
+}
+
+// windowLabel 验证时限的中文展示, 整分钟按分钟说, 否则按秒说
+func windowLabel(d time.Duration) string {
+	if d%time.Minute == 0 {
+		return fmt.Sprintf("%d 分钟", d/time.Minute)
+	}
+	return fmt.Sprintf("%d 秒", d/time.Second)
+}
+
+func answerCallback(bot *tgbotapi.BotAPI, queryID, text string) {
+	if _, err := bot.Request(tgbotapi.NewCallback(queryID, text)); err != nil {
+		log.Printf("[GroupMemberManagement] 回应回调失败: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package group_member_management
+
+import (
+	"testing"
+
+	"SunaiForum-Bot/core"
+)
+
+// TestNewQuestion 正确答案必须落在选项里, 且选项互不重复, 否则会出现无解或多解的题
+func TestNewQuestion(t *testing.T) {
+	for _, kind := range []string{core.CaptchaMath, core.CaptchaEmoji} {
+		for range 200 {
+			question := newQuestion(kind)
+			if question.answer < 0 || question.answer >= len(question.options) {
+				t.Fatalf("%s 题的答案下标 %d 越界: %+v", kind, question.answer, question)
+			}
+			seen := make(map[string]bool, len(question.options))
+			for _, option := range question.options {
+				if seen[option] {
+					t.Fatalf("%s 题出现重复选项: %+v", kind, question)
+				}
+				seen[option] = true
+			}
+		}
+	}
+	if got := len(newQuestion(core.CaptchaEmoji).options); got != emojiOptions {
+		t.Errorf("表情题选项数 = %d, 期望 %d", got, emojiOptions)
+	}
+}
+
+// TestCaptchaCallbackRoundTrip 按钮里编码的用户与选项能原样解析回来
+func TestCaptchaCallbackRoundTrip(t *testing.T) {
+	keyboard := captchaKeyboard(5912366993, []string{"a", "b", "c", "d", "e", "f"})
+	if len(keyboard.InlineKeyboard) != 2 {
+		t.Fatalf("6 个选项应排成 2 行, 实际 %d 行", len(keyboard.InlineKeyboard))
+	}
+
+	data := *keyboard.InlineKeyboard[1][2].CallbackData
+	if !IsCaptchaCallback(data) {
+		t.Fatalf("%q 应被识别为验证回调", data)
+	}
+	userID, choice, err := parseCaptchaCallback(data)
+	if err != nil || userID != 5912366993 || choice != 5 {
+		t.Errorf("parseCaptchaCallback(%q) = %d,%d,%v", data, userID, choice, err)
+	}
+
+	if _, _, err := parseCaptchaCallback("cap:abc"); err == nil {
+		t.Error("格式错误的回调应当报错")
+	}
+}
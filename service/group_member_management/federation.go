@@ -0,0 +1,35 @@
+package group_member_management
+
+// 封禁名单的入群拦截: 名单上的账号加入任一成员群时立即踢出, 不给它发第一条广告的机会
+import (
+	"log"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// kickIfListed 新成员在封禁名单上时将其封禁, 返回是否已处理。
+// 只在加入了名单的群执行; 查库或封禁失败都按未处理返回, 交给后续的入群验证兜底。
+func kickIfListed(bot *tgbotapi.BotAPI, group core.ManagedGroup, member tgbotapi.User) bool {
+	if !group.Federation {
+		return false
+	}
+
+	entry, listed, err := core.DB.GetBannedUser(member.ID)
+	if err != nil {
+		log.Printf("[GroupMemberManagement] 查询封禁名单失败 (用户 %d): %v", member.ID, err)
+		return false
+	}
+	if !listed {
+		return false
+	}
+
+	if err := core.BanUser(bot, group.ChatID, member.ID); err != nil {
+		log.Printf("[GroupMemberManagement] 踢出名单用户 %d 失败: %v", member.ID, err)
+		return false
+	}
+	log.Printf("[GroupMemberManagement] 名单用户 %d(%s) 加入群 %d, 已踢出; 入名单原因: %s",
+		member.ID, member.UserName, group.ChatID, entry.Reason)
+	return true
+}
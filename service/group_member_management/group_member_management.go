@@ -1,12 +1,14 @@
 package group_member_management
 
-// 群成员管理: 管理员回复某条消息发 /ban 即可删消息并永久封禁其作者
+// 群成员管理: 版主或所有者回复某条消息发 /ban 即可删消息并永久封禁其作者;
+// 所在群加入了封禁名单时, 封禁同步到其余成员群 (见 core/federation.go)
 import (
 	"fmt"
 	"log"
 	"time"
 
 	"SunaiForum-Bot/core"
+	"SunaiForum-Bot/service/moderation"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -17,7 +19,7 @@ const noticeTTL = 3 * time.Minute
 // HandleBanCommand 处理管理员对某条消息的 /ban 回复:
 // 删除被回复的原消息、永久踢出其作者, 并在群内留一条限时提示
 func HandleBanCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
-	if message.From == nil || !core.IsAdmin(message.From.ID) {
+	if message.From == nil || !core.Can(message.From.ID, core.PermModerate) {
 		return
 	}
 	// 被回复的消息可能来自匿名管理员或频道身份, 此时拿不到可封禁的用户
@@ -30,21 +32,36 @@ func HandleBanCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
 
 	core.DeleteMessages(bot, chatID, message.ReplyToMessage.MessageID)
 
-	kickConfig := tgbotapi.KickChatMemberConfig{
-		ChatMemberConfig: tgbotapi.ChatMemberConfig{
-			ChatID: chatID,
-			UserID: userToBan.ID,
-		},
-		UntilDate: 0, // 0 表示永久封禁
+	// 被封的是图片或贴纸广告时记下特征, 之后别的账号再发同一张图直接处置
+	if learned := moderation.LearnMedia(bot, message.ReplyToMessage, message.From.ID); learned > 0 {
+		log.Printf("[GroupMemberManagement] 已记录 %d 条图片特征", learned)
 	}
-	if _, err := bot.Request(kickConfig); err != nil {
+
+	// 以频道身份发的消息, From 只是占位账号, 要封的是频道身份
+	if moderation.SentAsChannel(message.ReplyToMessage) {
+		banSenderChat(bot, message)
+		return
+	}
+
+	if err := core.BanUser(bot, chatID, userToBan.ID); err != nil {
 		log.Printf("[GroupMemberManagement] 封禁用户 %d 失败: %v", userToBan.ID, err)
 		return
 	}
 	log.Printf("[GroupMemberManagement] 已封禁用户 %s (ID: %d)", userToBan.UserName, userToBan.ID)
 
-	notice := tgbotapi.NewMessage(chatID, fmt.Sprintf("用户 %s 已被封禁并踢出群组。", userToBan.UserName))
-	sentMsg, err := bot.Send(notice)
+	text := fmt.Sprintf("用户 %s 已被封禁并踢出群组。", userToBan.UserName)
+	synced := core.FederateBan(bot, core.BannedUser{
+		UserID:   userToBan.ID,
+		UserName: moderation.DisplayName(userToBan),
+		Reason:   "管理员 /ban",
+		ChatID:   chatID,
+		BannedBy: message.From.ID,
+	})
+	if synced > 0 {
+		text += fmt.Sprintf("\n已同步封禁到其余 %d 个群。", synced)
+	}
+
+	sentMsg, err := bot.Send(tgbotapi.NewMessage(chatID, text))
 	if err != nil {
 		log.Printf("[GroupMemberManagement] 发送封禁提示失败: %v", err)
 		return
@@ -53,3 +70,23 @@ func HandleBanCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
 	core.DeleteMessageAfterDelay(bot, chatID, sentMsg.MessageID, noticeTTL)
 	core.DeleteMessageAfterDelay(bot, chatID, message.MessageID, noticeTTL)
 }
+
+// banSenderChat 处理对频道身份消息的 /ban: 封禁该频道身份, 只在本群生效, 不进封禁名单
+func banSenderChat(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	senderChat := message.ReplyToMessage.SenderChat
+
+	if err := core.BanSenderChat(bot, chatID, senderChat.ID); err != nil {
+		log.Printf("[GroupMemberManagement] 封禁频道身份 %d 失败: %v", senderChat.ID, err)
+		return
+	}
+	log.Printf("[GroupMemberManagement] 已封禁频道身份 %s (ID: %d)", senderChat.Title, senderChat.ID)
+
+	sentMsg, err := bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("频道 %s 已被封禁，不能再以该频道身份发言。", senderChat.Title)))
+	if err != nil {
+		log.Printf("[GroupMemberManagement] 发送封禁提示失败: %v", err)
+		return
+	}
+	core.DeleteMessageAfterDelay(bot, chatID, sentMsg.MessageID, noticeTTL)
+	core.DeleteMessageAfterDelay(bot, chatID, message.MessageID, noticeTTL)
+}
@@ -29,12 +29,12 @@ func IsServiceMessage(message *tgbotapi.Message) bool {
 }
 
 // CleanServiceMessage 删除群务通知, 返回是否处理了本条消息。
-// 由 DeleteServiceMessages 开关控制; 机器人没有删除权限时只记日志, 不中断后续流程。
+// 由所在群的 DeleteServiceMessages 设置控制; 机器人没有删除权限时只记日志, 不中断后续流程。
 func CleanServiceMessage(bot *tgbotapi.BotAPI, message *tgbotapi.Message) bool {
 	if !IsServiceMessage(message) {
 		return false
 	}
-	if !core.DeleteServiceMessages {
+	if group, ok := core.GroupSettings(message.Chat.ID); !ok || !group.DeleteServiceMessages {
 		return true // 仍然算作已处理: 群务通知没有正文, 不需要走后续的内容审核
 	}
 
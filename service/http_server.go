@@ -0,0 +1,65 @@
+package service
+
+// HTTP 监听: 网页管理面板、管理 API 与 Prometheus 指标共用一个端口, 都默认关闭。
+// 设置了 HTTP_ADDR 才监听; API 挂在 /api/ 下, 指标在 /metrics, 其余路径交给面板。
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"SunaiForum-Bot/core"
+	"SunaiForum-Bot/service/api"
+	"SunaiForum-Bot/service/dashboard"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// RunHTTPServer 按配置挂载面板、API 与指标并阻塞监听; 没有配置监听地址或一样都没开时直接返回
+func RunHTTPServer(bot *tgbotapi.BotAPI) {
+	if core.HTTPAddr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mounted := 0
+	if core.APIToken != "" {
+		mux.Handle("/api/", api.New(bot, core.APIToken))
+		mounted++
+		log.Println("[HTTP] 管理 API 已挂载于 /api/v1")
+	}
+	if core.MetricsEnabled {
+		mux.Handle("GET /metrics", promhttp.Handler())
+		mounted++
+		log.Println("[HTTP] Prometheus 指标已挂载于 /metrics")
+	}
+	if core.DashboardToken != "" || core.DashboardTelegramLogin {
+		panel, err := dashboard.New(bot, dashboard.Options{
+			Token:         core.DashboardToken,
+			TelegramLogin: core.DashboardTelegramLogin,
+			BotToken:      core.BotToken,
+			BotName:       bot.Self.UserName,
+		})
+		if err != nil {
+			log.Printf("[HTTP] 网页面板初始化失败: %v", err)
+		} else {
+			mux.Handle("/", panel)
+			mounted++
+			log.Println("[HTTP] 网页面板已挂载")
+		}
+	}
+	if mounted == 0 {
+		log.Println("[HTTP] 设置了 HTTP_ADDR 但面板、API 与指标都没有开启, 不监听")
+		return
+	}
+
+	server := &http.Server{
+		Addr:              core.HTTPAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("[HTTP] 监听 %s", core.HTTPAddr)
+	if err := server.ListenAndServe(); err != nil {
+		log.Printf("[HTTP] 监听已停止: %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package service
+
+// 消息主循环的 Prometheus 指标, 注册说明见 core/metrics.go
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var (
+	updatesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sunai_updates_processed_total",
+		Help: "处理的更新数, 按类型 (message / edited_message / callback_query / other)",
+	}, []string{"type"})
+	reconnectsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sunai_reconnects_total",
+		Help: "长轮询断开后重连的次数 (webhook 模式下不计)",
+	})
+)
+
+// updateType 更新的类型标签, 与 handleUpdate 的分流对应
+func updateType(update tgbotapi.Update) string {
+	switch {
+	case update.Message != nil:
+		return "message"
+	case update.EditedMessage != nil:
+		return "edited_message"
+	case update.CallbackQuery != nil:
+		return "callback_query"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,155 @@
+package moderation
+
+// 关键词多模式匹配: Aho–Corasick 自动机。
+//
+// 逐词 strings.Contains 的开销是 消息数 × 词数, 而 AI 会持续往词表里加词, 越用越慢。
+// 自动机把全部归一化后的关键词预先编进一棵 trie, 一条消息只需从头到尾扫一遍, 耗时与词数基本无关。
+// 自动机挂在 core 的词表缓存上 (ScopeKeywordIndex), 只在词表变化时重建一次。
+import "SunaiForum-Bot/core"
+
+// keywordIndex 一个作用域全部关键词编成的自动机, 构建后只读, 可被多条消息并发使用
+type keywordIndex struct {
+	nodes []acNode
+	// words 原始关键词, 下标即模式 ID; 多个写法归一化后相同的词各占一个 ID, 命中时都会报告
+	words []string
+	// shadow 处于试行模式的词; 词在库里唯一, 按词本身索引即可
+	shadow map[string]bool
+}
+
+// acNode 自动机节点
+type acNode struct {
+	next map[rune]int32
+	// fail 失配时跳转的节点: 当前路径的最长真后缀所对应的节点
+	fail int32
+	// out 恰好在本节点结束的模式
+	out []int32
+	// dict 沿 fail 链往上第一个有 out 的节点, -1 表示没有; 省得每个位置都走完整条 fail 链
+	dict int32
+}
+
+// buildScopeIndex 用一个作用域的词条构建自动机: 停用的词不编入, 试行的词单独标记
+func buildScopeIndex(entries []core.Keyword) *keywordIndex {
+	words := make([]string, 0, len(entries))
+	shadow := make(map[string]bool)
+	for _, entry := range entries {
+		switch entry.Mode {
+		case core.ModeOff:
+			continue
+		case core.ModeShadow:
+			shadow[entry.Word] = true
+		}
+		words = append(words, entry.Word)
+	}
+
+	index := newKeywordIndex(words)
+	index.shadow = shadow
+	return index
+}
+
+// newKeywordIndex 用原始关键词构建自动机; 关键词在这里统一归一化, 归一化后为空的跳过
+func newKeywordIndex(keywords []string) *keywordIndex {
+	index := &keywordIndex{nodes: []acNode{{dict: -1}}}
+
+	for _, keyword := range keywords {
+		pattern := Normalize(keyword)
+		if pattern == "" {
+			continue
+		}
+
+		state := int32(0)
+		for _, r := range pattern {
+			next, ok := index.nodes[state].next[r]
+			if !ok {
+				next = int32(len(index.nodes))
+				index.nodes = append(index.nodes, acNode{dict: -1})
+				if index.nodes[state].next == nil {
+					index.nodes[state].next = make(map[rune]int32)
+				}
+				index.nodes[state].next[r] = next
+			}
+			state = next
+		}
+		index.nodes[state].out = append(index.nodes[state].out, int32(len(index.words)))
+		index.words = append(index.words, keyword)
+	}
+
+	index.buildLinks()
+	return index
+}
+
+// buildLinks 按广度优先补齐 fail 与 dict 链接; 浅层节点先处理, 保证深层节点用到的链接已就绪
+func (x *keywordIndex) buildLinks() {
+	queue := make([]int32, 0, len(x.nodes))
+	for _, child := range x.nodes[0].next {
+		queue = append(queue, child)
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for r, child := range x.nodes[current].next {
+			queue = append(queue, child)
+
+			fail := x.nodes[current].fail
+			for fail != 0 && !x.has(fail, r) {
+				fail = x.nodes[fail].fail
+			}
+			if target, ok := x.nodes[fail].next[r]; ok && target != child {
+				x.nodes[child].fail = target
+			}
+
+			failNode := x.nodes[child].fail
+			if len(x.nodes[failNode].out) > 0 {
+				x.nodes[child].dict = failNode
+			} else {
+				x.nodes[child].dict = x.nodes[failNode].dict
+			}
+		}
+	}
+}
+
+func (x *keywordIndex) has(state int32, r rune) bool {
+	_, ok := x.nodes[state].next[r]
+	return ok
+}
+
+// find 在已归一化的文本里找出全部命中的关键词, 按首次出现的先后返回原始形态, 每个词只报一次
+func (x *keywordIndex) find(normalized string) []string {
+	if len(x.words) == 0 || normalized == "" {
+		return nil
+	}
+
+	var (
+		hits []string
+		seen map[int32]bool
+	)
+	report := func(ids []int32) {
+		for _, id := range ids {
+			if seen[id] {
+				continue
+			}
+			if seen == nil {
+				seen = make(map[int32]bool)
+			}
+			seen[id] = true
+			hits = append(hits, x.words[id])
+		}
+	}
+
+	state := int32(0)
+	for _, r := range normalized {
+		for state != 0 && !x.has(state, r) {
+			state = x.nodes[state].fail
+		}
+		if next, ok := x.nodes[state].next[r]; ok {
+			state = next
+		}
+
+		report(x.nodes[state].out)
+		for d := x.nodes[state].dict; d != -1; d = x.nodes[d].dict {
+			report(x.nodes[d].out)
+		}
+	}
+	return hits
+}
@@ -0,0 +1,133 @@
+package moderation
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeywordIndexReportsEveryHit(t *testing.T) {
+	// 经典的重叠用例: 一个词是另一个词的后缀, 或者互相交叠
+	index := newKeywordIndex([]string{"he", "she", "his", "hers"})
+	got := index.find("ushers")
+	want := []string{"she", "he", "hers"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("find(ushers) = %v, 期望 %v", got, want)
+	}
+
+	// 同一个词出现多次只报一次
+	if got := index.find("hehehe"); !reflect.DeepEqual(got, []string{"he"}) {
+		t.Errorf("find(hehehe) = %v, 期望 [he]", got)
+	}
+}
+
+func TestKeywordIndexNormalizesKeywords(t *testing.T) {
+	// 词表里的繁体、分隔符写法与简体写法归一化后相同, 各自都要报告, 命中次数才能记到对应的行上
+	index := newKeywordIndex([]string{"水·果·機", "水果机", "特价", "·"})
+	got := index.find(Normalize("低..出·全·新·水..果·機, 特價"))
+	want := []string{"水·果·機", "水果机", "特价"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("find = %v, 期望 %v", got, want)
+	}
+
+	if got := newKeywordIndex(nil).find("任何文本"); got != nil {
+		t.Errorf("空词表 find = %v, 期望 nil", got)
+	}
+}
+
+// TestKeywordIndexMatchesNaive 随机词表下自动机与逐词比对的结果必须一致
+func TestKeywordIndexMatchesNaive(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	keywords := randomKeywords(rng, 500, "水果机特价出售私聊", 1, 4)
+	index := newKeywordIndex(keywords)
+
+	for i := 0; i < 200; i++ {
+		text := randomKeywords(rng, 1, "水果机特价出售私聊好的", 5, 30)[0]
+		got := index.find(text)
+		want := naiveMatch(text, keywords)
+		if !sameSet(got, want) {
+			t.Fatalf("文本 %q: 自动机 %v, 逐词比对 %v", text, got, want)
+		}
+	}
+}
+
+// 基准: 1 万个关键词下, 逐词 Normalize + Contains 与自动机的耗时对比
+//
+//	go test ./service/moderation -bench KeywordMatch -benchmem
+func BenchmarkKeywordMatchNaive(b *testing.B) {
+	keywords, text := benchmarkCorpus()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		naiveMatch(text, keywords)
+	}
+}
+
+func BenchmarkKeywordMatchAutomaton(b *testing.B) {
+	keywords, text := benchmarkCorpus()
+	index := newKeywordIndex(keywords)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.find(Normalize(text))
+	}
+}
+
+func BenchmarkKeywordIndexBuild(b *testing.B) {
+	keywords, _ := benchmarkCorpus()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		newKeywordIndex(keywords)
+	}
+}
+
+// benchmarkCorpus 1 万个 2-6 字的随机中文关键词, 以及一条正常长度的群消息
+func benchmarkCorpus() ([]string, string) {
+	rng := rand.New(rand.NewSource(42))
+	const alphabet = "的一是在不了有和人这中大为上个国我以要他时来用们生到作地于出就分对成会可主发年动同工也能下过子说产种面而方后多定行学法所民得经十三之进着等部度家电力里如水化高自二理起小物现实加量都两体制机当使点从业本去把性好应开它合还因由其些然前外天政四日那社义事平形相全表间样与关各重新线内数正心反你明看原又么利比或但质气第向道命此变条只没结解问意建月公无系军很情者最立代想已通并提直题党程展五果料象员革位入常文总次品式活设及管特件长求老头基资边流路级少图山统接知较将组见计别她手角期根论运农指几九区强放决西被干做必战先回则任取据处府研"
+	keywords := randomKeywords(rng, 10000, alphabet, 2, 6)
+	text := strings.Repeat("今天群里有人问水果机的价格，大家注意甄别，不要私下交易。", 3)
+	return keywords, text
+}
+
+func randomKeywords(rng *rand.Rand, n int, alphabet string, minLen, maxLen int) []string {
+	runes := []rune(alphabet)
+	keywords := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		length := minLen + rng.Intn(maxLen-minLen+1)
+		var b strings.Builder
+		for j := 0; j < length; j++ {
+			b.WriteRune(runes[rng.Intn(len(runes))])
+		}
+		keywords = append(keywords, b.String())
+	}
+	return keywords
+}
+
+// naiveMatch 自动机之前的做法: 每条消息把每个词归一化一遍再逐个 Contains
+func naiveMatch(text string, keywords []string) []string {
+	normalized := Normalize(text)
+	var hits []string
+	for _, keyword := range keywords {
+		if k := Normalize(keyword); k != "" && strings.Contains(normalized, k) {
+			hits = append(hits, keyword)
+		}
+	}
+	return hits
+}
+
+// sameSet 比较两组命中是否相同; 随机词表可能有重复词, 按出现次数比较
+func sameSet(a, b []string) bool {
+	count := make(map[string]int)
+	for _, s := range a {
+		count[s]++
+	}
+	for _, s := range b {
+		count[s]--
+	}
+	for _, n := range count {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
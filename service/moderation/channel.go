@@ -0,0 +1,150 @@
+package moderation
+
+// 消息来源的审核: 转发自频道的消息与以频道身份发出的消息。
+//
+// 这两类消息的"发送者"实际是一个频道, 光看正文不够 —— 广告频道的名字本身往往就是广告。
+// 来源频道按黑白名单 (见 core/db_channel.go) 与词表检查频道名和用户名;
+// 以频道身份发言的消息被处置时封禁的是该频道身份, 而不是 Telegram 为兼容塞进 From 的占位账号。
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// 来源频道相关的规则 ID
+const (
+	RuleIDSourceChannel = "channel"     // 来源频道在黑名单上
+	RuleIDChannelName   = "channelname" // 来源频道的名字或用户名命中词表
+)
+
+// 规则标识
+const (
+	ruleSourceChannel = "来源频道黑名单"
+	ruleChannelName   = "来源频道关键词"
+)
+
+// SentAsChannel 判断消息是否以频道身份发出。
+// 匿名管理员 (以本群身份发言) 与关联频道自动转发进讨论组的帖子不算: 前者是管理员, 后者是本群自己的频道。
+func SentAsChannel(message *tgbotapi.Message) bool {
+	chat := message.SenderChat
+	return chat != nil && chat.ID != message.Chat.ID && !message.IsAutomaticForward
+}
+
+// ExemptSender 匿名管理员与关联频道的自动转发不参与审核
+func ExemptSender(message *tgbotapi.Message) bool {
+	chat := message.SenderChat
+	return chat != nil && (chat.ID == message.Chat.ID || message.IsAutomaticForward)
+}
+
+// Sender 返回消息在审核意义上的发送者。以频道身份发言时用频道构造一个用户:
+// ID 是频道 ID (负数, 不会和真实用户撞上), 记分、处置记录与通知都落在这个频道身份上。
+func Sender(message *tgbotapi.Message) *tgbotapi.User {
+	if SentAsChannel(message) {
+		chat := message.SenderChat
+		return &tgbotapi.User{ID: chat.ID, FirstName: chat.Title, UserName: chat.UserName}
+	}
+	return message.From
+}
+
+// messageOrigins 消息涉及的来源频道: 转发来源与频道身份, 去重
+func messageOrigins(message *tgbotapi.Message) []*tgbotapi.Chat {
+	var origins []*tgbotapi.Chat
+	if message.ForwardFromChat != nil {
+		origins = append(origins, message.ForwardFromChat)
+	}
+	if SentAsChannel(message) && (len(origins) == 0 || origins[0].ID != message.SenderChat.ID) {
+		origins = append(origins, message.SenderChat)
+	}
+	return origins
+}
+
+// channelLabel 频道的展示名, 如 "某某频道 (@spam, -100123)"
+func channelLabel(chat *tgbotapi.Chat) string {
+	title := chat.Title
+	if title == "" {
+		title = "（无名频道）"
+	}
+	if chat.UserName != "" {
+		return fmt.Sprintf("%s (@%s, %d)", title, chat.UserName, chat.ID)
+	}
+	return fmt.Sprintf("%s (%d)", title, chat.ID)
+}
+
+// channelList 查频道所在的名单, 不在任何名单上返回空串; 查库失败按不在名单处理
+func channelList(chat *tgbotapi.Chat) string {
+	entry, ok, err := core.DB.LookupSourceChannel(chat.ID, chat.UserName)
+	if err != nil {
+		log.Printf("[Moderation] 查询来源频道名单失败: %v", err)
+		return ""
+	}
+	if !ok {
+		return ""
+	}
+	return entry.List
+}
+
+// InspectOrigins 检查消息的来源频道, 无副作用; 返回首个正式执行的结论, 没有时返回首个试行结论。
+// 白名单上的频道整个跳过; 黑名单先于词表检查, 命中黑名单的频道不必再匹配名字。
+func InspectOrigins(chatID int64, topicID int, origins []*tgbotapi.Chat) Verdict {
+	var shadow Verdict
+	// note 记下一条结论, 返回是否是正式执行的结论
+	note := func(v Verdict) bool {
+		if !v.Shadow {
+			return true
+		}
+		if !shadow.Hit {
+			shadow = v
+		}
+		return false
+	}
+
+	for _, chat := range origins {
+		switch channelList(chat) {
+		case core.ChannelAllow:
+			continue
+		case core.ChannelBlock:
+			if mode := RuleMode(RuleIDSourceChannel); mode != core.ModeOff {
+				v := Verdict{Hit: true, Rule: ruleSourceChannel, Detail: channelLabel(chat), Shadow: mode == core.ModeShadow}
+				if note(v) {
+					return v
+				}
+				continue
+			}
+		}
+
+		keywords, err := keywordsFor(chatID, topicID)
+		if err != nil {
+			log.Printf("[Moderation] 读取关键词失败, 跳过来源频道检查: %v", err)
+			return shadow
+		}
+		name := strings.TrimSpace(chat.Title + " " + chat.UserName)
+		enforced, shadowHit := matchText(keywords, name, ruleChannelName)
+		if enforced.Hit {
+			return enforced
+		}
+		if shadowHit.Hit {
+			note(shadowHit)
+		}
+	}
+	return shadow
+}
+
+// forwardAllowed 转发来源在白名单上; 新成员限制据此放行官方频道的转发
+func forwardAllowed(message *tgbotapi.Message) bool {
+	return message.ForwardFromChat != nil && channelList(message.ForwardFromChat) == core.ChannelAllow
+}
+
+// banSenderChat 封禁以频道身份发言的频道, 返回实际执行的处罚; 失败时降级为只删消息
+func banSenderChat(bot *tgbotapi.BotAPI, message *tgbotapi.Message) core.Penalty {
+	chat := message.SenderChat
+	if err := core.BanSenderChat(bot, message.Chat.ID, chat.ID); err != nil {
+		log.Printf("[Moderation] 封禁频道身份 %d 失败: %v", chat.ID, err)
+		return core.Penalty{Action: core.PenaltyDelete}
+	}
+	log.Printf("[Moderation] 已封禁频道身份 %s", channelLabel(chat))
+	return core.Penalty{Action: core.PenaltyBan}
+}
@@ -0,0 +1,69 @@
+package moderation
+
+import (
+	"testing"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TestSenderIdentity 频道身份按频道处理; 匿名管理员与关联频道的自动转发豁免
+func TestSenderIdentity(t *testing.T) {
+	group := &tgbotapi.Chat{ID: -100}
+	placeholder := &tgbotapi.User{ID: 136817688, UserName: "Channel_Bot"}
+
+	asChannel := &tgbotapi.Message{Chat: group, From: placeholder, SenderChat: &tgbotapi.Chat{ID: -200, Title: "水果特价", UserName: "fruit"}}
+	if !SentAsChannel(asChannel) || ExemptSender(asChannel) {
+		t.Error("以频道身份发言应当参与审核")
+	}
+	if sender := Sender(asChannel); sender.ID != -200 || DisplayName(sender) != "水果特价 fruit" {
+		t.Errorf("Sender = %+v, 期望频道本身", sender)
+	}
+
+	anonymousAdmin := &tgbotapi.Message{Chat: group, SenderChat: group}
+	linkedPost := &tgbotapi.Message{Chat: group, SenderChat: &tgbotapi.Chat{ID: -300}, IsAutomaticForward: true}
+	for _, message := range []*tgbotapi.Message{anonymousAdmin, linkedPost} {
+		if SentAsChannel(message) || !ExemptSender(message) {
+			t.Errorf("消息 %+v 应当豁免", message)
+		}
+	}
+
+	// 转发来源与频道身份相同时只检查一次
+	asChannel.ForwardFromChat = asChannel.SenderChat
+	if origins := messageOrigins(asChannel); len(origins) != 1 {
+		t.Errorf("messageOrigins = %d 个, 期望 1", len(origins))
+	}
+}
+
+// TestInspectOrigins 黑名单按 ID 或用户名命中, 白名单跳过名字检查, 其余频道名走词表
+func TestInspectOrigins(t *testing.T) {
+	db := useTempDB(t)
+	db.AddKeyword("水果", core.SourceManual)
+	db.SetSourceChannel(core.SourceChannel{Ref: "-1001", List: core.ChannelBlock})
+	db.SetSourceChannel(core.SourceChannel{Ref: "spamhub", List: core.ChannelBlock})
+	db.SetSourceChannel(core.SourceChannel{Ref: "fruitnews", List: core.ChannelAllow})
+
+	cases := []struct {
+		name string
+		chat tgbotapi.Chat
+		rule string
+	}{
+		{"黑名单 ID", tgbotapi.Chat{ID: -1001, Title: "正常名字"}, ruleSourceChannel},
+		{"黑名单用户名", tgbotapi.Chat{ID: -1002, UserName: "SpamHub"}, ruleSourceChannel},
+		{"白名单不查名字", tgbotapi.Chat{ID: -1003, Title: "水果资讯", UserName: "FruitNews"}, ""},
+		{"频道名命中词表", tgbotapi.Chat{ID: -1004, Title: "水果特价群"}, ruleChannelName},
+		{"普通频道", tgbotapi.Chat{ID: -1005, Title: "天气预报"}, ""},
+	}
+	for _, c := range cases {
+		if v := InspectOrigins(0, 0, []*tgbotapi.Chat{&c.chat}); v.Rule != c.rule {
+			t.Errorf("%s: 规则 = %q, 期望 %q", c.name, v.Rule, c.rule)
+		}
+	}
+
+	// 转为试行后只给出试行结论
+	db.SetRuleMode(RuleIDSourceChannel, core.ModeShadow)
+	if v := InspectOrigins(0, 0, []*tgbotapi.Chat{{ID: -1001}}); !v.Hit || !v.Shadow {
+		t.Errorf("试行模式下的黑名单结论 = %+v", v)
+	}
+}
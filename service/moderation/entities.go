@@ -0,0 +1,169 @@
+package moderation
+
+// 正文之外的"隐藏文本": 挂在文字上的超链接 (text_link)、@提及、内联按钮的文字与链接、via 机器人。
+//
+// 这些内容不在 Text / Caption 里, 但广告的落地链接和引流账号往往就藏在这里 ——
+// 正文写"点这里", 链接挂在实体上; 或者借 via 机器人附一排按钮。
+// 每段隐藏文本单独匹配词表并标注来源, 管理员在通知里能看到"实体中的隐藏链接：xxx"这样的细节。
+// 只跑词表与规则, 不跑零宽、拆字这类针对正文书写方式的规则: 链接里的点和斜杠本来就是分隔符。
+import (
+	"strings"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// 隐藏文本的来源标注, 写进 Verdict.Part 与 Detail
+const (
+	partHiddenLink = "实体中的隐藏链接"
+	partMention    = "提及的账号"
+	partButton     = "按钮文字"
+	partButtonURL  = "按钮链接"
+	partViaBot     = "via 机器人"
+)
+
+// textPart 一段带来源标注的隐藏文本
+type textPart struct {
+	label string
+	text  string
+}
+
+// hiddenParts 取出消息里全部隐藏文本, 同一来源的相同内容只保留一份
+func hiddenParts(message *tgbotapi.Message) []textPart {
+	var parts []textPart
+	seen := make(map[textPart]bool)
+	add := func(label, text string) {
+		part := textPart{label: label, text: strings.TrimSpace(text)}
+		if part.text == "" || seen[part] {
+			return
+		}
+		seen[part] = true
+		parts = append(parts, part)
+	}
+
+	for _, source := range []struct {
+		text     string
+		entities []tgbotapi.MessageEntity
+	}{{message.Text, message.Entities}, {message.Caption, message.CaptionEntities}} {
+		for _, entity := range source.entities {
+			switch entity.Type {
+			case "text_link":
+				add(partHiddenLink, entity.URL)
+			case "mention":
+				add(partMention, entityText(source.text, entity))
+			case "text_mention":
+				// 提及没有用户名的账号时, 显示的文字可以随便写, 真正指向的是 entity.User
+				if entity.User != nil {
+					add(partMention, DisplayName(entity.User))
+				}
+			}
+		}
+	}
+
+	if message.ReplyMarkup != nil {
+		for _, row := range message.ReplyMarkup.InlineKeyboard {
+			for _, button := range row {
+				add(partButton, button.Text)
+				if button.URL != nil {
+					add(partButtonURL, *button.URL)
+				}
+			}
+		}
+	}
+
+	if message.ViaBot != nil {
+		add(partViaBot, message.ViaBot.UserName)
+	}
+	return parts
+}
+
+// entityText 按实体的偏移取出对应文字; Telegram 的偏移与长度按 UTF-16 码元计
+func entityText(text string, entity tgbotapi.MessageEntity) string {
+	units := utf16.Encode([]rune(text))
+	end := entity.Offset + entity.Length
+	if entity.Offset < 0 || entity.Length <= 0 || end > len(units) {
+		return ""
+	}
+	return string(utf16.Decode(units[entity.Offset:end]))
+}
+
+// InspectionText 正文加上全部隐藏文本, 每段另起一行并标注来源; 供 AI 复核, 让它也看得到落地链接
+func InspectionText(message *tgbotapi.Message) string {
+	text := MessageText(message)
+	var b strings.Builder
+	b.WriteString(text)
+	for _, part := range hiddenParts(message) {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("[" + part.label + "] " + part.text)
+	}
+	return b.String()
+}
+
+// InspectHidden 用词表检查消息的隐藏文本, 无副作用; 返回首个正式执行的结论, 没有时返回首个试行结论
+func InspectHidden(chatID int64, topicID int, message *tgbotapi.Message) Verdict {
+	parts := hiddenParts(message)
+	if len(parts) == 0 {
+		return Verdict{}
+	}
+	keywords, err := keywordsFor(chatID, topicID)
+	if err != nil {
+		return Verdict{}
+	}
+
+	var shadow Verdict
+	for _, part := range parts {
+		enforced, shadowHit := matchText(keywords, part.text, "")
+		if enforced.Hit {
+			return tagPart(enforced, part.label)
+		}
+		if shadowHit.Hit && !shadow.Hit {
+			shadow = tagPart(shadowHit, part.label)
+		}
+	}
+	return shadow
+}
+
+// tagPart 给结论标上命中位置
+func tagPart(verdict Verdict, label string) Verdict {
+	verdict.Part = label
+	if verdict.Detail == "" {
+		verdict.Detail = label
+	} else {
+		verdict.Detail = label + "：" + verdict.Detail
+	}
+	return verdict
+}
+
+// matchText 只用词表与规则匹配一段文本, 返回首个正式命中与首个试行命中。
+// rule 非空时全部命中都记在这个规则标识下, 为空时按命中方式取 (关键词 / 正则 / 组合)。
+func matchText(keywords keywordSet, text, rule string) (enforced, shadow Verdict) {
+	label := func(byKind string) string {
+		if rule != "" {
+			return rule
+		}
+		return byKind
+	}
+
+	hits, shadowHits := keywords.match(text)
+	if len(shadowHits) > 0 {
+		shadow = keywordVerdict(label(ruleKeyword), shadowHits)
+		shadow.Shadow = true
+	}
+	if len(hits) > 0 {
+		return keywordVerdict(label(ruleKeyword), hits), shadow
+	}
+
+	for _, compiled := range matchRules(text, keywords.rules) {
+		v := keywordVerdict(label(ruleNames[compiled.kind]), []string{compiled.expr})
+		if !compiled.shadow {
+			return v, shadow
+		}
+		if !shadow.Hit {
+			v.Shadow = true
+			shadow = v
+		}
+	}
+	return Verdict{}, shadow
+}
@@ -0,0 +1,76 @@
+package moderation
+
+import (
+	"strings"
+	"testing"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TestHiddenParts 超链接、提及、按钮与 via 机器人都要取出并标注来源; 提及按 UTF-16 偏移截取
+func TestHiddenParts(t *testing.T) {
+	url := "https://t.me/fruitshop"
+	message := &tgbotapi.Message{
+		// "😀" 占两个 UTF-16 码元, 按字节或按 rune 截取都会错位
+		Text: "😀 点这里 @fruitbot",
+		Entities: []tgbotapi.MessageEntity{
+			{Type: "text_link", Offset: 3, Length: 3, URL: "https://spam.example/a"},
+			{Type: "mention", Offset: 7, Length: 9},
+		},
+		Caption:         "看看",
+		CaptionEntities: []tgbotapi.MessageEntity{{Type: "text_mention", Offset: 0, Length: 2, User: &tgbotapi.User{ID: 7, FirstName: "客服"}}},
+		ReplyMarkup: &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
+			{{Text: "领取福利", URL: &url}, {Text: "领取福利", URL: &url}},
+		}},
+		ViaBot: &tgbotapi.User{UserName: "adbot"},
+	}
+
+	want := []textPart{
+		{partHiddenLink, "https://spam.example/a"},
+		{partMention, "@fruitbot"},
+		{partMention, "客服"},
+		{partButton, "领取福利"},
+		{partButtonURL, url},
+		{partViaBot, "adbot"},
+	}
+	got := hiddenParts(message)
+	if len(got) != len(want) {
+		t.Fatalf("hiddenParts = %+v, 期望 %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("第 %d 段 = %+v, 期望 %+v", i, got[i], want[i])
+		}
+	}
+
+	text := InspectionText(message)
+	if !strings.HasPrefix(text, "😀 点这里 @fruitbot\n看看") || !strings.Contains(text, "[按钮链接] "+url) {
+		t.Errorf("InspectionText = %q", text)
+	}
+}
+
+// TestInspectHidden 隐藏文本命中词表时细节带上位置; 正文里的同一个词不归这里管
+func TestInspectHidden(t *testing.T) {
+	db := useTempDB(t)
+	db.AddKeyword("fruitshop", core.SourceManual)
+
+	message := &tgbotapi.Message{
+		Chat:     &tgbotapi.Chat{ID: -100},
+		Text:     "点这里",
+		Entities: []tgbotapi.MessageEntity{{Type: "text_link", Offset: 0, Length: 3, URL: "https://t.me/fruitshop"}},
+	}
+	v := InspectHidden(-100, 0, message)
+	if !v.Hit || v.Shadow || v.Part != partHiddenLink {
+		t.Fatalf("InspectHidden = %+v", v)
+	}
+	if !strings.HasPrefix(v.Detail, "实体中的隐藏链接：") || !strings.Contains(v.Detail, "fruitshop") {
+		t.Errorf("Detail = %q", v.Detail)
+	}
+
+	plain := &tgbotapi.Message{Chat: message.Chat, Text: "fruitshop"}
+	if v := InspectHidden(-100, 0, plain); v.Hit {
+		t.Errorf("没有隐藏文本时不应命中: %+v", v)
+	}
+}
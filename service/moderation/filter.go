@@ -25,6 +25,8 @@ const (
 	ruleZeroWidth   = "零宽字符"
 	ruleObfuscated  = "分隔符拆字"
 	ruleKeyword     = "关键词"
+	ruleRegex       = "正则规则"
+	ruleCombo       = "组合规则"
 	ruleDisplayName = "昵称关键词"
 	ruleFlooding    = "重复刷屏"
 	ruleAI          = "AI 判定"
@@ -34,71 +36,281 @@ const (
 type Verdict struct {
 	Hit    bool
 	Rule   string // 命中的规则
-	Detail string // 命中细节, 如具体关键词或重复次数
+	Detail string // 命中细节, 如具体关键词、规则表达式或重复次数
+	// Keywords 命中的全部词条原文 (关键词或规则表达式), 用于累加命中次数; 其余规则为空
+	Keywords []string
+	// Shadow 命中的是试行规则: 只记录"本会拦截", 不处置
+	Shadow bool
+	// Part 命中的是哪段隐藏文本 (如"实体中的隐藏链接"), 命中正文时为空; 见 entities.go
+	Part string
+	// MediaHash 命中的图片特征 ("kind:hash"), 撤销时据此把误登记的特征移除; 见 media.go
+	MediaHash string
+}
+
+// ruleNames 规则型词条命中时的规则标识
+var ruleNames = map[string]string{
+	core.KindRegex: ruleRegex,
+	core.KindCombo: ruleCombo,
+}
+
+// keywordSet 一条消息适用的全部词条: 每个作用域一台关键词自动机, 外加编译好的规则
+type keywordSet struct {
+	indexes []*keywordIndex
+	rules   []compiledRule
+}
+
+// match 找出文本命中的全部关键词, 按执行模式分成正式与试行两组;
+// 同一个词只会出现在一个作用域里, 不必跨自动机去重
+func (s keywordSet) match(text string) (enforced, shadow []string) {
+	normalized := Normalize(text)
+	if normalized == "" {
+		return nil, nil
+	}
+
+	for _, index := range s.indexes {
+		for _, hit := range index.find(normalized) {
+			if index.shadow[hit] {
+				shadow = append(shadow, hit)
+			} else {
+				enforced = append(enforced, hit)
+			}
+		}
+	}
+	return enforced, shadow
 }
 
 // Inspect 判定一条消息是否应当拦截, 无副作用。
-// displayName 传发送者的昵称与用户名拼接结果; 刷屏计数由调用方通过 repeatCount 传入。
-func Inspect(text, displayName string, repeatCount int) Verdict {
-	if ContainsZeroWidth(text) {
-		return Verdict{Hit: true, Rule: ruleZeroWidth}
+// chatID / topicID 决定参与匹配的词表; displayName 传发送者的昵称与用户名拼接结果; 刷屏计数由调用方通过 repeatCount 传入。
+// 只命中试行规则时返回 Shadow 为 true 的结论, 调用方只记录、不处置。
+func Inspect(chatID int64, topicID int, text, displayName string, repeatCount int) Verdict {
+	verdicts := inspect(chatID, topicID, text, displayName, repeatCount, true)
+	for _, verdict := range verdicts {
+		if !verdict.Shadow {
+			return verdict
+		}
+	}
+	if len(verdicts) > 0 {
+		return verdicts[0]
+	}
+	return Verdict{}
+}
+
+// InspectAll 与 Inspect 走同一套规则, 但不在首个命中处停下, 返回全部会触发的结论 (含试行规则)。
+// 供 /check 向管理员解释判定过程; chatID 为 0 时只用全局词表。
+func InspectAll(chatID int64, topicID int, text, displayName string, repeatCount int) []Verdict {
+	return inspect(chatID, topicID, text, displayName, repeatCount, false)
+}
+
+// inspect 按成本从低到高逐条检查规则; firstOnly 时遇到第一条正式执行的命中即返回。
+// 试行规则的命中只记下, 不会让检查停下 —— 后面可能还有正式规则要处置这条消息。
+func inspect(chatID int64, topicID int, text, displayName string, repeatCount int, firstOnly bool) []Verdict {
+	var verdicts []Verdict
+	// hit 记下一条结论, 返回是否该就此停下
+	hit := func(v Verdict) bool {
+		verdicts = append(verdicts, v)
+		return firstOnly && !v.Shadow
+	}
+	// builtin 按内置规则的执行模式检查; 停用的规则连检查都省掉
+	builtin := func(id string, matched func() bool, v Verdict) bool {
+		mode := RuleMode(id)
+		if mode == core.ModeOff || !matched() {
+			return false
+		}
+		v.Shadow = mode == core.ModeShadow
+		return hit(v)
+	}
+	// entries 记下词表类命中: 正式与试行的词分成两条结论
+	entries := func(rule string, enforced, shadow []string) bool {
+		if len(shadow) > 0 {
+			v := keywordVerdict(rule, shadow)
+			v.Shadow = true
+			hit(v)
+		}
+		return len(enforced) > 0 && hit(keywordVerdict(rule, enforced))
+	}
+	// ruleEntry 记下一条规则型词条的命中
+	ruleEntry := func(label string, rule compiledRule) bool {
+		v := keywordVerdict(label, []string{rule.expr})
+		v.Shadow = rule.shadow
+		return hit(v)
 	}
 
-	if looksObfuscated(text) {
-		return Verdict{Hit: true, Rule: ruleObfuscated}
+	if builtin(RuleIDZeroWidth, func() bool { return ContainsZeroWidth(text) }, Verdict{Hit: true, Rule: ruleZeroWidth}) {
+		return verdicts
 	}
 
-	keywords, err := core.DB.GetActiveKeywords()
+	if builtin(RuleIDObfuscated, func() bool { return looksObfuscated(text) }, Verdict{Hit: true, Rule: ruleObfuscated}) {
+		return verdicts
+	}
+
+	// 黑名单域名放在词表之前: 查的是整表缓存, 比逐词匹配便宜
+	if host, ok := blockedHost(textHosts(text)); ok {
+		if builtin(RuleIDDomain, func() bool { return true }, Verdict{Hit: true, Rule: ruleDomainBlock, Detail: host}) {
+			return verdicts
+		}
+	}
+
+	keywords, err := keywordsFor(chatID, topicID)
 	if err != nil {
 		// 查库失败按放行处理: 宁可漏拦, 不可因为数据库抖动误删用户消息
 		log.Printf("[Moderation] 读取关键词失败, 本条放行: %v", err)
-		return Verdict{}
+		return verdicts
 	}
 
-	if hit := matchKeyword(text, keywords); hit != "" {
-		return Verdict{Hit: true, Rule: ruleKeyword, Detail: hit}
+	// 报告全部命中的词而不只是第一个: 管理员能看出是哪几个词在起作用, 命中次数也都能累加上
+	if enforced, shadow := keywords.match(text); entries(ruleKeyword, enforced, shadow) {
+		return verdicts
+	}
+	// 规则命中时 Detail 填表达式原文, 告诉管理员是哪条规则
+	for _, rule := range matchRules(text, keywords.rules) {
+		if ruleEntry(ruleNames[rule.kind], rule) {
+			return verdicts
+		}
 	}
 
 	// 昵称带广告词的账号, 其消息一并拦截
-	if hit := matchKeyword(displayName, keywords); hit != "" {
-		return Verdict{Hit: true, Rule: ruleDisplayName, Detail: hit}
+	if enforced, shadow := keywords.match(displayName); entries(ruleDisplayName, enforced, shadow) {
+		return verdicts
 	}
-
-	if repeatCount >= repeatThreshold {
-		return Verdict{Hit: true, Rule: ruleFlooding, Detail: fmt.Sprintf("%d 分钟内重复 %d 次", int(repeatWindow.Minutes()), repeatCount)}
+	for _, rule := range matchRules(displayName, keywords.rules) {
+		if ruleEntry(ruleDisplayName, rule) {
+			return verdicts
+		}
 	}
 
-	return Verdict{}
+	builtin(RuleIDFlooding, func() bool { return repeatCount >= repeatThreshold },
+		Verdict{Hit: true, Rule: ruleFlooding, Detail: fmt.Sprintf("%d 分钟内重复 %d 次", int(repeatWindow.Minutes()), repeatCount)})
+
+	return verdicts
 }
 
-// matchKeyword 在归一化后的文本里查找命中的关键词, 返回原始关键词形态
-func matchKeyword(text string, keywords []string) string {
-	normalized := Normalize(text)
-	if normalized == "" {
-		return ""
+// keywordVerdict 词表类规则的结论
+func keywordVerdict(rule string, hits []string) Verdict {
+	return Verdict{Hit: true, Rule: rule, Detail: strings.Join(hits, "、"), Keywords: hits}
+}
+
+// keywordsFor 取一条消息适用的全部关键词与规则: 全局 (群设置为 global 时) + 本群专属 + 所在话题专属
+func keywordsFor(chatID int64, topicID int) (keywordSet, error) {
+	scopes := make([]core.Scope, 0, 3)
+	if group, ok := core.GroupSettings(chatID); !ok || group.KeywordScope != core.KeywordScopeLocal {
+		scopes = append(scopes, core.GlobalScope)
+	}
+	if chatID != 0 {
+		scopes = append(scopes, core.Scope{ChatID: chatID})
+	}
+	if chatID != 0 && topicID != 0 {
+		scopes = append(scopes, core.Scope{ChatID: chatID, TopicID: topicID})
 	}
 
-	for _, keyword := range keywords {
-		normalizedKeyword := Normalize(keyword)
-		if normalizedKeyword != "" && strings.Contains(normalized, normalizedKeyword) {
-			return keyword
+	var set keywordSet
+	for _, scope := range scopes {
+		index, err := core.DB.ScopeKeywordIndex(scope, func(entries []core.Keyword) any { return buildScopeIndex(entries) })
+		if err != nil {
+			return keywordSet{}, err
+		}
+		rules, err := core.DB.GetScopeRules(scope)
+		if err != nil {
+			return keywordSet{}, err
 		}
+		set.indexes = append(set.indexes, index.(*keywordIndex))
+		set.rules = append(set.rules, compileRules(rules)...)
 	}
-	return ""
+	return set, nil
 }
 
-// CheckAndFilter 审核一条群消息并执行处置, 返回是否已拦截。
+// CheckAndFilter 审核一条群消息并执行处置, 返回是否已拦截。stats 是 ObserveSender 记下的发送者统计。
 // 无论是否命中都会记录内容用于刷屏统计, 因此每条群消息都必须走这里。
-func CheckAndFilter(bot *tgbotapi.BotAPI, message *tgbotapi.Message) bool {
-	text := MessageText(message)
-	repeatCount := countRepeat(message.From.ID, text)
-
-	verdict := Inspect(text, DisplayName(message.From), repeatCount)
-	if !verdict.Hit {
+func CheckAndFilter(bot *tgbotapi.BotAPI, message *tgbotapi.Message, stats SenderStats) bool {
+	if ExemptSender(message) {
 		return false
 	}
+	// 发言频率不看内容, 先于一切内容检查; 突袭中的新账号与刷屏的人直接拦下。编辑不算一次新的发言
+	if group, ok := core.GroupSettings(message.Chat.ID); ok && message.EditDate == 0 &&
+		(checkRaid(bot, message, group, stats) || checkFlood(bot, message, group)) {
+		return true
+	}
+	sender := Sender(message)
+	text := MessageText(message)
+	trusted := stats.Trusted()
+	repeatCount := countRepeat(sender.ID, text)
+	wave := recordWave(message, sender.ID, text, trusted)
+	topicID := core.MessageTopic(message)
+
+	// 可信用户不查昵称: 老成员的昵称里碰巧带个词就被删消息, 是最招人烦的误伤
+	displayName := DisplayName(sender)
+	if trusted {
+		displayName = ""
+	}
+	verdict := Inspect(message.Chat.ID, topicID, text, displayName, repeatCount)
+	// 试行命中不拦截, 消息照常往下走 (包括 AI 审核), 才能看出试行规则在真实流量里的表现
+	if verdict.Shadow {
+		recordShadow(message, text, verdict)
+	}
+	// 多个账号发近似内容; 只查内存, 紧跟在正文检查之后
+	if !verdict.Hit || verdict.Shadow {
+		if dup := wave.verdict(); dup.Hit {
+			verdict = dup
+			if verdict.Shadow {
+				recordShadow(message, text, verdict)
+			}
+		}
+	}
+	// 正文干净时再看隐藏文本: 超链接、提及、按钮与 via 机器人。
+	// 命中隐藏文本时记录与通知带上这些内容, 否则管理员只能看到一句"点这里"
+	if !verdict.Hit || verdict.Shadow {
+		if hidden := InspectHidden(message.Chat.ID, topicID, message); hidden.Hit {
+			verdict = hidden
+			text = InspectionText(message)
+			if verdict.Shadow {
+				recordShadow(message, text, verdict)
+			}
+		}
+	}
+	// 超链接与按钮里的域名; 正文里的链接 Inspect 已经查过, 这里一并再查一遍也只是读缓存
+	if !verdict.Hit || verdict.Shadow {
+		if linked := InspectLinks(message); linked.Hit {
+			verdict = linked
+			text = InspectionText(message)
+			if verdict.Shadow {
+				recordShadow(message, text, verdict)
+			}
+		}
+	}
+	// 短链要发网络请求 (有缓存), 放在其余链接检查之后
+	if !verdict.Hit || verdict.Shadow {
+		if landing := InspectShortLinks(message.Chat.ID, topicID, message); landing.Hit {
+			verdict = landing
+			text = InspectionText(message)
+			if verdict.Shadow {
+				recordShadow(message, text, verdict)
+			}
+		}
+	}
+	// 图片与贴纸比对已知广告的特征库; 图片可能要下载缩略图, 放在文字检查之后
+	if !verdict.Hit || verdict.Shadow {
+		if media := InspectMedia(bot, message); media.Hit {
+			verdict = media
+			if verdict.Shadow {
+				recordShadow(message, text, verdict)
+			}
+		}
+	}
+	// 正文没有需要处置的问题时, 再看消息来自哪个频道
+	if origins := messageOrigins(message); len(origins) > 0 && (!verdict.Hit || verdict.Shadow) {
+		verdict = InspectOrigins(message.Chat.ID, topicID, origins)
+		if verdict.Shadow {
+			recordShadow(message, text, verdict)
+		}
+	}
+	if !verdict.Hit || verdict.Shadow {
+		// 内容没问题, 再看是不是限制期内的新成员发了不该发的类型或白名单以外的链接
+		return checkProbation(bot, message, text, stats) || checkStrictLinks(bot, message, text, stats)
+	}
 
+	// 无论按哪条规则处置, 都要在指纹里记下, 成批清理时才不会把它再处罚一遍
+	copies := waves.claim(wave, verdict.Rule == ruleDuplicate)
 	enforce(bot, message, text, verdict, nil)
+	sweepWave(bot, wave, copies, verdict)
 	return true
 }
 
@@ -117,43 +329,31 @@ func EnforceExternalVerdict(bot *tgbotapi.BotAPI, message *tgbotapi.Message, tex
 	enforce(bot, message, text, Verdict{Hit: true, Rule: ruleAI, Detail: detail}, learnedWords)
 }
 
-// enforce 执行处置: 删消息 -> 记分 -> 达阈值封禁 -> 记录可撤销的处置 -> 通知管理员
+// enforce 执行处置: 删消息 -> 记分 -> 按处罚阶梯处罚 -> 记录可撤销的处置 -> 通知管理员。
+// 以频道身份发出的消息不走阶梯: 换个频道就是新身份, 计分起不到作用, 直接封禁该频道身份。
 func enforce(bot *tgbotapi.BotAPI, message *tgbotapi.Message, text string, verdict Verdict, learnedWords []string) {
-	user := message.From
+	user := Sender(message)
 	chatID := message.Chat.ID
+	asChannel := SentAsChannel(message)
 
 	log.Printf("[Moderation] 命中「%s」%s, 用户 %d(%s): %s",
 		verdict.Rule, verdict.Detail, user.ID, user.UserName, truncate(text, logTextLimit))
+	verdictsTotal.WithLabelValues(verdict.Rule, core.ModeEnforce).Inc()
 
 	core.DeleteMessages(bot, chatID, message.MessageID)
 
 	// 命中计数用于定期整理: 长期零命中的 AI 词说明提取得不准, 应当清理
-	if verdict.Rule == ruleKeyword || verdict.Rule == ruleDisplayName {
-		if err := core.DB.RecordKeywordHit(verdict.Detail); err != nil {
+	for _, keyword := range verdict.Keywords {
+		if err := core.DB.RecordKeywordHit(keyword); err != nil {
 			log.Printf("[Moderation] 记录关键词命中失败: %v", err)
 		}
 	}
 
-	strikes, err := core.DB.AddStrike(user.ID, chatID)
-	if err != nil {
-		log.Printf("[Moderation] 记录违规次数失败: %v", err)
-	}
-
-	banned := false
-	if core.AutoBanThreshold > 0 && strikes >= core.AutoBanThreshold {
-		if err := core.BanUser(bot, chatID, user.ID); err != nil {
-			log.Printf("[Moderation] 自动封禁用户 %d 失败: %v", user.ID, err)
-		} else {
-			banned = true
-			log.Printf("[Moderation] 已自动封禁用户 %d(%s), 累计违规 %d 次", user.ID, user.UserName, strikes)
-		}
-	}
-
-	// 只在首次违规时在群里留提示, 避免刷屏时机器人跟着刷一遍
-	if strikes <= 1 && !banned {
-		if sent, err := bot.Send(tgbotapi.NewMessage(chatID, "已撤回该消息。")); err == nil {
-			core.DeleteMessageAfterDelay(bot, chatID, sent.MessageID, 3*time.Minute)
-		}
+	var outcome enforcement
+	if asChannel {
+		outcome.penalty = banSenderChat(bot, message)
+	} else {
+		outcome.strikes, outcome.weight, outcome.penalty = strikeAndPenalize(bot, message, verdict)
 	}
 
 	actionID, err := core.DB.RecordModerationAction(core.ModerationAction{
@@ -162,19 +362,120 @@ func enforce(bot *tgbotapi.BotAPI, message *tgbotapi.Message, text string, verdi
 		UserName:     DisplayName(user),
 		MessageText:  text,
 		Rule:         verdict.Rule,
+		Detail:       verdict.Detail,
 		LearnedWords: learnedWords,
-		Banned:       banned,
+		MediaHash:    verdict.MediaHash,
+		Banned:       outcome.penalty.Action == core.PenaltyBan,
+		Penalty:      outcome.penalty,
+		Weight:       outcome.weight,
+		SenderChat:   asChannel,
 	})
 	if err != nil {
 		log.Printf("[Moderation] 记录处置失败, 本次将无法一键撤销: %v", err)
 	}
+	outcome.learnedWords = learnedWords
+	outcome.actionID = actionID
+
+	// 封禁名单只收真实账号; 频道身份的封禁只在本群生效
+	if outcome.penalty.Action == core.PenaltyBan && !asChannel {
+		outcome.synced = core.FederateBan(bot, core.BannedUser{
+			UserID:   user.ID,
+			UserName: DisplayName(user),
+			Reason:   verdictReason(verdict),
+			ActionID: actionID,
+			ChatID:   chatID,
+		})
+	}
+
+	notifyAdmin(bot, message, text, verdict, outcome)
+}
+
+// strikeAndPenalize 按规则权重记分, 再按处罚阶梯执行处罚; 返回累计分数、本次记的分数与实际执行的处罚
+func strikeAndPenalize(bot *tgbotapi.BotAPI, message *tgbotapi.Message, verdict Verdict) (int, int, core.Penalty) {
+	user := message.From
+	chatID := message.Chat.ID
+
+	// 权重为 ban 的规则只记 1 分, 直接封禁; 撤销时按记下的分数扣回
+	weight := verdictWeight(verdict)
+	strikeWeight := max(weight, 1)
+	strikes, err := core.DB.AddStrike(user.ID, chatID, strikeWeight)
+	if err != nil {
+		log.Printf("[Moderation] 记录违规次数失败: %v", err)
+	}
+
+	// 路由层已保证只处理受管群, 这里取不到设置时得到的是只删消息的阶梯, 宁可少罚不可错罚
+	group, _ := core.GroupSettings(chatID)
+	ladder := group.Ladder()
+	penalty := core.PenaltyAt(ladder, strikes)
+	if weight == core.WeightBan {
+		penalty = core.Penalty{Action: core.PenaltyBan}
+	}
+	penalty = applyPenalty(bot, message, penalty, core.PenaltyAt(ladder, strikes+1), strikes, strikes <= strikeWeight)
+	return strikes, strikeWeight, penalty
+}
+
+// enforcement 一次处置的结果, 用于通知管理员
+type enforcement struct {
+	strikes, weight int
+	penalty         core.Penalty
+	synced          int // 同步封禁到的其他群数, 见 core.FederateBan
+	learnedWords    []string
+	actionID        int64
+}
+
+// verdictReason 结论的一行摘要, 写进封禁名单的原因栏
+func verdictReason(verdict Verdict) string {
+	if verdict.Detail == "" {
+		return verdict.Rule
+	}
+	return verdict.Rule + "：" + truncate(verdict.Detail, 60)
+}
+
+// applyPenalty 执行一级处罚并在群里留提示, 返回实际执行的处罚: 禁言、封禁调用失败时降级为只删消息,
+// 撤销时才不会去解一个根本没生效的禁言。next 是再犯一次会受的处罚, 写进警告里; first 表示这是该用户的首次违规。
+func applyPenalty(bot *tgbotapi.BotAPI, message *tgbotapi.Message, penalty, next core.Penalty, strikes int, first bool) core.Penalty {
+	user := message.From
+	chatID := message.Chat.ID
+
+	var notice string
+	switch penalty.Action {
+	case core.PenaltyBan:
+		if err := core.BanUser(bot, chatID, user.ID); err != nil {
+			log.Printf("[Moderation] 自动封禁用户 %d 失败: %v", user.ID, err)
+			return core.Penalty{Action: core.PenaltyDelete}
+		}
+		log.Printf("[Moderation] 已自动封禁用户 %d(%s), 累计计分 %d", user.ID, user.UserName, strikes)
+		return penalty
+	case core.PenaltyMute:
+		if err := core.MuteUser(bot, chatID, user.ID, time.Now().Add(penalty.Duration)); err != nil {
+			log.Printf("[Moderation] 禁言用户 %d 失败: %v", user.ID, err)
+			return core.Penalty{Action: core.PenaltyDelete}
+		}
+		log.Printf("[Moderation] 已禁言用户 %d(%s) %s, 累计计分 %d", user.ID, user.UserName, penalty.Label(), strikes)
+		notice = fmt.Sprintf("%s 多次违规，已%s。", DisplayName(user), penalty.Label())
+	case core.PenaltyWarn:
+		notice = fmt.Sprintf("⚠️ %s 的消息违规已撤回", DisplayName(user))
+		if next.Action == core.PenaltyMute || next.Action == core.PenaltyBan {
+			notice += "，再次违规将" + next.Label()
+		}
+		notice += "。"
+	default:
+		// 只删消息时仅在首次违规留提示, 避免刷屏时机器人跟着刷一遍
+		if first {
+			notice = "已撤回该消息。"
+		}
+	}
 
-	notifyAdmin(bot, message, text, verdict, strikes, banned, learnedWords, actionID)
+	if notice != "" {
+		if sent, err := bot.Send(tgbotapi.NewMessage(chatID, notice)); err == nil {
+			core.DeleteMessageAfterDelay(bot, chatID, sent.MessageID, 3*time.Minute)
+		}
+	}
+	return penalty
 }
 
-// notifyAdmin 把处置结果私聊推给管理员, 附撤销按钮供一键回滚误判
-func notifyAdmin(bot *tgbotapi.BotAPI, message *tgbotapi.Message, text string,
-	verdict Verdict, strikes int, banned bool, learnedWords []string, actionID int64) {
+// notifyAdmin 把处置结果私聊推给有处置权限的管理员, 附撤销按钮供一键回滚误判
+func notifyAdmin(bot *tgbotapi.BotAPI, message *tgbotapi.Message, text string, verdict Verdict, outcome enforcement) {
 
 	var b strings.Builder
 	b.WriteString("🛡 已撤回一条消息\n\n")
@@ -182,22 +483,42 @@ func notifyAdmin(bot *tgbotapi.BotAPI, message *tgbotapi.Message, text string,
 	if verdict.Detail != "" {
 		fmt.Fprintf(&b, " (%s)", verdict.Detail)
 	}
-	fmt.Fprintf(&b, "\n用户: %s (ID: %d)\n", DisplayName(message.From), message.From.ID)
-	fmt.Fprintf(&b, "累计违规: %d 次\n", strikes)
-	if banned {
+	if SentAsChannel(message) {
+		fmt.Fprintf(&b, "\n频道身份: %s\n", channelLabel(message.SenderChat))
+	} else {
+		fmt.Fprintf(&b, "\n用户: %s (ID: %d)\n", DisplayName(message.From), message.From.ID)
+		fmt.Fprintf(&b, "累计计分: %d（本次 +%d）\n", outcome.strikes, outcome.weight)
+	}
+	if message.ForwardFromChat != nil {
+		fmt.Fprintf(&b, "转发自: %s\n", channelLabel(message.ForwardFromChat))
+	}
+	switch outcome.penalty.Action {
+	case core.PenaltyBan:
+		if SentAsChannel(message) {
+			b.WriteString("处置: 已封禁该频道身份\n")
+			break
+		}
 		b.WriteString("处置: 已自动封禁并踢出\n")
+	case core.PenaltyMute, core.PenaltyWarn:
+		fmt.Fprintf(&b, "处置: 已%s\n", outcome.penalty.Label())
+	}
+	if outcome.synced > 0 {
+		fmt.Fprintf(&b, "封禁名单: 已同步封禁到其余 %d 个群\n", outcome.synced)
 	}
-	if len(learnedWords) > 0 {
-		fmt.Fprintf(&b, "新增关键词: %s\n", strings.Join(learnedWords, "、"))
+	if len(outcome.learnedWords) > 0 {
+		fmt.Fprintf(&b, "新增关键词: %s\n", strings.Join(outcome.learnedWords, "、"))
 	}
 	fmt.Fprintf(&b, "\n原文:\n%s", truncate(text, logTextLimit))
 
-	msg := tgbotapi.NewMessage(core.AdminID, b.String())
-	if actionID > 0 {
-		msg.ReplyMarkup = undoKeyboard(actionID)
-	}
-	if _, err := bot.Send(msg); err != nil {
-		log.Printf("[Moderation] 通知管理员失败: %v", err)
+	// 每位有处置权限的管理员各收一份; 撤销是幂等的, 谁先点都一样
+	for _, adminID := range core.AdminsWith(core.PermModerate) {
+		msg := tgbotapi.NewMessage(adminID, b.String())
+		if outcome.actionID > 0 {
+			msg.ReplyMarkup = undoKeyboard(outcome.actionID)
+		}
+		if _, err := bot.Send(msg); err != nil {
+			log.Printf("[Moderation] 通知管理员 %d 失败: %v", adminID, err)
+		}
 	}
 }
 
@@ -0,0 +1,103 @@
+package moderation
+
+import (
+	"path/filepath"
+	"testing"
+
+	"SunaiForum-Bot/core"
+)
+
+// useTempDB 让 core.DB 指向临时库, 测试结束后还原
+func useTempDB(t *testing.T) *core.Database {
+	t.Helper()
+
+	db, err := core.NewDatabaseAt(filepath.Join(t.TempDir(), "moderation.db"))
+	if err != nil {
+		t.Fatalf("创建库失败: %v", err)
+	}
+	previous := core.DB
+	core.DB = db
+	t.Cleanup(func() {
+		core.DB = previous
+		db.Close()
+	})
+	return db
+}
+
+// TestInspectAllReportsEveryRule Inspect 在首个命中处停下, InspectAll 要把会触发的规则全部列出
+func TestInspectAllReportsEveryRule(t *testing.T) {
+	db := useTempDB(t)
+	db.AddKeyword("水果", core.SourceManual)
+	db.AddKeyword("特价", core.SourceManual)
+	db.AddRule("水果 NEAR/3 特价", core.KindCombo, core.GlobalScope)
+
+	text := "水​·果·1.6·特·價"
+	all := InspectAll(0, 0, text, "", 0)
+
+	var rules []string
+	for _, v := range all {
+		rules = append(rules, v.Rule)
+	}
+	want := []string{ruleZeroWidth, ruleObfuscated, ruleKeyword, ruleCombo}
+	if len(rules) != len(want) {
+		t.Fatalf("InspectAll 规则 = %v, 期望 %v", rules, want)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("第 %d 条规则 = %s, 期望 %s", i, rules[i], want[i])
+		}
+	}
+	if got := all[2].Keywords; len(got) != 2 {
+		t.Errorf("关键词结论应列出全部命中的词, 实际 %v", got)
+	}
+
+	if first := Inspect(0, 0, text, "", 0); first.Rule != ruleZeroWidth {
+		t.Errorf("Inspect 应在首个命中处停下, 实际 %s", first.Rule)
+	}
+	if v := Inspect(0, 0, "今天天气不错", "", 0); v.Hit {
+		t.Errorf("正常消息被拦截: %+v", v)
+	}
+}
+
+// TestShadowVerdicts 试行命中不挡住执行规则; 只有试行命中时给出 Shadow 结论, 停用的词不参与匹配
+func TestShadowVerdicts(t *testing.T) {
+	db := useTempDB(t)
+	db.AddKeyword("水果机", core.SourceManual)
+	db.AddKeyword("特价", core.SourceManual)
+	db.AddKeyword("批发", core.SourceManual)
+	db.SetKeywordMode("水果机", core.ModeShadow)
+	db.SetKeywordMode("批发", core.ModeOff)
+
+	if v := Inspect(0, 0, "水果机到货", "", 0); !v.Hit || !v.Shadow || v.Keywords[0] != "水果机" {
+		t.Errorf("只命中试行词应得到 Shadow 结论, 实际 %+v", v)
+	}
+	if v := Inspect(0, 0, "水果机特价", "", 0); !v.Hit || v.Shadow || v.Keywords[0] != "特价" {
+		t.Errorf("同时命中执行词时应按执行词处置, 实际 %+v", v)
+	}
+	if v := Inspect(0, 0, "诚信批发", "", 0); v.Hit {
+		t.Errorf("停用的词不应命中, 实际 %+v", v)
+	}
+
+	db.SetRuleMode(RuleIDZeroWidth, core.ModeOff)
+	if v := Inspect(0, 0, "你​好", "", 0); v.Hit {
+		t.Errorf("停用的内置规则不应命中, 实际 %+v", v)
+	}
+}
+
+// TestVerdictWeight 每条会处置的规则都要能设置权重, 漏登记的规则会悄悄按 1 分记
+func TestVerdictWeight(t *testing.T) {
+	db := useTempDB(t)
+	for _, rule := range []string{ruleZeroWidth, ruleObfuscated, ruleKeyword, ruleRegex, ruleCombo, ruleDisplayName, ruleFlooding, ruleAI} {
+		if _, ok := weightRuleIDs[rule]; !ok {
+			t.Errorf("规则 %s 没有登记权重", rule)
+		}
+	}
+
+	db.SetRuleWeight(RuleIDDisplayName, 3)
+	if got := verdictWeight(Verdict{Hit: true, Rule: ruleDisplayName}); got != 3 {
+		t.Errorf("昵称关键词权重 = %d, 期望 3", got)
+	}
+	if got := verdictWeight(Verdict{Hit: true, Rule: ruleKeyword}); got != 1 {
+		t.Errorf("未设置的规则权重 = %d, 期望 1", got)
+	}
+}
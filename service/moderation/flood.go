@@ -0,0 +1,432 @@
+package moderation
+
+// 发言频率限制与突袭模式。
+//
+// 单人限速: 群设置 flood 8/10 时, 同一账号 10 秒内超过 8 条即撤回超出的消息并临时禁言, 不看内容, 不记分。
+// 相同内容的刷屏另有 flooding 规则, 这里管的是内容各不相同、纯靠数量刷屏的情况。
+//
+// 突袭模式: 群设置 raid 10/60 时, 60 秒内有 10 个新账号 (近期入群) 入群或发言, 即判定为成批涌入的广告号,
+// 开启突袭模式并通知管理员。突袭模式下新账号的消息直接撤回并禁言到突袭结束; 未开入群验证的群,
+// 新成员一进群就禁言 (开了入群验证的群由验证题拦着)。Bot API 没有设置慢速模式的接口, 因此手段只能是限制新账号。
+// raidCooldown 内没有新的突袭迹象即自动解除并再通知一次。
+// 计数与突袭状态都只放内存: 看的是几十秒内的节奏, 重启后重新观察即可。
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// 规则 ID
+const (
+	RuleIDRateLimit = "ratelimit" // 单人发言过快
+	RuleIDRaid      = "raid"      // 突袭模式
+)
+
+// 规则标识
+const (
+	ruleRateLimit = "发言过快"
+	ruleRaid      = "突袭模式"
+)
+
+const (
+	// floodMuteDuration 发言过快的禁言时长; 只为打断刷屏, 不是处罚, 给得很短
+	floodMuteDuration = 10 * time.Minute
+	// raidCooldown 突袭模式在最后一次突袭迹象之后持续的时长
+	raidCooldown = 15 * time.Minute
+	// raidNewcomerAge 入群多久以内算新账号
+	raidNewcomerAge = 24 * time.Hour
+)
+
+// floodKey 单人限速按群分别统计
+type floodKey struct {
+	chatID, userID int64
+}
+
+// floodTracker 记录每个账号最近的发言时间
+type floodTracker struct {
+	mu      sync.Mutex
+	senders map[floodKey][]time.Time
+}
+
+var floods = &floodTracker{senders: make(map[floodKey][]time.Time)}
+
+// record 记下一次发言并返回窗口内的发言条数 (含本条)。
+// 只保留判定用得到的最近 limit+2 条: 多出的一条用来区分"刚超出"与"已经超出过", 返回值因此最大为 limit+2
+func (t *floodTracker) record(key floodKey, limit int, window time.Duration, now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	kept := t.senders[key][:0]
+	for _, at := range t.senders[key] {
+		if now.Sub(at) <= window {
+			kept = append(kept, at)
+		}
+	}
+	kept = append(kept, now)
+	if len(kept) > limit+2 {
+		kept = kept[len(kept)-limit-2:]
+	}
+	t.senders[key] = kept
+	return len(kept)
+}
+
+// prune 清掉超出最大统计窗口的记录
+func (t *floodTracker) prune(now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	removed := 0
+	for key, stamps := range t.senders {
+		if len(stamps) == 0 || now.Sub(stamps[len(stamps)-1]) > core.MaxFloodSeconds*time.Second {
+			delete(t.senders, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// raidTracker 按群记录新账号的活跃情况与突袭模式的状态
+type raidTracker struct {
+	mu    sync.Mutex
+	chats map[int64]*raidState
+}
+
+// raidState 一个群的突袭状态
+type raidState struct {
+	title      string
+	joined     map[int64]time.Time // 近期入群的账号 -> 入群时间
+	active     map[int64]time.Time // 新账号 -> 最近一次入群或发言的时间
+	until      time.Time           // 突袭模式的结束时间, 零值表示未开启
+	shadow     bool                // 试行中: 只通知, 不限制
+	restricted map[int64]bool      // 本轮突袭限制的账号, 解除时告诉管理员人数; 撤销处置时据此不去提前解禁
+}
+
+var raids = &raidTracker{chats: make(map[int64]*raidState)}
+
+func (t *raidTracker) state(chatID int64) *raidState {
+	state, ok := t.chats[chatID]
+	if !ok {
+		state = &raidState{joined: make(map[int64]time.Time), active: make(map[int64]time.Time)}
+		t.chats[chatID] = state
+	}
+	return state
+}
+
+// observe 记下一次入群或发言, 返回窗口内活跃的新账号数; 不是新账号的发言不计, 返回 0
+func (t *raidTracker) observe(chatID, userID int64, join bool, window time.Duration, now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := t.state(chatID)
+	if join {
+		state.joined[userID] = now
+	} else if joinedAt, ok := state.joined[userID]; !ok || now.Sub(joinedAt) > raidNewcomerAge {
+		return 0
+	}
+	state.active[userID] = now
+
+	count := 0
+	for id, at := range state.active {
+		if now.Sub(at) > window {
+			delete(state.active, id)
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// joinedWithin 内存里是否有该账号在 age 以内的入群记录
+func (t *raidTracker) joinedWithin(chatID, userID int64, age time.Duration, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.chats[chatID]
+	if !ok {
+		return false
+	}
+	joinedAt, ok := state.joined[userID]
+	return ok && now.Sub(joinedAt) <= age
+}
+
+// trigger 开启或延长突袭模式, 返回是否是新开启的
+func (t *raidTracker) trigger(chatID int64, title string, shadow bool, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := t.state(chatID)
+	started := !now.Before(state.until)
+	if started {
+		state.title, state.shadow, state.restricted = title, shadow, make(map[int64]bool)
+	}
+	state.until = now.Add(raidCooldown)
+	return started
+}
+
+// activeUntil 突袭模式 (非试行) 的结束时间; 第二个返回值为 false 表示未开启
+func (t *raidTracker) activeUntil(chatID int64, now time.Time) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.chats[chatID]
+	if !ok || state.shadow || !now.Before(state.until) {
+		return time.Time{}, false
+	}
+	return state.until, true
+}
+
+// noteRestricted 记下本轮突袭又限制了一个账号
+func (t *raidTracker) noteRestricted(chatID, userID int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if state, ok := t.chats[chatID]; ok && state.restricted != nil {
+		state.restricted[userID] = true
+	}
+}
+
+// restricts 判断用户是否正被进行中的突袭模式限制着
+func (t *raidTracker) restricts(chatID, userID int64, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.chats[chatID]
+	return ok && now.Before(state.until) && state.restricted[userID]
+}
+
+// raidEnd 一轮结束的突袭
+type raidEnd struct {
+	chatID     int64
+	title      string
+	shadow     bool
+	restricted int
+}
+
+// expire 结束已过冷却期的突袭模式并返回它们
+func (t *raidTracker) expire(now time.Time) []raidEnd {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var ended []raidEnd
+	for chatID, state := range t.chats {
+		if state.until.IsZero() || now.Before(state.until) {
+			continue
+		}
+		ended = append(ended, raidEnd{chatID: chatID, title: state.title, shadow: state.shadow, restricted: len(state.restricted)})
+		state.until = time.Time{}
+	}
+	return ended
+}
+
+// prune 清掉过了新账号期限的入群记录与没有状态的群
+func (t *raidTracker) prune(now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	removed := 0
+	for chatID, state := range t.chats {
+		for userID, joinedAt := range state.joined {
+			if now.Sub(joinedAt) > raidNewcomerAge {
+				delete(state.joined, userID)
+				removed++
+			}
+		}
+		if len(state.joined) == 0 && state.until.IsZero() {
+			delete(t.chats, chatID)
+		}
+	}
+	return removed
+}
+
+// PruneFloodHistory 清理过期的发言频率与入群记录, 返回清理条数
+func PruneFloodHistory() int {
+	now := time.Now()
+	return floods.prune(now) + raids.prune(now)
+}
+
+// checkFlood 单人发言过快时撤回消息并临时禁言, 返回是否已撤回。频道身份无法禁言, 不在此列
+func checkFlood(bot *tgbotapi.BotAPI, message *tgbotapi.Message, group core.ManagedGroup) bool {
+	limit, window := group.FloodLimit()
+	if limit == 0 || SentAsChannel(message) || message.From == nil {
+		return false
+	}
+	mode := RuleMode(RuleIDRateLimit)
+	if mode == core.ModeOff {
+		return false
+	}
+
+	user := message.From
+	count := floods.record(floodKey{chatID: message.Chat.ID, userID: user.ID}, limit, window, time.Now())
+	if count <= limit {
+		return false
+	}
+	detail := fmt.Sprintf("%d 秒内超过 %d 条", int(window.Seconds()), limit)
+	if mode == core.ModeShadow {
+		// 超出之后的每一条都会走到这里, 只记第一条, 免得试行记录被刷屏
+		if count == limit+1 {
+			recordShadow(message, MessageText(message), Verdict{Hit: true, Rule: ruleRateLimit, Detail: detail, Shadow: true})
+		}
+		return false
+	}
+
+	core.DeleteMessages(bot, message.Chat.ID, message.MessageID)
+	// 禁言生效前已经发出的消息还会陆续到达, 只撤回, 不再重复禁言与提示
+	if count > limit+1 {
+		return true
+	}
+
+	penalty := core.Penalty{Action: core.PenaltyMute, Duration: floodMuteDuration}
+	if err := core.MuteUser(bot, message.Chat.ID, user.ID, time.Now().Add(floodMuteDuration)); err != nil {
+		log.Printf("[Moderation] 禁言发言过快的用户 %d 失败: %v", user.ID, err)
+		return true
+	}
+	log.Printf("[Moderation] 用户 %d(%s) %s, 已%s", user.ID, user.UserName, detail, penalty.Label())
+
+	notice := fmt.Sprintf("%s 发言过快，已%s。", DisplayName(user), penalty.Label())
+	if sent, err := bot.Send(tgbotapi.NewMessage(message.Chat.ID, notice)); err == nil {
+		core.DeleteMessageAfterDelay(bot, message.Chat.ID, sent.MessageID, probationNoticeTTL)
+	}
+	return true
+}
+
+// ObserveJoin 记下新成员入群, 用于突袭检测; 突袭模式下未开入群验证的群, 新成员一进群就禁言到突袭结束。
+// 由入群处理调用, 管理员拉进来的人不必调用。
+func ObserveJoin(bot *tgbotapi.BotAPI, group core.ManagedGroup, member tgbotapi.User) {
+	limit, window := group.RaidLimit()
+	if limit == 0 {
+		return
+	}
+	mode := RuleMode(RuleIDRaid)
+	if mode == core.ModeOff {
+		return
+	}
+
+	now := time.Now()
+	if count := raids.observe(group.ChatID, member.ID, true, window, now); count >= limit {
+		startRaid(bot, group, count, mode == core.ModeShadow, now)
+	}
+	if group.Captcha != "" {
+		return
+	}
+	if until, ok := raids.activeUntil(group.ChatID, now); ok {
+		if err := core.MuteUser(bot, group.ChatID, member.ID, until); err != nil {
+			log.Printf("[Moderation] 突袭模式下禁言新成员 %d 失败: %v", member.ID, err)
+			return
+		}
+		raids.noteRestricted(group.ChatID, member.ID)
+		log.Printf("[Moderation] 突袭模式: 新成员 %d(%s) 加入群 %d, 已禁言到突袭结束", member.ID, member.UserName, group.ChatID)
+	}
+}
+
+// checkRaid 统计新账号的发言用于突袭检测; 突袭模式下新账号的消息直接撤回并禁言到突袭结束, 返回是否已撤回
+func checkRaid(bot *tgbotapi.BotAPI, message *tgbotapi.Message, group core.ManagedGroup, stats SenderStats) bool {
+	limit, window := group.RaidLimit()
+	if limit == 0 || SentAsChannel(message) || message.From == nil {
+		return false
+	}
+	mode := RuleMode(RuleIDRaid)
+	if mode == core.ModeOff {
+		return false
+	}
+
+	user := message.From
+	now := time.Now()
+	if count := raids.observe(group.ChatID, user.ID, false, window, now); count >= limit {
+		if startRaid(bot, group, count, mode == core.ModeShadow, now) && mode == core.ModeShadow {
+			recordShadow(message, MessageText(message), Verdict{Hit: true, Rule: ruleRaid, Detail: raidDetail(count, window), Shadow: true})
+		}
+	}
+
+	until, ok := raids.activeUntil(group.ChatID, now)
+	if !ok {
+		return false
+	}
+	if !raidNewcomer(group.ChatID, user.ID, stats, now) {
+		return false
+	}
+
+	core.DeleteMessages(bot, group.ChatID, message.MessageID)
+	if err := core.MuteUser(bot, group.ChatID, user.ID, until); err != nil {
+		log.Printf("[Moderation] 突袭模式下禁言新账号 %d 失败: %v", user.ID, err)
+	} else {
+		raids.noteRestricted(group.ChatID, user.ID)
+	}
+	log.Printf("[Moderation] 突袭模式: 新账号 %d(%s) 在群 %d 发言, 已撤回并禁言到突袭结束", user.ID, user.UserName, group.ChatID)
+	return true
+}
+
+// raidNewcomer 突袭期间发言的人是否算新账号: 内存里有 24 小时内的入群记录, 或发言统计在本条消息之前就已存在、
+// 首次出现不到 24 小时 (入群时会写下统计, 重启后内存里的入群记录没了也认得出)。
+// 统计是本条消息才建的不算: 这样的人在本群没有任何记录, 多半是统计上线前就在的老成员, 不能当成新人禁言。
+// /release 过的不受限
+func raidNewcomer(chatID, userID int64, stats SenderStats, now time.Time) bool {
+	if !stats.Stat.ReleasedAt.IsZero() {
+		return false
+	}
+	if raids.joinedWithin(chatID, userID, raidNewcomerAge, now) {
+		return true
+	}
+	if stats.Fresh {
+		return false
+	}
+	_, newcomer := core.ProbationEnds(stats.Stat, stats.Found, raidNewcomerAge, now)
+	return newcomer
+}
+
+// startRaid 开启或延长突袭模式; 新开启时通知管理员并在群里留一条提示。返回是否是新开启的
+func startRaid(bot *tgbotapi.BotAPI, group core.ManagedGroup, count int, shadow bool, now time.Time) bool {
+	if !raids.trigger(group.ChatID, group.Title, shadow, now) {
+		return false
+	}
+	_, window := group.RaidLimit()
+	detail := raidDetail(count, window)
+	log.Printf("[Moderation] 群 %d 疑似遭遇突袭 (%s), 试行: %v", group.ChatID, detail, shadow)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🚨 群「%s」(ID: %d) 疑似遭遇突袭：%s。\n", group.Title, group.ChatID, detail)
+	if shadow {
+		b.WriteString("突袭模式处于试行，本会开启，当前只通知不限制。")
+	} else {
+		b.WriteString("已开启突袭模式：新账号的消息直接撤回并禁言到突袭结束")
+		if group.Captcha == "" {
+			b.WriteString("，新成员进群即禁言")
+		}
+		fmt.Fprintf(&b, "。\n%d 分钟内没有新的突袭迹象将自动解除。", int(raidCooldown.Minutes()))
+	}
+	core.NotifyAdmin(bot, b.String())
+
+	if !shadow {
+		notice := "⚠️ 检测到大量新账号涌入，已临时限制新成员发言，稍后自动解除。"
+		if sent, err := bot.Send(tgbotapi.NewMessage(group.ChatID, notice)); err == nil {
+			core.DeleteMessageAfterDelay(bot, group.ChatID, sent.MessageID, raidCooldown)
+		}
+	}
+	return true
+}
+
+// raidDetail 突袭迹象的描述
+func raidDetail(count int, window time.Duration) string {
+	return fmt.Sprintf("%d 秒内 %d 个新账号入群或发言", int(window.Seconds()), count)
+}
+
+// SweepRaids 解除已过冷却期的突袭模式并通知管理员, 由定时任务调用; 返回解除的群数
+func SweepRaids(bot *tgbotapi.BotAPI) int {
+	ended := raids.expire(time.Now())
+	for _, end := range ended {
+		text := fmt.Sprintf("✅ 群「%s」(ID: %d) 的突袭模式已自动解除", end.title, end.chatID)
+		if end.shadow {
+			text += "（试行）。"
+		} else {
+			text += fmt.Sprintf("，期间限制了 %d 个新账号。被禁言的账号到期自动恢复。", end.restricted)
+		}
+		core.NotifyAdmin(bot, text)
+		log.Printf("[Moderation] 群 %d 的突袭模式已解除", end.chatID)
+	}
+	return len(ended)
+}
@@ -0,0 +1,144 @@
+package moderation
+
+import (
+	"testing"
+	"time"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TestFloodTracker 窗口内超过上限才算过快; 超出之后的消息要能和"刚超出"区分开
+func TestFloodTracker(t *testing.T) {
+	tracker := &floodTracker{senders: make(map[floodKey][]time.Time)}
+	key := floodKey{chatID: -100, userID: 1}
+	start := time.Now()
+
+	var counts []int
+	for i := 0; i < 6; i++ {
+		counts = append(counts, tracker.record(key, 3, 10*time.Second, start.Add(time.Duration(i)*time.Second)))
+	}
+	want := []int{1, 2, 3, 4, 5, 5}
+	for i := range want {
+		if counts[i] != want[i] {
+			t.Fatalf("发言条数 = %v, 期望 %v", counts, want)
+		}
+	}
+
+	// 另一个群的发言单独计数; 窗口过去之后重新计数
+	if got := tracker.record(floodKey{chatID: -200, userID: 1}, 3, 10*time.Second, start); got != 1 {
+		t.Errorf("另一个群的条数 = %d", got)
+	}
+	if got := tracker.record(key, 3, 10*time.Second, start.Add(time.Minute)); got != 1 {
+		t.Errorf("窗口过去之后的条数 = %d", got)
+	}
+	if removed := tracker.prune(start.Add(time.Hour)); removed != 2 {
+		t.Errorf("应清理 2 条记录, 实际 %d", removed)
+	}
+}
+
+// TestRaidTracker 只有近期入群的账号计入突袭; 突袭模式在冷却期后结束, 期间再有迹象会顺延
+func TestRaidTracker(t *testing.T) {
+	tracker := &raidTracker{chats: make(map[int64]*raidState)}
+	window := time.Minute
+	now := time.Now()
+
+	if got := tracker.observe(-100, 1, false, window, now); got != 0 {
+		t.Errorf("老成员发言不应计入, 实际 %d", got)
+	}
+	tracker.observe(-100, 2, true, window, now)
+	tracker.observe(-100, 3, true, window, now.Add(10*time.Second))
+	if got := tracker.observe(-100, 2, false, window, now.Add(20*time.Second)); got != 2 {
+		t.Errorf("两个新账号活跃, 实际 %d", got)
+	}
+	// 窗口之外的活跃不再计数
+	if got := tracker.observe(-100, 4, true, window, now.Add(2*time.Minute)); got != 1 {
+		t.Errorf("窗口过去之后只剩 1 个, 实际 %d", got)
+	}
+
+	if !tracker.trigger(-100, "测试群", false, now) {
+		t.Fatal("首次触发应开启突袭模式")
+	}
+	if tracker.trigger(-100, "测试群", false, now.Add(time.Minute)) {
+		t.Error("突袭期间再次触发只应顺延")
+	}
+	until, ok := tracker.activeUntil(-100, now.Add(time.Minute))
+	if !ok || !until.Equal(now.Add(time.Minute+raidCooldown)) {
+		t.Errorf("突袭结束时间 = %v,%v", until, ok)
+	}
+	tracker.noteRestricted(-100, 7)
+	if !tracker.restricts(-100, 7, now.Add(time.Minute)) || tracker.restricts(-100, 8, now.Add(time.Minute)) {
+		t.Error("只有被突袭限制的账号才算受限")
+	}
+
+	if ended := tracker.expire(now.Add(raidCooldown)); len(ended) != 0 {
+		t.Errorf("顺延后还不该结束: %+v", ended)
+	}
+	ended := tracker.expire(now.Add(time.Minute + raidCooldown))
+	if len(ended) != 1 || ended[0].restricted != 1 || ended[0].title != "测试群" {
+		t.Errorf("冷却期后应结束: %+v", ended)
+	}
+	if _, ok := tracker.activeUntil(-100, now.Add(time.Hour)); ok {
+		t.Error("结束之后不应再处于突袭模式")
+	}
+	if tracker.restricts(-100, 7, now.Add(time.Hour)) {
+		t.Error("突袭结束后不应再算受限")
+	}
+
+	// 试行的突袭只通知, 不限制
+	tracker.trigger(-200, "", true, now)
+	if _, ok := tracker.activeUntil(-200, now); ok {
+		t.Error("试行的突袭模式不应限制新账号")
+	}
+}
+
+// TestCheckRaidNewcomers 突袭期间只限制有入群记录或首次出现不到 24 小时的账号; 本条消息才建出统计的老成员不受限
+func TestCheckRaidNewcomers(t *testing.T) {
+	db := useTempDB(t)
+	group := core.DefaultGroup(-100, "测试群")
+	group.RaidMembers, group.RaidSeconds = 100, 60
+	if err := db.SaveGroup(group); err != nil {
+		t.Fatalf("登记群失败: %v", err)
+	}
+	now := time.Now()
+	raids.trigger(-100, group.Title, false, now)
+	t.Cleanup(func() {
+		raids.mu.Lock()
+		delete(raids.chats, -100)
+		raids.mu.Unlock()
+	})
+	message := func(userID int64) *tgbotapi.Message {
+		return &tgbotapi.Message{MessageID: int(userID), From: &tgbotapi.User{ID: userID}, Chat: &tgbotapi.Chat{ID: -100, Type: "supergroup"}, Text: "大家好"}
+	}
+
+	// 2: 入群时写下了统计; 3: 只有内存里的入群记录; 4: 入群后被 /release; 5: 统计里的老成员
+	if _, err := db.RecordJoin(2, -100); err != nil {
+		t.Fatalf("RecordJoin 失败: %v", err)
+	}
+	raids.observe(-100, 3, true, time.Minute, now)
+	db.RecordJoin(4, -100)
+	db.ReleaseProbation(4, []int64{-100})
+	veteran := SenderStats{Stat: core.UserStat{UserID: 5, ChatID: -100, MessageCount: 80, FirstSeenAt: now.Add(-30 * 24 * time.Hour)}, Found: true}
+
+	cases := []struct {
+		name    string
+		userID  int64
+		stats   func() SenderStats
+		removed bool
+	}{
+		{"没有任何记录的老成员", 1, func() SenderStats { return ObserveSender(message(1)) }, false},
+		{"入群记录在库里", 2, func() SenderStats { return ObserveSender(message(2)) }, true},
+		{"入群记录只在内存里", 3, func() SenderStats { return ObserveSender(message(3)) }, true},
+		{"已被提前解除", 4, func() SenderStats { return ObserveSender(message(4)) }, false},
+		{"统计里的老成员", 5, func() SenderStats { return veteran }, false},
+		{"统计读取失败", 6, func() SenderStats { return SenderStats{} }, false},
+	}
+	for _, c := range cases {
+		bot, fake := newFakeBot(t)
+		got := checkRaid(bot, message(c.userID), group, c.stats())
+		if got != c.removed || fake.called("restrictChatMember") != c.removed {
+			t.Errorf("%s: 是否撤回并禁言 = %v, 期望 %v", c.name, got, c.removed)
+		}
+	}
+}
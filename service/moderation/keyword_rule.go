@@ -0,0 +1,160 @@
+package moderation
+
+// 规则型词条 (正则 / 组合) 的匹配。
+//
+// 规则的解析与校验在 core.ParseRule, 入库前已经过一遍; 这里只负责把表达式编译成匹配函数并缓存,
+// 避免每条群消息都重新编译一次正则。
+import (
+	"log"
+	"slices"
+	"strings"
+	"sync"
+
+	"SunaiForum-Bot/core"
+)
+
+// compiledRule 编译好的规则, match 同时拿到原文与归一化文本
+type compiledRule struct {
+	kind   string
+	expr   string
+	shadow bool // 试行中, 命中只记录不处置
+	match  func(raw, normalized string) bool
+}
+
+// ruleCache 按 kind+表达式缓存编译结果。规则由管理员手工维护, 总量很小, 删掉的规则留在缓存里也无妨
+var ruleCache sync.Map
+
+// compileRules 把词条编译成匹配函数, 停用的跳过; 编译失败的 (只可能是入库后校验规则变严) 记日志跳过
+func compileRules(entries []core.Keyword) []compiledRule {
+	rules := make([]compiledRule, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Mode == core.ModeOff {
+			continue
+		}
+
+		// 缓存只存编译结果, 执行模式随词条每次重新带上, 改模式不必清缓存
+		key := entry.Kind + "\x00" + entry.Word
+		cached, ok := ruleCache.Load(key)
+		if !ok {
+			rule, err := compileRule(entry.Kind, entry.Word)
+			if err != nil {
+				log.Printf("[Moderation] 规则 %q 无法编译, 已跳过: %v", entry.Word, err)
+				continue
+			}
+			cached, _ = ruleCache.LoadOrStore(key, rule)
+		}
+
+		rule := cached.(compiledRule)
+		rule.shadow = entry.Mode == core.ModeShadow
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// compileRule 编译单条规则。
+// 正则同时对原文和归一化文本匹配: 写 https?:// 的要看原文, 写 \d{7,}微信 的要看去掉分隔符后的文本。
+// 组合规则的词与消息一样先归一化, 因此 "水果 NEAR/5 特价" 也能命中 "水·果·机·特·價"。
+func compileRule(kind, expr string) (compiledRule, error) {
+	parsed, err := core.ParseRule(kind, expr)
+	if err != nil {
+		return compiledRule{}, err
+	}
+
+	rule := compiledRule{kind: kind, expr: expr}
+	switch kind {
+	case core.KindRegex:
+		re := parsed.Regexp
+		rule.match = func(raw, normalized string) bool {
+			return re.MatchString(raw) || re.MatchString(normalized)
+		}
+	case core.KindCombo:
+		terms := make([]string, 0, len(parsed.Terms))
+		for _, term := range parsed.Terms {
+			terms = append(terms, Normalize(term))
+		}
+		if parsed.Near > 0 {
+			distance := parsed.Near
+			rule.match = func(_, normalized string) bool {
+				return withinDistance(normalized, terms[0], terms[1], distance)
+			}
+		} else {
+			rule.match = func(_, normalized string) bool {
+				return containsAll(normalized, terms)
+			}
+		}
+	}
+	return rule, nil
+}
+
+// matchRules 返回全部命中的规则, 按词表顺序排列
+func matchRules(text string, rules []compiledRule) []compiledRule {
+	if len(rules) == 0 || strings.TrimSpace(text) == "" {
+		return nil
+	}
+
+	normalized := Normalize(text)
+	var hits []compiledRule
+	for _, rule := range rules {
+		if rule.match(text, normalized) {
+			hits = append(hits, rule)
+		}
+	}
+	return hits
+}
+
+// containsAll 判断每个词都出现在文本里; 归一化后为空的词视为不命中, 防止空词让规则恒真
+func containsAll(normalized string, terms []string) bool {
+	for _, term := range terms {
+		if term == "" || !strings.Contains(normalized, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// withinDistance 判断 a 与 b 是否在文本中相距不超过 distance 个字 (按字符计, 不分先后)。
+// 距离指前一个词结尾到后一个词开头之间的字数, 紧挨着为 0。
+func withinDistance(normalized, a, b string, distance int) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	// 绝大多数消息至少缺一个词, 先按字节查一遍, 省掉逐字比较
+	if !strings.Contains(normalized, a) || !strings.Contains(normalized, b) {
+		return false
+	}
+
+	text := []rune(normalized)
+	aRunes, bRunes := []rune(a), []rune(b)
+	aPos := runeIndexes(text, aRunes)
+	bPos := runeIndexes(text, bRunes)
+	aLen, bLen := len(aRunes), len(bRunes)
+
+	for _, i := range aPos {
+		for _, j := range bPos {
+			var gap int
+			switch {
+			case j >= i+aLen:
+				gap = j - (i + aLen)
+			case i >= j+bLen:
+				gap = i - (j + bLen)
+			default:
+				continue // 两个词重叠, 不算"相邻出现"
+			}
+			if gap <= distance {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// runeIndexes 返回 sub 在 text 中每次出现的起始字符下标; 原地比较, 不为每个位置分配字符串
+func runeIndexes(text, sub []rune) []int {
+	var indexes []int
+	for i := 0; i+len(sub) <= len(text); i++ {
+		if slices.Equal(text[i:i+len(sub)], sub) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
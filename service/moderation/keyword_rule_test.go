@@ -0,0 +1,55 @@
+package moderation
+
+import (
+	"testing"
+
+	"SunaiForum-Bot/core"
+)
+
+func TestMatchRule(t *testing.T) {
+	cases := []struct {
+		kind, expr, text string
+		wantHit          bool
+	}{
+		// 正则对归一化文本生效, 分隔符拆开的号码也能连上
+		{core.KindRegex, `\d{7,}微信`, "加我 138-1234-5678 微信", true},
+		{core.KindRegex, `\d{7,}微信`, "微信群 2024 年聚会", false},
+		// 正则也看原文, 链接里的标点在归一化后会丢
+		{core.KindRegex, `https?://\S+\.apk`, "下载 https://x.com/app.apk", true},
+
+		{core.KindCombo, "水果 NEAR/5 特价", "水·果·机·今·天·特·價", true},
+		{core.KindCombo, "水果 NEAR/5 特价", "特价出，全新未拆水果", true}, // 不分先后
+		{core.KindCombo, "水果 NEAR/2 特价", "水果店今天有很多东西特价", false},
+		{core.KindCombo, "出售 AND 私聊", "私聊我，诚心出售", true},
+		{core.KindCombo, "出售 AND 私聊", "有人出售显卡吗", false},
+	}
+	for _, c := range cases {
+		rule, err := compileRule(c.kind, c.expr)
+		if err != nil {
+			t.Fatalf("compileRule(%q) 报错: %v", c.expr, err)
+		}
+		hit := len(matchRules(c.text, []compiledRule{rule})) > 0
+		if hit != c.wantHit {
+			t.Errorf("规则 %q 对 %q 命中 = %v, 期望 %v", c.expr, c.text, hit, c.wantHit)
+		}
+	}
+}
+
+func TestWithinDistance(t *testing.T) {
+	cases := []struct {
+		text string
+		near int
+		want bool
+	}{
+		{"ab", 0, true},     // 紧挨着
+		{"axxb", 2, true},   // 间隔正好等于距离
+		{"axxxb", 2, false}, // 超出一个字
+		{"bxxa", 2, true},   // 反序
+		{"xxxx", 5, false},
+	}
+	for _, c := range cases {
+		if got := withinDistance(c.text, "a", "b", c.near); got != c.want {
+			t.Errorf("withinDistance(%q, NEAR/%d) = %v, 期望 %v", c.text, c.near, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package moderation
+
+// 链接的域名检查: 黑名单上的域名按违规处置; 开启 strictlinks 的群里, 新成员与低信任用户只能发白名单域名的链接。
+//
+// 链接从正文、挂在文字上的超链接与按钮里一起取, 广告的落地页多半不在正文里。
+// 域名的规整与名单的匹配方式见 core/domain.go 与 core/db_domain.go。
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// 链接相关的规则 ID
+const (
+	RuleIDDomain      = "domain"      // 链接域名在黑名单上
+	RuleIDStrictLinks = "strictlinks" // 新成员发了白名单以外的链接
+)
+
+// 规则标识
+const (
+	ruleDomainBlock = "域名黑名单"
+	ruleStrictLinks = "非白名单链接"
+)
+
+// textHosts 正文里出现的全部链接主机名, 去重并保持出现顺序
+func textHosts(text string) []string {
+	return uniqueHosts(linkPattern.FindAllString(text, -1))
+}
+
+// messageLinks 消息里的全部链接原文: 正文、超链接与按钮链接
+func messageLinks(message *tgbotapi.Message) []string {
+	links := linkPattern.FindAllString(MessageText(message), -1)
+	for _, part := range hiddenParts(message) {
+		if part.label == partHiddenLink || part.label == partButtonURL {
+			links = append(links, part.text)
+		}
+	}
+	return links
+}
+
+// messageHosts 消息里全部链接的主机名, 去重并保持出现顺序
+func messageHosts(message *tgbotapi.Message) []string {
+	return uniqueHosts(messageLinks(message))
+}
+
+func uniqueHosts(links []string) []string {
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, link := range links {
+		host := core.HostOf(link)
+		if host == "" || seen[host] {
+			continue
+		}
+		seen[host] = true
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// domainList 查主机名所在的名单, 不在任何名单上返回空串; 查库失败按不在名单处理
+func domainList(host string) string {
+	entry, ok, err := core.DB.LookupLinkDomain(host)
+	if err != nil {
+		log.Printf("[Moderation] 查询域名名单失败: %v", err)
+		return ""
+	}
+	if !ok {
+		return ""
+	}
+	return entry.List
+}
+
+// blockedHost 返回第一个在黑名单上的主机名
+func blockedHost(hosts []string) (string, bool) {
+	for _, host := range hosts {
+		if domainList(host) == core.DomainBlock {
+			return host, true
+		}
+	}
+	return "", false
+}
+
+// InspectLinks 检查消息里全部链接的域名是否在黑名单上, 无副作用
+func InspectLinks(message *tgbotapi.Message) Verdict {
+	mode := RuleMode(RuleIDDomain)
+	if mode == core.ModeOff {
+		return Verdict{}
+	}
+	host, ok := blockedHost(messageHosts(message))
+	if !ok {
+		return Verdict{}
+	}
+	return Verdict{Hit: true, Rule: ruleDomainBlock, Detail: host, Shadow: mode == core.ModeShadow}
+}
+
+// checkStrictLinks 新成员或低信任用户发了白名单以外的链接时撤回消息, 返回是否已撤回。
+// 与新成员限制一样不算违规: 只撤回、留提示, 不记分也不通知管理员。
+func checkStrictLinks(bot *tgbotapi.BotAPI, message *tgbotapi.Message, text string, stats SenderStats) bool {
+	group, ok := core.GroupSettings(message.Chat.ID)
+	if !ok || group.StrictLinkWindow() <= 0 {
+		return false
+	}
+	mode := RuleMode(RuleIDStrictLinks)
+	if mode == core.ModeOff {
+		return false
+	}
+
+	var outside []string
+	for _, host := range messageHosts(message) {
+		if domainList(host) != core.DomainAllow {
+			outside = append(outside, host)
+		}
+	}
+	if len(outside) == 0 {
+		return false
+	}
+
+	sender := Sender(message)
+	// 新成员期内与低信任用户都只能发白名单链接; 可信用户 (含管理员担保的) 即使还在新成员期也放行
+	ends, restricted := core.ProbationEnds(stats.Stat, stats.Found, group.StrictLinkWindow(), time.Now())
+	switch {
+	case stats.Trusted():
+		return false
+	case !restricted && !stats.Distrusted():
+		return false
+	}
+
+	detail := strings.Join(outside, "、")
+	if mode == core.ModeShadow {
+		recordShadow(message, text, Verdict{Hit: true, Rule: ruleStrictLinks, Detail: detail, Shadow: true})
+		return false
+	}
+
+	core.DeleteMessages(bot, message.Chat.ID, message.MessageID)
+	log.Printf("[Moderation] 新成员或低信任用户 %d(%s) 发送白名单以外的链接 %s, 已撤回", sender.ID, sender.UserName, detail)
+
+	notice := fmt.Sprintf("%s 入群 %d 小时内只能发送白名单网站的链接，消息已撤回（约 %s 后解除）。",
+		DisplayName(sender), group.StrictLinkHours, remainingLabel(time.Until(ends)))
+	if !restricted {
+		notice = fmt.Sprintf("%s 目前只能发送白名单网站的链接，消息已撤回。", DisplayName(sender))
+	}
+	if sent, err := bot.Send(tgbotapi.NewMessage(message.Chat.ID, notice)); err == nil {
+		core.DeleteMessageAfterDelay(bot, message.Chat.ID, sent.MessageID, probationNoticeTTL)
+	}
+	return true
+}
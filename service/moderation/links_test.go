@@ -0,0 +1,53 @@
+package moderation
+
+import (
+	"slices"
+	"testing"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TestMessageHosts 正文、超链接与按钮里的链接都要取出, 同一主机只报一次
+func TestMessageHosts(t *testing.T) {
+	url := "https://Shop.Example.com/buy?id=1"
+	message := &tgbotapi.Message{
+		Text:     "官网 example.com/about 和 t.me/fruit，点这里",
+		Entities: []tgbotapi.MessageEntity{{Type: "text_link", Offset: 0, Length: 2, URL: "http://landing.spam.example/"}},
+		ReplyMarkup: &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
+			{{Text: "购买", URL: &url}, {Text: "回调", CallbackData: &url}},
+		}},
+	}
+	want := []string{"example.com", "t.me", "landing.spam.example", "shop.example.com"}
+	if got := messageHosts(message); !slices.Equal(got, want) {
+		t.Errorf("messageHosts = %v, 期望 %v", got, want)
+	}
+}
+
+// TestInspectLinks 黑名单域名对子域名生效; 藏在按钮里的链接同样命中, 正文里的由 Inspect 报告
+func TestInspectLinks(t *testing.T) {
+	db := useTempDB(t)
+	db.SetLinkDomain(core.LinkDomain{Domain: "spam.example", List: core.DomainBlock})
+	db.SetLinkDomain(core.LinkDomain{Domain: "ok.spam.example", List: core.DomainAllow})
+
+	url := "https://promo.spam.example/x"
+	button := &tgbotapi.Message{Text: "福利", ReplyMarkup: &tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{{{Text: "领取", URL: &url}}},
+	}}
+	if v := InspectLinks(button); !v.Hit || v.Rule != ruleDomainBlock || v.Detail != "promo.spam.example" {
+		t.Errorf("按钮里的黑名单域名 = %+v", v)
+	}
+	if v := InspectLinks(&tgbotapi.Message{Text: "见 https://ok.spam.example/doc"}); v.Hit {
+		t.Errorf("白名单子域名不应命中: %+v", v)
+	}
+
+	if v := Inspect(0, 0, "快来 www.spam.example", "", 0); v.Rule != ruleDomainBlock {
+		t.Errorf("正文里的黑名单域名 = %+v", v)
+	}
+
+	db.SetRuleMode(RuleIDDomain, core.ModeShadow)
+	if v := InspectLinks(button); !v.Hit || !v.Shadow {
+		t.Errorf("试行模式下 = %+v", v)
+	}
+}
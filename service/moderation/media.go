@@ -0,0 +1,189 @@
+package moderation
+
+// 已知广告图片与贴纸的匹配: 管理员 /ban 过的图片记下特征, 之后同样的图片直接处置, 不必等 AI。
+//
+// 贴纸、动图按 file_unique_id 精确匹配, 不用下载。图片先比 file_unique_id (原样转发时不变),
+// 再下载最小一档缩略图算差值感知哈希 (dHash), 按汉明距离近似匹配 —— 重新压缩、缩放过的同一张图也认得出来。
+// 缩略图只有几 KB, 且库里没有感知哈希时不下载, 这条规则对正常图片几乎没有开销。
+// 特征库的存储见 core/db_media.go。
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif" // 注册解码器
+	_ "image/jpeg"
+	_ "image/png"
+	"log"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// RuleIDMediaHash 已知广告图片的规则 ID
+const RuleIDMediaHash = "media"
+
+// ruleMediaHash 已知广告图片的规则标识
+const ruleMediaHash = "已知广告图片"
+
+const (
+	// mediaHashDistance 感知哈希的汉明距离不超过它即视为同一张图; 64 位里差 6 位以内, 重新压缩与缩放都在这个范围
+	mediaHashDistance = 6
+	// maxThumbnailBytes 下载缩略图的大小上限; 最小一档通常只有几 KB
+	maxThumbnailBytes = 1 << 20
+)
+
+// mediaFile 消息里可按特征匹配的媒体: 贴纸、动图或图片 (取最小一档)
+type mediaFile struct {
+	fileID   string
+	uniqueID string
+	photo    bool // 图片才算感知哈希, 贴纸多是 webp, 标准库解不了
+}
+
+// messageMedia 取出消息里的媒体, 没有时第二个返回值为 false
+func messageMedia(message *tgbotapi.Message) (mediaFile, bool) {
+	switch {
+	case len(message.Photo) > 0:
+		// 学习与匹配都用最小一档: dHash 本身就缩到 9x8, 大图没有多余信息, 反而要多下载
+		smallest := message.Photo[0]
+		return mediaFile{fileID: smallest.FileID, uniqueID: smallest.FileUniqueID, photo: true}, true
+	case message.Sticker != nil:
+		return mediaFile{fileID: message.Sticker.FileID, uniqueID: message.Sticker.FileUniqueID}, true
+	case message.Animation != nil:
+		return mediaFile{fileID: message.Animation.FileID, uniqueID: message.Animation.FileUniqueID}, true
+	}
+	return mediaFile{}, false
+}
+
+// DHash 计算图片的差值感知哈希: 缩成 9x8 的灰度格, 每行相邻两格比较明暗, 得到 64 位。
+// 缩放用区域平均, 对重新压缩、等比缩放与轻微调色都不敏感。
+func DHash(img image.Image) uint64 {
+	const cols, rows = 9, 8
+	bounds := img.Bounds()
+	var cells [rows][cols]float64
+	for row := 0; row < rows; row++ {
+		y0, y1 := span(bounds.Min.Y, bounds.Dy(), row, rows)
+		for col := 0; col < cols; col++ {
+			x0, x1 := span(bounds.Min.X, bounds.Dx(), col, cols)
+			var sum float64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					r, g, b, _ := img.At(x, y).RGBA()
+					// ITU-R BT.601 亮度, 与 color.GrayModel 一致
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+				}
+			}
+			cells[row][col] = sum / float64((y1-y0)*(x1-x0))
+		}
+	}
+
+	var hash uint64
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols-1; col++ {
+			hash <<= 1
+			if cells[row][col] < cells[row][col+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// span 把长度 size 的一维区间均分成 parts 段, 返回第 index 段的 [start, end); 图片比格子还小时每段至少一像素
+func span(origin, size, index, parts int) (int, int) {
+	start := origin + index*size/parts
+	end := origin + (index+1)*size/parts
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
+
+// dhashBytes 解码图片并计算感知哈希
+func dhashBytes(data []byte) (uint64, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("解码图片失败: %w", err)
+	}
+	return DHash(img), nil
+}
+
+// photoDHash 下载图片缩略图并计算感知哈希
+func photoDHash(bot *tgbotapi.BotAPI, media mediaFile) (uint64, error) {
+	data, err := core.DownloadFile(bot, media.fileID, maxThumbnailBytes)
+	if err != nil {
+		return 0, err
+	}
+	return dhashBytes(data)
+}
+
+// InspectMedia 把消息里的图片、贴纸与特征库比对; 会下载图片缩略图, 除此之外无副作用
+func InspectMedia(bot *tgbotapi.BotAPI, message *tgbotapi.Message) Verdict {
+	media, ok := messageMedia(message)
+	if !ok {
+		return Verdict{}
+	}
+	mode := RuleMode(RuleIDMediaHash)
+	if mode == core.ModeOff {
+		return Verdict{}
+	}
+	verdict := func(entry core.MediaHash, detail string) Verdict {
+		return Verdict{Hit: true, Rule: ruleMediaHash, Detail: detail, MediaHash: entry.Key(), Shadow: mode == core.ModeShadow}
+	}
+
+	if entry, found, err := core.DB.LookupMediaFile(media.uniqueID); err != nil {
+		log.Printf("[Moderation] 查询图片特征库失败: %v", err)
+		return Verdict{}
+	} else if found {
+		return verdict(entry, "同一文件")
+	}
+	if !media.photo {
+		return Verdict{}
+	}
+
+	if has, err := core.DB.HasPerceptualHashes(); err != nil || !has {
+		return Verdict{}
+	}
+	hash, err := photoDHash(bot, media)
+	if err != nil {
+		log.Printf("[Moderation] 计算图片特征失败, 跳过: %v", err)
+		return Verdict{}
+	}
+	entry, dist, found, err := core.DB.NearestMediaHash(hash, mediaHashDistance)
+	if err != nil || !found {
+		return Verdict{}
+	}
+	return verdict(entry, fmt.Sprintf("相似图片（相差 %d 位）", dist))
+}
+
+// LearnMedia 把消息里的图片或贴纸记入特征库, 供管理员 /ban 时调用; 返回新登记的特征数
+func LearnMedia(bot *tgbotapi.BotAPI, message *tgbotapi.Message, adminID int64) int {
+	media, ok := messageMedia(message)
+	if !ok {
+		return 0
+	}
+
+	entries := []core.MediaHash{{Kind: core.MediaFile, Hash: media.uniqueID}}
+	if media.photo {
+		if hash, err := photoDHash(bot, media); err != nil {
+			log.Printf("[Moderation] 计算图片特征失败, 只记录文件 ID: %v", err)
+		} else {
+			entries = append(entries, core.MediaHash{Kind: core.MediaDHash, Hash: core.FormatDHash(hash)})
+		}
+	}
+
+	learned := 0
+	for _, entry := range entries {
+		entry.Note = "管理员 /ban"
+		entry.AddedBy = adminID
+		added, err := core.DB.AddMediaHash(entry)
+		if err != nil {
+			log.Printf("[Moderation] 记录图片特征 %s 失败: %v", entry.Key(), err)
+			continue
+		}
+		if added {
+			learned++
+		}
+	}
+	return learned
+}
@@ -0,0 +1,70 @@
+package moderation
+
+import (
+	"math/bits"
+	"os"
+	"testing"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func fixtureDHash(t *testing.T, name string) uint64 {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("读取 %s 失败: %v", name, err)
+	}
+	hash, err := dhashBytes(data)
+	if err != nil {
+		t.Fatalf("计算 %s 的哈希失败: %v", name, err)
+	}
+	return hash
+}
+
+// TestDHash 同一张图缩小并重新压缩成 JPEG 后仍在阈值内, 不同的图相差很远
+func TestDHash(t *testing.T) {
+	ad := fixtureDHash(t, "ad.png")
+	small := fixtureDHash(t, "ad_small.jpg")
+	other := fixtureDHash(t, "other.png")
+
+	if dist := bits.OnesCount64(ad ^ small); dist > mediaHashDistance {
+		t.Errorf("缩小重压的同一张图相差 %d 位, 超过阈值 %d", dist, mediaHashDistance)
+	}
+	if dist := bits.OnesCount64(ad ^ other); dist <= mediaHashDistance {
+		t.Errorf("不同的图只相差 %d 位", dist)
+	}
+	if _, err := dhashBytes([]byte("not an image")); err == nil {
+		t.Error("无法解码的内容应当报错")
+	}
+}
+
+// TestInspectMedia 贴纸按 file_unique_id 精确匹配, 不用下载; 库里没有感知哈希时图片也不下载
+func TestInspectMedia(t *testing.T) {
+	db := useTempDB(t)
+	db.AddMediaHash(core.MediaHash{Kind: core.MediaFile, Hash: "sticker-1"})
+
+	sticker := &tgbotapi.Message{Sticker: &tgbotapi.Sticker{FileID: "f1", FileUniqueID: "sticker-1"}}
+	v := InspectMedia(nil, sticker)
+	if !v.Hit || v.Rule != ruleMediaHash || v.MediaHash != "file:sticker-1" {
+		t.Errorf("已登记的贴纸 = %+v", v)
+	}
+	if v := InspectMedia(nil, &tgbotapi.Message{Sticker: &tgbotapi.Sticker{FileUniqueID: "sticker-2"}}); v.Hit {
+		t.Errorf("未登记的贴纸不应命中: %+v", v)
+	}
+	// 库里只有文件 ID 时不会去下载图片, 这里传 nil bot 也不应出错
+	photo := &tgbotapi.Message{Photo: []tgbotapi.PhotoSize{{FileID: "p1", FileUniqueID: "photo-1"}}}
+	if v := InspectMedia(nil, photo); v.Hit {
+		t.Errorf("未登记的图片不应命中: %+v", v)
+	}
+
+	db.SetRuleMode(RuleIDMediaHash, core.ModeShadow)
+	if v := InspectMedia(nil, sticker); !v.Hit || !v.Shadow {
+		t.Errorf("试行模式下 = %+v", v)
+	}
+	db.SetRuleMode(RuleIDMediaHash, core.ModeOff)
+	if v := InspectMedia(nil, sticker); v.Hit {
+		t.Errorf("关闭后不应命中: %+v", v)
+	}
+}
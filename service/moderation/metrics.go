@@ -0,0 +1,13 @@
+package moderation
+
+// 审核结论的 Prometheus 指标, 注册说明见 core/metrics.go
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// verdictsTotal 按规则统计的审核结论; mode 为 enforce (实际处置) 或 shadow (试行, 只记录)
+var verdictsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "sunai_verdicts_total",
+	Help: "审核命中次数, 按规则与执行模式",
+}, []string{"rule", "mode"})
@@ -0,0 +1,116 @@
+package moderation
+
+// 新成员限制的执行: 限制期内的新成员发了群设置禁发的内容 (链接、频道转发、媒体等) 即撤回。
+// 这不是违规判定 —— 内容本身未必是广告, 只是还没到能发的时候, 所以只撤回、留提示, 不记分也不通知管理员。
+// 时长、内容类型与限制期的计算见 core/probation.go。
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// RuleIDProbation 新成员限制的规则 ID; 可以用 /mode 转为试行或停用
+const RuleIDProbation = "probation"
+
+// ruleProbation 新成员限制的规则标识
+const ruleProbation = "新成员限制"
+
+// probationNoticeTTL 群内提示的自毁时间
+const probationNoticeTTL = time.Minute
+
+// probationContent 找出消息里属于限制范围的内容类型, 按 core.ProbationTypes 的顺序返回
+func probationContent(message *tgbotapi.Message, blocked []string) []string {
+	var found []string
+	for _, kind := range blocked {
+		if messageHas(message, kind) {
+			found = append(found, kind)
+		}
+	}
+	return found
+}
+
+// messageHas 判断消息是否含有某类内容
+func messageHas(message *tgbotapi.Message, kind string) bool {
+	switch kind {
+	case core.ProbationLink:
+		return containsLink(MessageText(message)) || hasLinkEntity(message.Entities) || hasLinkEntity(message.CaptionEntities)
+	case core.ProbationForward:
+		return message.ForwardFromChat != nil && !forwardAllowed(message)
+	case core.ProbationMedia:
+		// 贴纸和表情不算: 新成员打招呼常用, 拦了只会误伤
+		return len(message.Photo) > 0 || message.Video != nil || message.Document != nil || message.Animation != nil ||
+			message.Audio != nil || message.Voice != nil || message.VideoNote != nil
+	case core.ProbationButton:
+		return message.ReplyMarkup != nil && len(message.ReplyMarkup.InlineKeyboard) > 0
+	case core.ProbationContact:
+		return message.Contact != nil
+	default:
+		return false
+	}
+}
+
+// hasLinkEntity 正文里看不出链接、但挂了超链接的文字 (text_link) 同样算链接
+func hasLinkEntity(entities []tgbotapi.MessageEntity) bool {
+	for _, entity := range entities {
+		if entity.Type == "url" || entity.Type == "text_link" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkProbation 限制期内的新成员发了禁发内容时撤回消息, 返回是否已撤回
+func checkProbation(bot *tgbotapi.BotAPI, message *tgbotapi.Message, text string, stats SenderStats) bool {
+	group, ok := core.GroupSettings(message.Chat.ID)
+	if !ok || group.ProbationWindow() <= 0 {
+		return false
+	}
+	mode := RuleMode(RuleIDProbation)
+	if mode == core.ModeOff {
+		return false
+	}
+
+	found := probationContent(message, group.ProbationBlocks())
+	if len(found) == 0 {
+		return false
+	}
+
+	sender := Sender(message)
+	// 统计读取失败时 Found 为 false, 按放行处理, 与关键词读取失败时一致
+	ends, onProbation := core.ProbationEnds(stats.Stat, stats.Found, group.ProbationWindow(), time.Now())
+	if !onProbation {
+		return false
+	}
+
+	labels := make([]string, 0, len(found))
+	for _, kind := range found {
+		labels = append(labels, core.ProbationLabel(kind))
+	}
+	if mode == core.ModeShadow {
+		recordShadow(message, text, Verdict{Hit: true, Rule: ruleProbation, Detail: strings.Join(labels, "、"), Shadow: true})
+		return false
+	}
+
+	core.DeleteMessages(bot, message.Chat.ID, message.MessageID)
+	log.Printf("[Moderation] 新成员 %d(%s) 限制期内发送%s, 已撤回", sender.ID, sender.UserName, strings.Join(labels, "、"))
+
+	notice := fmt.Sprintf("%s 入群 %d 小时内不能发送%s，消息已撤回（约 %s 后解除）。",
+		DisplayName(sender), group.ProbationHours, strings.Join(labels, "、"), remainingLabel(time.Until(ends)))
+	if sent, err := bot.Send(tgbotapi.NewMessage(message.Chat.ID, notice)); err == nil {
+		core.DeleteMessageAfterDelay(bot, message.Chat.ID, sent.MessageID, probationNoticeTTL)
+	}
+	return true
+}
+
+// remainingLabel 剩余时长的粗略展示, 不足一小时按分钟说
+func remainingLabel(d time.Duration) string {
+	if d >= time.Hour {
+		return fmt.Sprintf("%d 小时", int(d.Round(time.Hour)/time.Hour))
+	}
+	return fmt.Sprintf("%d 分钟", max(int(d/time.Minute), 1))
+}
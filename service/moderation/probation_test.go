@@ -0,0 +1,85 @@
+package moderation
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TestProbationContent 只报告群设置里禁发的类型; 藏在超链接文字里的链接同样算
+func TestProbationContent(t *testing.T) {
+	db := useTempDB(t)
+	db.SetSourceChannel(core.SourceChannel{Ref: "officialnews", List: core.ChannelAllow})
+
+	all := core.ProbationTypes()
+	cases := []struct {
+		name    string
+		message tgbotapi.Message
+		blocked []string
+		want    []string
+	}{
+		{"纯文字", tgbotapi.Message{Text: "大家好"}, all, nil},
+		{"裸链接", tgbotapi.Message{Text: "看看 example.com"}, all, []string{core.ProbationLink}},
+		{"超链接文字", tgbotapi.Message{Text: "点这里", Entities: []tgbotapi.MessageEntity{{Type: "text_link", URL: "https://example.com"}}}, all, []string{core.ProbationLink}},
+		{"频道转发带图", tgbotapi.Message{ForwardFromChat: &tgbotapi.Chat{ID: -1001}, Photo: []tgbotapi.PhotoSize{{FileID: "x"}}}, all, []string{core.ProbationForward, core.ProbationMedia}},
+		{"白名单频道的转发", tgbotapi.Message{ForwardFromChat: &tgbotapi.Chat{ID: -1002, UserName: "OfficialNews"}}, all, nil},
+		{"贴纸不算媒体", tgbotapi.Message{Sticker: &tgbotapi.Sticker{FileID: "x"}}, all, nil},
+		{"名片", tgbotapi.Message{Contact: &tgbotapi.Contact{PhoneNumber: "123"}}, all, []string{core.ProbationContact}},
+		{"未禁发的类型放行", tgbotapi.Message{Text: "example.com", Contact: &tgbotapi.Contact{}}, []string{core.ProbationContact}, []string{core.ProbationContact}},
+	}
+	for _, c := range cases {
+		if got := probationContent(&c.message, c.blocked); !slices.Equal(got, c.want) {
+			t.Errorf("%s: probationContent = %v, 期望 %v", c.name, got, c.want)
+		}
+	}
+}
+
+// TestProbationWithoutAI AI 关闭时发言照样计数; 限制期按首次出现算, 老成员发链接不受限, 统计读不到时也不受限
+func TestProbationWithoutAI(t *testing.T) {
+	db := useTempDB(t)
+	previous := core.AIEnabled
+	core.AIEnabled = false
+	t.Cleanup(func() { core.AIEnabled = previous })
+
+	group := core.DefaultGroup(-100, "测试群")
+	group.ProbationHours = 24
+	if err := db.SaveGroup(group); err != nil {
+		t.Fatalf("登记群失败: %v", err)
+	}
+	message := func(userID int64, text string) *tgbotapi.Message {
+		return &tgbotapi.Message{MessageID: 1, From: &tgbotapi.User{ID: userID}, Chat: &tgbotapi.Chat{ID: -100, Type: "supergroup"}, Text: text}
+	}
+
+	var stats SenderStats
+	for range 3 {
+		stats = ObserveSender(message(2, "大家好"))
+	}
+	edited := message(2, "大家好呀")
+	edited.EditDate = 1
+	if got := ObserveSender(edited); !stats.Found || stats.Stat.MessageCount != 3 || got.Stat.MessageCount != 3 {
+		t.Fatalf("AI 关闭时的发言计数 = %+v / 编辑后 %+v, 期望 3 条且编辑不计数", stats, got)
+	}
+
+	veteran := SenderStats{Stat: core.UserStat{UserID: 1, ChatID: -100, MessageCount: 200, FirstSeenAt: time.Now().Add(-72 * time.Hour)}, Found: true}
+	cases := []struct {
+		name    string
+		userID  int64
+		stats   SenderStats
+		removed bool
+	}{
+		{"老成员", 1, veteran, false},
+		{"统计读取失败", 3, SenderStats{}, false},
+		{"刚开口的新成员", 2, stats, true},
+	}
+	for _, c := range cases {
+		bot, fake := newFakeBot(t)
+		got := checkProbation(bot, message(c.userID, "看看 example.com"), "看看 example.com", c.stats)
+		if got != c.removed || fake.called("deleteMessage") != c.removed {
+			t.Errorf("%s: 是否撤回 = %v, 期望 %v", c.name, got, c.removed)
+		}
+	}
+}
@@ -85,9 +85,9 @@ func TestNormalizeEnablesKeywordMatch(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		got := matchKeyword(c.text, []string{c.keyword}) != ""
+		got := len(newKeywordIndex([]string{c.keyword}).find(Normalize(c.text))) > 0
 		if got != c.wantHit {
-			t.Errorf("matchKeyword(%q, %q) = %v, 期望 %v", c.text, c.keyword, got, c.wantHit)
+			t.Errorf("关键词 %q 对 %q 命中 = %v, 期望 %v", c.keyword, c.text, got, c.wantHit)
 		}
 	}
 }
@@ -0,0 +1,200 @@
+package moderation
+
+// 试行 (shadow) 规则的记录与汇总。
+//
+// 试行命中不删消息、不记分, 只在 moderation_actions 里留一条 shadow 记录;
+// 管理员每天收到一份汇总, 看清楚新规则在真实流量里本会拦下什么, 再决定要不要转为正式执行。
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// 内置规则的 ID, 用于 /mode 指定与 config 表存储; 词表类规则按词条各自设置, 不在此列
+const (
+	RuleIDZeroWidth  = "zerowidth"
+	RuleIDObfuscated = "obfuscated"
+	RuleIDFlooding   = "flooding"
+	RuleIDAI         = "ai"
+)
+
+// builtinRules 内置规则 ID 到展示名的映射
+var builtinRules = map[string]string{
+	RuleIDZeroWidth:     ruleZeroWidth,
+	RuleIDObfuscated:    ruleObfuscated,
+	RuleIDFlooding:      ruleFlooding,
+	RuleIDAI:            ruleAI,
+	RuleIDProbation:     ruleProbation,
+	RuleIDSourceChannel: ruleSourceChannel,
+	RuleIDDomain:        ruleDomainBlock,
+	RuleIDStrictLinks:   ruleStrictLinks,
+	RuleIDMediaHash:     ruleMediaHash,
+	RuleIDDuplicate:     ruleDuplicate,
+	RuleIDRateLimit:     ruleRateLimit,
+	RuleIDRaid:          ruleRaid,
+}
+
+const (
+	// shadowDigestInterval 汇总间隔
+	shadowDigestInterval = 24 * time.Hour
+	// shadowDigestKey config 表里记录上次汇总时间的键; 落库是为了重启后不重复汇总、也不漏掉
+	shadowDigestKey = "shadow_digest_at"
+	// shadowDigestTop 汇总里最多列出的条目数
+	shadowDigestTop = 15
+)
+
+// BuiltinRule 返回内置规则的展示名, 第二个返回值表示 ID 是否存在
+func BuiltinRule(id string) (string, bool) {
+	label, ok := builtinRules[id]
+	return label, ok
+}
+
+// BuiltinRuleIDs 按字母序列出全部内置规则 ID
+func BuiltinRuleIDs() []string {
+	ids := make([]string, 0, len(builtinRules))
+	for id := range builtinRules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// RuleMode 读取内置规则的执行模式; 查库失败按 enforce 处理, 不因数据库抖动让规则失效
+func RuleMode(id string) string {
+	mode, err := core.DB.GetRuleMode(id)
+	if err != nil {
+		log.Printf("[Moderation] 读取规则 %s 的执行模式失败, 按执行处理: %v", id, err)
+		return core.ModeEnforce
+	}
+	return mode
+}
+
+// recordShadow 记下一次试行命中: 日志 + shadow 处置记录, 词条命中次数照常累加
+func recordShadow(message *tgbotapi.Message, text string, verdict Verdict) {
+	user := Sender(message)
+	log.Printf("[Moderation] 试行「%s」%s 本会拦截, 用户 %d(%s): %s",
+		verdict.Rule, verdict.Detail, user.ID, user.UserName, truncate(text, logTextLimit))
+	verdictsTotal.WithLabelValues(verdict.Rule, core.ModeShadow).Inc()
+
+	for _, keyword := range verdict.Keywords {
+		if err := core.DB.RecordKeywordHit(keyword); err != nil {
+			log.Printf("[Moderation] 记录关键词命中失败: %v", err)
+		}
+	}
+
+	_, err := core.DB.RecordModerationAction(core.ModerationAction{
+		UserID:      user.ID,
+		ChatID:      message.Chat.ID,
+		UserName:    DisplayName(user),
+		MessageText: text,
+		Rule:        verdict.Rule,
+		Detail:      verdict.Detail,
+		Shadow:      true,
+	})
+	if err != nil {
+		log.Printf("[Moderation] 记录试行命中失败: %v", err)
+	}
+}
+
+// RecordExternalShadow 记录外部判定器 (当前是 AI 审核) 的试行命中; rule 说明是哪种试行, 如"AI 判定（试行阈值）"
+func RecordExternalShadow(message *tgbotapi.Message, text, rule, detail string) {
+	recordShadow(message, text, Verdict{Hit: true, Rule: rule, Detail: detail, Shadow: true})
+}
+
+// SendShadowDigest 距上次汇总满一个周期时, 把期间的试行命中汇总推给有处置权限的管理员。
+// 没有命中时只推进汇总时间, 不打扰管理员。供定时任务周期调用, 不到时间直接返回。
+func SendShadowDigest(bot *tgbotapi.BotAPI) {
+	since := lastShadowDigest()
+	if time.Since(since) < shadowDigestInterval {
+		return
+	}
+
+	actions, err := core.DB.GetShadowActions(since)
+	if err != nil {
+		log.Printf("[Moderation] 读取试行记录失败: %v", err)
+		return
+	}
+
+	if len(actions) > 0 {
+		digest := buildShadowDigest(since, actions)
+		for _, adminID := range core.AdminsWith(core.PermModerate) {
+			if err := core.SendLongMessage(bot, adminID, digest.header, digest.lines); err != nil {
+				log.Printf("[Moderation] 推送试行汇总给管理员 %d 失败: %v", adminID, err)
+			}
+		}
+	}
+
+	if err := core.DB.SetConfig(shadowDigestKey, time.Now().Format(time.RFC3339)); err != nil {
+		log.Printf("[Moderation] 记录试行汇总时间失败: %v", err)
+	}
+}
+
+// lastShadowDigest 上次汇总时间; 从未汇总过时按一个周期前算, 首次汇总覆盖最近一天
+func lastShadowDigest() time.Time {
+	raw, err := core.DB.GetConfig(shadowDigestKey)
+	if err == nil && raw != "" {
+		if at, err := time.Parse(time.RFC3339, raw); err == nil {
+			return at
+		}
+	}
+	return time.Now().Add(-shadowDigestInterval)
+}
+
+// shadowDigest 汇总消息, 条目较多时由 SendLongMessage 分段发送
+type shadowDigest struct {
+	header string
+	lines  []string
+}
+
+// shadowGroup 同一规则、同一命中细节的试行记录
+type shadowGroup struct {
+	rule, detail string
+	count        int
+	sample       string
+}
+
+// buildShadowDigest 把试行记录按规则与命中细节聚合, 按次数降序列出, 每组附一条原文样例。
+// AI 的细节里带置信度, 每条都不同, 只按规则聚合。
+func buildShadowDigest(since time.Time, actions []core.ModerationAction) shadowDigest {
+	groups := make(map[string]*shadowGroup)
+	var order []*shadowGroup
+	for _, action := range actions {
+		detail := action.Detail
+		if strings.HasPrefix(action.Rule, ruleAI) {
+			detail = ""
+		}
+
+		key := action.Rule + "\x00" + detail
+		group, ok := groups[key]
+		if !ok {
+			group = &shadowGroup{rule: action.Rule, detail: detail, sample: action.MessageText}
+			groups[key] = group
+			order = append(order, group)
+		}
+		group.count++
+	}
+	sort.SliceStable(order, func(i, j int) bool { return order[i].count > order[j].count })
+
+	lines := make([]string, 0, shadowDigestTop+1)
+	for i, group := range order {
+		if i == shadowDigestTop {
+			lines = append(lines, fmt.Sprintf("……另有 %d 组未列出", len(order)-shadowDigestTop))
+			break
+		}
+		label := group.rule
+		if group.detail != "" {
+			label += "：" + group.detail
+		}
+		lines = append(lines, fmt.Sprintf("• %s —— %d 次\n  例：%s", label, group.count, truncate(group.sample, 60)))
+	}
+
+	header := fmt.Sprintf("🧪 试行规则汇总（%s 至今，共 %d 次本会拦截）\n确认无误后用 /mode enforce 转为正式执行：",
+		since.Format("01-02 15:04"), len(actions))
+	return shadowDigest{header: header, lines: lines}
+}
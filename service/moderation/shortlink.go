@@ -0,0 +1,234 @@
+package moderation
+
+// 短链展开: 广告用短链服务包一层, 消息里能看到的域名永远是 bit.ly 一类, 名单和词表都对不上。
+//
+// 开启后 (SHORT_LINK_RESOLVE) 对已知短链服务的链接逐跳跟随 HTTP 跳转, 拿到落地地址再查域名名单与词表。
+// 只请求短链服务本身: 跳到短链服务以外的地址就停下, 不去访问广告落地页。
+// 展开结果落库, 同一个短链只请求一次; 失败的结果也记下, 隔一段时间再试, 免得刷屏时反复请求一个失效的短链。
+// 每条更新各占一个 goroutine, 一波广告同时带着同一个短链进来时, 只让其中一个去请求, 其余等它的结果。
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// shortLinkTimeout 展开一个短链 (含全部跳转) 的总时限; 审核在消息处理路径上同步进行, 不能等太久
+	shortLinkTimeout = 5 * time.Second
+	// shortLinkMaxHops 最多跟随的跳转次数, 防止跳转环
+	shortLinkMaxHops = 5
+	// shortLinkRetry 展开失败的短链隔多久再试
+	shortLinkRetry = time.Hour
+	// shortLinkMaxPerMessage 一条消息最多展开几个短链; 每个都可能占满 shortLinkTimeout, 不设上限会拖住处理更新的 worker
+	shortLinkMaxPerMessage = 3
+)
+
+// partLanding 短链落地地址的来源标注
+const partLanding = "短链落地页"
+
+// defaultShortenerHosts 内置的短链服务清单; 其余的用 SHORT_LINK_HOSTS 补充
+var defaultShortenerHosts = []string{
+	"bit.ly", "bitly.com", "tinyurl.com", "t.cn", "url.cn", "dwz.cn", "suo.im", "is.gd", "v.gd",
+	"cutt.ly", "ow.ly", "rebrand.ly", "rb.gy", "s.id", "t.ly", "shorturl.at", "tiny.cc", "goo.su", "clck.ru",
+}
+
+// errTooManyHops 跳转次数超过 shortLinkMaxHops
+var errTooManyHops = errors.New("跳转次数过多")
+
+// ShortLinkResolver 跟随短链服务的跳转取得落地地址, 结果缓存在 short_links 表
+type ShortLinkResolver struct {
+	client  *http.Client
+	hosts   map[string]bool
+	maxHops int
+	flight  singleflight.Group // 按 shortLinkKey 合并同时进行的展开
+}
+
+// NewShortLinkResolver 按内置清单加上 extraHosts 创建展开器
+func NewShortLinkResolver(extraHosts []string) *ShortLinkResolver {
+	hosts := make(map[string]bool, len(defaultShortenerHosts)+len(extraHosts))
+	for _, host := range append(defaultShortenerHosts, extraHosts...) {
+		hosts[host] = true
+	}
+	return &ShortLinkResolver{
+		client: &http.Client{
+			Timeout: shortLinkTimeout,
+			// 跳转由 follow 逐跳处理, 才能在离开短链服务时停下
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		hosts:   hosts,
+		maxHops: shortLinkMaxHops,
+	}
+}
+
+// shortLinks 当前使用的展开器, 为 nil 表示未开启
+var shortLinks *ShortLinkResolver
+
+// ConfigureShortLinks 按配置开启短链展开, 启动时调用一次
+func ConfigureShortLinks() {
+	if !core.ShortLinkResolve {
+		return
+	}
+	shortLinks = NewShortLinkResolver(core.ShortLinkHosts)
+	log.Printf("[Moderation] 短链展开已开启, 共 %d 个短链域名", len(shortLinks.hosts))
+}
+
+// IsShortener 判断主机名是否属于短链服务
+func (r *ShortLinkResolver) IsShortener(host string) bool {
+	return r.hosts[host]
+}
+
+// Resolve 返回短链的落地地址; 先查缓存, 未展开过或上次失败已过重试间隔时才发请求。
+// 缓存与合并都按 shortLinkKey 归并, bit.ly/x 与 https://bit.ly/x 算同一个短链
+func (r *ShortLinkResolver) Resolve(ctx context.Context, link string) (string, error) {
+	key := shortLinkKey(link)
+	final, err, _ := r.flight.Do(key, func() (any, error) {
+		return r.resolve(ctx, key, link)
+	})
+	return final.(string), err
+}
+
+// resolve 查缓存, 需要时展开并落库; 由 Resolve 保证同一个 key 同时只有一个在跑
+func (r *ShortLinkResolver) resolve(ctx context.Context, key, link string) (string, error) {
+	cached, ok, err := core.DB.GetShortLink(key)
+	if err != nil {
+		log.Printf("[Moderation] 读取短链缓存失败: %v", err)
+	}
+	if ok && (cached.Error == "" || time.Since(cached.ResolvedAt) < shortLinkRetry) {
+		if cached.Error != "" {
+			return "", errors.New(cached.Error)
+		}
+		return cached.Final, nil
+	}
+
+	final, hops, err := r.follow(ctx, link)
+	record := core.ShortLink{URL: key, Final: final, Hops: hops}
+	if err != nil {
+		record = core.ShortLink{URL: key, Hops: hops, Error: err.Error()}
+	}
+	if saveErr := core.DB.SaveShortLink(record); saveErr != nil {
+		log.Printf("[Moderation] 保存短链缓存失败: %v", saveErr)
+	}
+	return final, err
+}
+
+// shortLinkKey 短链的归一形式: 去掉协议与锚点, 主机名转小写; 路径区分大小写, 短链的编码就在路径里
+func shortLinkKey(link string) string {
+	u, err := url.Parse(withScheme(strings.TrimSpace(link)))
+	if err != nil {
+		return link
+	}
+	key := strings.ToLower(u.Host) + strings.TrimSuffix(u.EscapedPath(), "/")
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key
+}
+
+// follow 逐跳请求, 直到不再跳转或跳出了短链服务; 返回落地地址与实际跳转次数
+func (r *ShortLinkResolver) follow(ctx context.Context, link string) (string, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, shortLinkTimeout)
+	defer cancel()
+
+	current, err := url.Parse(withScheme(link))
+	if err != nil {
+		return "", 0, err
+	}
+	for hops := 0; ; hops++ {
+		if !r.IsShortener(core.HostOf(current.String())) {
+			return current.String(), hops, nil
+		}
+		if hops >= r.maxHops {
+			return "", hops, errTooManyHops
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, current.String(), nil)
+		if err != nil {
+			return "", hops, err
+		}
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return "", hops, err
+		}
+		resp.Body.Close()
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+			// 不再跳转: 短链服务本身就是终点 (失效页、拦截页), 按原地址处理
+			return current.String(), hops, nil
+		}
+		next, err := current.Parse(location)
+		if err != nil {
+			return "", hops, fmt.Errorf("无法解析跳转地址 %q: %w", location, err)
+		}
+		current = next
+	}
+}
+
+// withScheme 消息里的链接常常不带协议, 按 http 补上; 短链服务一般会再跳到 https
+func withScheme(link string) string {
+	if u, err := url.Parse(link); err == nil && u.Scheme != "" && u.Host != "" {
+		return link
+	}
+	return "http://" + link
+}
+
+// InspectShortLinks 展开消息里的短链, 用落地地址查域名黑名单与词表; 未开启短链展开时直接返回
+func InspectShortLinks(chatID int64, topicID int, message *tgbotapi.Message) Verdict {
+	if shortLinks == nil {
+		return Verdict{}
+	}
+
+	var shadow Verdict
+	seen := make(map[string]bool)
+	for _, link := range messageLinks(message) {
+		if !shortLinks.IsShortener(core.HostOf(link)) || seen[shortLinkKey(link)] {
+			continue
+		}
+		if len(seen) >= shortLinkMaxPerMessage {
+			log.Printf("[Moderation] 消息 %d 的短链超过 %d 个, 其余不再展开", message.MessageID, shortLinkMaxPerMessage)
+			break
+		}
+		seen[shortLinkKey(link)] = true
+		final, err := shortLinks.Resolve(context.Background(), link)
+		if err != nil {
+			log.Printf("[Moderation] 展开短链 %s 失败: %v", link, err)
+			continue
+		}
+
+		if host := core.HostOf(final); domainList(host) == core.DomainBlock {
+			if mode := RuleMode(RuleIDDomain); mode != core.ModeOff {
+				v := Verdict{Hit: true, Rule: ruleDomainBlock, Detail: link + " → " + host, Shadow: mode == core.ModeShadow}
+				if !v.Shadow {
+					return v
+				}
+				if !shadow.Hit {
+					shadow = v
+				}
+			}
+		}
+
+		keywords, err := keywordsFor(chatID, topicID)
+		if err != nil {
+			log.Printf("[Moderation] 读取关键词失败, 跳过短链落地页检查: %v", err)
+			return shadow
+		}
+		enforced, shadowHit := matchText(keywords, final, "")
+		if enforced.Hit {
+			return tagPart(enforced, partLanding)
+		}
+		if shadowHit.Hit && !shadow.Hit {
+			shadow = tagPart(shadowHit, partLanding)
+		}
+	}
+	return shadow
+}
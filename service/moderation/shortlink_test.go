@@ -0,0 +1,140 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// newTestShortener 起一个本地短链服务: /a 跳 /b, /b 跳到外部落地页, /loop 跳回自己; 返回服务与请求计数
+func newTestShortener(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "https://promo.landing.example/buy?ref=fruitshop", http.StatusMovedPermanently)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/slow":
+			// 拖一会儿, 让并发的展开都赶上这一次请求
+			time.Sleep(100 * time.Millisecond)
+			http.Redirect(w, r, "https://promo.landing.example/slow", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// TestShortLinkResolve 逐跳跟随到离开短链服务为止, 不访问落地页; 结果落库, 第二次不再请求
+func TestShortLinkResolve(t *testing.T) {
+	useTempDB(t)
+	server, requests := newTestShortener(t)
+	resolver := NewShortLinkResolver([]string{"127.0.0.1"})
+
+	final, err := resolver.Resolve(context.Background(), server.URL+"/a")
+	if err != nil || final != "https://promo.landing.example/buy?ref=fruitshop" {
+		t.Fatalf("Resolve = %q,%v", final, err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("请求了 %d 次, 期望 2 次 (不访问落地页)", n)
+	}
+
+	if final, err := resolver.Resolve(context.Background(), server.URL+"/a"); err != nil || !strings.Contains(final, "promo.landing.example") {
+		t.Errorf("缓存命中 = %q,%v", final, err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("命中缓存后又请求了 %d 次", n-2)
+	}
+
+	if _, err := resolver.Resolve(context.Background(), server.URL+"/loop"); err == nil {
+		t.Error("跳转环应当在次数上限处报错")
+	}
+	if link, ok, _ := core.DB.GetShortLink(shortLinkKey(server.URL + "/loop")); !ok || link.Error == "" {
+		t.Errorf("失败结果也应落库: %+v", link)
+	}
+}
+
+// TestShortLinkResolveOnce 同一个短链的不同写法同时到达, 只向短链服务请求一次
+func TestShortLinkResolveOnce(t *testing.T) {
+	useTempDB(t)
+	server, requests := newTestShortener(t)
+	resolver := NewShortLinkResolver([]string{"127.0.0.1"})
+
+	bare := strings.TrimPrefix(server.URL, "http://") + "/slow"
+	forms := []string{server.URL + "/slow", bare, server.URL + "/slow#top", strings.ToUpper("http://") + bare}
+	var wg sync.WaitGroup
+	for i := range 12 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if final, err := resolver.Resolve(context.Background(), forms[i%len(forms)]); err != nil || final != "https://promo.landing.example/slow" {
+				t.Errorf("Resolve(%q) = %q,%v", forms[i%len(forms)], final, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := requests.Load(); n != 1 {
+		t.Errorf("请求了 %d 次, 期望只请求 1 次", n)
+	}
+}
+
+// TestInspectShortLinksLimit 一条消息里的短链超过上限时, 多出来的不再展开
+func TestInspectShortLinksLimit(t *testing.T) {
+	useTempDB(t)
+	server, requests := newTestShortener(t)
+	shortLinks = NewShortLinkResolver([]string{"127.0.0.1"})
+	t.Cleanup(func() { shortLinks = nil })
+
+	message := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: -100}, Text: "12345"}
+	for i := range 5 {
+		message.Entities = append(message.Entities, tgbotapi.MessageEntity{Type: "text_link", Offset: i, Length: 1, URL: fmt.Sprintf("%s/c%d", server.URL, i)})
+	}
+	InspectShortLinks(-100, 0, message)
+	if n := requests.Load(); n != shortLinkMaxPerMessage {
+		t.Errorf("展开了 %d 个短链, 期望只展开 %d 个", n, shortLinkMaxPerMessage)
+	}
+}
+
+// TestInspectShortLinks 落地页的域名查黑名单, 落地地址查词表并标注位置
+func TestInspectShortLinks(t *testing.T) {
+	db := useTempDB(t)
+	server, _ := newTestShortener(t)
+	shortLinks = NewShortLinkResolver([]string{"127.0.0.1"})
+	t.Cleanup(func() { shortLinks = nil })
+
+	// 本地服务的地址是 IP, 正文里的裸 IP 不算链接, 用超链接挂上
+	message := &tgbotapi.Message{
+		Chat:     &tgbotapi.Chat{ID: -100},
+		Text:     "领福利",
+		Entities: []tgbotapi.MessageEntity{{Type: "text_link", Offset: 0, Length: 3, URL: server.URL + "/a"}},
+	}
+	if v := InspectShortLinks(-100, 0, message); v.Hit {
+		t.Fatalf("名单与词表都为空时不应命中: %+v", v)
+	}
+
+	db.AddKeyword("fruitshop", core.SourceManual)
+	if v := InspectShortLinks(-100, 0, message); !v.Hit || v.Part != partLanding || v.Rule != ruleKeyword {
+		t.Errorf("落地地址命中词表 = %+v", v)
+	}
+
+	db.SetLinkDomain(core.LinkDomain{Domain: "landing.example", List: core.DomainBlock})
+	if v := InspectShortLinks(-100, 0, message); v.Rule != ruleDomainBlock || !strings.HasSuffix(v.Detail, "→ promo.landing.example") {
+		t.Errorf("落地域名在黑名单上 = %+v", v)
+	}
+}
@@ -0,0 +1,50 @@
+package moderation
+
+// 发送者在本群的发言统计。
+//
+// 每条受审核的消息先记一次发言并算出信任等级, 再把统计交给审核各环节 (突袭、新成员限制、链接白名单、
+// 昵称检查) 与 AI 审核共用, 一条消息只查一次库。计数与 AI 是否开启无关: 新成员限制与信任等级都靠它判断谁是老成员,
+// 只在 AI 开启时计数的话, 关掉 AI 的部署里人人都是"刚出现"。
+import (
+	"log"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// SenderStats 发送者在本群的发言统计
+type SenderStats struct {
+	Stat  core.UserStat
+	Found bool // 读到了统计; 查库失败时为 false, 各环节按老成员放行
+	Fresh bool // 统计是本条消息才建的: 此前在本群既没有入群记录也没发过言, 说明不了是不是新来的
+
+	Reputation core.Reputation
+	Rated      bool // 算出了信任等级; 频道身份或查库失败时为 false, 按普通用户对待
+}
+
+// ObserveSender 记下一次发言并返回发送者的统计与信任等级; 频道身份按频道计数。编辑不算新的发言, 只读不计数
+func ObserveSender(message *tgbotapi.Message) SenderStats {
+	sender := Sender(message)
+	if sender == nil {
+		return SenderStats{}
+	}
+
+	if message.EditDate != 0 {
+		stat, found, err := core.DB.GetUserStat(sender.ID, message.Chat.ID)
+		if err != nil {
+			log.Printf("[Moderation] 读取用户 %d 的发言统计失败: %v", sender.ID, err)
+			return SenderStats{}
+		}
+		rep, rated := rateSender(message, sender, stat, found)
+		return SenderStats{Stat: stat, Found: found, Reputation: rep, Rated: rated}
+	}
+
+	stat, created, err := core.DB.BumpUserMessageCount(sender.ID, message.Chat.ID)
+	if err != nil {
+		log.Printf("[Moderation] 更新用户 %d 的发言计数失败: %v", sender.ID, err)
+		return SenderStats{}
+	}
+	rep, rated := rateSender(message, sender, stat, true)
+	return SenderStats{Stat: stat, Found: true, Fresh: created, Reputation: rep, Rated: rated}
+}
@@ -0,0 +1,34 @@
+package moderation
+
+// 信任等级在审核中的用法: 可信用户跳过昵称检查与 AI 审核, 低信任用户的链接按新成员对待。
+// 等级的计算见 core/trust.go; 每条消息由 ObserveSender 算一次, 随 SenderStats 交给各环节, 不在各处重复查库。
+import (
+	"log"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// rateSender 按已读到的发言统计算出发送者在本群的信任等级; 以频道身份发言或查库失败时返回 false, 调用方按普通用户对待
+func rateSender(message *tgbotapi.Message, sender *tgbotapi.User, stat core.UserStat, found bool) (core.Reputation, bool) {
+	if SentAsChannel(message) {
+		return core.Reputation{}, false
+	}
+	rep, err := core.DB.ReputationFor(sender.ID, message.Chat.ID, stat, found)
+	if err != nil {
+		log.Printf("[Moderation] 读取用户 %d 的信任等级失败: %v", sender.ID, err)
+		return core.Reputation{}, false
+	}
+	return rep, true
+}
+
+// Trusted 发送者是否为本群的可信用户
+func (s SenderStats) Trusted() bool {
+	return s.Rated && s.Reputation.Trusted()
+}
+
+// Distrusted 发送者是否为本群的低信任用户
+func (s SenderStats) Distrusted() bool {
+	return s.Rated && s.Reputation.Distrusted()
+}
@@ -0,0 +1,38 @@
+package moderation
+
+import (
+	"testing"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TestSenderReputation 真人按本群的记录取等级; 以频道身份发言的没有等级, 不能借用占位账号的记录
+func TestSenderReputation(t *testing.T) {
+	db := useTempDB(t)
+	db.SetTrustOverride(7, []int64{-100}, core.TrustOverrideTrusted)
+
+	message := &tgbotapi.Message{From: &tgbotapi.User{ID: 7}, Chat: &tgbotapi.Chat{ID: -100, Type: "supergroup"}}
+	if stats := ObserveSender(message); !stats.Trusted() {
+		t.Errorf("被指定为可信的用户应当可信: %+v", stats)
+	}
+	// 没有记录只是不加分, 不能因此被当成低信任
+	message.Chat = &tgbotapi.Chat{ID: -200, Type: "supergroup"}
+	if stats := ObserveSender(message); !stats.Rated || stats.Distrusted() || stats.Trusted() {
+		t.Errorf("在别的群没有记录, 应为普通: %+v", stats)
+	}
+	db.AddStrike(7, -200, 1)
+	if stats := ObserveSender(message); !stats.Distrusted() {
+		t.Errorf("刚违规过的用户应为低信任: %+v", stats)
+	}
+
+	channel := &tgbotapi.Message{
+		From:       &tgbotapi.User{ID: 136817688, UserName: "Channel_Bot"},
+		SenderChat: &tgbotapi.Chat{ID: -1007, Type: "channel"},
+		Chat:       &tgbotapi.Chat{ID: -100, Type: "supergroup"},
+	}
+	if stats := ObserveSender(channel); stats.Rated {
+		t.Error("频道身份不应有信任等级")
+	}
+}
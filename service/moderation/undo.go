@@ -3,17 +3,19 @@ package moderation
 // 处置撤销: 管理员在通知消息上点一下按钮, 即可完整回滚一次误判。
 //
 // 撤销是整套自动化的安全阀, 也是 AI 的负反馈信号 —— 它同时做四件事:
-//  1. 解封用户 (若已被自动封禁)
-//  2. 扣回本次违规计分
+//  1. 解封或解除禁言 (若本次处罚执行了封禁或禁言), 由本次封禁带进封禁名单的一并移出; 频道身份解除对该频道的封禁
+//  2. 扣回本次违规计分 (按当时记的分数)
 //  3. 删除本次 AI 学到的关键词, 并写入否决表, AI 不得再添加
 //  4. 把被删的原文重新发回群里
 //
 // 第 3 步是关键: 误判不只是撤销一次动作, 还要阻止同样的误判再次发生。
 import (
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
+	"time"
 
 	"SunaiForum-Bot/core"
 
@@ -38,10 +40,10 @@ func IsUndoCallback(data string) bool {
 	return strings.HasPrefix(data, undoCallbackPrefix)
 }
 
-// HandleUndoCallback 处理撤销按钮点击。仅管理员可用。
+// HandleUndoCallback 处理撤销按钮点击。仅有处置权限的管理员可用。
 func HandleUndoCallback(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery) {
-	if query.From == nil || !core.IsAdmin(query.From.ID) {
-		answerCallback(bot, query.ID, "只有管理员可以操作")
+	if query.From == nil || !core.Can(query.From.ID, core.PermModerate) {
+		answerCallback(bot, query.ID, "只有版主或所有者可以操作")
 		return
 	}
 
@@ -51,29 +53,57 @@ func HandleUndoCallback(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery) {
 		return
 	}
 
+	summary, err := Undo(bot, actionID, query.From.ID)
+	switch {
+	case errors.Is(err, ErrActionNotFound):
+		answerCallback(bot, query.ID, "找不到这条处置记录")
+		return
+	case errors.Is(err, ErrAlreadyUndone):
+		answerCallback(bot, query.ID, "这条已经恢复过了")
+		return
+	case errors.Is(err, ErrShadowAction):
+		answerCallback(bot, query.ID, ErrShadowAction.Error())
+		return
+	case err != nil:
+		answerCallback(bot, query.ID, "操作失败")
+		return
+	}
+	answerCallback(bot, query.ID, "已恢复")
+	markNotificationUndone(bot, query, summary)
+}
+
+// 撤销失败的原因, 供按钮与网页面板分别给出提示
+var (
+	ErrActionNotFound = errors.New("找不到这条处置记录")
+	ErrAlreadyUndone  = errors.New("这条已经恢复过了")
+	ErrShadowAction   = errors.New("试行记录没有实际处置，无需恢复")
+)
+
+// Undo 按处置 id 完整回滚一次处置, 返回给管理员看的结果摘要; 权限由调用方检查。
+// 撤销按钮与网页面板共用, 两边连点或同时点都只会回滚一次。
+func Undo(bot *tgbotapi.BotAPI, actionID, adminID int64) (string, error) {
 	action, err := core.DB.GetModerationAction(actionID)
 	if err != nil {
 		log.Printf("[Moderation] 读取处置记录 %d 失败: %v", actionID, err)
-		answerCallback(bot, query.ID, "找不到这条处置记录")
-		return
+		return "", ErrActionNotFound
+	}
+	if action.Shadow {
+		return "", ErrShadowAction
 	}
 
 	// 幂等: 重复点击不应该反复解封、反复扣分
 	claimed, err := core.DB.MarkActionUndone(actionID)
 	if err != nil {
 		log.Printf("[Moderation] 标记撤销失败: %v", err)
-		answerCallback(bot, query.ID, "操作失败")
-		return
+		return "", err
 	}
 	if !claimed {
-		answerCallback(bot, query.ID, "这条已经恢复过了")
-		return
+		return "", ErrAlreadyUndone
 	}
 
 	summary := undoAction(bot, action)
-	answerCallback(bot, query.ID, "已恢复")
-	markNotificationUndone(bot, query, summary)
-	log.Printf("[Moderation] 管理员撤销了处置 %d (用户 %d): %s", actionID, action.UserID, summary)
+	log.Printf("[Moderation] 管理员 %d 撤销了处置 %d (用户 %d): %s", adminID, actionID, action.UserID, summary)
+	return summary, nil
 }
 
 // undoAction 执行实际的回滚动作, 返回给管理员看的结果摘要。
@@ -81,22 +111,52 @@ func HandleUndoCallback(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery) {
 func undoAction(bot *tgbotapi.BotAPI, action core.ModerationAction) string {
 	var done []string
 
-	if action.Banned {
-		unbanConfig := tgbotapi.UnbanChatMemberConfig{
-			ChatMemberConfig: tgbotapi.ChatMemberConfig{ChatID: action.ChatID, UserID: action.UserID},
-			OnlyIfBanned:     true,
+	switch {
+	case action.SenderChat && action.Banned:
+		if err := core.UnbanSenderChat(bot, action.ChatID, action.UserID); err != nil {
+			log.Printf("[Moderation] 解封频道身份 %d 失败: %v", action.UserID, err)
+		} else {
+			done = append(done, "已解封该频道身份")
 		}
-		if _, err := bot.Request(unbanConfig); err != nil {
+	case action.Banned:
+		if err := core.UnbanUser(bot, action.ChatID, action.UserID); err != nil {
 			log.Printf("[Moderation] 解封用户 %d 失败: %v", action.UserID, err)
 		} else {
 			done = append(done, "已解封")
 		}
+		// 本次封禁若把用户带进了封禁名单, 误判就要连名单一起撤掉, 否则其余群的同步封禁还在
+		if entry, ok, err := core.DB.GetBannedUser(action.UserID); err == nil && ok && entry.ActionID == action.ID {
+			if _, err := core.LiftFederatedBan(bot, action.UserID); err != nil {
+				log.Printf("[Moderation] 移出封禁名单失败: %v", err)
+			} else {
+				done = append(done, "已移出封禁名单并在各群解封")
+			}
+		}
+	case action.Penalty.Action == core.PenaltyMute:
+		// 禁言已自然到期的不必再解; 到期判断按处置时间推算, 不去问 Telegram
+		if time.Since(action.CreatedAt) >= action.Penalty.Duration {
+			break
+		}
+		// 同一个人身上可能还压着入群验证或突袭模式的禁言, 解禁会把它们一起解掉; 这两种到时会自行解除
+		if hold := pendingRestriction(action.ChatID, action.UserID); hold != "" {
+			done = append(done, hold+"，暂不解除禁言")
+			break
+		}
+		if err := core.UnmuteUser(bot, action.ChatID, action.UserID); err != nil {
+			log.Printf("[Moderation] 解除用户 %d 的禁言失败: %v", action.UserID, err)
+		} else {
+			done = append(done, "已解除禁言")
+		}
 	}
 
-	if err := core.DB.DecrementStrike(action.UserID, action.ChatID); err != nil {
-		log.Printf("[Moderation] 扣回违规计分失败: %v", err)
-	} else {
-		done = append(done, "已扣回 1 次计分")
+	// 引入权重之前的记录没有分数, 当时一律记 1 分; 频道身份的处置不记分, 也就没有可扣回的
+	if !action.SenderChat {
+		weight := max(action.Weight, 1)
+		if err := core.DB.DecrementStrike(action.UserID, action.ChatID, weight); err != nil {
+			log.Printf("[Moderation] 扣回违规计分失败: %v", err)
+		} else {
+			done = append(done, fmt.Sprintf("已扣回 %d 分", weight))
+		}
 	}
 
 	// 撤销属于"否决"语义: 删词之外还要显式拉黑, 阻止 AI 下次再提取同一个词
@@ -119,6 +179,15 @@ func undoAction(bot *tgbotapi.BotAPI, action core.ModerationAction) string {
 		done = append(done, fmt.Sprintf("已删除并永久否决关键词: %s", strings.Join(rejected, "、")))
 	}
 
+	// 按已知广告图片处置的误判, 说明那条特征登记错了, 留着还会继续误伤
+	if kind, hash, ok := core.ParseMediaKey(action.MediaHash); ok {
+		if removed, err := core.DB.RemoveMediaHash(kind, hash); err != nil {
+			log.Printf("[Moderation] 移除图片特征 %s 失败: %v", action.MediaHash, err)
+		} else if removed {
+			done = append(done, "已移除图片特征")
+		}
+	}
+
 	if restored := restoreMessage(bot, action); restored {
 		done = append(done, "已把原消息发回群里")
 	}
@@ -129,6 +198,21 @@ func undoAction(bot *tgbotapi.BotAPI, action core.ModerationAction) string {
 	return strings.Join(done, "；")
 }
 
+// pendingRestriction 返回用户身上另有的、不该被撤销一并解掉的限制; 没有时返回空串
+func pendingRestriction(chatID, userID int64) string {
+	if _, pending, err := core.DB.GetChallenge(chatID, userID); err != nil {
+		// 查不到就按仍在验证处理, 宁可让管理员手动解禁, 也不放过没答题的人
+		log.Printf("[Moderation] 读取用户 %d 的入群验证失败: %v", userID, err)
+		return "无法确认入群验证状态"
+	} else if pending {
+		return "该用户仍在入群验证中"
+	}
+	if raids.restricts(chatID, userID, time.Now()) {
+		return "该用户正受突袭模式限制"
+	}
+	return ""
+}
+
 // restoreMessage 把被删的原文重新发回群里。
 // Telegram 无法真正恢复已删除的消息, 只能由机器人代为转述并注明原作者。
 func restoreMessage(bot *tgbotapi.BotAPI, action core.ModerationAction) bool {
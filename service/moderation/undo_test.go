@@ -0,0 +1,92 @@
+package moderation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// fakeBot 假的 Bot API, 记下调用过的方法
+type fakeBot struct {
+	mu      sync.Mutex
+	methods []string
+}
+
+func (f *fakeBot) called(method string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Contains(f.methods, method)
+}
+
+func newFakeBot(t *testing.T) (*tgbotapi.BotAPI, *fakeBot) {
+	t.Helper()
+	fake := &fakeBot{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := path.Base(r.URL.Path)
+		fake.mu.Lock()
+		fake.methods = append(fake.methods, method)
+		fake.mu.Unlock()
+		switch method {
+		case "getMe":
+			w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"username":"test_bot"}}`))
+		case "getChat":
+			w.Write([]byte(`{"ok":true,"result":{"id":-100,"type":"supergroup","permissions":{"can_send_messages":true}}}`))
+		case "sendMessage":
+			w.Write([]byte(`{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":-100,"type":"supergroup"}}}`))
+		default:
+			w.Write([]byte(`{"ok":true,"result":true}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	bot, err := tgbotapi.NewBotAPIWithAPIEndpoint("123:test", server.URL+"/bot%s/%s")
+	if err != nil {
+		t.Fatalf("创建 bot 失败: %v", err)
+	}
+	return bot, fake
+}
+
+// TestUndoKeepsOtherRestrictions 撤销禁言时, 用户身上还压着入群验证或突袭限制的, 不去解禁
+func TestUndoKeepsOtherRestrictions(t *testing.T) {
+	db := useTempDB(t)
+	mute := core.Penalty{Action: core.PenaltyMute, Duration: time.Hour}
+	now := time.Now()
+
+	cases := []struct {
+		name   string
+		setup  func(userID int64)
+		unmute bool
+	}{
+		{"无其他限制", func(int64) {}, true},
+		{"入群验证中", func(userID int64) {
+			db.SaveChallenge(core.JoinChallenge{ChatID: -100, UserID: userID, Kind: core.CaptchaMath, ExpiresAt: now.Add(time.Minute)})
+		}, false},
+		{"突袭模式限制中", func(userID int64) {
+			raids.trigger(-100, "测试群", false, now)
+			raids.noteRestricted(-100, userID)
+		}, false},
+	}
+	t.Cleanup(func() { raids.expire(now.Add(raidCooldown)) })
+
+	for i, c := range cases {
+		userID := int64(1000 + i)
+		c.setup(userID)
+		bot, fake := newFakeBot(t)
+		summary := undoAction(bot, core.ModerationAction{ID: int64(i + 1), UserID: userID, ChatID: -100, Penalty: mute, CreatedAt: now})
+		if got := fake.called("restrictChatMember"); got != c.unmute {
+			t.Errorf("%s: 是否解除禁言 = %v, 期望 %v (%s)", c.name, got, c.unmute, summary)
+		}
+		if !c.unmute && !strings.Contains(summary, "暂不解除禁言") {
+			t.Errorf("%s: 摘要应说明没有解禁: %s", c.name, summary)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package moderation
+
+// 多账号重复内容: 广告团伙用十几个账号各发一次同样的内容, 单人刷屏的计数永远到不了阈值。
+//
+// 每条消息按归一化文本的相邻两字分片算 SimHash, 在本群最近的消息里找近似的副本 (汉明距离很小);
+// 时间窗内有足够多的不同账号发了近似内容 (群设置 duplicate, 默认 10 分钟 3 个), 就把这一批副本全部撤回,
+// 每个发送者各记一次违规。可信用户的消息照常记指纹但不计入账号数, 也不会被当成副本撤回:
+// 老成员转述、提醒"这是骗子"时原样引用广告是常事。
+// 首次成批清理时再找出这批副本共有的最长片段, 作为候选关键词推给管理员一键添加。
+// 与单人刷屏一样只放内存: 看的是几分钟内的节奏, 重启后重新观察即可。
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math/bits"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// RuleIDDuplicate 多账号重复内容的规则 ID
+const RuleIDDuplicate = "duplicate"
+
+// ruleDuplicate 多账号重复内容的规则标识
+const ruleDuplicate = "多账号重复内容"
+
+const (
+	// waveDistance SimHash 的汉明距离不超过它即视为近似; 换个联系方式、改几个字仍在范围内,
+	// 无关的两段文本一般相差 30 位上下
+	waveDistance = 10
+	// minWaveLength 归一化后太短的内容 ("谢谢分享" "收到") 多人同发属正常, 不参与比对
+	minWaveLength = 10
+	// maxWaveEntries 每个群保留的最近消息条数上限
+	maxWaveEntries = 500
+	// shingleSize SimHash 的分片长度; 中文没有空格分词, 按相邻两字切, 改一个字只影响两个分片
+	shingleSize = 2
+
+	// 候选关键词的长度范围: 太短容易误伤, 太长换一个字就匹配不上
+	minPhraseRunes = 4
+	maxPhraseRunes = 12
+	// phraseSourceRunes 求公共片段时每条副本最多取的字数, 控制计算量
+	phraseSourceRunes = 500
+)
+
+// waveCallbackPrefix "加入关键词"按钮的 callback_data 前缀, 后面直接跟候选词; 整体不能超过 64 字节
+const waveCallbackPrefix = "mdwave:"
+
+// maxCallbackBytes Telegram 对 callback_data 的长度限制
+const maxCallbackBytes = 64
+
+// SimHash 计算归一化文本的 64 位 SimHash: 相似的文本得到汉明距离很小的值
+func SimHash(normalized string) uint64 {
+	runes := []rune(normalized)
+	if len(runes) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	add := func(shingle []rune) {
+		h := fnv.New64a()
+		h.Write([]byte(string(shingle)))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	if len(runes) < shingleSize {
+		add(runes)
+	}
+	for i := 0; i+shingleSize <= len(runes); i++ {
+		add(runes[i : i+shingleSize])
+	}
+
+	var hash uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
+
+// waveTracker 按群记录最近的消息指纹
+type waveTracker struct {
+	mu    sync.Mutex
+	chats map[int64][]*waveEntry
+}
+
+// waveEntry 一条消息的指纹
+type waveEntry struct {
+	hash       uint64
+	normalized string
+	text       string // 原文, 成批清理时写进处置记录
+	senderID   int64
+	trusted    bool // 发送者是可信用户, 不计入账号数也不当作副本
+	message    *tgbotapi.Message
+	at         time.Time
+	window     time.Duration // 记录时本群的统计窗口, 定时清理按它判断是否过期
+	handled    bool          // 已被处置过 (无论哪条规则), 成批清理时跳过, 免得重复处罚
+}
+
+// waveMatch 一条消息与本群近期消息的比对结果
+type waveMatch struct {
+	entry     *waveEntry
+	users     int          // 发过近似内容的不同账号数 (含本条), 不含可信用户
+	copies    []*waveEntry // 近似的副本, 不含本条与可信用户的消息
+	threshold int          // 本群成批的账号数
+	window    time.Duration
+}
+
+var waves = &waveTracker{chats: make(map[int64][]*waveEntry)}
+
+// record 记下一条消息并按本群设置与时间窗内的消息比对; 内容过短时返回 nil
+func (t *waveTracker) record(message *tgbotapi.Message, senderID int64, text string, trusted bool, group core.ManagedGroup) *waveMatch {
+	normalized := Normalize(text)
+	if utf8.RuneCountInString(normalized) < minWaveLength {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	threshold, window := group.WaveLimit()
+	now := time.Now()
+	entry := &waveEntry{
+		hash:       SimHash(normalized),
+		normalized: normalized,
+		text:       text,
+		senderID:   senderID,
+		trusted:    trusted,
+		message:    message,
+		at:         now,
+		window:     window,
+	}
+	match := &waveMatch{entry: entry, threshold: threshold, window: window}
+	users := make(map[int64]bool)
+	if !trusted {
+		users[senderID] = true
+	}
+
+	chatID := message.Chat.ID
+	kept := t.chats[chatID][:0]
+	for _, other := range t.chats[chatID] {
+		// 编辑过的消息会再过一遍审核, 以新内容替换旧记录
+		if now.Sub(other.at) > window || other.message.MessageID == message.MessageID {
+			continue
+		}
+		kept = append(kept, other)
+		if !other.trusted && bits.OnesCount64(other.hash^entry.hash) <= waveDistance {
+			match.copies = append(match.copies, other)
+			users[other.senderID] = true
+		}
+	}
+	kept = append(kept, entry)
+	if len(kept) > maxWaveEntries {
+		kept = kept[len(kept)-maxWaveEntries:]
+	}
+	t.chats[chatID] = kept
+
+	match.users = len(users)
+	return match
+}
+
+// claim 把本条标记为已处置; withCopies 时一并认领尚未处置的副本并返回, 同一批副本只会被认领一次
+func (t *waveTracker) claim(match *waveMatch, withCopies bool) []*waveEntry {
+	if match == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	match.entry.handled = true
+	if !withCopies {
+		return nil
+	}
+	var claimed []*waveEntry
+	for _, other := range match.copies {
+		if !other.handled {
+			other.handled = true
+			claimed = append(claimed, other)
+		}
+	}
+	return claimed
+}
+
+// prune 清掉时间窗以外的记录, 由定时任务调用; 不再有人发言的群不会在 record 里被清理
+func (t *waveTracker) prune() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	removed := 0
+	for chatID, entries := range t.chats {
+		kept := entries[:0]
+		for _, entry := range entries {
+			if time.Since(entry.at) <= entry.window {
+				kept = append(kept, entry)
+			}
+		}
+		removed += len(entries) - len(kept)
+		if len(kept) == 0 {
+			delete(t.chats, chatID)
+		} else {
+			t.chats[chatID] = kept
+		}
+	}
+	return removed
+}
+
+// PruneWaveHistory 清理过期的消息指纹, 返回清理条数
+func PruneWaveHistory() int {
+	return waves.prune()
+}
+
+// recordWave 记录本条消息的指纹, 每条群消息都要记, 无论是否命中; trusted 表示发送者是可信用户
+func recordWave(message *tgbotapi.Message, senderID int64, text string, trusted bool) *waveMatch {
+	// 不受管或读不到设置时零值 ManagedGroup 给出默认阈值
+	group, _ := core.GroupSettings(message.Chat.ID)
+	return waves.record(message, senderID, text, trusted, group)
+}
+
+// verdict 比对结果对应的结论, 不够成批或本条出自可信用户时返回空结论
+func (m *waveMatch) verdict() Verdict {
+	if m == nil || m.entry.trusted || m.users < m.threshold {
+		return Verdict{}
+	}
+	mode := RuleMode(RuleIDDuplicate)
+	if mode == core.ModeOff {
+		return Verdict{}
+	}
+	return Verdict{
+		Hit:    true,
+		Rule:   ruleDuplicate,
+		Detail: fmt.Sprintf("%d 秒内 %d 个账号发送相似内容", int(m.window.Seconds()), m.users),
+		Shadow: mode == core.ModeShadow,
+	}
+}
+
+// sweepWave 撤回同一批里此前漏掉的副本并逐个处置发送者, 再把候选关键词推给管理员。
+// 只在首次成批时有副本可清: 之后的副本都已被认领, 只处置新来的那一条。
+func sweepWave(bot *tgbotapi.BotAPI, match *waveMatch, copies []*waveEntry, verdict Verdict) {
+	if len(copies) == 0 {
+		return
+	}
+	for _, entry := range copies {
+		enforce(bot, entry.message, entry.text, verdict, nil)
+	}
+	log.Printf("[Moderation] 群 %d 出现 %d 个账号的相似内容, 已一并撤回此前的 %d 条",
+		match.entry.message.Chat.ID, match.users, len(copies))
+
+	texts := []string{match.entry.normalized}
+	for _, entry := range match.copies {
+		texts = append(texts, entry.normalized)
+	}
+	if phrase := wavePhrase(texts); phrase != "" {
+		suggestKeyword(bot, match, phrase)
+	}
+}
+
+// wavePhrase 一批副本共有的最长片段, 截到候选关键词的长度上限; 太短时返回空串
+func wavePhrase(texts []string) string {
+	if len(texts) == 0 {
+		return ""
+	}
+	common := []rune(texts[0])
+	for _, text := range texts[1:] {
+		common = longestCommon(common, []rune(text))
+		if len(common) < minPhraseRunes {
+			return ""
+		}
+	}
+	if len(common) > maxPhraseRunes {
+		common = common[:maxPhraseRunes]
+	}
+	// callback_data 放不下时再截短; 汉字占 3 字节, 12 个字刚好放得下
+	for len(waveCallbackPrefix)+len(string(common)) > maxCallbackBytes {
+		common = common[:len(common)-1]
+	}
+	if len(common) < minPhraseRunes {
+		return ""
+	}
+	return string(common)
+}
+
+// longestCommon 两段文本的最长公共子串; 每段最多取 phraseSourceRunes 个字
+func longestCommon(a, b []rune) []rune {
+	if len(a) > phraseSourceRunes {
+		a = a[:phraseSourceRunes]
+	}
+	if len(b) > phraseSourceRunes {
+		b = b[:phraseSourceRunes]
+	}
+
+	// 滚动数组的动态规划: prev[j] 是 a[:i-1] 与 b[:j] 以末尾对齐的公共长度
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	bestLen, bestEnd := 0, 0
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				curr[j] = prev[j-1] + 1
+				if curr[j] > bestLen {
+					bestLen, bestEnd = curr[j], i
+				}
+			} else {
+				curr[j] = 0
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return a[bestEnd-bestLen : bestEnd]
+}
+
+// suggestKeyword 把候选关键词推给有词表权限的管理员, 附一键添加按钮
+func suggestKeyword(bot *tgbotapi.BotAPI, match *waveMatch, phrase string) {
+	var b strings.Builder
+	b.WriteString("📣 发现多账号成批发送的相似内容\n\n")
+	fmt.Fprintf(&b, "群组: %s\n", match.entry.message.Chat.Title)
+	fmt.Fprintf(&b, "账号数: %d，已全部撤回\n", match.users)
+	fmt.Fprintf(&b, "共有片段: %s\n\n", phrase)
+	b.WriteString("加入全局关键词后, 之后的同类内容不必再等多个账号出现。")
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("➕ 加入关键词", waveCallbackPrefix+phrase),
+	))
+	for _, adminID := range core.AdminsWith(core.PermKeywords) {
+		msg := tgbotapi.NewMessage(adminID, b.String())
+		msg.ReplyMarkup = keyboard
+		if _, err := bot.Send(msg); err != nil {
+			log.Printf("[Moderation] 推送候选关键词给管理员 %d 失败: %v", adminID, err)
+		}
+	}
+}
+
+// IsWaveCallback 判断回调是否为"加入关键词"按钮
+func IsWaveCallback(data string) bool {
+	return strings.HasPrefix(data, waveCallbackPrefix)
+}
+
+// HandleWaveCallback 处理"加入关键词"按钮点击。仅有词表权限的管理员可用
+func HandleWaveCallback(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery) {
+	if query.From == nil || !core.Can(query.From.ID, core.PermKeywords) {
+		answerCallback(bot, query.ID, "没有维护关键词的权限")
+		return
+	}
+	phrase := strings.TrimPrefix(query.Data, waveCallbackPrefix)
+	if phrase == "" {
+		answerCallback(bot, query.ID, "无效的操作")
+		return
+	}
+
+	added, err := core.DB.AddKeyword(phrase, core.SourceManual)
+	if err != nil {
+		log.Printf("[Moderation] 添加候选关键词 %q 失败: %v", phrase, err)
+		answerCallback(bot, query.ID, "操作失败")
+		return
+	}
+	result := "已加入关键词"
+	if !added {
+		result = "关键词已存在"
+	}
+	answerCallback(bot, query.ID, result)
+	log.Printf("[Moderation] 管理员 %d 采纳了候选关键词 %q", query.From.ID, phrase)
+
+	if query.Message != nil {
+		edit := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID, query.Message.Text+"\n\n✅ "+result)
+		if _, err := bot.Request(edit); err != nil {
+			log.Printf("[Moderation] 更新候选关键词消息失败: %v", err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package moderation
+
+import (
+	"math/bits"
+	"testing"
+	"time"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TestSimHash 只换了联系方式的广告在阈值内, 无关的文本相差很远
+func TestSimHash(t *testing.T) {
+	ad := SimHash(Normalize("出售各类水果手机号码，价格优惠，需要的请私聊联系我 @fruit123"))
+	variant := SimHash(Normalize("出售各类水果手机号码，价格优惠，需要的请私聊联系我 @fruit456"))
+	chat := SimHash(Normalize("今天的天气真不错，我们一起去公园散步吧，顺便买点吃的"))
+
+	if dist := bits.OnesCount64(ad ^ variant); dist > waveDistance {
+		t.Errorf("只换联系方式的副本相差 %d 位, 超过阈值 %d", dist, waveDistance)
+	}
+	if dist := bits.OnesCount64(ad ^ chat); dist <= waveDistance {
+		t.Errorf("无关文本只相差 %d 位", dist)
+	}
+}
+
+func waveMessage(id int, userID int64) *tgbotapi.Message {
+	return &tgbotapi.Message{MessageID: id, Chat: &tgbotapi.Chat{ID: -100}, From: &tgbotapi.User{ID: userID}}
+}
+
+// TestWaveTracker 不同账号的近似内容累计到阈值才成批; 同一批副本只会被认领一次
+func TestWaveTracker(t *testing.T) {
+	useTempDB(t)
+	tracker := &waveTracker{chats: make(map[int64][]*waveEntry)}
+	ad := "出售各类水果手机号码，价格优惠，需要的请私聊联系我"
+
+	if m := tracker.record(waveMessage(1, 1), 1, "收到，谢谢", false, core.ManagedGroup{}); m != nil {
+		t.Errorf("过短的内容不应参与比对: %+v", m)
+	}
+
+	// 同一个人发三次是单人刷屏, 不算成批
+	for id := 2; id <= 4; id++ {
+		if v := tracker.record(waveMessage(id, 1), 1, ad, false, core.ManagedGroup{}).verdict(); v.Hit {
+			t.Fatalf("同一账号重复发送不应算成批: %+v", v)
+		}
+	}
+	tracker.record(waveMessage(5, 2), 2, ad+" @a", false, core.ManagedGroup{})
+	match := tracker.record(waveMessage(6, 3), 3, ad+" @b", false, core.ManagedGroup{})
+	if v := match.verdict(); !v.Hit || v.Rule != ruleDuplicate || match.users != 3 {
+		t.Fatalf("三个账号发近似内容 = %+v (%d 个账号)", v, match.users)
+	}
+
+	if copies := tracker.claim(match, true); len(copies) != 4 {
+		t.Errorf("首次认领应得到此前的 4 条副本, 实际 %d 条", len(copies))
+	}
+	next := tracker.record(waveMessage(7, 4), 4, ad, false, core.ManagedGroup{})
+	if copies := tracker.claim(next, true); len(copies) != 0 {
+		t.Errorf("已认领的副本不应再次认领: %d 条", len(copies))
+	}
+
+	// 编辑过的消息以新内容替换旧记录, 不会和自己比对
+	edited := tracker.record(waveMessage(7, 4), 4, "今天的天气真不错，我们一起去公园散步吧", false, core.ManagedGroup{})
+	if len(edited.copies) != 0 {
+		t.Errorf("编辑后的消息不应和旧内容比对: %d 条副本", len(edited.copies))
+	}
+
+	core.DB.SetRuleMode(RuleIDDuplicate, core.ModeShadow)
+	if v := match.verdict(); !v.Hit || !v.Shadow {
+		t.Errorf("试行模式下 = %+v", v)
+	}
+}
+
+// TestWavePhrase 候选词取各副本共有的最长片段, 且能放进 callback_data
+// TestWaveTrusted 可信用户的消息不计入账号数、不当作副本, 自己也不会被判成批
+func TestWaveTrusted(t *testing.T) {
+	useTempDB(t)
+	tracker := &waveTracker{chats: make(map[int64][]*waveEntry)}
+	ad := "出售各类水果手机号码，价格优惠，需要的请私聊联系我"
+
+	tracker.record(waveMessage(1, 1), 1, ad, false, core.ManagedGroup{})
+	tracker.record(waveMessage(2, 2), 2, ad+" 这是骗子", true, core.ManagedGroup{})
+	match := tracker.record(waveMessage(3, 3), 3, ad, false, core.ManagedGroup{})
+	if v := match.verdict(); v.Hit || match.users != 2 || len(match.copies) != 1 {
+		t.Fatalf("可信用户不应计入账号数: %+v (%d 个账号, %d 条副本)", v, match.users, len(match.copies))
+	}
+
+	quoted := tracker.record(waveMessage(4, 4), 4, ad, true, core.ManagedGroup{})
+	if v := quoted.verdict(); v.Hit {
+		t.Errorf("可信用户引用广告不应判成批: %+v", v)
+	}
+	if match := tracker.record(waveMessage(5, 5), 5, ad, false, core.ManagedGroup{}); !match.verdict().Hit || len(match.copies) != 2 {
+		t.Errorf("第三个普通账号应当成批, 且副本不含可信用户的消息: %d 条副本", len(match.copies))
+	}
+}
+
+// TestWaveGroupLimit 账号数与时间窗按群设置
+func TestWaveGroupLimit(t *testing.T) {
+	useTempDB(t)
+	tracker := &waveTracker{chats: make(map[int64][]*waveEntry)}
+	ad := "出售各类水果手机号码，价格优惠，需要的请私聊联系我"
+	group := core.ManagedGroup{WaveAccounts: 4, WaveSeconds: 60}
+
+	for id := 1; id <= 3; id++ {
+		if v := tracker.record(waveMessage(id, int64(id)), int64(id), ad, false, group).verdict(); v.Hit {
+			t.Fatalf("设为 4 个账号时第 %d 个不应成批", id)
+		}
+	}
+	match := tracker.record(waveMessage(4, 4), 4, ad, false, group)
+	if v := match.verdict(); !v.Hit || v.Detail != "60 秒内 4 个账号发送相似内容" {
+		t.Fatalf("第 4 个账号 = %+v", v)
+	}
+
+	// 超出本群时间窗的记录不再参与比对, 定时清理也按记录时的时间窗判断
+	for _, entry := range tracker.chats[-100] {
+		entry.at = entry.at.Add(-2 * time.Minute)
+	}
+	if match := tracker.record(waveMessage(5, 5), 5, ad, false, group); match.users != 1 {
+		t.Errorf("时间窗外的记录仍被比对: %d 个账号", match.users)
+	}
+	tracker.chats[-100][0].at = time.Now().Add(-2 * time.Minute)
+	if removed := tracker.prune(); removed != 1 {
+		t.Errorf("prune 清理 %d 条, 期望 1", removed)
+	}
+}
+
+func TestWavePhrase(t *testing.T) {
+	texts := []string{
+		Normalize("出售水果手机号码，需要私聊 @a"),
+		Normalize("【特价】出售水果手机号码，要的私聊"),
+		Normalize("出售水果手机号码 联系 @b"),
+	}
+	if got := wavePhrase(texts); got != "出售水果手机号码" {
+		t.Errorf("wavePhrase = %q", got)
+	}
+
+	long := Normalize("这是一段非常长的完全相同的广告内容用来测试截断的效果")
+	got := wavePhrase([]string{long, long})
+	if n := len([]rune(got)); n != maxPhraseRunes || len(waveCallbackPrefix+got) > maxCallbackBytes {
+		t.Errorf("截断后的候选词 %q (%d 字)", got, n)
+	}
+
+	if got := wavePhrase([]string{Normalize("完全不同的内容甲"), Normalize("另外一段文字乙")}); got != "" {
+		t.Errorf("没有足够长的公共片段时应为空, 实际 %q", got)
+	}
+}
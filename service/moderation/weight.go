@@ -0,0 +1,77 @@
+package moderation
+
+// 规则权重的查询。权重本身的存储与取值范围见 core/weight.go。
+import (
+	"log"
+	"sort"
+
+	"SunaiForum-Bot/core"
+)
+
+// 词表类规则的 ID; 这几种规则的执行模式按词条各自设置, 权重则按命中方式整体设置
+const (
+	RuleIDKeyword     = "keyword"
+	RuleIDRegex       = "regex"
+	RuleIDCombo       = "combo"
+	RuleIDDisplayName = "displayname"
+)
+
+// weightedRules 可设置权重的规则 ID 到展示名的映射; 新增规则要在这里登记, 否则只能按默认权重记分
+var weightedRules = map[string]string{
+	RuleIDZeroWidth:     ruleZeroWidth,
+	RuleIDObfuscated:    ruleObfuscated,
+	RuleIDKeyword:       ruleKeyword,
+	RuleIDRegex:         ruleRegex,
+	RuleIDCombo:         ruleCombo,
+	RuleIDDisplayName:   ruleDisplayName,
+	RuleIDFlooding:      ruleFlooding,
+	RuleIDAI:            ruleAI,
+	RuleIDSourceChannel: ruleSourceChannel,
+	RuleIDChannelName:   ruleChannelName,
+	RuleIDDomain:        ruleDomainBlock,
+	RuleIDMediaHash:     ruleMediaHash,
+	RuleIDDuplicate:     ruleDuplicate,
+}
+
+// weightRuleIDs 展示名到 ID 的反查表; 结论里只带展示名
+var weightRuleIDs = func() map[string]string {
+	ids := make(map[string]string, len(weightedRules))
+	for id, label := range weightedRules {
+		ids[label] = id
+	}
+	return ids
+}()
+
+// WeightedRule 返回可设置权重的规则展示名, 第二个返回值表示 ID 是否存在
+func WeightedRule(id string) (string, bool) {
+	label, ok := weightedRules[id]
+	return label, ok
+}
+
+// WeightedRuleIDs 按字母序列出全部可设置权重的规则 ID
+func WeightedRuleIDs() []string {
+	ids := make([]string, 0, len(weightedRules))
+	for id := range weightedRules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// RuleWeight 按规则 ID 读取权重; 查库失败按默认 1 分处理, 与引入权重之前的行为一致
+func RuleWeight(id string) int {
+	weight, err := core.DB.GetRuleWeight(id)
+	if err != nil {
+		log.Printf("[Moderation] 读取规则 %s 的权重失败, 按默认处理: %v", id, err)
+	}
+	return weight
+}
+
+// verdictWeight 结论对应的规则权重; 未登记的规则按默认权重
+func verdictWeight(verdict Verdict) int {
+	id, ok := weightRuleIDs[verdict.Rule]
+	if !ok {
+		return 1
+	}
+	return RuleWeight(id)
+}
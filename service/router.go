@@ -2,6 +2,9 @@ package service
 
 // 消息路由: 按来源 (管理员私聊 / 群聊) 把更新分发到对应处理器
 import (
+	"fmt"
+	"log"
+
 	"SunaiForum-Bot/core"
 	"SunaiForum-Bot/service/ai_review"
 	"SunaiForum-Bot/service/binance"
@@ -16,27 +19,41 @@ import (
 // handleUpdate 分流一条更新。
 // 编辑后的消息同样要过审核 —— 先发正常内容再编辑成广告是常见的规避手法。
 func handleUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update, rateLimiter *core.RateLimiter) {
-	// 管理员在处置通知上点"恢复"按钮
+	updatesTotal.WithLabelValues(updateType(update)).Inc()
+
+	// 管理员在处置通知上点"恢复"按钮或采纳候选关键词, 或新成员点入群验证题
 	if query := update.CallbackQuery; query != nil {
-		if moderation.IsUndoCallback(query.Data) {
+		switch {
+		case moderation.IsUndoCallback(query.Data):
 			moderation.HandleUndoCallback(bot, query)
+		case moderation.IsWaveCallback(query.Data):
+			moderation.HandleWaveCallback(bot, query)
+		case group_member_management.IsCaptchaCallback(query.Data):
+			group_member_management.HandleCaptchaCallback(bot, query)
 		}
 		return
 	}
 
 	if edited := update.EditedMessage; edited != nil {
-		if edited.From != nil && edited.Chat.Type != "private" && !core.IsAdmin(edited.From.ID) {
-			moderation.CheckAndFilter(bot, edited)
+		if isManagedChat(edited.Chat) && needsModeration(edited) {
+			moderation.CheckAndFilter(bot, edited, moderation.ObserveSender(edited))
 		}
 		return
 	}
 
-	// From 为 nil 的情况确实存在 (匿名管理员、频道身份发言), 不能直接取 ID
-	if update.Message == nil || update.Message.From == nil {
+	if update.Message == nil {
 		return
 	}
 	message := update.Message
 
+	// From 为 nil 的情况确实存在 (匿名管理员、频道身份发言), 不能直接取 ID; 这类消息只做审核, 不响应命令
+	if message.From == nil {
+		if message.SenderChat != nil && isManagedChat(message.Chat) && needsModeration(message) {
+			moderation.CheckAndFilter(bot, message, moderation.ObserveSender(message))
+		}
+		return
+	}
+
 	if message.Chat.Type == "private" {
 		if core.IsAdmin(message.From.ID) {
 			command.HandleAdmin(bot, message)
@@ -44,14 +61,58 @@ func handleUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update, rateLimiter *cor
 		return
 	}
 
+	// 未登记的群一律不理会: 任何人把机器人拉进自己的群, 都不能让它开始删消息封人
+	if !isManagedChat(message.Chat) {
+		reportUnmanagedGroup(bot, message)
+		return
+	}
+
 	processMessage(bot, message, rateLimiter)
 }
 
+// needsModeration 判断消息是否需要审核: 管理员本人、匿名管理员与关联频道的自动转发除外
+func needsModeration(message *tgbotapi.Message) bool {
+	if moderation.ExemptSender(message) {
+		return false
+	}
+	// 以频道身份发言时 From 是 Telegram 的占位账号, 不代表真人, 不能拿去查管理员名册
+	if moderation.SentAsChannel(message) {
+		return true
+	}
+	return message.From != nil && !core.IsAdmin(message.From.ID)
+}
+
+// isManagedChat 判断消息所在的群是否已登记为受管群; 私聊与频道一律不算
+func isManagedChat(chat *tgbotapi.Chat) bool {
+	if chat == nil || chat.Type == "private" || chat.Type == "channel" {
+		return false
+	}
+	_, ok := core.GroupSettings(chat.ID)
+	return ok
+}
+
+// reportUnmanagedGroup 机器人被拉进未登记的群时通知管理员。
+// 群 ID 在客户端里不好查, 顺手告诉管理员, 想接管时直接照抄命令即可。
+func reportUnmanagedGroup(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+	for _, member := range message.NewChatMembers {
+		if member.ID != bot.Self.ID {
+			continue
+		}
+		log.Printf("[MessageHandler] 机器人被拉进未登记的群 %d (%s), 不做任何处理", message.Chat.ID, message.Chat.Title)
+		core.NotifyAdmin(bot, fmt.Sprintf("ℹ️ 机器人被拉进了未登记的群「%s」(ID: %d)，目前不会做任何处理。\n如需管理该群，所有者可发送：\n/addgroup %d",
+			message.Chat.Title, message.Chat.ID, message.Chat.ID))
+		return
+	}
+}
+
 // processMessage 处理群消息。
 //
 // 内容审核**不受限流约束**: 限流的本意是防止机器人被消息洪水拖垮, 但如果连审核都跳过,
 // 刷屏时反而是广告全部漏过。因此限流只作用于机器人的主动响应 (行情查询、自动回复)。
 func processMessage(bot *tgbotapi.BotAPI, message *tgbotapi.Message, rateLimiter *core.RateLimiter) {
+	// 封禁名单上的账号一进群就踢, 其余新成员按群设置出入群验证; 入群通知本身仍按群设置清理
+	group_member_management.HandleJoins(bot, message)
+
 	// 群务通知 (加入/退出/改群名) 没有正文, 清理掉即可, 不必走后续任何处理
 	if group_member_management.CleanServiceMessage(bot, message) {
 		return
@@ -62,13 +123,15 @@ func processMessage(bot *tgbotapi.BotAPI, message *tgbotapi.Message, rateLimiter
 		return
 	}
 
-	if !core.IsAdmin(message.From.ID) {
+	if needsModeration(message) {
+		// 发言计数对每条受审核的消息都做, 与 AI 是否开启无关; 审核与 AI 共用这一份统计
+		stats := moderation.ObserveSender(message)
 		// 确定性规则先跑, 命中即拦截, 不产生 AI 调用
-		if moderation.CheckAndFilter(bot, message) {
+		if moderation.CheckAndFilter(bot, message, stats) {
 			return
 		}
 		// 未命中的交给 AI 复核; 内部自行判断是否值得调用, 且异步执行不阻塞本函数
-		ai_review.MaybeReview(bot, message)
+		ai_review.MaybeReview(bot, message, stats)
 	}
 
 	if !rateLimiter.Allow() {
@@ -8,6 +8,7 @@ import (
 
 	"SunaiForum-Bot/core"
 	"SunaiForum-Bot/service/ai_review"
+	"SunaiForum-Bot/service/group_member_management"
 	"SunaiForum-Bot/service/moderation"
 )
 
@@ -18,16 +19,29 @@ const (
 	repeatHistoryTTL = time.Hour
 	// strikeTTL 违规计分多久无新增即归零, 相当于给用户的自动改过窗口
 	strikeTTL = 30 * 24 * time.Hour
-	// userStatsTTL 发言统计多久不活跃即清除; 清除后该用户再发言会重新按新用户走 AI 审核
+	// userStatsTTL 进群后从没发过言的统计多久不活跃即清除; 发过言的保留, 见 core.CleanupStaleUserStats
 	userStatsTTL = 90 * 24 * time.Hour
 	// actionTTL 处置记录保留时长, 过期后对应的撤销按钮失效
 	actionTTL = 30 * 24 * time.Hour
+	// shortLinkTTL 短链展开结果的保留时长; 短链很少改指向, 过期只是为了不让表无限增长
+	shortLinkTTL = 90 * 24 * time.Hour
+	// topicTTL 消息话题归属的保留时长; Telegram 只允许编辑 48 小时内的消息, 再往后用不到
+	topicTTL = 48 * time.Hour
+	// shadowDigestCheckInterval 检查是否该发试行汇总的间隔; 汇总本身一天一次, 由上次汇总时间决定
+	shadowDigestCheckInterval = time.Hour
+	// captchaSweepInterval 清理超时入群验证的间隔; 实际踢人时间最多比时限晚这么久
+	captchaSweepInterval = 15 * time.Second
+	// raidSweepInterval 检查突袭模式是否该解除的间隔
+	raidSweepInterval = time.Minute
 )
 
 // StartScheduledTasks 拉起全部后台定时任务, 立即返回
 func StartScheduledTasks() {
 	log.Println("[Scheduler] 启动定时任务")
 	go periodicCleanup()
+	go periodicShadowDigest()
+	go periodicCaptchaSweep()
+	go periodicRaidSweep()
 	ai_review.StartCuration(core.Bot)
 }
 
@@ -42,6 +56,41 @@ func periodicCleanup() {
 	}
 }
 
+// periodicShadowDigest 每小时看一次是否到了试行汇总时间。
+// 上次汇总时间落库, 按小时检查而不是每 24 小时一个 ticker, 是为了重启频繁时汇总也不会一直被推迟。
+func periodicShadowDigest() {
+	ticker := time.NewTicker(shadowDigestCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		moderation.SendShadowDigest(core.Bot)
+	}
+}
+
+// periodicCaptchaSweep 定时移出超时未通过入群验证的成员。
+// 启动时先跑一轮, 把停机期间到期的验证一并处理掉。
+func periodicCaptchaSweep() {
+	ticker := time.NewTicker(captchaSweepInterval)
+	defer ticker.Stop()
+
+	group_member_management.SweepExpiredChallenges(core.Bot)
+	for range ticker.C {
+		if swept := group_member_management.SweepExpiredChallenges(core.Bot); swept > 0 {
+			log.Printf("[Scheduler] 已移出 %d 个入群验证超时的成员", swept)
+		}
+	}
+}
+
+// periodicRaidSweep 定时解除过了冷却期的突袭模式
+func periodicRaidSweep() {
+	ticker := time.NewTicker(raidSweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		moderation.SweepRaids(core.Bot)
+	}
+}
+
 // runCleanup 跑一轮全部清理动作
 func runCleanup() {
 	snapshotDatabase()
@@ -49,10 +98,20 @@ func runCleanup() {
 	cleanupStaleStrikes()
 	cleanupRows("陈旧发言统计", func() (int64, error) { return core.DB.CleanupStaleUserStats(userStatsTTL) })
 	cleanupRows("过期处置记录", func() (int64, error) { return core.DB.CleanupOldActions(actionTTL) })
+	cleanupRows("短链展开缓存", func() (int64, error) { return core.DB.CleanupShortLinks(shortLinkTTL) })
 
 	if removed := moderation.PruneRepeatHistory(repeatHistoryTTL); removed > 0 {
 		log.Printf("[Scheduler] 已清理 %d 个不活跃用户的刷屏记录", removed)
 	}
+	if removed := moderation.PruneFloodHistory(); removed > 0 {
+		log.Printf("[Scheduler] 已清理 %d 条过期的发言频率与入群记录", removed)
+	}
+	if removed := moderation.PruneWaveHistory(); removed > 0 {
+		log.Printf("[Scheduler] 已清理 %d 条过期的重复内容指纹", removed)
+	}
+	if removed := core.PruneTopics(topicTTL); removed > 0 {
+		log.Printf("[Scheduler] 已清理 %d 条话题归属记录", removed)
+	}
 }
 
 // snapshotDatabase 生成每日数据库快照; 失败只告警, 不影响其余清理任务
@@ -0,0 +1,200 @@
+package service
+
+// 以 webhook 接收更新, 替代长轮询; 设置 WEBHOOK_URL 即开启。
+//
+// Telegram 把每条更新 POST 到 WEBHOOK_URL, 反向代理转发到 WEBHOOK_ADDR。这里只做校验与解码, 随即回 200,
+// 实际处理交给固定数量的 worker: 处理一条更新要等数据库和 Bot API, 让 Telegram 的连接一直挂着会拖慢后续推送。
+// 处理走与长轮询相同的 handleUpdate, 两种模式下机器人的行为完全一致。
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// webhookQueueSize 等待处理的更新上限; 满了回 503, Telegram 会稍后重投
+	webhookQueueSize = 256
+	// webhookMaxBody 单条更新的请求体上限, 正常的更新远小于此
+	webhookMaxBody = 1 << 20
+	// webhookShutdownTimeout 退出时等待进行中的请求与队列处理完的时长
+	webhookShutdownTimeout = 10 * time.Second
+	// secretTokenHeader Telegram 回传 secret_token 的请求头
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+)
+
+// webhookServer 接收 Telegram 推送的更新并交给 worker 处理
+type webhookServer struct {
+	path   string
+	secret string
+	queue  chan tgbotapi.Update
+	handle func(tgbotapi.Update)
+	wg     sync.WaitGroup
+}
+
+// newWebhookServer 构造并启动 worker; handle 在 worker 里调用, 需自行兜住 panic
+func newWebhookServer(path, secret string, workers, queueSize int, handle func(tgbotapi.Update)) *webhookServer {
+	s := &webhookServer{
+		path:   path,
+		secret: secret,
+		queue:  make(chan tgbotapi.Update, queueSize),
+		handle: handle,
+	}
+	s.wg.Add(workers)
+	for range workers {
+		go func() {
+			defer s.wg.Done()
+			for update := range s.queue {
+				s.handle(update)
+			}
+		}()
+	}
+	return s
+}
+
+// ServeHTTP 校验来源并把更新放进队列
+func (s *webhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != s.path {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// 地址是公开的, 不带正确口令的请求一律视为伪造
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(secretTokenHeader)), []byte(s.secret)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, webhookMaxBody))
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	update, err := core.DecodeUpdate(raw)
+	if err != nil {
+		// 与长轮询一致: 解不出来的跳过。回错误码只会让 Telegram 反复重投同一条
+		log.Printf("[Webhook] 解码更新失败, 跳过: %v", err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	select {
+	case s.queue <- update:
+		w.WriteHeader(http.StatusOK)
+	default:
+		log.Printf("[Webhook] 处理队列已满, 更新 %d 交由 Telegram 稍后重投", update.UpdateID)
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}
+}
+
+// close 停止接收并等 worker 处理完队列里剩下的更新; 调用前必须确保 ServeHTTP 不会再被调用
+func (s *webhookServer) close() {
+	close(s.queue)
+	s.wg.Wait()
+}
+
+// runWebhook 在 WEBHOOK_ADDR 上监听并向 Telegram 注册 webhook, 阻塞到收到退出信号。
+// 先监听再注册, 注册成功时已经能接住第一条推送; 退出时先注销, Telegram 把之后的更新攒着, 下次启动再推。
+func runWebhook(bot *tgbotapi.BotAPI, rateLimiter *core.RateLimiter) error {
+	link, err := url.Parse(core.WebhookURL)
+	if err != nil {
+		return fmt.Errorf("解析 WEBHOOK_URL 失败: %w", err)
+	}
+	path := link.Path
+	if path == "" {
+		path = "/"
+	}
+	secret := core.WebhookSecret
+	if secret == "" {
+		// 每次启动都会重新注册, 随机口令足够用, 省得运维再配一项
+		if secret, err = randomSecret(); err != nil {
+			return err
+		}
+	}
+
+	webhook := newWebhookServer(path, secret, core.WebhookWorkers, webhookQueueSize, func(update tgbotapi.Update) {
+		processUpdate(bot, update, rateLimiter)
+	})
+	listener, err := net.Listen("tcp", core.WebhookAddr)
+	if err != nil {
+		return fmt.Errorf("监听 %s 失败: %w", core.WebhookAddr, err)
+	}
+	server := &http.Server{Handler: webhook, ReadHeaderTimeout: 10 * time.Second}
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+	log.Printf("[Webhook] 监听 %s, 路径 %s, %d 个 worker", core.WebhookAddr, path, core.WebhookWorkers)
+
+	if err := setWebhook(bot, core.WebhookURL, secret); err != nil {
+		server.Close()
+		return fmt.Errorf("注册 webhook 失败: %w", err)
+	}
+	log.Printf("[Webhook] 已向 Telegram 注册 %s", core.WebhookURL)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		log.Println("[Webhook] 收到退出信号, 正在停止...")
+	case serveErr = <-served:
+		log.Printf("[Webhook] 监听意外停止: %v", serveErr)
+	}
+
+	if _, err := bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		log.Printf("[Webhook] 注销 webhook 失败: %v", err)
+	} else {
+		log.Println("[Webhook] 已注销 webhook")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		// 还有请求没处理完就不能关队列, 否则它们往已关闭的通道里送会 panic; 直接退出, 未处理的交给 Telegram 重投
+		log.Printf("[Webhook] 等待进行中的请求超时: %v", err)
+	} else {
+		webhook.close()
+	}
+
+	if serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+		return serveErr
+	}
+	return nil
+}
+
+// setWebhook 注册 webhook。所用的 telegram-bot-api 版本早于 secret_token 参数, 只能自己拼参数;
+// 不设 allowed_updates, 与长轮询一样沿用 Telegram 的默认值
+func setWebhook(bot *tgbotapi.BotAPI, link, secret string) error {
+	params := tgbotapi.Params{"url": link, "secret_token": secret}
+	_, err := bot.MakeRequest("setWebhook", params)
+	return err
+}
+
+// randomSecret 生成随机的 secret_token, 十六进制只含 Telegram 允许的字符
+func randomSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成 webhook 口令失败: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"SunaiForum-Bot/core"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const testSecret = "webhook-test-secret"
+
+// collector 收集 worker 处理过的更新
+type collector struct {
+	mu      sync.Mutex
+	updates []tgbotapi.Update
+}
+
+func (c *collector) handle(update tgbotapi.Update) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.updates = append(c.updates, update)
+}
+
+// postFixture 把 testdata 里的一条更新推给本地监听
+func postFixture(t *testing.T, server *httptest.Server, path, name, secret string) int {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("读取 %s 失败: %v", name, err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, server.URL+path, strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(secretTokenHeader, secret)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("推送 %s 失败: %v", name, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// TestWebhookDeliversFixtures 带正确口令的更新全部交给 worker, 解码与长轮询一致 (话题 ID 不丢)
+func TestWebhookDeliversFixtures(t *testing.T) {
+	var got collector
+	webhook := newWebhookServer("/telegram", testSecret, 2, 8, got.handle)
+	server := httptest.NewServer(webhook)
+	defer server.Close()
+
+	fixtures := []string{"webhook_message.json", "webhook_edited.json", "webhook_callback.json"}
+	for _, name := range fixtures {
+		if code := postFixture(t, server, "/telegram", name, testSecret); code != http.StatusOK {
+			t.Errorf("推送 %s = %d, 期望 200", name, code)
+		}
+	}
+	server.Close()
+	webhook.close()
+
+	if len(got.updates) != len(fixtures) {
+		t.Fatalf("处理了 %d 条更新, 期望 %d", len(got.updates), len(fixtures))
+	}
+	for _, update := range got.updates {
+		switch update.UpdateID {
+		case 900001:
+			if update.Message == nil || update.Message.Text != "大家好" || core.MessageTopic(update.Message) != 77 {
+				t.Errorf("普通消息解码不对: %+v", update.Message)
+			}
+		case 900002:
+			if update.EditedMessage == nil || update.EditedMessage.EditDate == 0 {
+				t.Errorf("编辑消息解码不对: %+v", update.EditedMessage)
+			}
+		case 900003:
+			if update.CallbackQuery == nil || update.CallbackQuery.Data != "undo:12" {
+				t.Errorf("按钮回调解码不对: %+v", update.CallbackQuery)
+			}
+		default:
+			t.Errorf("多出一条更新 %d", update.UpdateID)
+		}
+	}
+}
+
+// TestWebhookRejectsForgedRequests 口令不对、路径或方法不对的请求都不进队列
+func TestWebhookRejectsForgedRequests(t *testing.T) {
+	var got collector
+	webhook := newWebhookServer("/telegram", testSecret, 1, 8, got.handle)
+	server := httptest.NewServer(webhook)
+	defer server.Close()
+
+	if code := postFixture(t, server, "/telegram", "webhook_message.json", ""); code != http.StatusUnauthorized {
+		t.Errorf("不带口令 = %d, 期望 401", code)
+	}
+	if code := postFixture(t, server, "/telegram", "webhook_message.json", "wrong"); code != http.StatusUnauthorized {
+		t.Errorf("口令错误 = %d, 期望 401", code)
+	}
+	if code := postFixture(t, server, "/other", "webhook_message.json", testSecret); code != http.StatusNotFound {
+		t.Errorf("路径不对 = %d, 期望 404", code)
+	}
+	resp, err := server.Client().Get(server.URL + "/telegram")
+	if err != nil {
+		t.Fatalf("GET 失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET = %d, 期望 405", resp.StatusCode)
+	}
+
+	server.Close()
+	webhook.close()
+	if len(got.updates) != 0 {
+		t.Errorf("被拒的请求不应处理, 实际处理了 %d 条", len(got.updates))
+	}
+}
+
+// TestWebhookQueueFull 队列满时回 503 让 Telegram 重投, 而不是卡住连接
+func TestWebhookQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	webhook := newWebhookServer("/", testSecret, 1, 1, func(tgbotapi.Update) {
+		started <- struct{}{}
+		<-release
+	})
+	server := httptest.NewServer(webhook)
+	defer server.Close()
+
+	// 第一条占住唯一的 worker, 第二条占满队列, 第三条无处可放
+	postFixture(t, server, "/", "webhook_message.json", testSecret)
+	<-started
+	postFixture(t, server, "/", "webhook_edited.json", testSecret)
+	if code := postFixture(t, server, "/", "webhook_callback.json", testSecret); code != http.StatusServiceUnavailable {
+		t.Errorf("队列已满 = %d, 期望 503", code)
+	}
+
+	close(release)
+	server.Close()
+	webhook.close()
+}